@@ -46,6 +46,13 @@ var (
 	enableProfiling           = flag.Bool("enable-profiling", false, "enable the golang pprof at port 6060")
 	informerResyncDurationSec = flag.Int("informer-resync-duration-sec", 1800, "informer resync duration in seconds")
 	fuseSocketDir             = flag.String("fuse-socket-dir", "/sockets", "FUSE socket directory")
+	mountPropagation          = flag.String("mount-propagation", "", "The mount propagation mode applied to the gcsfuse mount on the target path, must be one of None, HostToContainer or Bidirectional. The default is empty string, which keeps the propagation unchanged.")
+	disableReadAheadTuning    = flag.Bool("disable-readahead-tuning", false, "skip the read_ahead_kb bdi adjustment of the gcsfuse mounts, for the nodes whose kernel policies forbid writing the bdi knobs")
+	fuseAllowRoot             = flag.Bool("fuse-allow-root", false, "permit the volumes to use the allow_root mount option, which restricts the access to the gcsfuse mounts to root")
+	fuseProtocolVersion       = flag.String("fuse-protocol-version", "", "The FUSE kernel protocol version of the nodes in the form of 7.<minor>, which decides the fuse options passed to gcsfuse. The default is empty string, which detects the version from the node kernel release.")
+	fuseSecurityRelaxations   = flag.String("fuse-allowed-security-relaxations", "", "comma separated list of the security relaxations the volumes may request, supported values are suid and dev, which lift the default nosuid and nodev mount options, disable_writeback_throttle, which lifts the bdi writeback limits of the mount, and defer_permissions, which skips the file mode checks of the mount. The default is empty string, which keeps all the gcsfuse mounts nosuid and nodev with the default writeback limits and file mode checks")
+	unmountBusyTimeout        = flag.Duration("unmount-busy-timeout", 10*time.Second, "How long NodeUnpublishVolume retries the unmount of a busy target path with backoff before lazily unmounting it. Zero disables the retries, and the unmount of a busy target path fails.")
+	enableTopology            = flag.Bool("enable-topology", false, "restrict the volumes provisioned for single region buckets to the nodes in the bucket region, and advertise the node region. The external-provisioner must run with --feature-gates=Topology=true")
 	metricsEndpoint           = flag.String("metrics-endpoint", "", "The TCP network address where the Prometheus metrics endpoint will listen (example: `:8080`). The default is empty string, which means that the metrics endpoint is disabled.")
 
 	// These are set at compile time.
@@ -103,7 +110,7 @@ func main() {
 		clientset.ConfigurePodLister(*nodeID)
 		clientset.ConfigureNodeLister(*nodeID)
 
-		mounter, err = csimounter.New("", *fuseSocketDir)
+		mounter, err = csimounter.New("", *fuseSocketDir, *disableReadAheadTuning, *fuseAllowRoot, *fuseSecurityRelaxations, *fuseProtocolVersion)
 		if err != nil {
 			klog.Fatalf("Failed to prepare CSI mounter: %v", err)
 		}
@@ -125,6 +132,9 @@ func main() {
 		Mounter:               mounter,
 		K8sClients:            clientset,
 		MetricsManager:        mm,
+		MountPropagation:      *mountPropagation,
+		UnmountBusyTimeout:    *unmountBusyTimeout,
+		EnableTopology:        *enableTopology,
 	}
 
 	gcfsDriver, err := driver.NewGCSDriver(config)
@@ -25,6 +25,7 @@ import (
 	"strings"
 	"syscall"
 
+	metadataprefetch "github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/metadata_prefetch"
 	"k8s.io/klog/v2"
 )
 
@@ -32,7 +33,24 @@ const (
 	mountPathsLocation = "/volumes/"
 )
 
+var readyFile = flag.String("ready-file", "", "The file created once the objects listed in the prefetch manifests are prefetched and the cache barrier files are cached, which the readiness probe checks.")
+
+// stringSliceFlag collects the values of a flag set more than once.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+
+	return nil
+}
+
 func main() {
+	var cacheBarrierFiles stringSliceFlag
+	flag.Var(&cacheBarrierFiles, "cache-barrier-file", "A file to load into the gcsfuse file cache before the container reports ready, relative to the objects dir, e.g. my-volume/models/model.bin. Can be set more than once.")
 	klog.InitFlags(nil)
 	flag.Parse()
 
@@ -51,6 +69,29 @@ func main() {
 		os.Exit(0) // Exit gracefully
 	}()
 
+	// Warm the gcsfuse file cache with the objects listed in the prefetch manifests and the cache barrier files of the volumes.
+	// The reads stop when the Pod terminates.
+	go func() {
+		metadataprefetch.PrefetchObjects(ctx, metadataprefetch.ObjectsPath, metadataprefetch.ManifestsPath)
+		if err := metadataprefetch.WaitForCacheBarriers(ctx, metadataprefetch.ObjectsPath, cacheBarrierFiles, metadataprefetch.CacheBarrierPollInterval); err != nil {
+			klog.Errorf("failed to wait for the cache barrier files: %v", err)
+
+			return
+		}
+		if *readyFile == "" {
+			return
+		}
+
+		if err := os.WriteFile(*readyFile, nil, 0o644); err != nil {
+			klog.Errorf("failed to create the ready file %q: %v", *readyFile, err)
+		}
+	}()
+
+	if _, err := os.Stat(mountPathsLocation); os.IsNotExist(err) {
+		klog.Info("No volumes request metadata prefetch, going to sleep...")
+		select {}
+	}
+
 	// Start the "ls" command in the background.
 	// All our volumes are mounted under the /volumes/ directory.
 	cmd := exec.CommandContext(ctx, "ls", "-R", mountPathsLocation)
@@ -25,6 +25,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -34,9 +35,12 @@ import (
 )
 
 var (
-	gcsfusePath    = flag.String("gcsfuse-path", "/gcsfuse", "gcsfuse path")
-	volumeBasePath = flag.String("volume-base-path", webhook.SidecarContainerTmpVolumeMountPath+"/.volumes", "volume base path")
-	_              = flag.Int("grace-period", 0, "grace period for gcsfuse termination. This flag has been deprecated, has no effect and will be removed in the future.")
+	gcsfusePath           = flag.String("gcsfuse-path", "/gcsfuse", "gcsfuse path")
+	volumeBasePath        = flag.String("volume-base-path", webhook.SidecarContainerTmpVolumeMountPath+"/.volumes", "volume base path")
+	totalCacheSizeLimitMb = flag.Int64("total-cache-size-limit-mb", 0, "the total size limit in MiB of the file cache of all the volumes, 0 means no limit")
+	cacheDebugPort        = flag.Int("cache-debug-port", 0, "the loopback port of the debug endpoint listing the file cache of the volumes, 0 disables the endpoint. The webhook only sets it when the cluster admin enables the endpoint and the Pod does not use the host network")
+	binaryPathAllowlist   = flag.String("gcsfuse-binary-path-allowlist", sidecarmounter.DefaultGCSFuseBinaryPathAllowlist, "comma separated list of the dirs in the image holding the alternate gcsfuse binaries the volumes may select, an empty value disables the selection")
+	_                     = flag.Int("grace-period", 0, "grace period for gcsfuse termination. This flag has been deprecated, has no effect and will be removed in the future.")
 	// This is set at compile time.
 	version = "unknown"
 )
@@ -53,18 +57,44 @@ func main() {
 	}
 
 	mounter := sidecarmounter.New(*gcsfusePath)
+	for _, dir := range strings.Split(*binaryPathAllowlist, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			mounter.BinaryPathAllowlist = append(mounter.BinaryPathAllowlist, dir)
+		}
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if *cacheDebugPort > 0 {
+		go sidecarmounter.ServeCacheDebug(ctx, *cacheDebugPort, filepath.Join(webhook.SidecarContainerCacheVolumeMountPath, ".volumes"))
+	}
+
+	mcs := []*sidecarmounter.MountConfig{}
 	for _, sp := range socketPaths {
+		if mc := sidecarmounter.NewMountConfig(sp); mc != nil {
+			mcs = append(mcs, mc)
+		}
+	}
+
+	// split the total file cache size limit evenly between the volumes enabling the file cache,
+	// each gcsfuse process then keeps the file cache of its volume under its share.
+	cachedVolumes := sidecarmounter.CountFileCacheVolumes(mcs)
+	fileCacheSizeLimitMb := sidecarmounter.FileCacheSizeLimitShareMb(*totalCacheSizeLimitMb, cachedVolumes)
+	if fileCacheSizeLimitMb > 0 {
+		klog.Infof("bounding the file cache of each cached volume to %v MiB, the total cache size limit is %v MiB", fileCacheSizeLimitMb, *totalCacheSizeLimitMb)
+	}
+
+	for _, mc := range mcs {
 		// sleep 1.5 seconds before launch the next gcsfuse to avoid
 		// 1. different gcsfuse logs mixed together.
 		// 2. memory usage peak.
 		time.Sleep(1500 * time.Millisecond)
-		mc := sidecarmounter.NewMountConfig(sp)
-		if mc != nil {
-			if err := mounter.Mount(ctx, mc); err != nil {
-				mc.ErrWriter.WriteMsg(fmt.Sprintf("failed to mount bucket %q for volume %q: %v\n", mc.BucketName, mc.VolumeName, err))
-			}
+		if err := mc.Prepare(fileCacheSizeLimitMb, cachedVolumes); err != nil {
+			mc.ErrWriter.WriteMsg(fmt.Sprintf("failed to prepare the mount of volume %q: %v\n", mc.VolumeName, err))
+
+			continue
+		}
+		if err := mounter.Mount(ctx, mc); err != nil {
+			mc.ErrWriter.WriteMsg(fmt.Sprintf("failed to mount bucket %q for volume %q: %v\n", mc.BucketName, mc.VolumeName, err))
 		}
 	}
 
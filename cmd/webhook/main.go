@@ -20,6 +20,7 @@ package main
 import (
 	"flag"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -28,7 +29,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -53,6 +56,7 @@ var (
 	ephemeralStorageLimit                   = flag.String("sidecar-ephemeral-storage-limit", "5Gi", "The default ephemeral storage limit for gcsfuse sidecar container.")
 	sidecarImage                            = flag.String("sidecar-image", "", "The gcsfuse sidecar container image.")
 	metadataSidecarImage                    = flag.String("metadata-sidecar-image", "", "The metadata prefetch sidecar container image.")
+	bucketAccessCheckImage                  = flag.String("bucket-access-check-image", "", "The container image of the init container that validates bucket access, it should be pinned by digest. The gke-gcsfuse/enable-bucket-access-check annotation is rejected when empty.")
 	injectSAVol                             = flag.Bool("should-inject-sa-vol", false, "Inject projected service account volume when true")
 	metadataMemoryRequest                   = flag.String("metadata-sidecar-memory-request", "10Mi", "Flag to use default value for gcsfuse memory prefetch sidecar container memory request.")
 	metadataMemoryLimit                     = flag.String("metadata-sidecar-memory-limit", "10Mi", "Flag to use default value for gcsfuse memory prefetch sidecar container memory limit.")
@@ -60,6 +64,9 @@ var (
 	metadataPrefetchCPULimit                = flag.String("metadata-sidecar-cpu-limit", "50m", "Flag to use default value for gcsfuse memory prefetch sidecar container cpu limit.")
 	metadataPrefetchEphemeralStorageRequest = flag.String("metadata-sidecar-ephemeral-storage-request", "10Mi", "The default value for gcsfuse memory prefetch sidecar ephemeral storage request.")
 	metadataPrefetchEphemeralStorageLimit   = flag.String("metadata-sidecar-ephemeral-storage-limit", "10Mi", "The default value for gcsfuse memory prefetch sidecar ephemeral storage limit.")
+	sidecarVolumeMountPathAllowlist         = flag.String("sidecar-volume-mount-path-allowlist", "/etc/gcsfuse-secrets", "Comma separated list of the path prefixes the Pod Secret and ConfigMap volumes listed in the gke-gcsfuse/sidecar-volume-mounts annotation can be mounted at in the gcsfuse sidecar container. The annotation is rejected when empty.")
+	enableCacheDebugEndpoint                = flag.Bool("enable-cache-debug-endpoint", false, "Permit the gke-gcsfuse/cache-debug-port annotation, which serves the file cache listing of the volumes on a loopback port of the gcsfuse sidecar container. The annotation is rejected when false, and always for the Pods using the host network.")
+	mountOptionPolicyConfigMap              = flag.String("mount-option-policy-configmap", "", "The namespace/name of the ConfigMap listing the mount options and volume attributes forbidden by the cluster policy. The policy is disabled when empty.")
 	// These are set at compile time.
 	webhookVersion = "unknown"
 )
@@ -79,11 +86,17 @@ func main() {
 	klog.Infof("Running Google Cloud Storage FUSE CSI driver admission webhook version %v, sidecar container image %v", webhookVersion, *sidecarImage)
 
 	// Load webhook config
-	fuseSideCarConfig := wh.LoadConfig(*sidecarImage, *imagePullPolicy, *cpuRequest, *cpuLimit, *memoryRequest, *memoryLimit, *ephemeralStorageRequest, *ephemeralStorageLimit)
+	fuseSideCarConfig, err := wh.ParseConfig(*sidecarImage, *imagePullPolicy, *cpuRequest, *cpuLimit, *memoryRequest, *memoryLimit, *ephemeralStorageRequest, *ephemeralStorageLimit)
+	if err != nil {
+		klog.Fatalf("Invalid default gcsfuse sidecar container resource settings: %v", err)
+	}
 	fuseSideCarConfig.ShouldInjectSAVolume = *injectSAVol
 	klog.Infof("Webhook should inject SA volume: %t", fuseSideCarConfig.ShouldInjectSAVolume)
 
-	metadataPrefetchSideCarConfig := wh.LoadConfig(*metadataSidecarImage, *imagePullPolicy, *metadataPrefetchCPURequest, *metadataPrefetchCPULimit, *metadataMemoryRequest, *metadataMemoryLimit, *metadataPrefetchEphemeralStorageRequest, *metadataPrefetchEphemeralStorageLimit)
+	metadataPrefetchSideCarConfig, err := wh.ParseConfig(*metadataSidecarImage, *imagePullPolicy, *metadataPrefetchCPURequest, *metadataPrefetchCPULimit, *metadataMemoryRequest, *metadataMemoryLimit, *metadataPrefetchEphemeralStorageRequest, *metadataPrefetchEphemeralStorageLimit)
+	if err != nil {
+		klog.Fatalf("Invalid default metadata prefetch sidecar container resource settings: %v", err)
+	}
 
 	// Load config for manager, informers, listers
 	kubeConfig := config.GetConfigOrDie()
@@ -120,6 +133,20 @@ func main() {
 	informerFactory.Start(context.Done())
 	informerFactory.WaitForCacheSync(context.Done())
 
+	// Only watch the ConfigMaps in the namespace of the mount option policy ConfigMap.
+	var configMapLister listersv1.ConfigMapLister
+	if *mountOptionPolicyConfigMap != "" {
+		namespace, _, err := cache.SplitMetaNamespaceKey(*mountOptionPolicyConfigMap)
+		if err != nil || namespace == "" {
+			klog.Fatalf("Invalid mount option policy ConfigMap %q, expected namespace/name: %v", *mountOptionPolicyConfigMap, err)
+		}
+
+		configMapInformerFactory := informers.NewSharedInformerFactoryWithOptions(client, resyncDuration, informers.WithNamespace(namespace))
+		configMapLister = configMapInformerFactory.Core().V1().ConfigMaps().Lister()
+		configMapInformerFactory.Start(context.Done())
+		configMapInformerFactory.WaitForCacheSync(context.Done())
+	}
+
 	// Setup a Manager
 	klog.Info("Setting up manager.")
 	mgr, err := manager.New(kubeConfig, manager.Options{
@@ -146,17 +173,29 @@ func main() {
 	klog.Info("Setting up webhook server.")
 	hookServer := mgr.GetWebhookServer()
 
+	sidecarVolumeMountPaths := []string{}
+	for _, p := range strings.Split(*sidecarVolumeMountPathAllowlist, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			sidecarVolumeMountPaths = append(sidecarVolumeMountPaths, p)
+		}
+	}
+
 	klog.Info("Registering webhooks to the webhook server.")
 	hookServer.Register("/inject", &webhook.Admission{
 		Handler: &wh.SidecarInjector{
-			Client:                 mgr.GetClient(),
-			Config:                 fuseSideCarConfig,
-			MetadataPrefetchConfig: metadataPrefetchSideCarConfig,
-			Decoder:                admission.NewDecoder(runtime.NewScheme()),
-			NodeLister:             nodeLister,
-			PvLister:               pvLister,
-			PvcLister:              pvcLister,
-			ServerVersion:          serverVersion,
+			Client:                          mgr.GetClient(),
+			Config:                          fuseSideCarConfig,
+			MetadataPrefetchConfig:          metadataPrefetchSideCarConfig,
+			Decoder:                         admission.NewDecoder(runtime.NewScheme()),
+			NodeLister:                      nodeLister,
+			PvLister:                        pvLister,
+			PvcLister:                       pvcLister,
+			ServerVersion:                   serverVersion,
+			BucketAccessCheckImage:          *bucketAccessCheckImage,
+			ConfigMapLister:                 configMapLister,
+			MountOptionPolicyConfigMap:      *mountOptionPolicyConfigMap,
+			SidecarVolumeMountPathAllowlist: sidecarVolumeMountPaths,
+			EnableCacheDebugEndpoint:        *enableCacheDebugEndpoint,
 		},
 	})
 
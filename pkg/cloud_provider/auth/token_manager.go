@@ -59,5 +59,6 @@ func (tm *tokenManager) GetTokenSourceFromK8sServiceAccount(saNamespace, saName,
 		k8sSANamespace: saNamespace,
 		k8sSAToken:     saToken,
 		k8sClients:     tm.k8sClients,
+		backoff:        tokenFetchBackoff,
 	}
 }
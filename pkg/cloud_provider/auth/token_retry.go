@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// tokenFetchBackoff bounds the retries of a token fetch failing with transient errors,
+// e.g. when the GKE metadata server or the token endpoints are briefly unavailable.
+var tokenFetchBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    4,
+}
+
+// IsTransientError returns true if the token fetch error is caused by an unavailable or overloaded endpoint,
+// and retrying the fetch may succeed. The other errors, e.g. a missing or unauthorized service account, are permanent.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+			return true
+		default:
+			return false
+		}
+	}
+
+	return apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
+
+// fetchTokenWithRetry calls fetch until it succeeds, fails with a permanent error, or the backoff steps run out.
+// The last error is returned when all the attempts fail.
+func fetchTokenWithRetry(backoff wait.Backoff, fetch func() (*oauth2.Token, error)) (*oauth2.Token, error) {
+	var token *oauth2.Token
+	var fetchErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		token, fetchErr = fetch()
+		if fetchErr == nil {
+			return true, nil
+		}
+
+		if !IsTransientError(fetchErr) {
+			return false, fetchErr
+		}
+
+		klog.Warningf("transient error fetching the token, retrying: %v", fetchErr)
+
+		return false, nil
+	})
+	if err != nil {
+		if wait.Interrupted(err) {
+			return nil, fetchErr
+		}
+
+		return nil, err
+	}
+
+	return token, nil
+}
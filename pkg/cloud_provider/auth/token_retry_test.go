@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestIsTransientError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "connection refused by the metadata server",
+			err:      fmt.Errorf("identity binding token fetch error: %w", syscall.ECONNREFUSED),
+			expected: true,
+		},
+		{
+			name:     "deadline exceeded",
+			err:      fmt.Errorf("k8s service account token fetch error: %w", context.DeadlineExceeded),
+			expected: true,
+		},
+		{
+			name:     "unavailable STS endpoint",
+			err:      fmt.Errorf("identity binding token fetch error: %w", &googleapi.Error{Code: http.StatusServiceUnavailable}),
+			expected: true,
+		},
+		{
+			name:     "rate limited STS endpoint",
+			err:      &googleapi.Error{Code: http.StatusTooManyRequests},
+			expected: true,
+		},
+		{
+			name:     "unauthorized STS request",
+			err:      fmt.Errorf("identity binding token fetch error: %w", &googleapi.Error{Code: http.StatusUnauthorized}),
+			expected: false,
+		},
+		{
+			name:     "unavailable IAM credentials endpoint",
+			err:      fmt.Errorf("GCP service account token fetch error: %w", status.Error(codes.Unavailable, "unavailable")),
+			expected: true,
+		},
+		{
+			name:     "permission denied by the IAM credentials endpoint",
+			err:      status.Error(codes.PermissionDenied, "permission denied"),
+			expected: false,
+		},
+		{
+			name:     "throttled Kubernetes API server",
+			err:      apierrors.NewTooManyRequests("too many requests", 1),
+			expected: true,
+		},
+		{
+			name:     "missing Kubernetes service account",
+			err:      fmt.Errorf("failed to call Kubernetes ServiceAccount.CreateToken API: %w", apierrors.NewNotFound(schema.GroupResource{Resource: "serviceaccounts"}, "test-sa")),
+			expected: false,
+		},
+		{
+			name:     "missing token for the identity pool",
+			err:      errors.New(`could not find token for the identity pool "test-project.svc.id.goog"`),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsTransientError(tc.err); got != tc.expected {
+				t.Errorf("Got IsTransientError %v, but expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFetchTokenWithRetry(t *testing.T) {
+	t.Parallel()
+
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	unavailableErr := fmt.Errorf("identity binding token fetch error: %w", syscall.ECONNREFUSED)
+	permanentErr := fmt.Errorf("identity binding token fetch error: %w", &googleapi.Error{Code: http.StatusForbidden})
+
+	testCases := []struct {
+		name          string
+		failures      int
+		err           error
+		expectedErr   error
+		expectedCalls int
+	}{
+		{
+			name:          "should return the token without retry",
+			expectedCalls: 1,
+		},
+		{
+			name:          "should retry the transient errors",
+			failures:      2,
+			err:           unavailableErr,
+			expectedCalls: 3,
+		},
+		{
+			name:          "should return the last transient error after the backoff steps run out",
+			failures:      5,
+			err:           unavailableErr,
+			expectedErr:   unavailableErr,
+			expectedCalls: 3,
+		},
+		{
+			name:          "should not retry the permanent errors",
+			failures:      5,
+			err:           permanentErr,
+			expectedErr:   permanentErr,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			fetch := func() (*oauth2.Token, error) {
+				calls++
+				if calls <= tc.failures {
+					return nil, tc.err
+				}
+
+				return &oauth2.Token{AccessToken: "test-token"}, nil
+			}
+
+			token, err := fetchTokenWithRetry(backoff, fetch)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Got error %v, but expected %v", err, tc.expectedErr)
+			}
+			if tc.expectedErr == nil && (token == nil || token.AccessToken != "test-token") {
+				t.Errorf("Got token %v, but expected the test token", token)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("Got %v fetches, but expected %v", calls, tc.expectedCalls)
+			}
+		})
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"google.golang.org/api/option"
 	sts "google.golang.org/api/sts/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 )
 
@@ -43,10 +44,16 @@ type GCPTokenSource struct {
 	k8sSANamespace string
 	k8sSAToken     string
 	k8sClients     clientset.Interface
+	backoff        wait.Backoff
 }
 
 // Token exchanges a GCP IAM SA Token with a Kubernetes Service Account token.
+// The exchange is retried with backoff when it fails with transient errors.
 func (ts *GCPTokenSource) Token() (*oauth2.Token, error) {
+	return fetchTokenWithRetry(ts.backoff, ts.fetchToken)
+}
+
+func (ts *GCPTokenSource) fetchToken() (*oauth2.Token, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
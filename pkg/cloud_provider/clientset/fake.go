@@ -23,6 +23,7 @@ import (
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -78,6 +79,11 @@ func (c *FakeClientset) CreateNode(isWorkloadIdentityEnabled bool) {
 	}
 }
 
+// SetNodeRegion sets the region label of the node created by CreateNode.
+func (c *FakeClientset) SetNodeRegion(region string) {
+	c.fakeNode.Labels[corev1.LabelTopologyRegion] = region
+}
+
 func (c *FakeClientset) GetPod(namespace, name string) (*corev1.Pod, error) {
 	c.fakePod.ObjectMeta.Name = name
 	c.fakePod.ObjectMeta.Namespace = namespace
@@ -85,6 +91,9 @@ func (c *FakeClientset) GetPod(namespace, name string) (*corev1.Pod, error) {
 }
 
 func (c *FakeClientset) GetNode(name string) (*corev1.Node, error) {
+	if c.fakeNode == nil {
+		return nil, apierrors.NewNotFound(corev1.Resource("nodes"), name)
+	}
 	c.fakeNode.ObjectMeta.Name = name
 	return c.fakeNode, nil
 }
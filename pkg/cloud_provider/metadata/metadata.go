@@ -19,12 +19,24 @@ package metadata
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"cloud.google.com/go/compute/metadata"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 )
 
+// projectIDBackoff bounds the retries of the project ID lookup when the GKE metadata server is briefly unavailable.
+var projectIDBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
 type Service interface {
 	GetProjectID() string
 	GetIdentityPool() string
@@ -40,7 +52,11 @@ type metadataServiceManager struct {
 var _ Service = &metadataServiceManager{}
 
 func NewMetadataService(identityPool, identityProvider string) (Service, error) {
-	projectID, err := metadata.ProjectIDWithContext(context.Background())
+	return newMetadataService(identityPool, identityProvider, metadata.ProjectIDWithContext, projectIDBackoff)
+}
+
+func newMetadataService(identityPool, identityProvider string, projectIDFunc func(context.Context) (string, error), backoff wait.Backoff) (Service, error) {
+	projectID, err := fetchProjectID(projectIDFunc, backoff)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
@@ -68,3 +84,48 @@ func (manager *metadataServiceManager) GetIdentityPool() string {
 func (manager *metadataServiceManager) GetIdentityProvider() string {
 	return manager.identityProvider
 }
+
+// fetchProjectID looks up the project ID, retrying the transient metadata server errors with backoff.
+func fetchProjectID(projectIDFunc func(context.Context) (string, error), backoff wait.Backoff) (string, error) {
+	var projectID string
+	var lookupErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		projectID, lookupErr = projectIDFunc(context.Background())
+		if lookupErr == nil {
+			return true, nil
+		}
+
+		if !isTransientMetadataErr(lookupErr) {
+			return false, lookupErr
+		}
+
+		klog.Warningf("transient error getting the project ID from the metadata server, retrying: %v", lookupErr)
+
+		return false, nil
+	})
+	if err != nil {
+		if wait.Interrupted(err) {
+			return "", lookupErr
+		}
+
+		return "", err
+	}
+
+	return projectID, nil
+}
+
+// isTransientMetadataErr returns false for the metadata server responses that do not change on retry,
+// i.e. an undefined metadata key or a client error, and true for the unavailable or failing metadata server.
+func isTransientMetadataErr(err error) bool {
+	var notDefinedErr metadata.NotDefinedError
+	if errors.As(err, &notDefinedErr) {
+		return false
+	}
+
+	var metadataErr *metadata.Error
+	if errors.As(err, &metadataErr) {
+		return metadataErr.Code == http.StatusTooManyRequests || metadataErr.Code >= http.StatusInternalServerError
+	}
+
+	return true
+}
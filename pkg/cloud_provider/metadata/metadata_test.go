@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// flakyMetadataServer fails the first project ID lookups with the given error.
+type flakyMetadataServer struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyMetadataServer) ProjectIDWithContext(context.Context) (string, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return "", s.err
+	}
+
+	return "test-project", nil
+}
+
+func TestNewMetadataServiceRetry(t *testing.T) {
+	t.Parallel()
+
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+
+	testCases := []struct {
+		name          string
+		server        *flakyMetadataServer
+		expectErr     bool
+		expectedCalls int
+	}{
+		{
+			name:          "should get the project ID without retry",
+			server:        &flakyMetadataServer{},
+			expectedCalls: 1,
+		},
+		{
+			name:          "should retry the unavailable metadata server",
+			server:        &flakyMetadataServer{failures: 2, err: &metadata.Error{Code: http.StatusServiceUnavailable}},
+			expectedCalls: 3,
+		},
+		{
+			name:          "should retry the metadata server connection errors",
+			server:        &flakyMetadataServer{failures: 1, err: errors.New("dial tcp 169.254.169.254:80: connect: connection refused")},
+			expectedCalls: 2,
+		},
+		{
+			name:          "should fail after the backoff steps run out",
+			server:        &flakyMetadataServer{failures: 5, err: &metadata.Error{Code: http.StatusServiceUnavailable}},
+			expectErr:     true,
+			expectedCalls: 3,
+		},
+		{
+			name:          "should not retry an undefined metadata key",
+			server:        &flakyMetadataServer{failures: 5, err: metadata.NotDefinedError("project/project-id")},
+			expectErr:     true,
+			expectedCalls: 1,
+		},
+		{
+			name:          "should not retry a client error",
+			server:        &flakyMetadataServer{failures: 5, err: &metadata.Error{Code: http.StatusForbidden}},
+			expectErr:     true,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := newMetadataService("", "", tc.server.ProjectIDWithContext, backoff)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.server.calls != tc.expectedCalls {
+				t.Errorf("Got %v project ID lookups, but expected %v", tc.server.calls, tc.expectedCalls)
+			}
+			if err == nil && s.GetIdentityPool() != "test-project.svc.id.goog" {
+				t.Errorf("Got identity pool %q, but expected %q", s.GetIdentityPool(), "test-project.svc.id.goog")
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ type fakeService struct {
 
 type fakeServiceManager struct {
 	createdBuckets map[string]*ServiceBucket
+	checkBucketErr error
 }
 
 func (manager *fakeServiceManager) SetupService(_ context.Context, _ oauth2.TokenSource) (Service, error) {
@@ -44,6 +45,12 @@ func NewFakeServiceManager() ServiceManager {
 	return &fakeServiceManager{createdBuckets: map[string]*ServiceBucket{}}
 }
 
+// NewFakeServiceManagerWithCheckBucketErr returns a fake ServiceManager
+// whose services fail the bucket existence check with the given error.
+func NewFakeServiceManagerWithCheckBucketErr(err error) ServiceManager {
+	return &fakeServiceManager{createdBuckets: map[string]*ServiceBucket{}, checkBucketErr: err}
+}
+
 func (service *fakeService) CreateBucket(_ context.Context, obj *ServiceBucket) (*ServiceBucket, error) {
 	sb := &ServiceBucket{
 		Project:   obj.Project,
@@ -58,7 +65,9 @@ func (service *fakeService) CreateBucket(_ context.Context, obj *ServiceBucket)
 	return sb, nil
 }
 
-func (service *fakeService) DeleteBucket(_ context.Context, _ *ServiceBucket) error {
+func (service *fakeService) DeleteBucket(_ context.Context, obj *ServiceBucket) error {
+	delete(service.sm.createdBuckets, obj.Name)
+
 	return nil
 }
 
@@ -79,6 +88,10 @@ func (service *fakeService) RemoveIAMPolicy(_ context.Context, _ *ServiceBucket,
 }
 
 func (service *fakeService) CheckBucketExists(_ context.Context, obj *ServiceBucket) (bool, error) {
+	if service.sm.checkBucketErr != nil {
+		return false, service.sm.checkBucketErr
+	}
+
 	if _, ok := service.sm.createdBuckets[obj.Name]; ok {
 		return true, nil
 	}
@@ -21,12 +21,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/iam"
 	"cloud.google.com/go/storage"
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/cloud_provider/auth"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
@@ -42,6 +45,10 @@ type ServiceBucket struct {
 	Labels                         map[string]string
 	EnableUniformBucketLevelAccess bool
 	EnableHierarchicalNamespace    bool
+	// BillingProject is the project billed for the requests to requester-pays buckets.
+	BillingProject string
+	// RetryStatusCodes are the HTTP status codes retried in addition to the default retryable errors.
+	RetryStatusCodes []int
 }
 
 type Service interface {
@@ -70,8 +77,14 @@ func NewGCSServiceManager() (ServiceManager, error) {
 }
 
 func (manager *gcsServiceManager) SetupService(ctx context.Context, ts oauth2.TokenSource) (Service, error) {
+	// Hold the setup for a bounded time while the token endpoints are unavailable,
+	// but fail fast on the permanent errors, e.g. a missing service account.
 	if err := wait.PollUntilContextTimeout(ctx, 5*time.Second, 30*time.Second, true, func(context.Context) (bool, error) {
 		if _, err := ts.Token(); err != nil {
+			if !auth.IsTransientError(err) {
+				return false, err
+			}
+
 			klog.Errorf("error fetching initial token: %v", err)
 
 			return false, nil
@@ -135,7 +148,7 @@ func (service *gcsService) DeleteBucket(ctx context.Context, obj *ServiceBucket)
 	}
 
 	// Delete all objects in the bucket first
-	bkt := service.storageClient.Bucket(obj.Name)
+	bkt := service.bucketHandle(obj)
 	it := bkt.Objects(ctx, nil)
 	for {
 		attrs, err := it.Next()
@@ -160,7 +173,7 @@ func (service *gcsService) DeleteBucket(ctx context.Context, obj *ServiceBucket)
 }
 
 func (service *gcsService) GetBucket(ctx context.Context, obj *ServiceBucket) (*ServiceBucket, error) {
-	bkt := service.storageClient.Bucket(obj.Name)
+	bkt := service.bucketHandle(obj)
 	attrs, err := bkt.Attrs(ctx)
 	if err != nil {
 		klog.Errorf("Failed to get bucket %q: %v", obj.Name, err)
@@ -176,7 +189,7 @@ func (service *gcsService) GetBucket(ctx context.Context, obj *ServiceBucket) (*
 }
 
 func (service *gcsService) CheckBucketExists(ctx context.Context, obj *ServiceBucket) (bool, error) {
-	bkt := service.storageClient.Bucket(obj.Name)
+	bkt := service.bucketHandle(obj)
 	_, err := bkt.Objects(ctx, &storage.Query{Prefix: ""}).Next()
 
 	if err == nil || errors.Is(err, iterator.Done) {
@@ -216,6 +229,33 @@ func (service *gcsService) RemoveIAMPolicy(ctx context.Context, obj *ServiceBuck
 	return nil
 }
 
+// bucketHandle returns the bucket handle, billing the requests to the BillingProject if it is set,
+// and retrying the RetryStatusCodes.
+func (service *gcsService) bucketHandle(obj *ServiceBucket) *storage.BucketHandle {
+	bkt := service.storageClient.Bucket(obj.Name)
+	if obj.BillingProject != "" {
+		bkt = bkt.UserProject(obj.BillingProject)
+	}
+	if len(obj.RetryStatusCodes) > 0 {
+		bkt = bkt.Retryer(storage.WithErrorFunc(shouldRetryOnStatusCodes(obj.RetryStatusCodes)))
+	}
+
+	return bkt
+}
+
+// shouldRetryOnStatusCodes returns a retry error func that retries the default retryable errors and the given HTTP status codes.
+func shouldRetryOnStatusCodes(statusCodes []int) func(err error) bool {
+	return func(err error) bool {
+		if storage.ShouldRetry(err) {
+			return true
+		}
+
+		var apiErr *googleapi.Error
+
+		return errors.As(err, &apiErr) && slices.Contains(statusCodes, apiErr.Code)
+	}
+}
+
 func (service *gcsService) Close() {
 	service.storageClient.Close()
 }
@@ -254,8 +294,31 @@ func IsNotExistErr(err error) bool {
 	return errors.Is(err, storage.ErrBucketNotExist)
 }
 
-func isPermissionDeniedErr(err error) bool {
-	return strings.Contains(err.Error(), "googleapi: Error 403")
+// The substrings of the error messages of the storage client and gcsfuse classified by ErrMsgCode.
+var (
+	permissionDeniedErrMsgs = []string{"googleapi: Error 403", "IAM returned 403 Forbidden: Permission", "google: could not find default credentials"}
+	quotaExceededErrMsgs    = []string{"googleapi: Error 429", "rateLimitExceeded", "quotaExceeded"}
+	unavailableErrMsgs      = []string{"googleapi: Error 503", "connection refused", "connection reset by peer", "no such host", "i/o timeout"}
+)
+
+// ErrMsgCode classifies the GCS error in an error message of the storage client or gcsfuse,
+// and returns codes.Internal if the error is not recognized.
+// The auth errors take precedence over the quota and network errors, which are often logged by the retries before them.
+func ErrMsgCode(msg string) codes.Code {
+	containsAny := func(substrs []string) bool {
+		return slices.ContainsFunc(substrs, func(s string) bool { return strings.Contains(msg, s) })
+	}
+
+	switch {
+	case containsAny(permissionDeniedErrMsgs):
+		return codes.PermissionDenied
+	case containsAny(quotaExceededErrMsgs):
+		return codes.ResourceExhausted
+	case containsAny(unavailableErrMsgs):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
 }
 
 func isCanceledErr(err error) bool {
@@ -264,15 +327,11 @@ func isCanceledErr(err error) bool {
 
 // ParseErrCode parses error and returns a gRPC code.
 func ParseErrCode(err error) codes.Code {
-	code := codes.Internal
+	code := ErrMsgCode(err.Error())
 	if IsNotExistErr(err) {
 		code = codes.NotFound
 	}
 
-	if isPermissionDeniedErr(err) {
-		code = codes.PermissionDenied
-	}
-
 	if isCanceledErr(err) {
 		code = codes.Aborted
 	}
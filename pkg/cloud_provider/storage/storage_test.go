@@ -18,10 +18,14 @@ limitations under the License.
 package storage
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
 )
 
 func TestCompareBuckets(t *testing.T) {
@@ -93,3 +97,91 @@ func TestCompareBuckets(t *testing.T) {
 		}
 	}
 }
+
+func TestShouldRetryOnStatusCodes(t *testing.T) {
+	t.Parallel()
+	shouldRetry := shouldRetryOnStatusCodes([]int{520, 529})
+
+	cases := []struct {
+		name        string
+		err         error
+		expectRetry bool
+	}{
+		{
+			name:        "configured status code",
+			err:         &googleapi.Error{Code: 520},
+			expectRetry: true,
+		},
+		{
+			name:        "wrapped configured status code",
+			err:         fmt.Errorf("failed to list objects: %w", &googleapi.Error{Code: 529}),
+			expectRetry: true,
+		},
+		{
+			name:        "default retryable status code",
+			err:         &googleapi.Error{Code: 503},
+			expectRetry: true,
+		},
+		{
+			name: "non-retryable status code",
+			err:  &googleapi.Error{Code: 404},
+		},
+		{
+			name: "non-API error",
+			err:  errors.New("bucket is invalid"),
+		},
+	}
+
+	for _, test := range cases {
+		if retry := shouldRetry(test.err); retry != test.expectRetry {
+			t.Errorf("test %v failed: got retry %v, expected %v", test.name, retry, test.expectRetry)
+		}
+	}
+}
+
+func TestErrMsgCode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		msg          string
+		expectedCode codes.Code
+	}{
+		{
+			name:         "permission denied",
+			msg:          "googleapi: Error 403: does not have storage.objects.list access",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "missing credentials",
+			msg:          "google: could not find default credentials",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "quota exceeded",
+			msg:          "googleapi: Error 429: rateLimitExceeded",
+			expectedCode: codes.ResourceExhausted,
+		},
+		{
+			name:         "network unavailable",
+			msg:          "dial tcp: lookup storage.googleapis.com: no such host",
+			expectedCode: codes.Unavailable,
+		},
+		{
+			name:         "permission denied after the retries",
+			msg:          "googleapi: Error 503: backend error\ngoogleapi: Error 403: permission denied",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "unknown error",
+			msg:          "bucket is invalid",
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, test := range cases {
+		if code := ErrMsgCode(test.msg); code != test.expectedCode {
+			t.Errorf("test %v failed: got code %v, expected %v", test.name, code, test.expectedCode)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// auditLoggerName is the name of the logger emitting the mount operation audit records.
+	auditLoggerName = "audit"
+	redactedValue   = "***redacted***"
+)
+
+// sensitiveMountOptions are the mount options whose values may point to credentials,
+// their values are redacted in the audit records.
+var sensitiveMountOptions = []string{"key-file", "token-url", "reuse-token-from-url", "gcs-auth:key-file", "gcs-auth:token-url"}
+
+func newAuditLogger() logr.Logger {
+	return klog.Background().WithName(auditLoggerName)
+}
+
+// auditNodePublishVolume emits a structured audit record of a NodePublishVolume mount operation.
+func auditNodePublishVolume(logger logr.Logger, podNamespace, podName, bucketName, volumeHandle, targetPath string, options []string) {
+	logger.Info("NodePublishVolume",
+		"pod", klog.KRef(podNamespace, podName),
+		"bucket", bucketName,
+		"volumeHandle", volumeHandle,
+		"targetPath", targetPath,
+		"options", redactMountOptions(options),
+	)
+}
+
+// auditNodeUnpublishVolume emits a structured audit record of a NodeUnpublishVolume unmount operation.
+func auditNodeUnpublishVolume(logger logr.Logger, podUID, volumeName, volumeHandle, targetPath string) {
+	logger.Info("NodeUnpublishVolume",
+		"podUID", podUID,
+		"volume", volumeName,
+		"volumeHandle", volumeHandle,
+		"targetPath", targetPath,
+	)
+}
+
+// redactMountOptions returns a copy of the mount options with the values of the sensitive options redacted.
+func redactMountOptions(options []string) []string {
+	redacted := make([]string, 0, len(options))
+	for _, o := range options {
+		for _, sensitive := range sensitiveMountOptions {
+			if value, ok := strings.CutPrefix(o, sensitive); ok && value != "" && (value[0] == '=' || value[0] == ':') {
+				o = sensitive + value[:1] + redactedValue
+
+				break
+			}
+		}
+
+		redacted = append(redacted, o)
+	}
+
+	return redacted
+}
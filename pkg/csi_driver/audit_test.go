@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+)
+
+// newCapturingLogger returns a logger appending the formatted records to the given slice.
+func newCapturingLogger(records *[]string) logr.Logger {
+	return funcr.New(func(prefix, args string) {
+		*records = append(*records, prefix+" "+args)
+	}, funcr.Options{}).WithName(auditLoggerName)
+}
+
+func TestAuditNodePublishVolume(t *testing.T) {
+	t.Parallel()
+
+	records := []string{}
+	logger := newCapturingLogger(&records)
+
+	options := []string{"implicit-dirs", "key-file=/secrets/key.json", "gcs-auth:token-url:https://example.com/token", "token-server-identity-provider=provider"}
+	auditNodePublishVolume(logger, "test-ns", "test-pod", "test-bucket", "test-volume-handle", testSharedTargetPathPod1Vol1, options)
+
+	if len(records) != 1 {
+		t.Fatalf("Got %v audit records, but expected 1: %v", len(records), records)
+	}
+	if !strings.HasPrefix(records[0], auditLoggerName+" ") {
+		t.Errorf("Expected the audit record to be emitted by the %q logger, got %s", auditLoggerName, records[0])
+	}
+
+	record := records[0]
+	for _, want := range []string{
+		`"msg"="NodePublishVolume"`,
+		`"pod"={"name"="test-pod" "namespace"="test-ns"}`,
+		`"bucket"="test-bucket"`,
+		`"volumeHandle"="test-volume-handle"`,
+		`"targetPath"="` + testSharedTargetPathPod1Vol1 + `"`,
+		`"implicit-dirs"`,
+		`"key-file=` + redactedValue + `"`,
+		`"gcs-auth:token-url:` + redactedValue + `"`,
+		`"token-server-identity-provider=provider"`,
+	} {
+		if !strings.Contains(record, want) {
+			t.Errorf("Expected the audit record to contain %s, got %s", want, record)
+		}
+	}
+
+	for _, secret := range []string{"/secrets/key.json", "https://example.com/token"} {
+		if strings.Contains(record, secret) {
+			t.Errorf("Expected %q to be redacted in the audit record, got %s", secret, record)
+		}
+	}
+
+	if options[1] != "key-file=/secrets/key.json" {
+		t.Errorf("Expected the mount options not to be modified, got %v", options)
+	}
+}
+
+func TestAuditNodeUnpublishVolume(t *testing.T) {
+	t.Parallel()
+
+	records := []string{}
+	logger := newCapturingLogger(&records)
+
+	auditNodeUnpublishVolume(logger, "pod-1", "vol-1", "test-volume-handle", testSharedTargetPathPod1Vol1)
+
+	if len(records) != 1 {
+		t.Fatalf("Got %v audit records, but expected 1: %v", len(records), records)
+	}
+	if !strings.HasPrefix(records[0], auditLoggerName+" ") {
+		t.Errorf("Expected the audit record to be emitted by the %q logger, got %s", auditLoggerName, records[0])
+	}
+
+	for _, want := range []string{
+		`"msg"="NodeUnpublishVolume"`,
+		`"podUID"="pod-1"`,
+		`"volume"="vol-1"`,
+		`"volumeHandle"="test-volume-handle"`,
+		`"targetPath"="` + testSharedTargetPathPod1Vol1 + `"`,
+	} {
+		if !strings.Contains(records[0], want) {
+			t.Errorf("Expected the audit record to contain %s, got %s", want, records[0])
+		}
+	}
+}
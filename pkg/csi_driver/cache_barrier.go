@@ -0,0 +1,35 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// cacheBarrierFilePath returns the cleaned path of the cache barrier file relative to the volume root,
+// and false if the path is empty or escapes the volume root.
+func cacheBarrierFilePath(value string) (string, bool) {
+	filePath := strings.TrimPrefix(path.Clean("/"+value), "/")
+	if filePath == "" || slices.Contains(strings.Split(value, "/"), "..") {
+		return "", false
+	}
+
+	return filePath, true
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+)
+
+func TestCacheBarrierFilePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value        string
+		expectedPath string
+		expectedOK   bool
+	}{
+		{value: "models/model.bin", expectedPath: "models/model.bin", expectedOK: true},
+		{value: "/models//model.bin", expectedPath: "models/model.bin", expectedOK: true},
+		{value: ""},
+		{value: "/"},
+		{value: "../model.bin"},
+		{value: "models/../../model.bin"},
+	}
+
+	for _, tc := range testCases {
+		filePath, ok := cacheBarrierFilePath(tc.value)
+		if filePath != tc.expectedPath || ok != tc.expectedOK {
+			t.Errorf("cacheBarrierFilePath(%q) = %q, %v, expected %q, %v", tc.value, filePath, ok, tc.expectedPath, tc.expectedOK)
+		}
+	}
+}
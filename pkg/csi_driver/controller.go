@@ -19,6 +19,8 @@ package driver
 
 import (
 	"fmt"
+	"math"
+	"regexp"
 	"strings"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
@@ -32,6 +34,9 @@ import (
 
 const (
 	MinimumVolumeSizeInBytes int64 = 1 * util.Mb
+
+	// UnlimitedCapacityInBytes is reported by GetCapacity, the capacity of GCS buckets is effectively unbounded.
+	UnlimitedCapacityInBytes int64 = math.MaxInt64
 )
 
 // CreateVolume parameters.
@@ -44,13 +49,35 @@ const (
 	// User provided labels.
 	ParameterKeyLabels = "labels"
 
+	// User provided bucket location.
+	ParameterKeyLocation = "location"
+
+	// User provided policy deciding whether DeleteVolume deletes the bucket and its objects.
+	ParameterKeyDeletePolicy = "deletePolicy"
+
 	// Keys for tags to attach to the provisioned disk.
 	tagKeyCreatedForClaimNamespace = "kubernetes_io_created-for_pvc_namespace"
 	tagKeyCreatedForClaimName      = "kubernetes_io_created-for_pvc_name"
 	tagKeyCreatedForVolumeName     = "kubernetes_io_created-for_pv_name"
 	tagKeyCreatedBy                = "storage_gke_io_created-by"
+	tagKeyDeletePolicy             = "storage_gke_io_delete-policy"
 )
 
+// deletePolicy values.
+const (
+	// deletePolicyPurge deletes the objects and the bucket when the volume is deleted.
+	deletePolicyPurge = "purge"
+	// deletePolicyRetain keeps the bucket and the objects when the volume is deleted.
+	deletePolicyRetain = "retain"
+)
+
+// TopologyKeyRegion is the topology key of the region the node or the bucket is in.
+const TopologyKeyRegion = "topology.gcsfuse.csi.storage.gke.io/region"
+
+// regionRegex matches the single region bucket locations, e.g. us-central1.
+// Multi-region and dual-region buckets, e.g. US or NAM4, are accessible from all the regions.
+var regionRegex = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+
 // controllerServer handles volume provisioning.
 type controllerServer struct {
 	csi.UnimplementedControllerServer
@@ -96,11 +123,22 @@ func (s *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req *
 	}
 
 	// Validate that the volume matches the capabilities
-	// Note that there is nothing in the bucket that we actually need to validate
+	// Note that there is nothing in the bucket that we actually need to validate.
+	// Unsupported capabilities are not an error, the response is left unconfirmed with the reason.
 	if err := s.driver.validateVolumeCapabilities(caps); err != nil {
 		return &csi.ValidateVolumeCapabilitiesResponse{
 			Message: err.Error(),
-		}, status.Error(codes.InvalidArgument, err.Error())
+		}, nil
+	}
+
+	// The volume is always mounted as a fuse file system. Other fs types are only reported here,
+	// the publish and create paths keep accepting them, so the existing volumes setting them still mount.
+	for _, c := range caps {
+		if fsType := c.GetMount().GetFsType(); fsType != "" && fsType != FuseMountType {
+			return &csi.ValidateVolumeCapabilitiesResponse{
+				Message: fmt.Sprintf("driver does not support fstype %v", fsType),
+			}, nil
+		}
 	}
 
 	return &csi.ValidateVolumeCapabilitiesResponse{
@@ -141,12 +179,24 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 	defer s.volumeLocks.Release(volumeID)
 
 	param := req.GetParameters()
+	deletePolicy := deletePolicyPurge
+	if v, ok := param[ParameterKeyDeletePolicy]; ok {
+		if v != deletePolicyPurge && v != deletePolicyRetain {
+			return nil, status.Errorf(codes.InvalidArgument, "parameter %v only accepts %q or %q, got %q", ParameterKeyDeletePolicy, deletePolicyPurge, deletePolicyRetain, v)
+		}
+		deletePolicy = v
+	}
+
 	newBucket := &storage.ServiceBucket{
 		Project:                        projectID,
 		Name:                           volumeID,
 		SizeBytes:                      capBytes,
 		EnableUniformBucketLevelAccess: true,
 	}
+	if location := param[ParameterKeyLocation]; location != "" {
+		// GCS reports the bucket location in upper case.
+		newBucket.Location = strings.ToUpper(location)
+	}
 
 	storageService, err := s.prepareStorageService(ctx, secrets)
 	if err != nil {
@@ -171,6 +221,9 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		if err != nil {
 			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
+		// DeleteVolume reads the delete policy from the bucket labels,
+		// since the StorageClass parameters are not passed to DeleteVolume.
+		labels[tagKeyDeletePolicy] = deletePolicy
 		newBucket.Labels = labels
 
 		// Create the bucket
@@ -180,7 +233,7 @@ func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 			return nil, status.Error(codes.Internal, createErr.Error())
 		}
 	}
-	resp := &csi.CreateVolumeResponse{Volume: bucketToCSIVolume(bucket)}
+	resp := &csi.CreateVolumeResponse{Volume: bucketToCSIVolume(bucket, s.driver.config.EnableTopology)}
 
 	return resp, nil
 }
@@ -201,8 +254,31 @@ func (s *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 	if err != nil {
 		return nil, status.Errorf(codes.Unauthenticated, "failed to prepare storage service: %v", err)
 	}
+	defer storageService.Close()
+
+	bucket, err := storageService.GetBucket(ctx, &storage.ServiceBucket{Name: volumeID})
+	if err != nil {
+		if storage.IsNotExistErr(err) {
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+
+		return nil, status.Error(storage.ParseErrCode(err), err.Error())
+	}
+
+	// Only purge the buckets provisioned by the driver, the buckets created out of band may hold shared data.
+	if bucket.Labels[tagKeyCreatedBy] != createdByLabelValue(s.driver.config.Name) {
+		klog.Warningf("DeleteVolume retains bucket %q because it was not created by the driver %q", volumeID, s.driver.config.Name)
+
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	if bucket.Labels[tagKeyDeletePolicy] == deletePolicyRetain {
+		klog.V(4).Infof("DeleteVolume retains bucket %q because its delete policy is %q", volumeID, deletePolicyRetain)
 
-	// Delete the volume
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	// Delete the objects and the volume
 	err = storageService.DeleteBucket(ctx, &storage.ServiceBucket{Name: volumeID})
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
@@ -211,6 +287,46 @@ func (s *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
+// GetCapacity reports the unlimited capacity, so that the capacity-aware scheduling does not block the volumes.
+func (s *controllerServer) GetCapacity(_ context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	if caps := req.GetVolumeCapabilities(); len(caps) > 0 {
+		if err := s.driver.validateVolumeCapabilities(caps); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	for key := range req.GetAccessibleTopology().GetSegments() {
+		if key != TopologyKeyRegion {
+			return nil, status.Errorf(codes.InvalidArgument, "GetCapacity accessible topology key %q is not supported, only %q is supported", key, TopologyKeyRegion)
+		}
+	}
+
+	return &csi.GetCapacityResponse{
+		AvailableCapacity: UnlimitedCapacityInBytes,
+	}, nil
+}
+
+// snapshotUnsupportedMsg explains why the volume snapshots are not supported.
+// A bucket has no point-in-time snapshot that a new volume could be restored from,
+// so the snapshot RPCs fail instead of recording a snapshot that cannot be restored.
+const snapshotUnsupportedMsg = "volume snapshots are not supported, Cloud Storage buckets have no point-in-time snapshot: " +
+	"enable Object Versioning on the bucket, or copy the objects to another bucket with the Storage Transfer Service, to back up the volume"
+
+// CreateSnapshot is not supported, the CREATE_DELETE_SNAPSHOT capability is not advertised.
+func (s *controllerServer) CreateSnapshot(_ context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "CreateSnapshot %q of volume %q failed: %v", req.GetName(), req.GetSourceVolumeId(), snapshotUnsupportedMsg)
+}
+
+// DeleteSnapshot is not supported, the CREATE_DELETE_SNAPSHOT capability is not advertised.
+func (s *controllerServer) DeleteSnapshot(_ context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "DeleteSnapshot %q failed: %v", req.GetSnapshotId(), snapshotUnsupportedMsg)
+}
+
+// ListSnapshots is not supported, the LIST_SNAPSHOTS capability is not advertised.
+func (s *controllerServer) ListSnapshots(_ context.Context, _ *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "ListSnapshots failed: %v", snapshotUnsupportedMsg)
+}
+
 // prepareStorageService prepares the GCS Storage Service using CreateVolume/DeleteVolume sercets.
 func (s *controllerServer) prepareStorageService(ctx context.Context, secrets map[string]string) (storage.Service, error) {
 	serviceAccountName, ok := secrets["serviceAccountName"]
@@ -232,12 +348,20 @@ func (s *controllerServer) prepareStorageService(ctx context.Context, secrets ma
 }
 
 // bucketToCSIVolume generates a CSI volume spec from the Google Cloud Storage Bucket.
-func bucketToCSIVolume(bucket *storage.ServiceBucket) *csi.Volume {
+// The accessible topology is only set if the topology is enabled.
+func bucketToCSIVolume(bucket *storage.ServiceBucket, enableTopology bool) *csi.Volume {
 	resp := &csi.Volume{
 		CapacityBytes: bucket.SizeBytes,
 		VolumeId:      bucket.Name,
 	}
 
+	// Schedule the Pods to the nodes in the region of single region buckets to avoid cross-region egress.
+	if region := strings.ToLower(bucket.Location); enableTopology && regionRegex.MatchString(region) {
+		resp.AccessibleTopology = []*csi.Topology{
+			{Segments: map[string]string{TopologyKeyRegion: region}},
+		}
+	}
+
 	return resp
 }
 
@@ -296,7 +420,7 @@ func extractLabels(parameters map[string]string, driverName string) (map[string]
 		}
 	}
 
-	labels[tagKeyCreatedBy] = strings.ReplaceAll(driverName, ".", "_")
+	labels[tagKeyCreatedBy] = createdByLabelValue(driverName)
 	labels, err := mergeLabels(scLabels, labels)
 	if err != nil {
 		return nil, err
@@ -310,6 +434,11 @@ func extractLabels(parameters map[string]string, driverName string) (map[string]
 	return mergeLabels(scLabels, labels)
 }
 
+// createdByLabelValue returns the value of the created-by label of the buckets provisioned by the driver.
+func createdByLabelValue(driverName string) string {
+	return strings.ReplaceAll(driverName, ".", "_")
+}
+
 func mergeLabels(scLabels map[string]string, metedataLabels map[string]string) (map[string]string, error) {
 	result := make(map[string]string)
 	for k, v := range metedataLabels {
@@ -22,7 +22,9 @@ import (
 	"reflect"
 	"testing"
 
+	gcs "cloud.google.com/go/storage"
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/cloud_provider/storage"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
@@ -43,10 +45,11 @@ func initTestController(t *testing.T) csi.ControllerServer {
 func TestCreateVolume(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
-		name      string
-		req       *csi.CreateVolumeRequest
-		resp      *csi.CreateVolumeResponse
-		expectErr error
+		name           string
+		req            *csi.CreateVolumeRequest
+		enableTopology bool
+		resp           *csi.CreateVolumeResponse
+		expectErr      error
 	}{
 		{
 			name: "valid defaults",
@@ -75,6 +78,95 @@ func TestCreateVolume(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "single region location",
+			req: &csi.CreateVolumeRequest{
+				Name: testVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters: map[string]string{ParameterKeyLocation: "us-central1"},
+				Secrets: map[string]string{
+					"projectID":               "test-project",
+					"serviceAccountName":      "test-sa-name",
+					"serviceAccountNamespace": "test-sa-namespace",
+				},
+			},
+			resp: &csi.CreateVolumeResponse{
+				Volume: &csi.Volume{
+					CapacityBytes: 1 * util.Mb,
+					VolumeId:      testVolumeID,
+				},
+			},
+		},
+		{
+			name:           "single region location with topology enabled",
+			enableTopology: true,
+			req: &csi.CreateVolumeRequest{
+				Name: testVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters: map[string]string{ParameterKeyLocation: "us-central1"},
+				Secrets: map[string]string{
+					"projectID":               "test-project",
+					"serviceAccountName":      "test-sa-name",
+					"serviceAccountNamespace": "test-sa-namespace",
+				},
+			},
+			resp: &csi.CreateVolumeResponse{
+				Volume: &csi.Volume{
+					CapacityBytes: 1 * util.Mb,
+					VolumeId:      testVolumeID,
+					AccessibleTopology: []*csi.Topology{
+						{Segments: map[string]string{TopologyKeyRegion: "us-central1"}},
+					},
+				},
+			},
+		},
+		{
+			name:           "multi-region location with topology enabled",
+			enableTopology: true,
+			req: &csi.CreateVolumeRequest{
+				Name: testVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+						},
+					},
+				},
+				Parameters: map[string]string{ParameterKeyLocation: "us"},
+				Secrets: map[string]string{
+					"projectID":               "test-project",
+					"serviceAccountName":      "test-sa-name",
+					"serviceAccountNamespace": "test-sa-namespace",
+				},
+			},
+			resp: &csi.CreateVolumeResponse{
+				Volume: &csi.Volume{
+					CapacityBytes: 1 * util.Mb,
+					VolumeId:      testVolumeID,
+				},
+			},
+		},
 		{
 			name: "empty name",
 			req: &csi.CreateVolumeRequest{
@@ -95,6 +187,7 @@ func TestCreateVolume(t *testing.T) {
 
 	for _, test := range cases {
 		cs := initTestController(t)
+		cs.(*controllerServer).driver.config.EnableTopology = test.enableTopology
 		resp, err := cs.CreateVolume(context.TODO(), test.req)
 		if test.expectErr == nil && err != nil {
 			t.Errorf("test %q failed:\ngot error %q,\nexpected error nil", test.name, err)
@@ -161,3 +254,323 @@ func TestDeleteVolume(t *testing.T) {
 		}
 	}
 }
+
+func TestDeleteVolumeDeletePolicy(t *testing.T) {
+	t.Parallel()
+	secrets := map[string]string{
+		"projectID":               "test-project",
+		"serviceAccountName":      "test-sa-name",
+		"serviceAccountNamespace": "test-sa-namespace",
+	}
+	cases := []struct {
+		name                 string
+		parameters           map[string]string
+		preExisting          bool
+		expectCreateErr      error
+		expectBucketRetained bool
+	}{
+		{
+			name: "should purge the bucket by default",
+		},
+		{
+			name:       "should purge the bucket with the purge delete policy",
+			parameters: map[string]string{ParameterKeyDeletePolicy: deletePolicyPurge},
+		},
+		{
+			name:                 "should retain the bucket with the retain delete policy",
+			parameters:           map[string]string{ParameterKeyDeletePolicy: deletePolicyRetain},
+			expectBucketRetained: true,
+		},
+		{
+			name:                 "should retain the bucket not created by the driver",
+			preExisting:          true,
+			expectBucketRetained: true,
+		},
+		{
+			name:            "invalid delete policy",
+			parameters:      map[string]string{ParameterKeyDeletePolicy: "delete"},
+			expectCreateErr: status.Error(codes.InvalidArgument, `parameter deletePolicy only accepts "purge" or "retain", got "delete"`),
+		},
+	}
+
+	for _, test := range cases {
+		driver := initTestDriver(t, nil)
+		cs := newControllerServer(driver, driver.config.StorageServiceManager)
+		storageService, err := driver.config.StorageServiceManager.SetupService(context.TODO(), nil)
+		if err != nil {
+			t.Fatalf("test %q failed to setup the storage service: %v", test.name, err)
+		}
+
+		// CreateVolume reuses the existing bucket that matches the request.
+		if test.preExisting {
+			if _, err := storageService.CreateBucket(context.TODO(), &storage.ServiceBucket{Project: "test-project", Name: testVolumeID, SizeBytes: 1 * util.Mb}); err != nil {
+				t.Fatalf("test %q failed to create the bucket: %v", test.name, err)
+			}
+		}
+
+		_, err = cs.CreateVolume(context.TODO(), &csi.CreateVolumeRequest{
+			Name: testVolumeID,
+			VolumeCapabilities: []*csi.VolumeCapability{
+				{
+					AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+					AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+				},
+			},
+			Parameters: test.parameters,
+			Secrets:    secrets,
+		})
+		if test.expectCreateErr != nil {
+			if !errors.Is(err, test.expectCreateErr) {
+				t.Errorf("test %q failed:\ngot error %q,\nexpected error %q", test.name, err, test.expectCreateErr)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %q failed to create the volume: %v", test.name, err)
+		}
+
+		if _, err := cs.DeleteVolume(context.TODO(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID, Secrets: secrets}); err != nil {
+			t.Errorf("test %q failed to delete the volume: %v", test.name, err)
+		}
+
+		_, err = storageService.GetBucket(context.TODO(), &storage.ServiceBucket{Name: testVolumeID})
+		if retained := err == nil; retained != test.expectBucketRetained {
+			t.Errorf("test %q failed:\ngot bucket retained %v,\nexpected %v", test.name, retained, test.expectBucketRetained)
+		}
+	}
+}
+
+func TestGetCapacity(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name      string
+		req       *csi.GetCapacityRequest
+		resp      *csi.GetCapacityResponse
+		expectErr error
+	}{
+		{
+			name: "empty request",
+			req:  &csi.GetCapacityRequest{},
+			resp: &csi.GetCapacityResponse{AvailableCapacity: UnlimitedCapacityInBytes},
+		},
+		{
+			name: "region topology",
+			req: &csi.GetCapacityRequest{
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+						},
+					},
+				},
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{TopologyKeyRegion: "us-central1"},
+				},
+			},
+			resp: &csi.GetCapacityResponse{AvailableCapacity: UnlimitedCapacityInBytes},
+		},
+		{
+			name: "unsupported topology",
+			req: &csi.GetCapacityRequest{
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{"topology.kubernetes.io/zone": "us-central1-a"},
+				},
+			},
+			expectErr: status.Errorf(codes.InvalidArgument, "GetCapacity accessible topology key %q is not supported, only %q is supported", "topology.kubernetes.io/zone", TopologyKeyRegion),
+		},
+		{
+			name: "unsupported volume capabilities",
+			req: &csi.GetCapacityRequest{
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Block{
+							Block: &csi.VolumeCapability_BlockVolume{},
+						},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+						},
+					},
+				},
+			},
+			expectErr: status.Error(codes.InvalidArgument, "driver only supports mount access type volume capability"),
+		},
+	}
+
+	for _, test := range cases {
+		cs := initTestController(t)
+		resp, err := cs.GetCapacity(context.TODO(), test.req)
+		if test.expectErr == nil && err != nil {
+			t.Errorf("test %q failed:\ngot error %q,\nexpected error nil", test.name, err)
+		}
+		if test.expectErr != nil && !errors.Is(err, test.expectErr) {
+			t.Errorf("test %q failed:\ngot error %q,\nexpected error %q", test.name, err, test.expectErr)
+		}
+		if !reflect.DeepEqual(resp, test.resp) {
+			t.Errorf("test %q failed:\ngot resp %+v,\nexpected resp %+v", test.name, resp, test.resp)
+		}
+	}
+}
+
+func TestValidateVolumeCapabilities(t *testing.T) {
+	t.Parallel()
+	secrets := map[string]string{
+		"projectID":               "test-project",
+		"serviceAccountName":      "test-sa-name",
+		"serviceAccountNamespace": "test-sa-namespace",
+	}
+	capability := func(mode csi.VolumeCapability_AccessMode_Mode, fsType string) *csi.VolumeCapability {
+		return &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{FsType: fsType}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+		}
+	}
+	supportedCaps := []*csi.VolumeCapability{
+		capability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, ""),
+		capability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY, ""),
+		capability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER, "fuse"),
+	}
+
+	cases := []struct {
+		name      string
+		req       *csi.ValidateVolumeCapabilitiesRequest
+		resp      *csi.ValidateVolumeCapabilitiesResponse
+		expectErr error
+	}{
+		{
+			name: "supported capabilities",
+			req: &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId:           testVolumeID,
+				VolumeCapabilities: supportedCaps,
+				VolumeContext:      map[string]string{VolumeContextKeyMountOptions: "implicit-dirs"},
+				Secrets:            secrets,
+			},
+			resp: &csi.ValidateVolumeCapabilitiesResponse{
+				Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+					VolumeContext:      map[string]string{VolumeContextKeyMountOptions: "implicit-dirs"},
+					VolumeCapabilities: supportedCaps,
+				},
+			},
+		},
+		{
+			name: "unsupported access mode",
+			req: &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId: testVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{
+					capability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, ""),
+					capability(csi.VolumeCapability_AccessMode_UNKNOWN, ""),
+				},
+				Secrets: secrets,
+			},
+			resp: &csi.ValidateVolumeCapabilitiesResponse{
+				Message: "driver does not support access mode: UNKNOWN",
+			},
+		},
+		{
+			name: "unsupported fs type",
+			req: &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId:           testVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{capability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER, "ext4")},
+				Secrets:            secrets,
+			},
+			resp: &csi.ValidateVolumeCapabilitiesResponse{
+				Message: "driver does not support fstype ext4",
+			},
+		},
+		{
+			name: "unsupported block access type",
+			req: &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId: testVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+						AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+					},
+				},
+				Secrets: secrets,
+			},
+			resp: &csi.ValidateVolumeCapabilitiesResponse{
+				Message: "driver only supports mount access type volume capability",
+			},
+		},
+		{
+			name:      "empty id",
+			req:       &csi.ValidateVolumeCapabilitiesRequest{VolumeCapabilities: supportedCaps, Secrets: secrets},
+			expectErr: status.Error(codes.InvalidArgument, "ValidateVolumeCapabilities volumeID must be provided"),
+		},
+		{
+			name:      "empty capabilities",
+			req:       &csi.ValidateVolumeCapabilitiesRequest{VolumeId: testVolumeID, Secrets: secrets},
+			expectErr: status.Error(codes.InvalidArgument, "ValidateVolumeCapabilities volume capabilities must be provided"),
+		},
+		{
+			name: "volume not found",
+			req: &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId:           "abc",
+				VolumeCapabilities: supportedCaps,
+				Secrets:            secrets,
+			},
+			expectErr: status.Errorf(codes.NotFound, "volume abc doesn't exist: %v", gcs.ErrBucketNotExist),
+		},
+	}
+
+	for _, test := range cases {
+		cs := initTestController(t)
+		if _, err := cs.CreateVolume(context.TODO(), &csi.CreateVolumeRequest{
+			Name:               testVolumeID,
+			VolumeCapabilities: supportedCaps,
+			Secrets:            secrets,
+		}); err != nil {
+			t.Fatalf("test %q failed: failed to create the volume: %v", test.name, err)
+		}
+
+		resp, err := cs.ValidateVolumeCapabilities(context.TODO(), test.req)
+		if test.expectErr == nil && err != nil {
+			t.Errorf("test %q failed:\ngot error %q,\nexpected error nil", test.name, err)
+		}
+		if test.expectErr != nil && (err == nil || err.Error() != test.expectErr.Error()) {
+			t.Errorf("test %q failed:\ngot error %q,\nexpected error %q", test.name, err, test.expectErr)
+		}
+		if !reflect.DeepEqual(resp, test.resp) {
+			t.Errorf("test %q failed:\ngot resp %+v,\nexpected resp %+v", test.name, resp, test.resp)
+		}
+	}
+}
+
+func TestSnapshotsUnsupported(t *testing.T) {
+	t.Parallel()
+	cs := initTestController(t)
+
+	_, err := cs.CreateSnapshot(context.TODO(), &csi.CreateSnapshotRequest{Name: "snapshot-1", SourceVolumeId: "bucket-1"})
+	expectedErr := status.Error(codes.Unimplemented, "CreateSnapshot \"snapshot-1\" of volume \"bucket-1\" failed: "+snapshotUnsupportedMsg)
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("CreateSnapshot got error %q, expected error %q", err, expectedErr)
+	}
+
+	_, err = cs.DeleteSnapshot(context.TODO(), &csi.DeleteSnapshotRequest{SnapshotId: "snapshot-1"})
+	expectedErr = status.Error(codes.Unimplemented, "DeleteSnapshot \"snapshot-1\" failed: "+snapshotUnsupportedMsg)
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("DeleteSnapshot got error %q, expected error %q", err, expectedErr)
+	}
+
+	_, err = cs.ListSnapshots(context.TODO(), &csi.ListSnapshotsRequest{})
+	expectedErr = status.Error(codes.Unimplemented, "ListSnapshots failed: "+snapshotUnsupportedMsg)
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("ListSnapshots got error %q, expected error %q", err, expectedErr)
+	}
+
+	resp, err := cs.ControllerGetCapabilities(context.TODO(), &csi.ControllerGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("ControllerGetCapabilities failed: %v", err)
+	}
+	for _, c := range resp.GetCapabilities() {
+		switch c.GetRpc().GetType() {
+		case csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT, csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS:
+			t.Errorf("unexpected snapshot capability %v", c.GetRpc().GetType())
+		default:
+		}
+	}
+}
@@ -20,6 +20,7 @@ package driver
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/cloud_provider/auth"
@@ -45,6 +46,9 @@ type GCSDriverConfig struct {
 	Mounter               mount.Interface
 	K8sClients            clientset.Interface
 	MetricsManager        metrics.Manager
+	MountPropagation      string        // Mount propagation mode applied to the target path after mounting
+	UnmountBusyTimeout    time.Duration // How long a busy target path is retried before it is lazily unmounted, zero disables the retries
+	EnableTopology        bool          // Restrict the volumes of single region buckets to the nodes in the bucket region
 }
 
 type GCSDriver struct {
@@ -71,6 +75,9 @@ func NewGCSDriver(config *GCSDriverConfig) (*GCSDriver, error) {
 	if !config.RunController && !config.RunNode {
 		return nil, errors.New("must run at least one controller or node service")
 	}
+	if _, err := mountPropagationFlags(config.MountPropagation); err != nil {
+		return nil, err
+	}
 
 	driver := &GCSDriver{
 		config: config,
@@ -91,6 +98,7 @@ func NewGCSDriver(config *GCSDriverConfig) (*GCSDriver, error) {
 	if config.RunNode {
 		nscap := []csi.NodeServiceCapability_RPC_Type{
 			csi.NodeServiceCapability_RPC_VOLUME_MOUNT_GROUP,
+			csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
 		}
 		driver.ns = newNodeServer(driver, config.Mounter)
 		driver.addNodeServiceCapabilities(nscap)
@@ -98,6 +106,7 @@ func NewGCSDriver(config *GCSDriverConfig) (*GCSDriver, error) {
 	if config.RunController {
 		csc := []csi.ControllerServiceCapability_RPC_Type{
 			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+			csi.ControllerServiceCapability_RPC_GET_CAPACITY,
 		}
 		driver.addControllerServiceCapabilities(csc)
 
@@ -84,6 +84,34 @@ func initTestDriverWithCustomNodeServer(t *testing.T, fm *mount.FakeMounter, cli
 	return driver
 }
 
+func TestNewGCSDriverMountPropagation(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		mountPropagation string
+		expectErr        bool
+	}{
+		{mountPropagation: ""},
+		{mountPropagation: "None"},
+		{mountPropagation: "HostToContainer"},
+		{mountPropagation: "Bidirectional"},
+		{mountPropagation: "bidirectional", expectErr: true},
+		{mountPropagation: "rshared", expectErr: true},
+	}
+
+	for _, tc := range cases {
+		config := &GCSDriverConfig{
+			Name:             "test-driver",
+			Version:          "test-version",
+			RunController:    true,
+			MountPropagation: tc.mountPropagation,
+		}
+		_, err := NewGCSDriver(config)
+		if tc.expectErr != (err != nil) {
+			t.Errorf("mount propagation %q: expected error %v, got error: %v", tc.mountPropagation, tc.expectErr, err)
+		}
+	}
+}
+
 func TestDriverValidateVolumeCapability(t *testing.T) {
 	t.Parallel()
 	driver := initTestDriver(t, nil)
@@ -59,32 +59,56 @@ func TestGetPluginInfo(t *testing.T) {
 
 func TestGetPluginCapabilities(t *testing.T) {
 	t.Parallel()
-	s := initTestIdentityServer(t)
-
-	resp, err := s.GetPluginCapabilities(context.TODO(), nil)
-	if err != nil {
-		t.Fatalf("GetPluginCapabilities failed: %v", err)
+	cases := []struct {
+		name                 string
+		enableTopology       bool
+		expectedServiceTypes []csi.PluginCapability_Service_Type
+	}{
+		{
+			name:                 "topology disabled",
+			expectedServiceTypes: []csi.PluginCapability_Service_Type{csi.PluginCapability_Service_CONTROLLER_SERVICE},
+		},
+		{
+			name:           "topology enabled",
+			enableTopology: true,
+			expectedServiceTypes: []csi.PluginCapability_Service_Type{
+				csi.PluginCapability_Service_CONTROLLER_SERVICE,
+				csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS,
+			},
+		},
 	}
-
-	if resp == nil {
-		t.Fatalf("GetPluginCapabilities resp is nil")
-	}
-
-	if len(resp.GetCapabilities()) != 1 {
-		t.Fatalf("returned %v capabilities", len(resp.GetCapabilities()))
-	}
-
-	if resp.GetCapabilities()[0].GetType() == nil {
-		t.Fatalf("returned nil capability type")
-	}
-
-	service := resp.GetCapabilities()[0].GetService()
-	if service == nil {
-		t.Fatalf("returned nil capability service")
-	}
-
-	if serviceType := service.GetType(); serviceType != csi.PluginCapability_Service_CONTROLLER_SERVICE {
-		t.Fatalf("returned %v capability service", serviceType)
+	for _, test := range cases {
+		driver := initTestDriver(t, nil)
+		driver.config.EnableTopology = test.enableTopology
+		s := newIdentityServer(driver)
+
+		resp, err := s.GetPluginCapabilities(context.TODO(), nil)
+		if err != nil {
+			t.Fatalf("test %q: GetPluginCapabilities failed: %v", test.name, err)
+		}
+
+		if resp == nil {
+			t.Fatalf("test %q: GetPluginCapabilities resp is nil", test.name)
+		}
+
+		if len(resp.GetCapabilities()) != len(test.expectedServiceTypes) {
+			t.Fatalf("test %q: returned %v capabilities", test.name, len(resp.GetCapabilities()))
+		}
+
+		for i, c := range resp.GetCapabilities() {
+			if c.GetType() == nil {
+				t.Fatalf("test %q: returned nil capability type", test.name)
+			}
+
+			service := c.GetService()
+			if service == nil {
+				t.Fatalf("test %q: returned nil capability service", test.name)
+			}
+
+			if serviceType := service.GetType(); serviceType != test.expectedServiceTypes[i] {
+				t.Fatalf("test %q: returned %v capability service", test.name, serviceType)
+			}
+		}
 	}
 }
 
@@ -18,25 +18,41 @@ limitations under the License.
 package driver
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/go-logr/logr"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/cloud_provider/clientset"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/cloud_provider/storage"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
 	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 	mount "k8s.io/mount-utils"
 )
 
+// unmountBusyBackoff spaces the unmount retries of a busy target path, the retries are bounded by the driver UnmountBusyTimeout.
+var unmountBusyBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      2 * time.Second,
+}
+
 const (
 	UmountTimeout = time.Second * 5
 
@@ -53,6 +69,13 @@ type nodeServer struct {
 	k8sClients            clientset.Interface
 	limiter               rate.Limiter
 	volumeStateStore      *util.VolumeStateStore
+	sharedMounts          *sharedMounts
+	cacheUsage            *cacheUsageStore
+	auditLogger           logr.Logger
+	// setMountPropagation applies the mount propagation flags to the target path, it can be replaced in tests.
+	setMountPropagation func(targetPath string, flags uintptr) error
+	// lazyUnmount detaches a busy mount from the target path, it can be replaced in tests.
+	lazyUnmount func(targetPath string) error
 }
 
 func newNodeServer(driver *GCSDriver, mounter mount.Interface) csi.NodeServer {
@@ -64,13 +87,39 @@ func newNodeServer(driver *GCSDriver, mounter mount.Interface) csi.NodeServer {
 		k8sClients:            driver.config.K8sClients,
 		limiter:               *rate.NewLimiter(rate.Every(time.Second), 10),
 		volumeStateStore:      util.NewVolumeStateStore(),
+		sharedMounts:          newSharedMounts(),
+		cacheUsage:            newCacheUsageStore(cacheUsageTTL),
+		auditLogger:           newAuditLogger(),
+		setMountPropagation:   setMountPropagation,
+		lazyUnmount:           lazyUnmount,
 	}
 }
 
 func (s *nodeServer) NodeGetInfo(_ context.Context, _ *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
-	return &csi.NodeGetInfoResponse{
+	resp := &csi.NodeGetInfoResponse{
 		NodeId: s.driver.config.NodeID,
-	}, nil
+	}
+	if !s.driver.config.EnableTopology {
+		return resp, nil
+	}
+
+	// The kubelet only registers the node with the CSI driver after NodeGetInfo succeeds,
+	// so the node is registered without the topology if the API server is not reachable.
+	node, err := s.k8sClients.GetNode(s.driver.config.NodeID)
+	if err != nil {
+		klog.Warningf("failed to get node %q, registering the node without the topology: %v", s.driver.config.NodeID, err)
+
+		return resp, nil
+	}
+
+	// Advertise the node region, so the Pods using single region buckets are scheduled to the nodes in the bucket region.
+	if region := node.Labels[corev1.LabelTopologyRegion]; region != "" {
+		resp.AccessibleTopology = &csi.Topology{
+			Segments: map[string]string{TopologyKeyRegion: region},
+		}
+	}
+
+	return resp, nil
 }
 
 func (s *nodeServer) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
@@ -79,6 +128,35 @@ func (s *nodeServer) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabi
 	}, nil
 }
 
+// NodeGetVolumeStats reports the usage of the per-volume file cache directory, since a gcsfuse mount has no capacity of its own.
+func (s *nodeServer) NodeGetVolumeStats(_ context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	// Validate arguments
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "NodeGetVolumeStats volume ID must be provided")
+	}
+	volumePath := req.GetVolumePath()
+	if len(volumePath) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "NodeGetVolumeStats volume path must be provided")
+	}
+
+	mounted, err := s.isDirMounted(volumePath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check if path %q is mounted: %v", volumePath, err)
+	}
+	if !mounted {
+		return nil, status.Errorf(codes.NotFound, "volume %q is not mounted on path %q", volumeID, volumePath)
+	}
+
+	// A volume sharing the gcsfuse process of another volume uses the cache dir of that volume.
+	usage, err := s.cacheUsage.cacheDirUsage(util.CacheDirPath(s.sharedMounts.owner(volumePath)))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get the cache dir usage of volume path %q: %v", volumePath, err)
+	}
+
+	return &csi.NodeGetVolumeStatsResponse{Usage: usage}, nil
+}
+
 func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	// Rate limit NodePublishVolume calls to avoid kube API throttling.
 	if err := s.limiter.Wait(ctx); err != nil {
@@ -106,7 +184,8 @@ func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 
 	// Check if the given Service Account has the access to the GCS bucket, and the bucket exists.
 	// skip check if it has ever succeeded
-	if bucketName != "_" && !skipBucketAccessCheck {
+	// skip check for anonymous access because gcsfuse does not use the Pod service account
+	if bucketName != "_" && !skipBucketAccessCheck && vc[VolumeContextKeyAuthMode] != authModeAnonymous {
 		// Use target path as an volume identifier because it corresponds to Pods and volumes.
 		// Pods may belong to different namespaces and would need their own access check.
 		vs, ok := s.volumeStateStore.Load(targetPath)
@@ -118,12 +197,20 @@ func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 		if !vs.BucketAccessCheckPassed {
 			storageService, err := s.prepareStorageService(ctx, vc)
 			if err != nil {
-				return nil, status.Errorf(codes.Unauthenticated, "failed to prepare storage service: %v", err)
+				return nil, status.Error(codes.Unauthenticated, withErrReason(ErrReasonAuthFailed, fmt.Errorf("failed to prepare storage service: %w", err)).Error())
 			}
 			defer storageService.Close()
 
-			if exist, err := storageService.CheckBucketExists(ctx, &storage.ServiceBucket{Name: bucketName}); !exist {
-				return nil, status.Errorf(storage.ParseErrCode(err), "failed to get GCS bucket %q: %v", bucketName, err)
+			bucket := &storage.ServiceBucket{Name: bucketName, BillingProject: vc[VolumeContextKeyBillingProject]}
+			// The retryOnStatusCodes volume attribute is validated by parseRequestArguments.
+			if value, ok := vc[VolumeContextKeyRetryOnStatusCodes]; ok {
+				bucket.RetryStatusCodes, _ = parseRetryOnStatusCodes(value)
+			}
+
+			if exist, err := storageService.CheckBucketExists(ctx, bucket); !exist {
+				code := storage.ParseErrCode(err)
+
+				return nil, status.Error(code, withErrReason(errReasonFromCode(code), fmt.Errorf("failed to get GCS bucket %q: %w", bucketName, err)).Error())
 			}
 
 			vs.BucketAccessCheckPassed = true
@@ -136,10 +223,14 @@ func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 		return nil, status.Errorf(codes.NotFound, "failed to get pod: %v", err)
 	}
 
-	if s.shouldStartTokenServer(pod) && pod.Spec.HostNetwork {
-		identityProvider := s.driver.config.TokenManager.GetIdentityProvider()
-		fuseMountOptions = joinMountOptions(fuseMountOptions, []string{"token-server-identity-provider=" + identityProvider})
+	authMode := vc[VolumeContextKeyAuthMode]
+	tokenServerOptions, err := tokenServerMountOptions(authMode, s.driver.config.TokenManager.GetIdentityProvider(), s.shouldStartTokenServer(pod), pod.Spec.HostNetwork)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, withErrReason(ErrReasonInvalidMountOptions, err).Error())
 	}
+	fuseMountOptions = joinMountOptions(fuseMountOptions, tokenServerOptions)
+	fuseMountOptions = joinMountOptions(fuseMountOptions, supplementalGroupMountOptions(pod, fuseMountOptions))
+	fuseMountOptions = joinMountOptions(fuseMountOptions, seLinuxContextMountOptions(pod, fuseMountOptions))
 
 	node, err := s.k8sClients.GetNode(s.driver.config.NodeID)
 	if err != nil {
@@ -149,7 +240,8 @@ func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 	val, ok := node.Labels[clientset.GkeMetaDataServerKey]
 	// If Workload Identity is not enabled, the key should be missing; the check for "val == false" is just for extra caution
 	isWorkloadIdentityDisabled := val != "true" || !ok
-	if isWorkloadIdentityDisabled && !pod.Spec.HostNetwork {
+	// Anonymous access does not need any credentials from the GKE metadata server.
+	if isWorkloadIdentityDisabled && !pod.Spec.HostNetwork && authMode != authModeAnonymous {
 		return nil, status.Errorf(codes.FailedPrecondition, "Workload Identity Federation is not enabled on node. Please make sure this is enabled on both cluster and node pool level (https://cloud.google.com/kubernetes-engine/docs/how-to/workload-identity)")
 	}
 
@@ -165,6 +257,10 @@ func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 		return nil, status.Error(codes.FailedPrecondition, "failed to find the sidecar container in Pod spec")
 	}
 
+	if err := validateSidecarVersionForMountOptions(getSidecarContainerImage(pod), fuseMountOptions); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, withErrReason(ErrReasonInvalidMountOptions, err).Error())
+	}
+
 	// Register metrics collecter.
 	// It is idempotent to register the same collector in node republish calls.
 	if s.driver.config.MetricsManager != nil && !disableMetricsCollection {
@@ -218,17 +314,55 @@ func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 		return nil, status.Errorf(codes.Internal, "mkdir failed for path %q: %v", targetPath, err)
 	}
 
-	// Start to mount
-	if err = s.mounter.Mount(bucketName, targetPath, FuseMountType, fuseMountOptions); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to mount volume %q to target path %q: %v", bucketName, targetPath, err)
+	// Let the non-root Pods use the per-volume cache directory.
+	if uid, gid, ok := cacheDirOwner(pod); ok {
+		if _, err := util.PrepareCacheDir(targetPath, uid, gid); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to prepare the cache dir for target path %q: %v", targetPath, err)
+		}
+	}
+
+	// Serve the volume by the gcsfuse process of a compatible volume in the same Pod if the sharedMounter is enabled.
+	shareKey, shareable := "", false
+	if sharedMounter, _ := strconv.ParseBool(vc[VolumeContextKeySharedMounter]); sharedMounter {
+		shareKey, shareable = sharedMountKey(targetPath, bucketName, fuseMountOptions)
+	}
+
+	if source := s.sharedMountSource(shareKey, targetPath, shareable); source != "" {
+		klog.V(4).Infof("NodePublishVolume bind mounting target path %q to target path %q to share the gcsfuse process", source, targetPath)
+		if err = s.mounter.Mount(source, targetPath, "", []string{"bind", "ro"}); err != nil {
+			s.sharedMounts.leave(targetPath)
+
+			return nil, status.Errorf(codes.Internal, "failed to bind mount target path %q to target path %q: %v", source, targetPath, err)
+		}
+
+		// The metrics of the shared gcsfuse process are collected from the source target path.
+		if s.driver.config.MetricsManager != nil {
+			s.driver.config.MetricsManager.UnregisterMetricsCollector(targetPath)
+		}
+	} else {
+		// Start to mount
+		if err = s.mounter.Mount(bucketName, targetPath, FuseMountType, fuseMountOptions); err != nil {
+			s.sharedMounts.leave(targetPath)
+
+			return nil, status.Errorf(codes.Internal, "failed to mount volume %q to target path %q: %v", bucketName, targetPath, err)
+		}
+	}
+
+	// The mount propagation mode is validated when the driver starts.
+	if flags, _ := mountPropagationFlags(s.driver.config.MountPropagation); flags != 0 {
+		if err := s.setMountPropagation(targetPath, flags); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to set mount propagation %q on target path %q: %v", s.driver.config.MountPropagation, targetPath, err)
+		}
 	}
 
+	auditNodePublishVolume(s.auditLogger, pod.Namespace, pod.Name, bucketName, req.GetVolumeId(), targetPath, fuseMountOptions)
+
 	klog.V(4).Infof("NodePublishVolume succeeded on volume %q to target path %q", bucketName, targetPath)
 
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
-func (s *nodeServer) NodeUnpublishVolume(_ context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+func (s *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
 	// Validate arguments
 	targetPath := req.GetTargetPath()
 	if len(targetPath) == 0 {
@@ -249,35 +383,126 @@ func (s *nodeServer) NodeUnpublishVolume(_ context.Context, req *csi.NodeUnpubli
 
 	s.volumeStateStore.Delete(targetPath)
 
+	// A force unmount aborts the fuse connection, which breaks the other volumes sharing the gcsfuse process.
+	stillShared := s.sharedMounts.leave(targetPath)
+
 	// Check if the target path is already mounted
 	if mounted, err := s.isDirMounted(targetPath); mounted || err != nil {
 		if err != nil {
 			klog.Errorf("failed to check if path %q is already mounted: %v", targetPath, err)
 		}
+		if err := s.unmountWithBusyRetry(ctx, targetPath, stillShared); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	// Cleanup the mount point
+	if err := mount.CleanupMountPoint(targetPath, s.mounter, false /* bind mount */); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to cleanup the mount point %q: %v", targetPath, err)
+	}
+
+	podUID, volumeName, _ := util.ParsePodIDVolumeFromTargetpath(targetPath)
+	auditNodeUnpublishVolume(s.auditLogger, podUID, volumeName, req.GetVolumeId(), targetPath)
+
+	klog.V(4).Infof("NodeUnpublishVolume succeeded on target path %q", targetPath)
+
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+// unmountWithBusyRetry unmounts the target path, retrying with backoff while the mount is busy, e.g. when the workload
+// still holds a file open momentarily. The retries are bounded by the driver UnmountBusyTimeout,
+// after which the mount is lazily detached. The other unmount errors are returned without retries.
+func (s *nodeServer) unmountWithBusyRetry(ctx context.Context, targetPath string, stillShared bool) error {
+	unmount := func() error {
 		// Force unmount the target path
 		// Try to do force unmount firstly because if the file descriptor was not closed,
 		// mount.CleanupMountPoint() call will hang.
 		forceUnmounter, ok := s.mounter.(mount.MounterForceUnmounter)
-		if ok {
-			if err = forceUnmounter.UnmountWithForce(targetPath, UmountTimeout); err != nil {
-				return nil, status.Errorf(codes.Internal, "failed to force unmount target path %q: %v", targetPath, err)
+		if ok && !stillShared {
+			if err := forceUnmounter.UnmountWithForce(targetPath, UmountTimeout); err != nil {
+				return fmt.Errorf("failed to force unmount target path %q: %w", targetPath, err)
 			}
-		} else {
+
+			return nil
+		}
+
+		if !stillShared {
 			klog.Warningf("failed to cast the mounter to a forceUnmounter, proceed with the default mounter Unmount")
-			if err = s.mounter.Unmount(targetPath); err != nil {
-				return nil, status.Errorf(codes.Internal, "failed to unmount target path %q: %v", targetPath, err)
-			}
 		}
+		if err := s.mounter.Unmount(targetPath); err != nil {
+			return fmt.Errorf("failed to unmount target path %q: %w", targetPath, err)
+		}
+
+		return nil
 	}
 
-	// Cleanup the mount point
-	if err := mount.CleanupMountPoint(targetPath, s.mounter, false /* bind mount */); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to cleanup the mount point %q: %v", targetPath, err)
+	timeout := s.driver.config.UnmountBusyTimeout
+	if timeout <= 0 {
+		return unmount()
 	}
 
-	klog.V(4).Infof("NodeUnpublishVolume succeeded on target path %q", targetPath)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	return &csi.NodeUnpublishVolumeResponse{}, nil
+	var unmountErr error
+	err := wait.ExponentialBackoffWithContext(ctx, unmountBusyBackoff, func(context.Context) (bool, error) {
+		unmountErr = unmount()
+		if unmountErr == nil {
+			return true, nil
+		}
+		if !isUnmountBusyErr(unmountErr) {
+			return false, unmountErr
+		}
+		klog.V(4).Infof("target path %q is busy, retrying the unmount: %v", targetPath, unmountErr)
+
+		return false, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if unmountErr != nil && !isUnmountBusyErr(unmountErr) {
+		return unmountErr
+	}
+
+	klog.Warningf("target path %q is still busy after %v, lazily unmounting it: %v", targetPath, timeout, unmountErr)
+	if err := s.lazyUnmount(targetPath); err != nil {
+		return fmt.Errorf("failed to lazily unmount target path %q: %w", targetPath, err)
+	}
+
+	return nil
+}
+
+// isUnmountBusyErr returns true if the unmount failed because the mount is in use.
+// The mount-utils mounter wraps the umount output rather than the errno.
+func isUnmountBusyErr(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || strings.Contains(err.Error(), "target is busy") || strings.Contains(err.Error(), "device is busy")
+}
+
+// lazyUnmount detaches the mount from the target path, the mount is cleaned up once it is no longer busy.
+func lazyUnmount(targetPath string) error {
+	return unix.Unmount(targetPath, unix.MNT_DETACH)
+}
+
+// sharedMountSource returns the target path whose gcsfuse mount can be bind mounted to the target path,
+// or an empty string if the target path should be mounted by a new gcsfuse process.
+func (s *nodeServer) sharedMountSource(key, targetPath string, shareable bool) string {
+	if !shareable {
+		return ""
+	}
+
+	source, ok := s.sharedMounts.join(key, targetPath)
+	if !ok {
+		return ""
+	}
+
+	if mounted, err := s.isDirMounted(source); err != nil || !mounted {
+		klog.V(4).Infof("shared target path %q is not mounted, target path %q will be mounted by a new gcsfuse process", source, targetPath)
+		s.sharedMounts.setSource(key, targetPath)
+
+		return ""
+	}
+
+	return source
 }
 
 // isDirMounted checks if the path is already a mount point.
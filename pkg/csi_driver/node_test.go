@@ -21,9 +21,15 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	gcs "cloud.google.com/go/storage"
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -33,6 +39,7 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 	mount "k8s.io/mount-utils"
 )
 
@@ -80,6 +87,59 @@ func initTestNodeServerWithCustomClientset(t *testing.T, clientSet *clientset.Fa
 	}
 }
 
+func TestNodeGetInfo(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name             string
+		enableTopology   bool
+		nodeNotFound     bool
+		region           string
+		expectedTopology *csi.Topology
+	}{
+		{
+			name:   "topology disabled",
+			region: "us-central1",
+		},
+		{
+			name:           "node without region label",
+			enableTopology: true,
+		},
+		{
+			name:             "node with region label",
+			enableTopology:   true,
+			region:           "us-central1",
+			expectedTopology: &csi.Topology{Segments: map[string]string{TopologyKeyRegion: "us-central1"}},
+		},
+		{
+			name:           "failed to get node",
+			enableTopology: true,
+			nodeNotFound:   true,
+		},
+	}
+	for _, test := range cases {
+		fakeClientSet := &clientset.FakeClientset{}
+		if !test.nodeNotFound {
+			fakeClientSet.CreateNode( /* workloadIdentityEnabled */ true)
+		}
+		if test.region != "" {
+			fakeClientSet.SetNodeRegion(test.region)
+		}
+		testEnv := initTestNodeServerWithCustomClientset(t, fakeClientSet)
+		testEnv.ns.(*nodeServer).driver.config.EnableTopology = test.enableTopology
+
+		resp, err := testEnv.ns.NodeGetInfo(context.TODO(), &csi.NodeGetInfoRequest{})
+		if err != nil {
+			t.Fatalf("test %q failed: %v", test.name, err)
+		}
+		if resp.GetNodeId() != testEnv.ns.(*nodeServer).driver.config.NodeID {
+			t.Errorf("test %q failed: got node ID %q", test.name, resp.GetNodeId())
+		}
+		if !reflect.DeepEqual(resp.GetAccessibleTopology(), test.expectedTopology) {
+			t.Errorf("test %q failed:\ngot topology %+v,\nexpected topology %+v", test.name, resp.GetAccessibleTopology(), test.expectedTopology)
+		}
+	}
+}
+
 func TestNodePublishVolume(t *testing.T) {
 	t.Parallel()
 	defaultPerm := os.FileMode(0o750) + os.ModeDir
@@ -261,6 +321,365 @@ func TestNodePublishVolumeWIDisabledOnNode(t *testing.T) {
 
 }
 
+func TestNodePublishVolumeSupplementalGroups(t *testing.T) {
+	t.Parallel()
+	defaultPerm := os.FileMode(0o750) + os.ModeDir
+
+	// Setup mount target path
+	tmpDir := "/tmp/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/"
+	if err := os.MkdirAll(tmpDir, defaultPerm); err != nil {
+		t.Fatalf("failed to setup tmp dir path: %v", err)
+	}
+	base, err := os.MkdirTemp(tmpDir, "node-publish-")
+	if err != nil {
+		t.Fatalf("failed to setup testdir: %v", err)
+	}
+	testTargetPath := filepath.Join(base, "mount")
+	if err = os.MkdirAll(testTargetPath, defaultPerm); err != nil {
+		t.Fatalf("failed to setup target path: %v", err)
+	}
+	defer os.RemoveAll(base)
+
+	fakeClientSet := &clientset.FakeClientset{}
+	fakeClientSet.CreateNode( /* workloadIdentityEnabled */ true)
+	fakeClientSet.CreatePod( /* hostNetworkEnabled */ false)
+	pod, _ := fakeClientSet.GetPod("", "")
+	pod.Spec.SecurityContext = &corev1.PodSecurityContext{SupplementalGroups: []int64{2002, 3003}}
+	testEnv := initTestNodeServerWithCustomClientset(t, fakeClientSet)
+
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:         testVolumeID,
+		TargetPath:       testTargetPath,
+		VolumeCapability: testVolumeCapability,
+	}
+	if _, err := testEnv.ns.NodePublishVolume(context.TODO(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedMount := &mount.MountPoint{Device: testVolumeID, Path: testTargetPath, Type: "fuse", Opts: []string{"gid=2002", "file-mode=664", "dir-mode=775"}}
+	validateMountPoint(t, "supplemental groups", testEnv.fm, expectedMount)
+}
+
+func TestNodePublishVolumeErrorClassification(t *testing.T) {
+	t.Parallel()
+	defaultPerm := os.FileMode(0o750) + os.ModeDir
+
+	// Setup mount target path
+	tmpDir := "/tmp/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/"
+	if err := os.MkdirAll(tmpDir, defaultPerm); err != nil {
+		t.Fatalf("failed to setup tmp dir path: %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		checkBucketErr error
+		gcsfuseErr     string
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{
+			name:           "permission denied",
+			checkBucketErr: errors.New("googleapi: Error 403: does not have storage.objects.list access"),
+			expectedCode:   codes.PermissionDenied,
+			expectedReason: ErrReasonAuthFailed,
+		},
+		{
+			name:           "bucket not found",
+			checkBucketErr: gcs.ErrBucketNotExist,
+			expectedCode:   codes.NotFound,
+			expectedReason: ErrReasonBucketNotFound,
+		},
+		{
+			name:           "quota exceeded",
+			checkBucketErr: errors.New("googleapi: Error 429: rateLimitExceeded"),
+			expectedCode:   codes.ResourceExhausted,
+			expectedReason: ErrReasonQuotaExceeded,
+		},
+		{
+			name:           "transient network issue",
+			checkBucketErr: errors.New("dial tcp: connect: connection refused"),
+			expectedCode:   codes.Unavailable,
+			expectedReason: ErrReasonNetworkUnavailable,
+		},
+		{
+			name:           "gcsfuse permission denied",
+			gcsfuseErr:     "googleapi: Error 403: Permission denied",
+			expectedCode:   codes.PermissionDenied,
+			expectedReason: ErrReasonAuthFailed,
+		},
+		{
+			name:           "gcsfuse bucket not found",
+			gcsfuseErr:     "bucket doesn't exist",
+			expectedCode:   codes.NotFound,
+			expectedReason: ErrReasonBucketNotFound,
+		},
+		{
+			name:           "gcsfuse quota exceeded",
+			gcsfuseErr:     "googleapi: Error 429: quotaExceeded",
+			expectedCode:   codes.ResourceExhausted,
+			expectedReason: ErrReasonQuotaExceeded,
+		},
+		{
+			name:           "gcsfuse transient network issue",
+			gcsfuseErr:     "dial tcp: lookup storage.googleapis.com: no such host",
+			expectedCode:   codes.Unavailable,
+			expectedReason: ErrReasonNetworkUnavailable,
+		},
+		{
+			name:           "gcsfuse out of memory",
+			gcsfuseErr:     "signal: killed",
+			expectedCode:   codes.ResourceExhausted,
+			expectedReason: ErrReasonOutOfMemory,
+		},
+		{
+			name:           "gcsfuse out of memory after a transient network issue",
+			gcsfuseErr:     "dial tcp: connect: connection refused\ngcsfuse exited with error: signal: killed",
+			expectedCode:   codes.ResourceExhausted,
+			expectedReason: ErrReasonOutOfMemory,
+		},
+		{
+			name:           "gcsfuse permission denied before it is killed",
+			gcsfuseErr:     "googleapi: Error 403: Permission denied\ngcsfuse exited with error: signal: killed",
+			expectedCode:   codes.PermissionDenied,
+			expectedReason: ErrReasonAuthFailed,
+		},
+		{
+			name:           "gcsfuse bucket not found before it is killed",
+			gcsfuseErr:     "bucket doesn't exist\ngcsfuse exited with error: signal: killed",
+			expectedCode:   codes.NotFound,
+			expectedReason: ErrReasonBucketNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		base, err := os.MkdirTemp(tmpDir, "node-publish-")
+		if err != nil {
+			t.Fatalf("failed to setup testdir: %v", err)
+		}
+		defer os.RemoveAll(base)
+		testTargetPath := filepath.Join(base, "mount")
+
+		req := &csi.NodePublishVolumeRequest{
+			VolumeId:         testVolumeID,
+			TargetPath:       testTargetPath,
+			VolumeCapability: testVolumeCapability,
+		}
+
+		mounter := mount.NewFakeMounter([]mount.MountPoint{})
+		driver := initTestDriver(t, mounter)
+		if test.checkBucketErr != nil {
+			driver.config.StorageServiceManager = storage.NewFakeServiceManagerWithCheckBucketErr(test.checkBucketErr)
+		} else {
+			req.VolumeContext = map[string]string{VolumeContextKeySkipCSIBucketAccessCheck: util.TrueStr}
+		}
+
+		if test.gcsfuseErr != "" {
+			emptyDirBasePath, err := util.PrepareEmptyDir(testTargetPath, true)
+			if err != nil {
+				t.Fatalf("failed to prepare emptyDir path: %v", err)
+			}
+			if err := os.WriteFile(emptyDirBasePath+"/error", []byte(test.gcsfuseErr), 0o600); err != nil {
+				t.Fatalf("failed to write the error file: %v", err)
+			}
+		}
+
+		ns := newNodeServer(driver, mounter)
+		_, err = ns.NodePublishVolume(context.TODO(), req)
+		st, ok := status.FromError(err)
+		if !ok || err == nil {
+			t.Errorf("test %q failed:\ngot error %v,\nexpected a gRPC status error", test.name, err)
+
+			continue
+		}
+		if st.Code() != test.expectedCode {
+			t.Errorf("test %q failed:\ngot code %v,\nexpected code %v", test.name, st.Code(), test.expectedCode)
+		}
+		if !strings.Contains(st.Message(), "reason="+test.expectedReason) {
+			t.Errorf("test %q failed:\ngot message %q,\nexpected reason %q", test.name, st.Message(), test.expectedReason)
+		}
+	}
+}
+
+func TestNodePublishVolumeMountPropagation(t *testing.T) {
+	t.Parallel()
+	defaultPerm := os.FileMode(0o750) + os.ModeDir
+
+	// Setup mount target path
+	tmpDir := "/tmp/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/"
+	if err := os.MkdirAll(tmpDir, defaultPerm); err != nil {
+		t.Fatalf("failed to setup tmp dir path: %v", err)
+	}
+	base, err := os.MkdirTemp(tmpDir, "node-publish-propagation-")
+	if err != nil {
+		t.Fatalf("failed to setup testdir: %v", err)
+	}
+	testTargetPath := filepath.Join(base, "mount")
+	if err = os.MkdirAll(testTargetPath, defaultPerm); err != nil {
+		t.Fatalf("failed to setup target path: %v", err)
+	}
+	defer os.RemoveAll(base)
+
+	cases := []struct {
+		name             string
+		mountPropagation string
+		expectedFlags    uintptr
+		expectApplied    bool
+	}{
+		{
+			name: "propagation unchanged by default",
+		},
+		{
+			name:             "Bidirectional propagation",
+			mountPropagation: "Bidirectional",
+			expectedFlags:    syscall.MS_SHARED | syscall.MS_REC,
+			expectApplied:    true,
+		},
+		{
+			name:             "HostToContainer propagation",
+			mountPropagation: "HostToContainer",
+			expectedFlags:    syscall.MS_SLAVE | syscall.MS_REC,
+			expectApplied:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		testEnv := initTestNodeServer(t)
+		ns, ok := testEnv.ns.(*nodeServer)
+		if !ok {
+			t.Fatalf("failed to cast node server")
+		}
+		ns.driver.config.MountPropagation = tc.mountPropagation
+
+		applied := false
+		var gotTargetPath string
+		var gotFlags uintptr
+		ns.setMountPropagation = func(targetPath string, flags uintptr) error {
+			applied = true
+			gotTargetPath = targetPath
+			gotFlags = flags
+
+			return nil
+		}
+
+		req := &csi.NodePublishVolumeRequest{
+			VolumeId:         testVolumeID,
+			TargetPath:       testTargetPath,
+			VolumeCapability: testVolumeCapability,
+		}
+		if _, err := ns.NodePublishVolume(context.TODO(), req); err != nil {
+			t.Errorf("test %q failed: unexpected error: %v", tc.name, err)
+		}
+
+		if applied != tc.expectApplied {
+			t.Errorf("test %q failed: expected mount propagation applied %v, got %v", tc.name, tc.expectApplied, applied)
+		}
+		if applied && (gotTargetPath != testTargetPath || gotFlags != tc.expectedFlags) {
+			t.Errorf("test %q failed: got propagation flags %#x on path %q, expected flags %#x on path %q", tc.name, gotFlags, gotTargetPath, tc.expectedFlags, testTargetPath)
+		}
+	}
+}
+
+func TestNodePublishVolumeSharedMounter(t *testing.T) {
+	t.Parallel()
+	defaultPerm := os.FileMode(0o750) + os.ModeDir
+
+	// Setup mount target paths of two volumes in the same Pod
+	tmpDir := "/tmp/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/"
+	if err := os.MkdirAll(tmpDir, defaultPerm); err != nil {
+		t.Fatalf("failed to setup tmp dir path: %v", err)
+	}
+	targetPaths := []string{}
+	for range 2 {
+		base, err := os.MkdirTemp(tmpDir, "node-publish-shared-")
+		if err != nil {
+			t.Fatalf("failed to setup testdir: %v", err)
+		}
+		defer os.RemoveAll(base)
+
+		targetPath := filepath.Join(base, "mount")
+		if err = os.MkdirAll(targetPath, defaultPerm); err != nil {
+			t.Fatalf("failed to setup target path: %v", err)
+		}
+		targetPaths = append(targetPaths, targetPath)
+
+		// The shared mount state is saved in the sidecar tmp dir of the volume.
+		if emptyDirBasePath, err := util.PrepareEmptyDir(targetPath, false); err == nil {
+			defer os.RemoveAll(emptyDirBasePath)
+		}
+	}
+
+	cases := []struct {
+		name           string
+		volumeContext  map[string]string
+		readOnly       bool
+		expectBindOpts []string
+	}{
+		{
+			name:           "read-only volumes share the gcsfuse mount",
+			volumeContext:  map[string]string{VolumeContextKeySharedMounter: util.TrueStr},
+			readOnly:       true,
+			expectBindOpts: []string{"bind", "ro"},
+		},
+		{
+			name:          "read-write volumes do not share the gcsfuse mount",
+			volumeContext: map[string]string{VolumeContextKeySharedMounter: util.TrueStr},
+		},
+		{
+			name:     "volumes without sharedMounter do not share the gcsfuse mount",
+			readOnly: true,
+		},
+	}
+
+	for _, tc := range cases {
+		testEnv := initTestNodeServer(t)
+		for _, targetPath := range targetPaths {
+			req := &csi.NodePublishVolumeRequest{
+				VolumeId:         testVolumeID,
+				TargetPath:       targetPath,
+				VolumeCapability: testVolumeCapability,
+				VolumeContext:    tc.volumeContext,
+				Readonly:         tc.readOnly,
+			}
+			if _, err := testEnv.ns.NodePublishVolume(context.TODO(), req); err != nil {
+				t.Fatalf("test %q failed: unexpected error: %v", tc.name, err)
+			}
+		}
+
+		if mLen := len(testEnv.fm.MountPoints); mLen != 2 {
+			t.Fatalf("test %q failed: got %v mounts(%+v), expected 2", tc.name, mLen, testEnv.fm.MountPoints)
+		}
+
+		if mp := testEnv.fm.MountPoints[0]; mp.Type != FuseMountType {
+			t.Errorf("test %q failed: got first mount type %q, expected %q", tc.name, mp.Type, FuseMountType)
+		}
+
+		second := testEnv.fm.MountPoints[1]
+		if tc.expectBindOpts == nil {
+			if second.Type != FuseMountType {
+				t.Errorf("test %q failed: got second mount type %q, expected %q", tc.name, second.Type, FuseMountType)
+			}
+
+			continue
+		}
+
+		if second.Device != testVolumeID || second.Path != targetPaths[1] {
+			t.Errorf("test %q failed: got second mount %+v, expected a bind mount of device %q", tc.name, second, testVolumeID)
+		}
+		if diff := cmp.Diff(tc.expectBindOpts, second.Opts); diff != "" {
+			t.Errorf("test %q failed: unexpected bind mount options (-want +got): %s", tc.name, diff)
+		}
+
+		unpublishReq := &csi.NodeUnpublishVolumeRequest{TargetPath: targetPaths[0]}
+		if _, err := testEnv.ns.NodeUnpublishVolume(context.TODO(), unpublishReq); err != nil {
+			t.Fatalf("test %q failed: unexpected unpublish error: %v", tc.name, err)
+		}
+
+		// The bind mount keeps serving the volume after the source target path is unpublished,
+		// the fake mounter drops the mount options of the remaining mount points on unmount.
+		validateMountPoint(t, tc.name, testEnv.fm, &mount.MountPoint{Device: testVolumeID, Path: targetPaths[1]})
+	}
+}
+
 func TestNodeUnpublishVolume(t *testing.T) {
 	t.Parallel()
 	defaultPerm := os.FileMode(0o750) + os.ModeDir
@@ -333,6 +752,168 @@ func TestNodeUnpublishVolume(t *testing.T) {
 	}
 }
 
+func TestNodeUnpublishVolumeBusyRetry(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name                string
+		unmountErrs         []error
+		unmountBusyTimeout  time.Duration
+		expectedAttempts    int
+		expectedLazyUnmount bool
+		expectErr           bool
+	}{
+		{
+			name:               "should retry the unmount while the target path is busy",
+			unmountErrs:        []error{syscall.EBUSY, syscall.EBUSY, syscall.EBUSY},
+			unmountBusyTimeout: 10 * time.Second,
+			expectedAttempts:   4,
+		},
+		{
+			name:               "should detect the busy umount output",
+			unmountErrs:        []error{errors.New("unmount failed: exit status 32\nOutput: umount: /mount: target is busy.")},
+			unmountBusyTimeout: 10 * time.Second,
+			expectedAttempts:   2,
+		},
+		{
+			name:                "should lazily unmount the target path that is still busy after the timeout",
+			unmountErrs:         slices.Repeat([]error{syscall.EBUSY}, 1000),
+			unmountBusyTimeout:  300 * time.Millisecond,
+			expectedLazyUnmount: true,
+		},
+		{
+			name:               "should not retry the other unmount errors",
+			unmountErrs:        []error{syscall.EINVAL},
+			unmountBusyTimeout: 10 * time.Second,
+			expectedAttempts:   1,
+			expectErr:          true,
+		},
+		{
+			name:             "should not retry the busy target path when the retries are disabled",
+			unmountErrs:      []error{syscall.EBUSY},
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			targetPath := filepath.Join(t.TempDir(), "mount")
+			if err := os.MkdirAll(targetPath, 0o750); err != nil {
+				t.Fatalf("failed to setup target path: %v", err)
+			}
+
+			testEnv := initTestNodeServer(t)
+			testEnv.fm.MountPoints = []mount.MountPoint{{Device: testVolumeID, Path: targetPath, Type: FuseMountType}}
+			attempts := 0
+			testEnv.fm.UnmountFunc = func(string) error {
+				attempts++
+				if attempts <= len(tc.unmountErrs) {
+					return tc.unmountErrs[attempts-1]
+				}
+
+				return nil
+			}
+			ns, _ := testEnv.ns.(*nodeServer)
+			ns.driver.config.UnmountBusyTimeout = tc.unmountBusyTimeout
+			lazilyUnmounted := false
+			ns.lazyUnmount = func(string) error {
+				lazilyUnmounted = true
+				testEnv.fm.MountPoints = nil
+
+				return nil
+			}
+
+			_, err := ns.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: testVolumeID, TargetPath: targetPath})
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error %v", err, tc.expectErr)
+			}
+			if !tc.expectedLazyUnmount && attempts != tc.expectedAttempts {
+				t.Errorf("got %v unmount attempts, expected %v", attempts, tc.expectedAttempts)
+			}
+			if lazilyUnmounted != tc.expectedLazyUnmount {
+				t.Errorf("got lazy unmount %v, expected %v", lazilyUnmounted, tc.expectedLazyUnmount)
+			}
+		})
+	}
+}
+
+func TestNodeUnpublishVolumeCacheCleanup(t *testing.T) {
+	t.Parallel()
+	defaultPerm := os.FileMode(0o750) + os.ModeDir
+
+	tmpDir := "/tmp/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/"
+	if err := os.MkdirAll(tmpDir, defaultPerm); err != nil {
+		t.Fatalf("failed to setup tmp dir path: %v", err)
+	}
+
+	cases := []struct {
+		name                 string
+		volumeContext        map[string]string
+		expectCacheDirExists bool
+	}{
+		{
+			name:                 "should retain the cache dir by default",
+			expectCacheDirExists: true,
+		},
+		{
+			name:                 "should retain the cache dir in retain mode",
+			volumeContext:        map[string]string{VolumeContextKeyCacheCleanupOnUnmount: cacheCleanupOnUnmountRetain},
+			expectCacheDirExists: true,
+		},
+		{
+			// The sidecar mounter removes the cache dir when gcsfuse exits.
+			name:                 "should leave the cache dir to the sidecar mounter in delete mode",
+			volumeContext:        map[string]string{VolumeContextKeyCacheCleanupOnUnmount: cacheCleanupOnUnmountDelete},
+			expectCacheDirExists: true,
+		},
+	}
+
+	for _, tc := range cases {
+		base, err := os.MkdirTemp(tmpDir, "node-unpublish-cache-")
+		if err != nil {
+			t.Fatalf("failed to setup testdir: %v", err)
+		}
+		defer os.RemoveAll(base)
+
+		targetPath := filepath.Join(base, "mount")
+		if err = os.MkdirAll(targetPath, defaultPerm); err != nil {
+			t.Fatalf("failed to setup target path: %v", err)
+		}
+
+		cacheDir := util.CacheDirPath(targetPath)
+		defer os.RemoveAll(cacheDir)
+		if err := os.MkdirAll(cacheDir, defaultPerm); err != nil {
+			t.Fatalf("failed to setup cache dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(cacheDir, "cached-object"), []byte("data"), 0o600); err != nil {
+			t.Fatalf("failed to write the cache file: %v", err)
+		}
+
+		testEnv := initTestNodeServer(t)
+		publishReq := &csi.NodePublishVolumeRequest{
+			VolumeId:         testVolumeID,
+			TargetPath:       targetPath,
+			VolumeCapability: testVolumeCapability,
+			VolumeContext:    tc.volumeContext,
+		}
+		if _, err := testEnv.ns.NodePublishVolume(context.TODO(), publishReq); err != nil {
+			t.Fatalf("test %q failed: unexpected publish error: %v", tc.name, err)
+		}
+
+		unpublishReq := &csi.NodeUnpublishVolumeRequest{VolumeId: testVolumeID, TargetPath: targetPath}
+		if _, err := testEnv.ns.NodeUnpublishVolume(context.TODO(), unpublishReq); err != nil {
+			t.Fatalf("test %q failed: unexpected unpublish error: %v", tc.name, err)
+		}
+
+		_, err = os.Stat(cacheDir)
+		if exists := err == nil; exists != tc.expectCacheDirExists {
+			t.Errorf("test %q failed: got cache dir exists %v, expected %v", tc.name, exists, tc.expectCacheDirExists)
+		}
+	}
+}
+
 func validateMountPoint(t *testing.T, name string, fm *mount.FakeMounter, e *mount.MountPoint) {
 	t.Helper()
 	if e == nil {
@@ -394,3 +975,99 @@ func TestConcurrentMapWrites(t *testing.T) {
 		t.Errorf("expected %d entries in the map, got %d", numWrites, sharedVSS.Size())
 	}
 }
+
+func TestNodeGetVolumeStats(t *testing.T) {
+	t.Parallel()
+	defaultPerm := os.FileMode(0o750) + os.ModeDir
+	volumePath := filepath.Join(t.TempDir(), "pods/test-pod-id/volumes/kubernetes.io~csi/test-volume/mount")
+	cacheDir := util.CacheDirPath(volumePath)
+	if err := os.MkdirAll(filepath.Join(cacheDir, "gcsfuse-file-cache", testVolumeID), defaultPerm); err != nil {
+		t.Fatalf("failed to setup cache dir: %v", err)
+	}
+	// The usage counts the blocks allocated to the cache files, like du.
+	var cachedBytes int64
+	for name, size := range map[string]int{"object-a": 1024, "object-b": 4096} {
+		cacheFile := filepath.Join(cacheDir, "gcsfuse-file-cache", testVolumeID, name)
+		if err := os.WriteFile(cacheFile, make([]byte, size), 0o600); err != nil {
+			t.Fatalf("failed to write the cache file: %v", err)
+		}
+		cachedBytes += allocatedBytes(t, cacheFile)
+	}
+	// A file of another volume in the sidecar cache emptyDir is not counted.
+	otherCacheDir := util.CacheDirPath(filepath.Join(filepath.Dir(filepath.Dir(volumePath)), "other-volume/mount"))
+	if err := os.MkdirAll(otherCacheDir, defaultPerm); err != nil {
+		t.Fatalf("failed to setup cache dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(otherCacheDir, "object-c"), make([]byte, 8192), 0o600); err != nil {
+		t.Fatalf("failed to write the cache file: %v", err)
+	}
+	notCachedVolumePath := filepath.Join(filepath.Dir(filepath.Dir(volumePath)), "not-cached-volume/mount")
+
+	cases := []struct {
+		name           string
+		req            *csi.NodeGetVolumeStatsRequest
+		expectedBytes  int64
+		expectedInodes int64
+		expectErr      codes.Code
+	}{
+		{
+			name:           "should report the usage of the cache subdirectory",
+			req:            &csi.NodeGetVolumeStatsRequest{VolumeId: testVolumeID, VolumePath: volumePath},
+			expectedBytes:  cachedBytes,
+			expectedInodes: 4,
+		},
+		{
+			name: "should report zero usage without the cache subdirectory",
+			req:  &csi.NodeGetVolumeStatsRequest{VolumeId: testVolumeID, VolumePath: notCachedVolumePath},
+		},
+		{
+			name:      "should fail when the volume path is not mounted",
+			req:       &csi.NodeGetVolumeStatsRequest{VolumeId: testVolumeID, VolumePath: "/not/mounted"},
+			expectErr: codes.NotFound,
+		},
+		{
+			name:      "should fail without a volume ID",
+			req:       &csi.NodeGetVolumeStatsRequest{VolumePath: volumePath},
+			expectErr: codes.InvalidArgument,
+		},
+		{
+			name:      "should fail without a volume path",
+			req:       &csi.NodeGetVolumeStatsRequest{VolumeId: testVolumeID},
+			expectErr: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			testEnv := initTestNodeServer(t)
+			testEnv.fm.MountPoints = []mount.MountPoint{
+				{Device: testVolumeID, Path: volumePath, Type: FuseMountType},
+				{Device: testVolumeID, Path: notCachedVolumePath, Type: FuseMountType},
+			}
+
+			resp, err := testEnv.ns.NodeGetVolumeStats(context.TODO(), tc.req)
+			if tc.expectErr != codes.OK {
+				if status.Code(err) != tc.expectErr {
+					t.Fatalf("got error %v, expected code %v", err, tc.expectErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			usage := map[csi.VolumeUsage_Unit]*csi.VolumeUsage{}
+			for _, u := range resp.GetUsage() {
+				usage[u.GetUnit()] = u
+			}
+			if got := usage[csi.VolumeUsage_BYTES].GetUsed(); got != tc.expectedBytes {
+				t.Errorf("got used bytes %v, expected %v", got, tc.expectedBytes)
+			}
+			if got := usage[csi.VolumeUsage_INODES].GetUsed(); got != tc.expectedInodes {
+				t.Errorf("got used inodes %v, expected %v", got, tc.expectedInodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"golang.org/x/sys/unix"
+)
+
+// cacheUsageTTL is how long the measured usage of a cache directory is reused.
+// The kubelet polls the volume stats every minute by default.
+const cacheUsageTTL = time.Minute
+
+// cacheUsage is the measured usage of a cache directory.
+type cacheUsage struct {
+	bytes      int64
+	inodes     int64
+	measuredAt time.Time
+}
+
+// cacheUsageStore caches the usage of the cache directories, so that the volume stats polls
+// do not walk a large file cache on every call.
+type cacheUsageStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	now   func() time.Time
+	usage map[string]cacheUsage
+}
+
+func newCacheUsageStore(ttl time.Duration) *cacheUsageStore {
+	return &cacheUsageStore{
+		ttl:   ttl,
+		now:   time.Now,
+		usage: map[string]cacheUsage{},
+	}
+}
+
+// get returns the bytes and inodes used by the files in the cache directory, measured at most ttl ago.
+// The expired entries, e.g. of the unpublished volumes, are dropped.
+func (cs *cacheUsageStore) get(cacheDir string) (cacheUsage, error) {
+	cs.mu.Lock()
+	now := cs.now()
+	for dir, u := range cs.usage {
+		if now.Sub(u.measuredAt) >= cs.ttl {
+			delete(cs.usage, dir)
+		}
+	}
+	u, ok := cs.usage[cacheDir]
+	cs.mu.Unlock()
+	if ok {
+		return u, nil
+	}
+
+	u, err := measureCacheDir(cacheDir)
+	if err != nil {
+		return cacheUsage{}, err
+	}
+	u.measuredAt = now
+
+	cs.mu.Lock()
+	cs.usage[cacheDir] = u
+	cs.mu.Unlock()
+
+	return u, nil
+}
+
+// measureCacheDir counts the inodes under the cache directory and the bytes allocated to its files, like du.
+// The allocated blocks are counted rather than the file sizes, since gcsfuse writes the cache files sparsely.
+// A missing cache directory, e.g. when the file cache is not enabled, reports zero usage.
+func measureCacheDir(cacheDir string) (cacheUsage, error) {
+	var u cacheUsage
+	if _, err := os.Stat(cacheDir); errors.Is(err, fs.ErrNotExist) {
+		return u, nil
+	}
+
+	err := filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The files can be evicted by gcsfuse while walking the cache directory.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+		if path == cacheDir {
+			return nil
+		}
+
+		u.inodes++
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			// st_blocks is in 512-byte units regardless of the file system block size.
+			u.bytes += stat.Blocks * 512
+		} else {
+			u.bytes += info.Size()
+		}
+
+		return nil
+	})
+
+	return u, err
+}
+
+// cacheDirUsage returns the bytes and inodes used by the files in the cache directory.
+// The capacity and the available bytes and inodes describe the file system backing the cache directory,
+// e.g. the sidecar container cache emptyDir on the node boot disk, not the bucket.
+func (cs *cacheUsageStore) cacheDirUsage(cacheDir string) ([]*csi.VolumeUsage, error) {
+	u, err := cs.get(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	bytesUsage := &csi.VolumeUsage{Unit: csi.VolumeUsage_BYTES, Used: u.bytes}
+	inodesUsage := &csi.VolumeUsage{Unit: csi.VolumeUsage_INODES, Used: u.inodes}
+	usage := []*csi.VolumeUsage{bytesUsage, inodesUsage}
+
+	var statfs unix.Statfs_t
+	if err := unix.Statfs(cacheDir, &statfs); err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return usage, nil
+		}
+
+		return nil, err
+	}
+	//nolint:gosec
+	bytesUsage.Total = int64(statfs.Blocks) * statfs.Bsize
+	//nolint:gosec
+	bytesUsage.Available = int64(statfs.Bavail) * statfs.Bsize
+	//nolint:gosec
+	inodesUsage.Total = int64(statfs.Files)
+	//nolint:gosec
+	inodesUsage.Available = int64(statfs.Ffree)
+
+	return usage, nil
+}
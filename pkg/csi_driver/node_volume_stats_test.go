@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocatedBytes returns the bytes allocated to the file.
+func allocatedBytes(t *testing.T, path string) int64 {
+	t.Helper()
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		t.Fatalf("failed to stat %q: %v", path, err)
+	}
+
+	return stat.Blocks * 512
+}
+
+func TestCacheUsageStore(t *testing.T) {
+	t.Parallel()
+	cacheDir := t.TempDir()
+	writeCacheFile := func(name string) int64 {
+		path := filepath.Join(cacheDir, name)
+		if err := os.WriteFile(path, make([]byte, 8192), 0o600); err != nil {
+			t.Fatalf("failed to write the cache file: %v", err)
+		}
+
+		return allocatedBytes(t, path)
+	}
+
+	now := time.Now()
+	cs := newCacheUsageStore(time.Minute)
+	cs.now = func() time.Time { return now }
+
+	expectedBytes := writeCacheFile("object-a")
+	u, err := cs.get(cacheDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.bytes != expectedBytes || u.inodes != 1 {
+		t.Errorf("got %v bytes and %v inodes, expected %v bytes and 1 inode", u.bytes, u.inodes, expectedBytes)
+	}
+
+	// The usage measured within the TTL is reused.
+	newBytes := writeCacheFile("object-b")
+	now = now.Add(30 * time.Second)
+	u, err = cs.get(cacheDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.bytes != expectedBytes || u.inodes != 1 {
+		t.Errorf("got %v bytes and %v inodes within the TTL, expected %v bytes and 1 inode", u.bytes, u.inodes, expectedBytes)
+	}
+
+	// The usage is measured again once the TTL expires.
+	expectedBytes += newBytes
+	now = now.Add(time.Minute)
+	u, err = cs.get(cacheDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.bytes != expectedBytes || u.inodes != 2 {
+		t.Errorf("got %v bytes and %v inodes after the TTL, expected %v bytes and 2 inodes", u.bytes, u.inodes, expectedBytes)
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// sharedMountStateFile records the share group of a volume in the sidecar tmp dir of the volume,
+// so that the share groups of the running Pods are rebuilt after the CSI driver restarts.
+// The file is removed with the Pod.
+const sharedMountStateFile = "shared-mount.json"
+
+// sharedMountState is the share group membership of a target path.
+type sharedMountState struct {
+	Key        string `json:"key"`
+	TargetPath string `json:"targetPath"`
+	Owner      string `json:"owner"`
+}
+
+// sharedMountGroup is a group of compatible volumes served by one gcsfuse process.
+// The owner target path is mounted by gcsfuse, the other target paths are bind mounts of it.
+// The source target path is a mounted target path of the group to bind mount, it is the owner until the owner leaves the group.
+type sharedMountGroup struct {
+	source      string
+	owner       string
+	targetPaths sets.String
+}
+
+// sharedMounts tracks the gcsfuse mounts shared by compatible volumes in the same Pod.
+type sharedMounts struct {
+	mu         sync.Mutex
+	groups     map[string]*sharedMountGroup
+	loadedPods sets.String
+	// stateDir returns the dir holding the share group state file of the target path.
+	stateDir func(targetPath string) (string, error)
+}
+
+func newSharedMounts() *sharedMounts {
+	return &sharedMounts{
+		groups:     map[string]*sharedMountGroup{},
+		loadedPods: sets.NewString(),
+		stateDir: func(targetPath string) (string, error) {
+			return util.PrepareEmptyDir(targetPath, false)
+		},
+	}
+}
+
+// sharedMountKey returns the key of the group the volume can share a gcsfuse process with.
+// Volumes are compatible when
+//   - they belong to the same Pod, because the gcsfuse process runs in the sidecar container of the Pod,
+//   - they mount the same bucket, which excludes the "_" volumes mounting all the accessible buckets,
+//   - they are read-only, so writes to one volume are never served by the file handles of another volume,
+//   - their gcsfuse mount options are identical, which covers the auth, cache and only-dir options.
+func sharedMountKey(targetPath, bucketName string, fuseMountOptions []string) (string, bool) {
+	podID, _, err := util.ParsePodIDVolumeFromTargetpath(targetPath)
+	if err != nil || bucketName == "" || bucketName == "_" {
+		return "", false
+	}
+
+	options := sets.NewString(fuseMountOptions...)
+	if !options.Has("ro") {
+		return "", false
+	}
+
+	return strings.Join([]string{podID, bucketName, strings.Join(options.List(), ",")}, "/"), true
+}
+
+// join adds the target path to the group of the key and returns the source target path to bind mount.
+// It returns false if the target path is the first one in the group and should be mounted by gcsfuse.
+func (m *sharedMounts) join(key, targetPath string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadPod(targetPath)
+	g, ok := m.groups[key]
+	if !ok {
+		g = &sharedMountGroup{source: targetPath, owner: targetPath, targetPaths: sets.NewString()}
+		m.groups[key] = g
+	}
+
+	g.targetPaths.Insert(targetPath)
+	m.saveState(key, g, targetPath)
+	if g.source == targetPath {
+		return "", false
+	}
+
+	return g.source, true
+}
+
+// setSource makes the target path the source and the owner of the group of the key,
+// it is used when the previous source target path is no longer mounted.
+func (m *sharedMounts) setSource(key, targetPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if g, ok := m.groups[key]; ok {
+		g.source, g.owner = targetPath, targetPath
+		g.targetPaths.Insert(targetPath)
+		m.saveState(key, g, targetPath)
+	}
+}
+
+// leave removes the target path from its group and returns true if other target paths still share its gcsfuse mount.
+// If the target path was the source of the group, any remaining bind mount becomes the new source.
+func (m *sharedMounts) leave(targetPath string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadPod(targetPath)
+	m.removeState(targetPath)
+	for key, g := range m.groups {
+		if !g.targetPaths.Has(targetPath) {
+			continue
+		}
+
+		g.targetPaths.Delete(targetPath)
+		if g.targetPaths.Len() == 0 {
+			delete(m.groups, key)
+			m.forgetPod(targetPath)
+
+			return false
+		}
+
+		if g.source == targetPath {
+			g.source = g.targetPaths.List()[0]
+		}
+
+		return true
+	}
+
+	m.forgetPod(targetPath)
+
+	return false
+}
+
+// owner returns the target path mounted by the gcsfuse process serving the target path.
+// The gcsfuse process uses the cache dir of the owner target path.
+func (m *sharedMounts) owner(targetPath string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadPod(targetPath)
+	for _, g := range m.groups {
+		if g.targetPaths.Has(targetPath) {
+			return g.owner
+		}
+	}
+
+	return targetPath
+}
+
+// loadPod rebuilds the groups of the Pod of the target path from the state files of the Pod volumes,
+// the first time the Pod is seen since the CSI driver started. The caller must hold the lock.
+func (m *sharedMounts) loadPod(targetPath string) {
+	podID, _, err := util.ParsePodIDVolumeFromTargetpath(targetPath)
+	if err != nil || m.loadedPods.Has(podID) {
+		return
+	}
+	m.loadedPods.Insert(podID)
+
+	dir, err := m.stateDir(targetPath)
+	if err != nil {
+		return
+	}
+
+	stateFiles, err := filepath.Glob(filepath.Join(filepath.Dir(dir), "*", sharedMountStateFile))
+	if err != nil {
+		klog.Warningf("failed to look up the shared mount state files of Pod %q: %v", podID, err)
+
+		return
+	}
+
+	loadedKeys := sets.NewString()
+	for _, f := range stateFiles {
+		state := sharedMountState{}
+		data, err := os.ReadFile(f)
+		if err == nil {
+			err = json.Unmarshal(data, &state)
+		}
+		if err != nil {
+			klog.Warningf("failed to load the shared mount state file %q: %v", f, err)
+
+			continue
+		}
+
+		g, ok := m.groups[state.Key]
+		if !ok {
+			g = &sharedMountGroup{source: state.Owner, owner: state.Owner, targetPaths: sets.NewString()}
+			m.groups[state.Key] = g
+		}
+		g.targetPaths.Insert(state.TargetPath)
+		loadedKeys.Insert(state.Key)
+	}
+
+	// the owner may have left the group before the restart, any remaining target path becomes the source.
+	for _, key := range loadedKeys.List() {
+		if g := m.groups[key]; !g.targetPaths.Has(g.source) {
+			g.source = g.targetPaths.List()[0]
+		}
+	}
+
+	if len(stateFiles) > 0 {
+		klog.V(4).Infof("loaded %v shared mount groups of Pod %q", loadedKeys.Len(), podID)
+	}
+}
+
+// forgetPod stops tracking the Pod of the target path when the Pod has no group left. The caller must hold the lock.
+func (m *sharedMounts) forgetPod(targetPath string) {
+	podID, _, err := util.ParsePodIDVolumeFromTargetpath(targetPath)
+	if err != nil {
+		return
+	}
+
+	for key := range m.groups {
+		if strings.HasPrefix(key, podID+"/") {
+			return
+		}
+	}
+	m.loadedPods.Delete(podID)
+}
+
+// saveState persists the group membership of the target path. The caller must hold the lock.
+func (m *sharedMounts) saveState(key string, g *sharedMountGroup, targetPath string) {
+	if err := m.writeState(sharedMountState{Key: key, TargetPath: targetPath, Owner: g.owner}); err != nil {
+		klog.Warningf("failed to save the shared mount state of target path %q, the share group is lost if the CSI driver restarts: %v", targetPath, err)
+	}
+}
+
+func (m *sharedMounts) writeState(state sharedMountState) error {
+	dir, err := m.stateDir(state.TargetPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the state: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("mkdir failed for path %q: %w", dir, err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, sharedMountStateFile), data, 0o600)
+}
+
+// removeState removes the state file of the target path. The caller must hold the lock.
+func (m *sharedMounts) removeState(targetPath string) {
+	dir, err := m.stateDir(targetPath)
+	if err != nil {
+		return
+	}
+
+	if err := os.Remove(filepath.Join(dir, sharedMountStateFile)); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("failed to remove the shared mount state of target path %q: %v", targetPath, err)
+	}
+}
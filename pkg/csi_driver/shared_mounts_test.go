@@ -0,0 +1,239 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
+)
+
+const (
+	testSharedTargetPathPod1Vol1 = "/var/lib/kubelet/pods/pod-1/volumes/kubernetes.io~csi/vol-1/mount"
+	testSharedTargetPathPod1Vol2 = "/var/lib/kubelet/pods/pod-1/volumes/kubernetes.io~csi/vol-2/mount"
+	testSharedTargetPathPod1Vol3 = "/var/lib/kubelet/pods/pod-1/volumes/kubernetes.io~csi/vol-3/mount"
+	testSharedTargetPathPod2Vol1 = "/var/lib/kubelet/pods/pod-2/volumes/kubernetes.io~csi/vol-1/mount"
+)
+
+func TestSharedMountKey(t *testing.T) {
+	t.Parallel()
+
+	key := func(targetPath, bucketName string, options ...string) string {
+		k, ok := sharedMountKey(targetPath, bucketName, options)
+		if !ok {
+			return ""
+		}
+
+		return k
+	}
+
+	baseKey := key(testSharedTargetPathPod1Vol1, "bucket", "implicit-dirs", "ro")
+	if baseKey == "" {
+		t.Fatalf("expected a read-only volume to be shareable")
+	}
+
+	testCases := []struct {
+		name             string
+		targetPath       string
+		bucketName       string
+		options          []string
+		expectCompatible bool
+	}{
+		{
+			name:             "another volume of the same Pod with the same bucket and options",
+			targetPath:       testSharedTargetPathPod1Vol2,
+			bucketName:       "bucket",
+			options:          []string{"ro", "implicit-dirs"},
+			expectCompatible: true,
+		},
+		{
+			name:       "a volume of another Pod",
+			targetPath: testSharedTargetPathPod2Vol1,
+			bucketName: "bucket",
+			options:    []string{"implicit-dirs", "ro"},
+		},
+		{
+			name:       "a volume of another bucket",
+			targetPath: testSharedTargetPathPod1Vol2,
+			bucketName: "another-bucket",
+			options:    []string{"implicit-dirs", "ro"},
+		},
+		{
+			name:       "a volume with different mount options",
+			targetPath: testSharedTargetPathPod1Vol2,
+			bucketName: "bucket",
+			options:    []string{"implicit-dirs", "only-dir=dir", "ro"},
+		},
+		{
+			name:       "a volume with different auth options",
+			targetPath: testSharedTargetPathPod1Vol2,
+			bucketName: "bucket",
+			options:    []string{"gcs-auth:anonymous-access:true", "implicit-dirs", "ro"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("test case: %s", tc.name)
+		if compatible := key(tc.targetPath, tc.bucketName, tc.options...) == baseKey; compatible != tc.expectCompatible {
+			t.Errorf("Got compatible %v, but expected %v", compatible, tc.expectCompatible)
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		targetPath string
+		bucketName string
+		options    []string
+	}{
+		{
+			name:       "a read-write volume",
+			targetPath: testSharedTargetPathPod1Vol1,
+			bucketName: "bucket",
+			options:    []string{"implicit-dirs"},
+		},
+		{
+			name:       "a volume mounting all the accessible buckets",
+			targetPath: testSharedTargetPathPod1Vol1,
+			bucketName: "_",
+			options:    []string{"ro"},
+		},
+		{
+			name:       "an invalid target path",
+			targetPath: "/tmp/mount",
+			bucketName: "bucket",
+			options:    []string{"ro"},
+		},
+	} {
+		t.Logf("test case: %s", tc.name)
+		if _, ok := sharedMountKey(tc.targetPath, tc.bucketName, tc.options); ok {
+			t.Errorf("expected %s to be not shareable", tc.name)
+		}
+	}
+}
+
+// newTestSharedMounts returns a sharedMounts keeping the state files of the target paths in the state root.
+func newTestSharedMounts(stateRoot string) *sharedMounts {
+	m := newSharedMounts()
+	m.stateDir = func(targetPath string) (string, error) {
+		podID, volumeName, err := util.ParsePodIDVolumeFromTargetpath(targetPath)
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(stateRoot, podID, volumeName), nil
+	}
+
+	return m
+}
+
+func TestSharedMounts(t *testing.T) {
+	t.Parallel()
+
+	m := newTestSharedMounts(t.TempDir())
+	key := "pod-1/bucket/ro"
+
+	if _, ok := m.join(key, testSharedTargetPathPod1Vol1); ok {
+		t.Fatalf("expected the first target path to be mounted by gcsfuse")
+	}
+
+	// Joining again, e.g. when NodePublishVolume is retried, keeps the target path as the source.
+	if _, ok := m.join(key, testSharedTargetPathPod1Vol1); ok {
+		t.Fatalf("expected the source target path to be mounted by gcsfuse")
+	}
+
+	for _, targetPath := range []string{testSharedTargetPathPod1Vol2, testSharedTargetPathPod1Vol3} {
+		source, ok := m.join(key, targetPath)
+		if !ok || source != testSharedTargetPathPod1Vol1 {
+			t.Fatalf("Got source %q, %v, but expected %q", source, ok, testSharedTargetPathPod1Vol1)
+		}
+	}
+
+	if stillShared := m.leave(testSharedTargetPathPod1Vol1); !stillShared {
+		t.Errorf("expected the gcsfuse mount to be still shared after the source target path left")
+	}
+
+	// One of the bind mounts becomes the new source.
+	source, ok := m.join(key, testSharedTargetPathPod1Vol1)
+	if !ok || (source != testSharedTargetPathPod1Vol2 && source != testSharedTargetPathPod1Vol3) {
+		t.Errorf("Got source %q, %v, but expected one of the remaining target paths", source, ok)
+	}
+
+	m.setSource(key, testSharedTargetPathPod1Vol1)
+	if source, ok := m.join(key, testSharedTargetPathPod1Vol2); !ok || source != testSharedTargetPathPod1Vol1 {
+		t.Errorf("Got source %q, %v, but expected %q", source, ok, testSharedTargetPathPod1Vol1)
+	}
+
+	m.leave(testSharedTargetPathPod1Vol1)
+	m.leave(testSharedTargetPathPod1Vol2)
+	if stillShared := m.leave(testSharedTargetPathPod1Vol3); stillShared {
+		t.Errorf("expected the gcsfuse mount to be not shared after all the target paths left")
+	}
+
+	if _, ok := m.join(key, testSharedTargetPathPod1Vol2); ok {
+		t.Errorf("expected the first target path of a new group to be mounted by gcsfuse")
+	}
+
+	if stillShared := m.leave(testSharedTargetPathPod2Vol1); stillShared {
+		t.Errorf("expected an unknown target path to be not shared")
+	}
+}
+
+func TestSharedMountsAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	stateRoot := t.TempDir()
+	m := newTestSharedMounts(stateRoot)
+	key := "pod-1/bucket/ro"
+
+	m.join(key, testSharedTargetPathPod1Vol1)
+	m.join(key, testSharedTargetPathPod1Vol2)
+	m.join(key, testSharedTargetPathPod1Vol3)
+	m.leave(testSharedTargetPathPod1Vol1)
+
+	// The groups are rebuilt from the state files after the CSI driver restarts.
+	m = newTestSharedMounts(stateRoot)
+
+	if owner := m.owner(testSharedTargetPathPod1Vol2); owner != testSharedTargetPathPod1Vol1 {
+		t.Errorf("Got owner %q, but expected %q", owner, testSharedTargetPathPod1Vol1)
+	}
+
+	if owner := m.owner(testSharedTargetPathPod2Vol1); owner != testSharedTargetPathPod2Vol1 {
+		t.Errorf("Got owner %q of a target path not sharing a gcsfuse mount, but expected itself", owner)
+	}
+
+	source, ok := m.join(key, testSharedTargetPathPod1Vol1)
+	if !ok || (source != testSharedTargetPathPod1Vol2 && source != testSharedTargetPathPod1Vol3) {
+		t.Errorf("Got source %q, %v, but expected one of the remaining target paths", source, ok)
+	}
+
+	m = newTestSharedMounts(stateRoot)
+	if stillShared := m.leave(testSharedTargetPathPod1Vol2); !stillShared {
+		t.Errorf("expected the gcsfuse mount to be still shared after the CSI driver restarts")
+	}
+	m.leave(testSharedTargetPathPod1Vol1)
+	if stillShared := m.leave(testSharedTargetPathPod1Vol3); stillShared {
+		t.Errorf("expected the gcsfuse mount to be not shared after all the target paths left")
+	}
+
+	// All the state files are removed once the target paths left.
+	m = newTestSharedMounts(stateRoot)
+	if _, ok := m.join(key, testSharedTargetPathPod1Vol2); ok {
+		t.Errorf("expected the first target path of a new group to be mounted by gcsfuse")
+	}
+}
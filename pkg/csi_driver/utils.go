@@ -20,13 +20,17 @@ package driver
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/cloud_provider/storage"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
 	pbSanitizer "github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
@@ -37,6 +41,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 )
 
@@ -45,15 +50,71 @@ const (
 	DeleteVolumeCSIFullMethod      = "/csi.v1.Controller/DeleteVolume"
 	NodePublishVolumeCSIFullMethod = "/csi.v1.Node/NodePublishVolume"
 
-	VolumeContextKeyMountOptions              = "mountOptions"
-	VolumeContextKeyFileCacheCapacity         = "fileCacheCapacity"
-	VolumeContextKeyFileCacheForRangeRead     = "fileCacheForRangeRead"
-	VolumeContextKeyMetadataStatCacheCapacity = "metadataStatCacheCapacity"
-	VolumeContextKeyMetadataTypeCacheCapacity = "metadataTypeCacheCapacity"
-	VolumeContextKeyMetadataCacheTTLSeconds   = "metadataCacheTTLSeconds"
-	VolumeContextKeyGcsfuseLoggingSeverity    = "gcsfuseLoggingSeverity"
-	VolumeContextKeySkipCSIBucketAccessCheck  = "skipCSIBucketAccessCheck"
-	VolumeContextKeyDisableMetrics            = "disableMetrics"
+	VolumeContextKeyMountOptions                = "mountOptions"
+	VolumeContextKeyFileCacheCapacity           = "fileCacheCapacity"
+	VolumeContextKeyFileCacheForRangeRead       = "fileCacheForRangeRead"
+	VolumeContextKeyMetadataStatCacheCapacity   = "metadataStatCacheCapacity"
+	VolumeContextKeyMetadataTypeCacheCapacity   = "metadataTypeCacheCapacity"
+	VolumeContextKeyMetadataCacheTTLSeconds     = "metadataCacheTTLSeconds"
+	VolumeContextKeyGcsfuseLoggingSeverity      = "gcsfuseLoggingSeverity"
+	VolumeContextKeySkipCSIBucketAccessCheck    = "skipCSIBucketAccessCheck"
+	VolumeContextKeyDisableMetrics              = "disableMetrics"
+	VolumeContextKeyDisableAtime                = "disableAtime"
+	VolumeContextKeyStatCacheTTLSeconds         = "statCacheTtlSeconds"
+	VolumeContextKeyNegativeStatCacheTTLSeconds = "negativeStatCacheTtlSeconds"
+	VolumeContextKeyFuseMaxRead                 = "fuseMaxRead"
+	VolumeContextKeyCacheValidationMode         = "cacheValidationMode"
+	VolumeContextKeyEnableReadStallRetry        = "enableReadStallRetry"
+	VolumeContextKeyReadStallInitialTimeoutMs   = "readStallInitialTimeoutMs"
+	VolumeContextKeyBillingProject              = "billingProject"
+	VolumeContextKeyAuthMode                    = "authMode"
+	VolumeContextKeyMaxOpenFiles                = "maxOpenFiles"
+	VolumeContextKeyWriteDurability             = "writeDurability"
+	VolumeContextKeySharedMounter               = "sharedMounter"
+	VolumeContextKeyLocalFileCacheMode          = "localFileCacheMode"
+	VolumeContextKeyLogMaxFileSizeMb            = "logMaxFileSizeMb"
+	VolumeContextKeyLogBackupCount              = "logBackupCount"
+	VolumeContextKeyLogCompress                 = "logCompress"
+	VolumeContextKeyFileCacheMinFreePercent     = "fileCacheMinFreePercent"
+	VolumeContextKeySourceReadOnly              = "sourceReadOnly"
+	VolumeContextKeyTypeCacheMaxEntries         = "typeCacheMaxEntries"
+	VolumeContextKeyPinGeneration               = "pinGeneration"
+	VolumeContextKeyEnableBufferedRead          = "enableBufferedRead"
+	VolumeContextKeyReadBufferSizeMb            = "readBufferSizeMb"
+	VolumeContextKeyPrefetchManifestConfigMap   = "prefetchManifestConfigMap"
+	VolumeContextKeyDisableReadAheadTuning      = "disableReadAheadTuning"
+	VolumeContextKeyCreateUmask                 = "createUmask"
+	VolumeContextKeyMaxConcurrentOperations     = "maxConcurrentOperations"
+	VolumeContextKeyEnableEmptyManagedFolders   = "enableEmptyManagedFolders"
+	VolumeContextKeyChunkTransferTimeoutSeconds = "chunkTransferTimeoutSeconds"
+	VolumeContextKeyCollectDiagnosticsOnFailure = "collectDiagnosticsOnFailure"
+	VolumeContextKeyUserAgentSuffix             = "userAgentSuffix"
+	VolumeContextKeyAllowRoot                   = "allowRoot"
+	VolumeContextKeyCacheCleanupOnUnmount       = "cacheCleanupOnUnmount"
+	VolumeContextKeyMaxRetryAttempts            = "maxRetryAttempts"
+	VolumeContextKeyFuseMaxBackground           = "fuseMaxBackground"
+	VolumeContextKeyFuseCongestionThreshold     = "fuseCongestionThreshold"
+	VolumeContextKeyCacheMedium                 = "cacheMedium"
+	VolumeContextKeyEnableParallelDirops        = "enableParallelDirops"
+	VolumeContextKeyMountOverNonEmpty           = "mountOverNonEmpty"
+	VolumeContextKeyAccessLogPath               = "accessLogPath"
+	VolumeContextKeyRetryOnStatusCodes          = "retryOnStatusCodes"
+	VolumeContextKeyFsName                      = "fsName"
+	VolumeContextKeyCustomEndpoint              = "customEndpoint"
+	VolumeContextKeyAllowSuid                   = "allowSuid"
+	VolumeContextKeyAllowDev                    = "allowDev"
+	VolumeContextKeyDisableExec                 = "disableExec"
+	VolumeContextKeyDisableWritebackThrottle    = "disableWritebackThrottle"
+	VolumeContextKeyOfflineCacheServing         = "offlineCacheServing"
+	VolumeContextKeyDeferPermissions            = "deferPermissions"
+	VolumeContextKeyAutoWriteStrategy           = "autoWriteStrategy"
+	VolumeContextKeyWriteFileSizeHintMb         = "writeFileSizeHintMb"
+	VolumeContextKeyCacheBarrierFile            = "cacheBarrierFile"
+	VolumeContextKeyDisableReadAhead            = "disableReadAhead"
+	VolumeContextKeyGCSFuseBinaryPath           = "gcsfuseBinaryPath"
+	VolumeContextKeyRequestLogSampleRate        = "requestLogSampleRate"
+	VolumeContextKeyTracingMode                 = "tracingMode"
+	VolumeContextKeyTracingSamplingRatio        = "tracingSamplingRatio"
 
 	//nolint:revive,stylecheck
 	VolumeContextKeyMetadataCacheTtlSeconds = "metadataCacheTtlSeconds"
@@ -66,6 +127,71 @@ const (
 	VolumeContextKeyEphemeral           = "csi.storage.k8s.io/ephemeral"
 	VolumeContextKeyBucketName          = "bucketName"
 	tokenServerSidecarMinVersion        = "v1.12.2-gke.0" // #nosec G101
+
+	// Supported values of the cacheValidationMode volume attribute.
+	cacheValidationModeNone = "none"
+	cacheValidationModeEtag = "etag"
+	cacheValidationModeTTL  = "ttl"
+
+	// Supported values of the authMode volume attribute.
+	authModeDriver    = "driver"
+	authModeGcsfuse   = "gcsfuse"
+	authModeAnonymous = "anonymous"
+
+	// Supported values of the writeDurability volume attribute.
+	writeDurabilityBuffered    = "buffered"
+	writeDurabilitySynchronous = "synchronous"
+
+	// Supported values of the cacheCleanupOnUnmount volume attribute.
+	// The default retain policy keeps the cache warm for the volume remounted in the same Pod.
+	cacheCleanupOnUnmountRetain = "retain"
+	cacheCleanupOnUnmountDelete = "delete"
+
+	// Supported values of the cacheMedium volume attribute.
+	cacheMediumDisk   = "disk"
+	cacheMediumMemory = "memory"
+	cacheMediumSSD    = "ssd"
+
+	// Supported values of the localFileCacheMode volume attribute.
+	localFileCacheModeDefault  = "default"
+	localFileCacheModeDirect   = "direct"
+	localFileCacheModeParallel = "parallel"
+
+	// Supported values of the tracingMode volume attribute.
+	tracingModeStdout   = "stdout"
+	tracingModeGCPTrace = "gcptrace"
+
+	// The kernel-supported range of the fuse max_read mount option in bytes.
+	minFuseTransferSize = 4096
+	maxFuseTransferSize = 1024 * 1024
+
+	// The kernel default of the fuse max_background mount option.
+	defaultFuseMaxBackground = 12
+
+	// The estimated memory of a gcsfuse type cache entry in bytes, used to convert typeCacheMaxEntries to the cache size.
+	typeCacheEntrySizeBytes = 200
+
+	// gcsfuse streams the writes in blocks of 32 MiB by default, the files smaller than one block gain nothing from the streaming writes.
+	streamingWritesMinFileSizeMb = 32
+	// The prefix of the gcsfuse mount option enabling or disabling the streaming writes.
+	streamingWritesMountOptionPrefix = "write:enable-streaming-writes:"
+
+	// gcsfuse logs the GCS requests only at the trace logging severity.
+	requestLogSeverity = "trace"
+
+	// The prefix of the SELinux context mount option, which is passed to the kernel rather than to gcsfuse.
+	seLinuxContextMountOptionPrefix = "context="
+)
+
+// Machine-parseable reasons included in the NodePublishVolume error messages.
+const (
+	ErrReasonAuthFailed          = "AuthFailed"
+	ErrReasonBucketNotFound      = "BucketNotFound"
+	ErrReasonQuotaExceeded       = "QuotaExceeded"
+	ErrReasonNetworkUnavailable  = "NetworkUnavailable"
+	ErrReasonOutOfMemory         = "OutOfMemory"
+	ErrReasonInvalidMountOptions = "InvalidMountOptions"
+	ErrReasonUnknown             = "Unknown"
 )
 
 func NewVolumeCapabilityAccessMode(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability_AccessMode {
@@ -174,17 +300,168 @@ func joinMountOptions(existingOptions []string, newOptions []string) []string {
 }
 
 var volumeAttributesToMountOptionsMapping = map[string]string{
-	VolumeContextKeyFileCacheCapacity:         "file-cache:max-size-mb:",
-	VolumeContextKeyFileCacheForRangeRead:     "file-cache:cache-file-for-range-read:",
-	VolumeContextKeyMetadataStatCacheCapacity: "metadata-cache:stat-cache-max-size-mb:",
-	VolumeContextKeyMetadataTypeCacheCapacity: "metadata-cache:type-cache-max-size-mb:",
-	VolumeContextKeyMetadataCacheTTLSeconds:   "metadata-cache:ttl-secs:",
-	VolumeContextKeyMetadataCacheTtlSeconds:   "metadata-cache:ttl-secs:",
-	VolumeContextKeyGcsfuseLoggingSeverity:    "logging:severity:",
-	VolumeContextKeySkipCSIBucketAccessCheck:  "",
-	VolumeContextKeyDisableMetrics:            util.DisableMetricsForGKE + ":",
+	VolumeContextKeyFileCacheCapacity:           "file-cache:max-size-mb:",
+	VolumeContextKeyFileCacheForRangeRead:       "file-cache:cache-file-for-range-read:",
+	VolumeContextKeyMetadataStatCacheCapacity:   "metadata-cache:stat-cache-max-size-mb:",
+	VolumeContextKeyMetadataTypeCacheCapacity:   "metadata-cache:type-cache-max-size-mb:",
+	VolumeContextKeyMetadataCacheTTLSeconds:     "metadata-cache:ttl-secs:",
+	VolumeContextKeyMetadataCacheTtlSeconds:     "metadata-cache:ttl-secs:",
+	VolumeContextKeyGcsfuseLoggingSeverity:      "logging:severity:",
+	VolumeContextKeySkipCSIBucketAccessCheck:    "",
+	VolumeContextKeyDisableMetrics:              util.DisableMetricsForGKE + ":",
+	VolumeContextKeyDisableAtime:                "o=noatime",
+	VolumeContextKeyStatCacheTTLSeconds:         "metadata-cache:ttl-secs:",
+	VolumeContextKeyNegativeStatCacheTTLSeconds: "metadata-cache:negative-ttl-secs:",
+	VolumeContextKeyFuseMaxRead:                 "max_read=",
+	VolumeContextKeyCacheValidationMode:         "metadata-cache:ttl-secs:",
+	VolumeContextKeyEnableReadStallRetry:        readStallRetryMountOptionPrefix + "enable:",
+	VolumeContextKeyReadStallInitialTimeoutMs:   readStallRetryMountOptionPrefix + "initial-req-timeout:",
+	VolumeContextKeyBillingProject:              "billing-project=",
+	VolumeContextKeyAuthMode:                    "gcs-auth:anonymous-access:",
+	VolumeContextKeyMaxOpenFiles:                "max-open-files=",
+	VolumeContextKeyWriteDurability:             "o=sync",
+	VolumeContextKeySharedMounter:               "",
+	VolumeContextKeyLocalFileCacheMode:          "file-cache:",
+	VolumeContextKeyLogMaxFileSizeMb:            "logging:log-rotate:max-file-size-mb:",
+	VolumeContextKeyLogBackupCount:              "logging:log-rotate:backup-file-count:",
+	VolumeContextKeyLogCompress:                 "logging:log-rotate:compress:",
+	VolumeContextKeyFileCacheMinFreePercent:     "file-cache-min-free-percent=",
+	VolumeContextKeySourceReadOnly:              "source-read-only=",
+	VolumeContextKeyTypeCacheMaxEntries:         "metadata-cache:type-cache-max-size-mb:",
+	VolumeContextKeyPinGeneration:               "",
+	VolumeContextKeyEnableBufferedRead:          "read:enable-buffered-read:",
+	VolumeContextKeyReadBufferSizeMb:            "read:block-size-mb:",
+	VolumeContextKeyPrefetchManifestConfigMap:   "",
+	VolumeContextKeyDisableReadAheadTuning:      "disable_read_ahead_tuning",
+	VolumeContextKeyCreateUmask:                 "file-mode=",
+	VolumeContextKeyMaxConcurrentOperations:     maxConnsPerHostMountOption + ":",
+	VolumeContextKeyEnableEmptyManagedFolders:   "list:enable-empty-managed-folders:",
+	VolumeContextKeyChunkTransferTimeoutSeconds: "gcs-retries:chunk-transfer-timeout-secs:",
+	VolumeContextKeyCollectDiagnosticsOnFailure: "collect-diagnostics-on-failure=",
+	VolumeContextKeyUserAgentSuffix:             "app-name=",
+	VolumeContextKeyAllowRoot:                   "allow_root",
+	VolumeContextKeyCacheCleanupOnUnmount:       "cache-cleanup-on-unmount=",
+	VolumeContextKeyMaxRetryAttempts:            "gcs-retries:max-retry-attempts:",
+	VolumeContextKeyFuseMaxBackground:           "max_background=",
+	VolumeContextKeyFuseCongestionThreshold:     "congestion_threshold=",
+	VolumeContextKeyCacheMedium:                 "",
+	VolumeContextKeyEnableParallelDirops:        "file-system:enable-parallel-dirops:true",
+	VolumeContextKeyMountOverNonEmpty:           "nonempty",
+	VolumeContextKeyAccessLogPath:               "logging:file-path:",
+	VolumeContextKeyRetryOnStatusCodes:          "",
+	VolumeContextKeyFsName:                      "fsname=",
+	VolumeContextKeyCustomEndpoint:              "custom-endpoint=",
+	VolumeContextKeyAllowSuid:                   "suid",
+	VolumeContextKeyAllowDev:                    "dev",
+	VolumeContextKeyDisableExec:                 "o=noexec",
+	VolumeContextKeyDisableWritebackThrottle:    "disable_writeback_throttle",
+	VolumeContextKeyOfflineCacheServing:         "",
+	VolumeContextKeyDeferPermissions:            "defer_permissions",
+	VolumeContextKeyAutoWriteStrategy:           "",
+	VolumeContextKeyWriteFileSizeHintMb:         "",
+	VolumeContextKeyCacheBarrierFile:            "",
+	VolumeContextKeyDisableReadAhead:            "disable_read_ahead",
+	VolumeContextKeyGCSFuseBinaryPath:           "gcsfuse-binary-path=",
+	VolumeContextKeyRequestLogSampleRate:        "request-log-sample-rate=",
+	VolumeContextKeyTracingMode:                 "monitoring:experimental-tracing-mode:",
+	VolumeContextKeyTracingSamplingRatio:        "monitoring:experimental-tracing-sampling-ratio:",
+}
+
+// accessLogVolumeMountPaths are the mount paths of the writable sidecar container volumes that can hold the access log file.
+var accessLogVolumeMountPaths = []string{
+	webhook.SidecarContainerTmpVolumeMountPath,
+	webhook.SidecarContainerBufferVolumeMountPath,
+	webhook.SidecarContainerCacheVolumeMountPath,
+}
+
+// pinnedMetadataCacheMountOptions cache the metadata without expiry or eviction.
+var pinnedMetadataCacheMountOptions = []string{
+	"metadata-cache:ttl-secs:-1",
+	"metadata-cache:negative-ttl-secs:-1",
+	"metadata-cache:stat-cache-max-size-mb:-1",
+	"metadata-cache:type-cache-max-size-mb:-1",
+	"file-system:kernel-list-cache-ttl-secs:-1",
+}
+
+// pinGenerationMountOptions make gcsfuse keep serving every object at the generation it observed first:
+// the metadata is cached without expiry or eviction, and the writes are refused.
+var pinGenerationMountOptions = slices.Concat(pinnedMetadataCacheMountOptions, []string{"source-read-only=true"})
+
+// offlineCacheServingMountOptions make gcsfuse serve the files in the file cache without reaching GCS:
+// the metadata of the cached files is never revalidated, so the cached content stays valid when GCS is unreachable.
+// The negative entries still expire, so the uncached files are looked up in GCS and fail with an I/O error when GCS is unreachable.
+var offlineCacheServingMountOptions = slices.DeleteFunc(slices.Clone(pinnedMetadataCacheMountOptions), func(o string) bool {
+	return o == "metadata-cache:negative-ttl-secs:-1"
+})
+
+// autoWriteStrategyMountOptions picks the gcsfuse write path of a volume from the expected size of the written files.
+// With the streaming writes enabled, gcsfuse streams the sequential writes of a new file to GCS as they arrive,
+// and falls back to staging the file in the temp dir for the out-of-order writes and the edits of the existing files,
+// so the streaming writes are picked unless the files are known to be smaller than a streaming block,
+// which are then staged in the temp dir to avoid the fallback on random writes.
+func autoWriteStrategyMountOptions(fileSizeHintMb *int64) []string {
+	if fileSizeHintMb != nil && *fileSizeHintMb < streamingWritesMinFileSizeMb {
+		return []string{streamingWritesMountOptionPrefix + "false"}
+	}
+
+	return []string{streamingWritesMountOptionPrefix + "true"}
 }
 
+// pinGenerationConflictingAttributes are the volume attributes that would refresh or evict the pinned metadata.
+var pinGenerationConflictingAttributes = []string{
+	VolumeContextKeyMetadataCacheTTLSeconds,
+	VolumeContextKeyMetadataCacheTtlSeconds,
+	VolumeContextKeyNegativeStatCacheTTLSeconds,
+	VolumeContextKeyStatCacheTTLSeconds,
+	VolumeContextKeyCacheValidationMode,
+	VolumeContextKeyMetadataStatCacheCapacity,
+	VolumeContextKeyMetadataTypeCacheCapacity,
+	VolumeContextKeyTypeCacheMaxEntries,
+}
+
+// authMountOptions are the gcsfuse mount options that decide where gcsfuse gets its credentials from.
+var authMountOptions = []string{"gcs-auth:", "key-file", "token-url", "reuse-token-from-url", "anonymous-access", identityProviderMountOption}
+
+// identityProviderMountOption makes the sidecar start the token server using the given identity provider.
+const identityProviderMountOption = "token-server-identity-provider="
+
+// projectIDRegex matches a GCP project ID: 6 to 30 lowercase letters, digits or hyphens,
+// starting with a letter and not ending with a hyphen.
+var projectIDRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+
+// userAgentSuffixRegex matches 1 to 64 letters, digits, dots, underscores, hyphens or slashes,
+// rejecting the spaces, control characters and separators that could inject headers or mount options.
+var userAgentSuffixRegex = regexp.MustCompile(`^[A-Za-z0-9._/-]{1,64}$`)
+
+// fsNameRegex matches 1 to 64 letters, digits, dots, underscores, hyphens or slashes,
+// rejecting the spaces, which the mount table escapes, and the separators of the mount options.
+var fsNameRegex = regexp.MustCompile(`^[A-Za-z0-9._/-]{1,64}$`)
+
+// localFileCacheModeToMountOptions maps the allowlisted localFileCacheMode values to the gcsfuse file cache settings.
+// The default mode keeps the gcsfuse default behavior.
+var localFileCacheModeToMountOptions = map[string]string{
+	localFileCacheModeDefault:  "",
+	localFileCacheModeDirect:   "enable-o-direct:true",
+	localFileCacheModeParallel: "enable-parallel-downloads:true",
+}
+
+// fileCacheODirectMountOption makes gcsfuse read the cached files with O_DIRECT, bypassing the page cache.
+const fileCacheODirectMountOption = "file-cache:enable-o-direct"
+
+// maxConnsPerHostMountOption caps the concurrent GCS connections of gcsfuse,
+// which bounds the concurrent GCS operations of the volume.
+const maxConnsPerHostMountOption = "gcs-connection:max-conns-per-host"
+
+// maxConcurrentOperationsCeiling is the largest maxConcurrentOperations value,
+// the larger values are clamped to avoid exhausting the node network and the gcsfuse memory.
+const maxConcurrentOperationsCeiling = 1024
+
+// gcsfuseDefaultFileMode is the permission bits gcsfuse reports for the files when the file-mode flag is not set.
+const gcsfuseDefaultFileMode = 0o644
+
+// readStallRetryMountOptionPrefix is the gcsfuse config file section of the read stall retry settings.
+const readStallRetryMountOptionPrefix = "gcs-retries:read-stall:"
+
 // parseVolumeAttributes parses volume attributes and convert them to gcsfuse mount options.
 func parseVolumeAttributes(fuseMountOptions []string, volumeContext map[string]string) ([]string, bool, bool, error) {
 	if mountOptions, ok := volumeContext[VolumeContextKeyMountOptions]; ok {
@@ -222,7 +499,7 @@ func parseVolumeAttributes(fuseMountOptions []string, volumeContext map[string]s
 			mountOptionWithValue = mountOption + value
 
 		// parse bool volume attributes
-		case VolumeContextKeyFileCacheForRangeRead, VolumeContextKeySkipCSIBucketAccessCheck, VolumeContextKeyDisableMetrics:
+		case VolumeContextKeyFileCacheForRangeRead, VolumeContextKeySkipCSIBucketAccessCheck, VolumeContextKeyDisableMetrics, VolumeContextKeyEnableReadStallRetry, VolumeContextKeySharedMounter, VolumeContextKeyLogCompress, VolumeContextKeySourceReadOnly, VolumeContextKeyEnableBufferedRead, VolumeContextKeyEnableEmptyManagedFolders, VolumeContextKeyCollectDiagnosticsOnFailure:
 			if boolVal, err := strconv.ParseBool(value); err == nil {
 				if volumeAttribute == VolumeContextKeySkipCSIBucketAccessCheck {
 					skipCSIBucketAccessCheck = boolVal
@@ -232,6 +509,12 @@ func parseVolumeAttributes(fuseMountOptions []string, volumeContext map[string]s
 					continue
 				}
 
+				if volumeAttribute == VolumeContextKeySharedMounter {
+					// The sharedMounter volume attribute is read by NodePublishVolume,
+					// and there is no translation to GCSFuse mount options.
+					continue
+				}
+
 				if volumeAttribute == VolumeContextKeyDisableMetrics {
 					disableMetricsCollection = boolVal
 				}
@@ -241,8 +524,42 @@ func parseVolumeAttributes(fuseMountOptions []string, volumeContext map[string]s
 				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid bool value, got %q", volumeAttribute, value)
 			}
 
+		// disableAtime is translated to the noatime kernel mount option,
+		// atime updates are left as is when the value is false.
+		// allowRoot is translated to the allow_root mount option, which replaces the allow_other kernel mount option.
+		// enableParallelDirops is only passed to gcsfuse when enabled, so the older gcsfuse versions can mount the volume with the default.
+		// mountOverNonEmpty is translated to the nonempty mount option, which lets the mount hide the existing files in the target path.
+		// allowSuid and allowDev are translated to the suid and dev mount options, which lift the default nosuid and nodev kernel mount options
+		// on the nodes that allowlist them, the secure defaults are kept when the values are false,
+		// so an explicit false conflicts with the matching mount option.
+		// disableExec is translated to the noexec kernel mount option.
+		// disableWritebackThrottle is translated to the disable_writeback_throttle mount option, which lifts the bdi writeback limits of the mount.
+		// deferPermissions is translated to the defer_permissions mount option, which leaves out the default_permissions kernel mount option.
+		// disableReadAhead is translated to the disable_read_ahead mount option, which turns off the kernel and gcsfuse read-ahead.
+		case VolumeContextKeyDisableAtime, VolumeContextKeyDisableReadAheadTuning, VolumeContextKeyAllowRoot, VolumeContextKeyEnableParallelDirops,
+			VolumeContextKeyMountOverNonEmpty, VolumeContextKeyAllowSuid, VolumeContextKeyAllowDev, VolumeContextKeyDisableExec, VolumeContextKeyDisableWritebackThrottle,
+			VolumeContextKeyDeferPermissions, VolumeContextKeyDisableReadAhead:
+			boolVal, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid bool value, got %q", volumeAttribute, value)
+			}
+
+			if !boolVal {
+				if (volumeAttribute == VolumeContextKeyAllowSuid || volumeAttribute == VolumeContextKeyAllowDev) && slices.Contains(fuseMountOptions, mountOption) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, mountOption)
+				}
+
+				continue
+			}
+
+			if volumeAttribute == VolumeContextKeyAllowRoot && slices.Contains(fuseMountOptions, "allow_other") {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, "allow_other")
+			}
+
+			mountOptionWithValue = mountOption
+
 		// parse int volume attributes
-		case VolumeContextKeyMetadataCacheTTLSeconds, VolumeContextKeyMetadataCacheTtlSeconds:
+		case VolumeContextKeyMetadataCacheTTLSeconds, VolumeContextKeyMetadataCacheTtlSeconds, VolumeContextKeyNegativeStatCacheTTLSeconds:
 			if intVal, err := strconv.Atoi(value); err == nil {
 				if intVal < 0 {
 					intVal = -1
@@ -253,6 +570,504 @@ func parseVolumeAttributes(fuseMountOptions []string, volumeContext map[string]s
 				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid int value, got %q", volumeAttribute, value)
 			}
 
+		// gcsfuse has no separate TTL for the positive stat cache entries, the deprecated stat-cache-ttl flag is capped by the type cache TTL
+		// and ignored once the metadata cache TTL is set. The attribute sets the metadata cache TTL, which applies to both the stat and the type cache,
+		// so it conflicts with the other attributes setting the metadata cache TTL.
+		case VolumeContextKeyStatCacheTTLSeconds:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal < 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid non-negative int value, got %q", volumeAttribute, value)
+			}
+
+			for _, ttlAttribute := range []string{VolumeContextKeyMetadataCacheTTLSeconds, VolumeContextKeyMetadataCacheTtlSeconds, VolumeContextKeyCacheValidationMode} {
+				// The ttl cache validation mode leaves the metadata cache TTL to the other attributes.
+				if v, ok := volumeContext[ttlAttribute]; ok && !(ttlAttribute == VolumeContextKeyCacheValidationMode && v == cacheValidationModeTTL) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with volume attribute %v", volumeAttribute, value, ttlAttribute)
+				}
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// max_read is a kernel fuse mount option, the CSI mounter passes it to the kernel mount.
+		case VolumeContextKeyFuseMaxRead:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal < minFuseTransferSize || intVal > maxFuseTransferSize {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts an int value between %d and %d, got %q", volumeAttribute, minFuseTransferSize, maxFuseTransferSize, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// the kernel marks the fuse connection congested when the background requests reach the congestion threshold,
+		// so the threshold cannot exceed the max background requests.
+		case VolumeContextKeyFuseMaxBackground, VolumeContextKeyFuseCongestionThreshold:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			if volumeAttribute == VolumeContextKeyFuseCongestionThreshold {
+				maxBackground := defaultFuseMaxBackground
+				if v, err := strconv.Atoi(volumeContext[VolumeContextKeyFuseMaxBackground]); err == nil {
+					maxBackground = v
+				}
+
+				if intVal > maxBackground {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q cannot be larger than the fuse max background requests %v, set by the volume attribute %v", volumeAttribute, value, maxBackground, VolumeContextKeyFuseMaxBackground)
+				}
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// the initial-req-timeout config takes a duration, convert the milliseconds to a duration string.
+		case VolumeContextKeyReadStallInitialTimeoutMs:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal) + "ms"
+
+		// localFileCacheMode selects how gcsfuse fills and reads the local file cache.
+		case VolumeContextKeyLocalFileCacheMode:
+			fileCacheOption, ok := localFileCacheModeToMountOptions[value]
+			if !ok {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q, %q or %q, got %q", volumeAttribute, localFileCacheModeDefault, localFileCacheModeDirect, localFileCacheModeParallel, value)
+			}
+
+			if fileCacheOption == "" {
+				continue
+			}
+
+			mountOptionWithValue = mountOption + fileCacheOption
+
+		// synchronous writeDurability is translated to the sync kernel mount option,
+		// the kernel then flushes every write to gcsfuse, which uploads the object before the write returns.
+		case VolumeContextKeyWriteDurability:
+			if value != writeDurabilityBuffered && value != writeDurabilitySynchronous {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q or %q, got %q", volumeAttribute, writeDurabilityBuffered, writeDurabilitySynchronous, value)
+			}
+
+			if value == writeDurabilityBuffered {
+				// gcsfuse uploads the object when the file is closed or synced.
+				continue
+			}
+
+			mountOptionWithValue = mountOption
+
+		// the gcsfuse log file is rotated when it reaches the max size.
+		case VolumeContextKeyLogMaxFileSizeMb:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// a zero backup count keeps all the rotated gcsfuse log files,
+		// and zero retry attempts keep gcsfuse retrying until the max retry sleep is reached.
+		case VolumeContextKeyLogBackupCount, VolumeContextKeyMaxRetryAttempts:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal < 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid non-negative int value, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// the sidecar mounter bounds the file cache size to keep the percentage of the cache volume free.
+		case VolumeContextKeyFileCacheMinFreePercent:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 || intVal >= 100 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts an int value between 1 and 99, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// gcsfuse caps the type cache in MiB, convert the number of entries to MiB and round it up.
+		case VolumeContextKeyTypeCacheMaxEntries:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			if _, ok := volumeContext[VolumeContextKeyMetadataTypeCacheCapacity]; ok {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with volume attribute %v", volumeAttribute, value, VolumeContextKeyMetadataTypeCacheCapacity)
+			}
+
+			const mebibyte = 1024 * 1024
+			megabytes := (int64(intVal)*typeCacheEntrySizeBytes + mebibyte - 1) / mebibyte
+			mountOptionWithValue = mountOption + strconv.FormatInt(megabytes, 10)
+
+		// gcsfuse reads ahead into blocks of the read buffer size when the buffered read is enabled.
+		case VolumeContextKeyReadBufferSizeMb, VolumeContextKeyChunkTransferTimeoutSeconds:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		case VolumeContextKeyMaxConcurrentOperations:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			for _, o := range fuseMountOptions {
+				if strings.HasPrefix(o, "max-conns-per-host") || strings.HasPrefix(o, maxConnsPerHostMountOption) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+				}
+			}
+
+			if intVal > maxConcurrentOperationsCeiling {
+				klog.Warningf("volume attribute %v %q is larger than the limit, clamping it to %v", volumeAttribute, value, maxConcurrentOperationsCeiling)
+				intVal = maxConcurrentOperationsCeiling
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// the sidecar mounter sets the open files rlimit of the gcsfuse process.
+		case VolumeContextKeyMaxOpenFiles:
+			intVal, err := strconv.Atoi(value)
+			if err != nil || intVal <= 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid positive int value, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.Itoa(intVal)
+
+		// the billing project is billed for the requests to requester-pays buckets,
+		// and the GCS API quota of the requests is attributed to it.
+		case VolumeContextKeyBillingProject:
+			if !projectIDRegex.MatchString(value) {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid project ID, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		// gcsfuse writes its logs to the access log file instead of the sidecar container stdout,
+		// and rotates the file following the logMaxFileSizeMb, logBackupCount and logCompress volume attributes.
+		case VolumeContextKeyAccessLogPath:
+			if err := validateAccessLogPath(value); err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q is invalid: %w", volumeAttribute, value, err)
+			}
+
+			mountOptionWithValue = mountOption + filepath.Clean(value)
+
+		// gcsfuse appends the app name to its user agent, the sidecar mounter prefixes it with the CSI driver app name.
+		case VolumeContextKeyUserAgentSuffix:
+			if !userAgentSuffixRegex.MatchString(value) {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts 1 to 64 letters, digits, '.', '_', '-' or '/', got %q", volumeAttribute, value)
+			}
+
+			for _, o := range fuseMountOptions {
+				if strings.HasPrefix(o, mountOption) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+				}
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		// The gcsfuseBinaryPath volume attribute is validated against the binary path allowlist by the sidecar mounter,
+		// which launches the binary from the sidecar container image.
+		case VolumeContextKeyGCSFuseBinaryPath:
+			if !filepath.IsAbs(value) || filepath.Clean(value) != value || strings.ContainsAny(value, ", ") {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a clean absolute path without commas or spaces, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		// the fsname is shown as the mount source in the mount table instead of the bucket name,
+		// the CSI mounter passes it to the kernel and not to gcsfuse.
+		case VolumeContextKeyFsName:
+			if !fsNameRegex.MatchString(value) {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts 1 to 64 letters, digits, '.', '_', '-' or '/', got %q", volumeAttribute, value)
+			}
+
+			for _, o := range fuseMountOptions {
+				if strings.HasPrefix(o, mountOption) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+				}
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		case VolumeContextKeyCustomEndpoint:
+			endpoint, err := customEndpointURL(value)
+			if err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q is invalid: %w", volumeAttribute, value, err)
+			}
+
+			for _, o := range fuseMountOptions {
+				if strings.HasPrefix(o, mountOption) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+				}
+			}
+
+			mountOptionWithValue = mountOption + endpoint
+
+		// gcsfuse refetches a cached object when the object generation changes,
+		// the metadata cache TTL decides how often the generation is validated.
+		case VolumeContextKeyCacheValidationMode:
+			if value == cacheValidationModeTTL {
+				// Freshness is decided by the metadata cache TTL, which can be set by metadataCacheTTLSeconds.
+				continue
+			}
+
+			if value != cacheValidationModeNone && value != cacheValidationModeEtag {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q, %q or %q, got %q", volumeAttribute, cacheValidationModeNone, cacheValidationModeEtag, cacheValidationModeTTL, value)
+			}
+
+			for _, ttlAttribute := range []string{VolumeContextKeyMetadataCacheTTLSeconds, VolumeContextKeyMetadataCacheTtlSeconds} {
+				if _, ok := volumeContext[ttlAttribute]; ok {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with volume attribute %v", volumeAttribute, value, ttlAttribute)
+				}
+			}
+
+			if value == cacheValidationModeNone {
+				// Never validate the cached objects.
+				mountOptionWithValue = mountOption + "-1"
+			} else {
+				// Validate the object generation on every access.
+				mountOptionWithValue = mountOption + "0"
+			}
+
+		// pinGeneration is translated to the metadata cache settings that never refresh the object generations.
+		case VolumeContextKeyPinGeneration:
+			boolVal, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid bool value, got %q", volumeAttribute, value)
+			}
+
+			if !boolVal {
+				continue
+			}
+
+			for _, attribute := range pinGenerationConflictingAttributes {
+				if _, ok := volumeContext[attribute]; ok {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with volume attribute %v", volumeAttribute, value, attribute)
+				}
+			}
+
+			fuseMountOptions = joinMountOptions(fuseMountOptions, pinGenerationMountOptions)
+
+			continue
+
+		// offlineCacheServing is translated to the metadata cache settings that never revalidate the cached files,
+		// it is only permitted on the read-only volumes with the file cache enabled, as the cached content is never refreshed.
+		case VolumeContextKeyOfflineCacheServing:
+			boolVal, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid bool value, got %q", volumeAttribute, value)
+			}
+
+			if !boolVal {
+				continue
+			}
+
+			if !slices.Contains(fuseMountOptions, "ro") && !strings.EqualFold(volumeContext[VolumeContextKeySourceReadOnly], util.TrueStr) {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v is only supported on read-only volumes", volumeAttribute)
+			}
+
+			if capacity, err := resource.ParseQuantity(volumeContext[VolumeContextKeyFileCacheCapacity]); err != nil || capacity.IsZero() {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v requires the file cache, set the volume attribute %v", volumeAttribute, VolumeContextKeyFileCacheCapacity)
+			}
+
+			// the negative entries are not pinned, so the negative stat cache TTL can still be tuned.
+			for _, attribute := range pinGenerationConflictingAttributes {
+				if _, ok := volumeContext[attribute]; ok && attribute != VolumeContextKeyNegativeStatCacheTTLSeconds {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with volume attribute %v", volumeAttribute, value, attribute)
+				}
+			}
+
+			fuseMountOptions = joinMountOptions(fuseMountOptions, offlineCacheServingMountOptions)
+
+			continue
+
+		// gcsfuse logs the GCS requests with their latency at the trace severity, the sidecar mounter keeps the sampled fraction of them.
+		// The trace severity is set unless the volume sets another severity, which would not log the GCS requests.
+		case VolumeContextKeyRequestLogSampleRate:
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || !(rate >= 0 && rate <= 1) {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a number between 0.0 and 1.0, got %q", volumeAttribute, value)
+			}
+
+			severityOption := volumeAttributesToMountOptionsMapping[VolumeContextKeyGcsfuseLoggingSeverity]
+			severity, hasSeverity := volumeContext[VolumeContextKeyGcsfuseLoggingSeverity]
+			for _, o := range fuseMountOptions {
+				if v, ok := strings.CutPrefix(o, severityOption); ok {
+					severity, hasSeverity = v, true
+				}
+			}
+
+			// The sidecar mounter only samples the gcsfuse stdout, the logs written to a file would be kept in full.
+			accessLogOption := volumeAttributesToMountOptionsMapping[VolumeContextKeyAccessLogPath]
+			if _, ok := volumeContext[VolumeContextKeyAccessLogPath]; ok {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with volume attribute %v", volumeAttribute, value, VolumeContextKeyAccessLogPath)
+			}
+			for _, o := range fuseMountOptions {
+				if strings.HasPrefix(o, strings.TrimSuffix(accessLogOption, ":")) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+				}
+			}
+
+			switch {
+			case rate == 0:
+			case !hasSeverity:
+				fuseMountOptions = append(fuseMountOptions, severityOption+requestLogSeverity)
+			case severity != requestLogSeverity:
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q requires the %q logging severity, got %q", volumeAttribute, value, requestLogSeverity, severity)
+			}
+
+			mountOptionWithValue = mountOption + strconv.FormatFloat(rate, 'f', -1, 64)
+
+		// gcsfuse exports the traces of the file system operations to its stdout, which ends up in the sidecar container logs, or to Cloud Trace.
+		case VolumeContextKeyTracingMode:
+			if value != tracingModeStdout && value != tracingModeGCPTrace {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q or %q, got %q", volumeAttribute, tracingModeStdout, tracingModeGCPTrace, value)
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		// The sampling ratio is the fraction of the file system operations gcsfuse traces, it has no effect without a tracing mode.
+		case VolumeContextKeyTracingSamplingRatio:
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil || !(ratio >= 0 && ratio <= 1) {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a number between 0.0 and 1.0, got %q", volumeAttribute, value)
+			}
+
+			if _, ok := volumeContext[VolumeContextKeyTracingMode]; !ok {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v requires the volume attribute %v", volumeAttribute, VolumeContextKeyTracingMode)
+			}
+
+			// Keep a decimal point, so the sidecar mounter writes the value to the gcsfuse config file as a float.
+			value = strconv.FormatFloat(ratio, 'f', -1, 64)
+			if !strings.Contains(value, ".") {
+				value += ".0"
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		case VolumeContextKeyAutoWriteStrategy:
+			boolVal, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid bool value, got %q", volumeAttribute, value)
+			}
+
+			if !boolVal {
+				continue
+			}
+
+			for _, o := range fuseMountOptions {
+				if strings.HasPrefix(o, streamingWritesMountOptionPrefix) {
+					return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+				}
+			}
+
+			var fileSizeHintMb *int64
+			if hint, ok := volumeContext[VolumeContextKeyWriteFileSizeHintMb]; ok {
+				// The writeFileSizeHintMb volume attribute is validated in its own case.
+				sizeMb, _ := strconv.ParseInt(hint, 10, 64)
+				fileSizeHintMb = &sizeMb
+			}
+
+			fuseMountOptions = joinMountOptions(fuseMountOptions, autoWriteStrategyMountOptions(fileSizeHintMb))
+
+			continue
+
+		// The writeFileSizeHintMb volume attribute is only a hint for the autoWriteStrategy volume attribute.
+		case VolumeContextKeyWriteFileSizeHintMb:
+			if sizeMb, err := strconv.ParseInt(value, 10, 64); err != nil || sizeMb < 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a non-negative integer, got %q", volumeAttribute, value)
+			}
+
+			if autoWriteStrategy, _ := strconv.ParseBool(volumeContext[VolumeContextKeyAutoWriteStrategy]); !autoWriteStrategy {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v requires the volume attribute %v", volumeAttribute, VolumeContextKeyAutoWriteStrategy)
+			}
+
+			continue
+
+		// gcsfuse reports the same permission bits for all the files,
+		// the umask is applied to the file-mode set by the mount options or the gcsfuse default.
+		case VolumeContextKeyCreateUmask:
+			umask, err := strconv.ParseUint(value, 8, 32)
+			if err != nil || umask > 0o777 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts an octal value between 0 and 0777, got %q", volumeAttribute, value)
+			}
+
+			mountOptionWithValue = mountOption + strconv.FormatUint(fileMode(fuseMountOptions)&^umask, 8)
+
+		// The prefetchManifestConfigMap volume attribute is read by the webhook, which projects the ConfigMap into the metadata prefetch sidecar container,
+		// and there is no translation to GCSFuse mount options.
+		case VolumeContextKeyPrefetchManifestConfigMap:
+			if errs := validation.IsDNS1123Subdomain(value); len(errs) > 0 {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a valid ConfigMap name, got %q: %v", volumeAttribute, value, strings.Join(errs, ", "))
+			}
+
+			continue
+
+		// The cacheBarrierFile volume attribute is read by the webhook, which passes the file to the metadata prefetch sidecar container,
+		// and there is no translation to GCSFuse mount options.
+		case VolumeContextKeyCacheBarrierFile:
+			if _, ok := cacheBarrierFilePath(value); !ok {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts a file path relative to the volume root, got %q", volumeAttribute, value)
+			}
+
+			if _, ok := volumeContext[VolumeContextKeyFileCacheCapacity]; !ok {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v requires the file cache, set the volume attribute %v", volumeAttribute, VolumeContextKeyFileCacheCapacity)
+			}
+
+			continue
+
+		// The delete policy of the cacheCleanupOnUnmount volume attribute is passed to the sidecar mounter,
+		// which removes the cache dir when gcsfuse exits.
+		case VolumeContextKeyCacheCleanupOnUnmount:
+			if value != cacheCleanupOnUnmountRetain && value != cacheCleanupOnUnmountDelete {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q or %q, got %q", volumeAttribute, cacheCleanupOnUnmountRetain, cacheCleanupOnUnmountDelete, value)
+			}
+			if value == cacheCleanupOnUnmountRetain {
+				continue
+			}
+
+			mountOptionWithValue = mountOption + value
+
+		// The cacheMedium volume attribute is read by the webhook, which provisions the sidecar cache volume,
+		// and there is no translation to GCSFuse mount options.
+		case VolumeContextKeyCacheMedium:
+			if value != cacheMediumDisk && value != cacheMediumMemory && value != cacheMediumSSD {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q, %q or %q, got %q", volumeAttribute, cacheMediumDisk, cacheMediumMemory, cacheMediumSSD, value)
+			}
+
+			continue
+
+		// The retryOnStatusCodes volume attribute is read by NodePublishVolume for the bucket access check,
+		// and there is no translation to GCSFuse mount options.
+		case VolumeContextKeyRetryOnStatusCodes:
+			if _, err := parseRetryOnStatusCodes(value); err != nil {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q is invalid: %w", volumeAttribute, value, err)
+			}
+
+			continue
+
+		// the token server for the driver mode is set up by NodePublishVolume,
+		// the gcsfuse mode leaves the credentials to gcsfuse.
+		case VolumeContextKeyAuthMode:
+			if value != authModeDriver && value != authModeGcsfuse && value != authModeAnonymous {
+				return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v only accepts %q, %q or %q, got %q", volumeAttribute, authModeDriver, authModeGcsfuse, authModeAnonymous, value)
+			}
+
+			for _, o := range fuseMountOptions {
+				for _, authOption := range authMountOptions {
+					if strings.HasPrefix(o, authOption) {
+						return nil, skipCSIBucketAccessCheck, disableMetricsCollection, fmt.Errorf("volume attribute %v %q conflicts with mount option %q", volumeAttribute, value, o)
+					}
+				}
+			}
+
+			if value != authModeAnonymous {
+				continue
+			}
+
+			mountOptionWithValue = mountOption + util.TrueStr
+
 		default:
 			mountOptionWithValue = mountOption + value
 		}
@@ -263,6 +1078,96 @@ func parseVolumeAttributes(fuseMountOptions []string, volumeContext map[string]s
 	return fuseMountOptions, skipCSIBucketAccessCheck, disableMetricsCollection, nil
 }
 
+// validateAccessLogPath returns an error if the access log path is not a file on a writable sidecar container volume.
+// The .volumes directories of the volumes are reserved for the per-volume gcsfuse files.
+func validateAccessLogPath(path string) error {
+	if !filepath.IsAbs(path) {
+		return errors.New("the path must be absolute")
+	}
+	if strings.ContainsAny(path, ":,") {
+		return errors.New("the path cannot contain ':' or ','")
+	}
+
+	path = filepath.Clean(path)
+	for _, mountPath := range accessLogVolumeMountPaths {
+		rel, err := filepath.Rel(mountPath, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+
+		if rel == ".volumes" || strings.HasPrefix(rel, ".volumes/") {
+			return fmt.Errorf("the path cannot be in the reserved directory %q", filepath.Join(mountPath, ".volumes"))
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("the path must be on a writable sidecar container volume mounted at %v", strings.Join(accessLogVolumeMountPaths, ", "))
+}
+
+// parseRetryOnStatusCodes parses the comma-separated HTTP status codes, and returns them sorted without duplicates.
+func parseRetryOnStatusCodes(value string) ([]int, error) {
+	statusCodes := []int{}
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		statusCode, err := strconv.Atoi(s)
+		if err != nil || statusCode < 100 || statusCode > 599 {
+			return nil, fmt.Errorf("%q is not a valid HTTP status code", s)
+		}
+
+		if !slices.Contains(statusCodes, statusCode) {
+			statusCodes = append(statusCodes, statusCode)
+		}
+	}
+	slices.Sort(statusCodes)
+
+	return statusCodes, nil
+}
+
+// customEndpointURL returns the HTTPS URL of the GCS endpoint given as a hostname or an IP, with an optional port,
+// e.g. storage.example.com, 10.0.0.2:443 or [2001:db8::1]:443. An IP endpoint is connected to without a DNS lookup.
+func customEndpointURL(value string) (string, error) {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		// the value has no port, an IPv6 address without brackets is accepted as well
+		host, port = value, ""
+	}
+
+	if net.ParseIP(host) == nil {
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+			return "", fmt.Errorf("%q is neither a valid IP nor a valid hostname: %v", host, strings.Join(errs, ", "))
+		}
+	}
+
+	if port == "" {
+		if strings.Contains(host, ":") {
+			return "https://[" + host + "]", nil
+		}
+
+		return "https://" + host, nil
+	}
+
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return "", fmt.Errorf("%q is not a valid port", port)
+	}
+
+	return "https://" + net.JoinHostPort(host, port), nil
+}
+
+// fileMode returns the octal file-mode set by the mount options, or the gcsfuse default file mode.
+func fileMode(fuseMountOptions []string) uint64 {
+	mode := uint64(gcsfuseDefaultFileMode)
+	for _, o := range fuseMountOptions {
+		if v, ok := strings.CutPrefix(o, "file-mode="); ok {
+			if m, err := strconv.ParseUint(v, 8, 32); err == nil {
+				mode = m
+			}
+		}
+	}
+
+	return mode
+}
+
 // parseRequestArguments parses arguments from given NodePublishVolumeRequest.
 func parseRequestArguments(req *csi.NodePublishVolumeRequest) (string, string, []string, bool, bool, error) {
 	targetPath := req.GetTargetPath()
@@ -365,6 +1270,29 @@ func putExitFile(pod *corev1.Pod, targetPath string) error {
 	return nil
 }
 
+// errReasonFromCode returns the machine-parseable reason for the given gRPC code.
+func errReasonFromCode(code codes.Code) string {
+	switch code {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return ErrReasonAuthFailed
+	case codes.NotFound:
+		return ErrReasonBucketNotFound
+	case codes.ResourceExhausted:
+		return ErrReasonQuotaExceeded
+	case codes.Unavailable:
+		return ErrReasonNetworkUnavailable
+	case codes.InvalidArgument:
+		return ErrReasonInvalidMountOptions
+	default:
+		return ErrReasonUnknown
+	}
+}
+
+// withErrReason prefixes the error message with a machine-parseable reason.
+func withErrReason(reason string, err error) error {
+	return fmt.Errorf("reason=%s: %w", reason, err)
+}
+
 func checkGcsFuseErr(isInitContainer bool, pod *corev1.Pod, targetPath string) (codes.Code, error) {
 	code := codes.Internal
 	cs, err := getSidecarContainerStatus(isInitContainer, pod)
@@ -394,25 +1322,27 @@ func checkGcsFuseErr(isInitContainer bool, pod *corev1.Pod, targetPath string) (
 			code = codes.InvalidArgument
 		}
 
-		if strings.Contains(errMsgStr, "signal: killed") {
-			code = codes.ResourceExhausted
-		}
-
 		if strings.Contains(errMsgStr, "signal: terminated") {
 			code = codes.Canceled
 		}
 
-		if strings.Contains(errMsgStr, "googleapi: Error 403") ||
-			strings.Contains(errMsgStr, "IAM returned 403 Forbidden: Permission") ||
-			strings.Contains(errMsgStr, "google: could not find default credentials") {
-			code = codes.PermissionDenied
+		if gcsCode := storage.ErrMsgCode(errMsgStr); gcsCode != codes.Internal {
+			code = gcsCode
 		}
 
 		if strings.Contains(errMsgStr, "bucket doesn't exist") {
 			code = codes.NotFound
 		}
 
-		return code, fmt.Errorf("gcsfuse failed with error: %v", errMsgStr)
+		reason := errReasonFromCode(code)
+		// gcsfuse is killed by the OOM killer when the sidecar container runs out of memory.
+		// The auth and bucket errors logged before the kill explain the failure better, so they are kept.
+		if strings.Contains(errMsgStr, "signal: killed") && code != codes.PermissionDenied && code != codes.NotFound {
+			code = codes.ResourceExhausted
+			reason = ErrReasonOutOfMemory
+		}
+
+		return code, withErrReason(reason, fmt.Errorf("gcsfuse failed with error: %v", errMsgStr))
 	}
 
 	return codes.OK, nil
@@ -436,11 +1366,13 @@ func checkSidecarContainerErr(isInitContainer bool, pod *corev1.Pod) (codes.Code
 	}
 
 	if exitCode != 0 {
+		errReason := ErrReasonUnknown
 		if reason == "OOMKilled" || exitCode == 137 {
 			code = codes.ResourceExhausted
+			errReason = ErrReasonOutOfMemory
 		}
 
-		return code, fmt.Errorf("the sidecar container terminated due to %v, exit code: %v", reason, exitCode)
+		return code, withErrReason(errReason, fmt.Errorf("the sidecar container terminated due to %v, exit code: %v", reason, exitCode))
 	}
 
 	return codes.OK, nil
@@ -465,16 +1397,12 @@ func getSidecarContainerStatus(isInitContainer bool, pod *corev1.Pod) (*corev1.C
 }
 
 func isSidecarVersionSupportedForTokenServer(imageName string) bool {
-	managedSidecarPattern := `.*/gke-release(-staging)?/gcs-fuse-csi-driver-sidecar-mounter:v\d+.\d+.\d+-gke\.\d+.*`
-	re := regexp.MustCompile(managedSidecarPattern)
-	isManagedSidecar := re.MatchString(imageName)
-
-	if !isManagedSidecar {
+	imageVersion, ok := getManagedSidecarVersion(imageName)
+	if !ok {
 		klog.Infof("mountOptions should not be passed because this is a private sidecar image %q", imageName)
 
 		return false
 	}
-	imageVersion := strings.Split(strings.Split(imageName, ":")[1], "@")[0]
 	klog.Infof("sidecar image version: %v", imageVersion)
 	if semver.Compare(imageVersion, tokenServerSidecarMinVersion) >= 0 {
 		klog.Infof("sidecar version is supported for token server")
@@ -484,3 +1412,182 @@ func isSidecarVersionSupportedForTokenServer(imageName string) bool {
 
 	return false
 }
+
+// mountPropagationFlags returns the mount(2) flags that apply the given mount propagation mode to a mount point.
+// An empty mode keeps the propagation of the mount point unchanged and returns zero flags.
+func mountPropagationFlags(mode string) (uintptr, error) {
+	switch corev1.MountPropagationMode(mode) {
+	case "":
+		return 0, nil
+	case corev1.MountPropagationNone:
+		return syscall.MS_PRIVATE | syscall.MS_REC, nil
+	case corev1.MountPropagationHostToContainer:
+		return syscall.MS_SLAVE | syscall.MS_REC, nil
+	case corev1.MountPropagationBidirectional:
+		return syscall.MS_SHARED | syscall.MS_REC, nil
+	default:
+		return 0, fmt.Errorf("invalid mount propagation mode %q, must be one of %q, %q or %q", mode, corev1.MountPropagationNone, corev1.MountPropagationHostToContainer, corev1.MountPropagationBidirectional)
+	}
+}
+
+// setMountPropagation changes the propagation type of the mount point at the target path.
+func setMountPropagation(targetPath string, flags uintptr) error {
+	return syscall.Mount("none", targetPath, "", flags, "")
+}
+
+// getSidecarContainerImage returns the image of the gcsfuse sidecar container in the Pod spec.
+func getSidecarContainerImage(pod *corev1.Pod) string {
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if container.Name == webhook.GcsFuseSidecarName {
+			return container.Image
+		}
+	}
+
+	return ""
+}
+
+// getManagedSidecarVersion returns the version of a managed sidecar image,
+// the second return value is false for private sidecar images.
+func getManagedSidecarVersion(imageName string) (string, bool) {
+	managedSidecarPattern := `.*/gke-release(-staging)?/gcs-fuse-csi-driver-sidecar-mounter:v\d+.\d+.\d+-gke\.\d+.*`
+	re := regexp.MustCompile(managedSidecarPattern)
+	if !re.MatchString(imageName) {
+		return "", false
+	}
+
+	return strings.Split(strings.Split(imageName, ":")[1], "@")[0], true
+}
+
+// validateSidecarVersionForMountOptions returns an error if a mount option requires
+// a newer gcsfuse than the one shipped in the managed sidecar image.
+// Private sidecar images are not validated because their gcsfuse version is unknown.
+func validateSidecarVersionForMountOptions(imageName string, fuseMountOptions []string) error {
+	imageVersion, ok := getManagedSidecarVersion(imageName)
+	if !ok {
+		return nil
+	}
+
+	for _, mountOption := range fuseMountOptions {
+		for prefix, minVersion := range util.GCSFuseOptionPrefixesToMinVersion {
+			if minVersion.Sidecar != "" && strings.HasPrefix(mountOption, prefix) && semver.Compare(imageVersion, minVersion.Sidecar) < 0 {
+				return fmt.Errorf("mount option %q requires sidecar version %v or later, got sidecar image %q", mountOption, minVersion.Sidecar, imageName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// cacheDirOwner returns the owner of the per-volume cache directory for Pods running as a non-root user or with a fsGroup.
+// The group is the fsGroup, which the sidecar container also belongs to, or the sidecar container group.
+// Custom cache volumes other than emptyDir are left as is.
+func cacheDirOwner(pod *corev1.Pod) (int, int, bool) {
+	sc := pod.Spec.SecurityContext
+	if sc == nil || (sc.RunAsUser == nil && sc.FSGroup == nil) {
+		return 0, 0, false
+	}
+
+	emptyDirCache := false
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == webhook.SidecarContainerCacheVolumeName {
+			emptyDirCache = v.EmptyDir != nil
+
+			break
+		}
+	}
+	if !emptyDirCache {
+		return 0, 0, false
+	}
+
+	uid, gid := webhook.NobodyUID, webhook.NobodyGID
+	if sc.RunAsUser != nil {
+		uid = int(*sc.RunAsUser)
+	}
+	if sc.FSGroup != nil {
+		gid = int(*sc.FSGroup)
+	}
+
+	return uid, gid, true
+}
+
+// supplementalGroupMountOptions returns the mount options that make the first supplemental group of the Pod
+// the group owner of the files and directories, so the group permission checks pass for the non-root containers.
+// gcsfuse only supports a single group owner, the fsGroup or a gid mount option takes precedence.
+// The file-mode and dir-mode mount options of the user are kept.
+func supplementalGroupMountOptions(pod *corev1.Pod, options []string) []string {
+	sc := pod.Spec.SecurityContext
+	if sc == nil || len(sc.SupplementalGroups) == 0 {
+		return nil
+	}
+
+	hasOption := func(name string) bool {
+		return slices.ContainsFunc(options, func(o string) bool {
+			return strings.HasPrefix(o, name+"=")
+		})
+	}
+	if hasOption("gid") {
+		return nil
+	}
+
+	groupOptions := []string{fmt.Sprintf("gid=%d", sc.SupplementalGroups[0])}
+	if !hasOption("file-mode") {
+		groupOptions = append(groupOptions, "file-mode=664")
+	}
+	if !hasOption("dir-mode") {
+		groupOptions = append(groupOptions, "dir-mode=775")
+	}
+
+	return groupOptions
+}
+
+// seLinuxContextMountOptions returns the context mount option that labels the fuse mount with the SELinux level of the Pod,
+// so the containers can access the mount on the SELinux-enforcing nodes. The user, role and type default to the container file label.
+// The context mount option set by kubelet for the CSI drivers supporting seLinuxMount takes precedence.
+func seLinuxContextMountOptions(pod *corev1.Pod, options []string) []string {
+	sc := pod.Spec.SecurityContext
+	if sc == nil || sc.SELinuxOptions == nil || sc.SELinuxOptions.Level == "" {
+		return nil
+	}
+	if slices.ContainsFunc(options, func(o string) bool {
+		return strings.HasPrefix(o, seLinuxContextMountOptionPrefix)
+	}) {
+		return nil
+	}
+
+	valueOrDefault := func(value, defaultValue string) string {
+		if value == "" {
+			return defaultValue
+		}
+
+		return value
+	}
+	label := strings.Join([]string{
+		valueOrDefault(sc.SELinuxOptions.User, "system_u"),
+		valueOrDefault(sc.SELinuxOptions.Role, "object_r"),
+		valueOrDefault(sc.SELinuxOptions.Type, "container_file_t"),
+		sc.SELinuxOptions.Level,
+	}, ":")
+
+	// The level may contain commas, e.g. s0:c1,c2, so the label is quoted.
+	return []string{fmt.Sprintf("%v%q", seLinuxContextMountOptionPrefix, label)}
+}
+
+// tokenServerMountOptions returns the mount options that make the sidecar start the token server.
+// The token server is always used in the driver authMode, and by default for host network Pods.
+func tokenServerMountOptions(authMode, identityProvider string, tokenServerSupported, hostNetwork bool) ([]string, error) {
+	switch authMode {
+	case authModeDriver:
+		if !tokenServerSupported {
+			return nil, fmt.Errorf("volume attribute %v %q requires the sidecar container version %v or later and the service account token volume injected by the webhook", VolumeContextKeyAuthMode, authMode, tokenServerSidecarMinVersion)
+		}
+	case "":
+		if !tokenServerSupported || !hostNetwork {
+			return nil, nil
+		}
+	default:
+		// gcsfuse uses its own credentials, or no credentials in the anonymous mode.
+		return nil, nil
+	}
+
+	return []string{identityProviderMountOption + identityProvider}, nil
+}
@@ -18,11 +18,14 @@ limitations under the License.
 package driver
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const (
@@ -106,6 +109,351 @@ func TestIsSidecarVersionSupportedForTokenServer(t *testing.T) {
 	})
 }
 
+func TestTokenServerMountOptions(t *testing.T) {
+	t.Parallel()
+	identityProvider := "https://container.googleapis.com/v1/projects/fake-project/locations/us-central1/clusters/fake-cluster"
+	tokenServerOptions := []string{identityProviderMountOption + identityProvider}
+
+	testCases := []struct {
+		name                 string
+		authMode             string
+		tokenServerSupported bool
+		hostNetwork          bool
+		expectedMountOptions []string
+		expectedErr          bool
+	}{
+		{
+			name:                 "default mode starts the token server for host network Pods",
+			tokenServerSupported: true,
+			hostNetwork:          true,
+			expectedMountOptions: tokenServerOptions,
+		},
+		{
+			name:                 "default mode leaves the credentials to gcsfuse for Pods not using host network",
+			tokenServerSupported: true,
+		},
+		{
+			name:        "default mode leaves the credentials to gcsfuse if the token server is not supported",
+			hostNetwork: true,
+		},
+		{
+			name:                 "driver mode starts the token server",
+			authMode:             authModeDriver,
+			tokenServerSupported: true,
+			expectedMountOptions: tokenServerOptions,
+		},
+		{
+			name:        "driver mode fails if the token server is not supported",
+			authMode:    authModeDriver,
+			hostNetwork: true,
+			expectedErr: true,
+		},
+		{
+			name:                 "gcsfuse mode does not start the token server",
+			authMode:             authModeGcsfuse,
+			tokenServerSupported: true,
+			hostNetwork:          true,
+		},
+		{
+			name:                 "anonymous mode does not start the token server",
+			authMode:             authModeAnonymous,
+			tokenServerSupported: true,
+			hostNetwork:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("test case: %s", tc.name)
+		options, err := tokenServerMountOptions(tc.authMode, identityProvider, tc.tokenServerSupported, tc.hostNetwork)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Got error %v, but expected error %v", err, tc.expectedErr)
+		}
+		if diff := cmp.Diff(tc.expectedMountOptions, options); diff != "" {
+			t.Errorf("unexpected mount options (-want +got): %s", diff)
+		}
+	}
+}
+
+func TestCacheDirOwner(t *testing.T) {
+	t.Parallel()
+	uid, fsGroup := int64(1001), int64(3003)
+	emptyDirCache := corev1.Volume{
+		Name:         webhook.SidecarContainerCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	pvcCache := corev1.Volume{
+		Name:         webhook.SidecarContainerCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "cache"}},
+	}
+
+	testCases := []struct {
+		name            string
+		securityContext *corev1.PodSecurityContext
+		volumes         []corev1.Volume
+		expectedUID     int
+		expectedGID     int
+		expectedOK      bool
+	}{
+		{
+			name:    "Pods without a security context are left as is",
+			volumes: []corev1.Volume{emptyDirCache},
+		},
+		{
+			name:            "Pods without runAsUser or fsGroup are left as is",
+			securityContext: &corev1.PodSecurityContext{},
+			volumes:         []corev1.Volume{emptyDirCache},
+		},
+		{
+			name:            "runAsUser and fsGroup own the cache dir",
+			securityContext: &corev1.PodSecurityContext{RunAsUser: &uid, FSGroup: &fsGroup},
+			volumes:         []corev1.Volume{emptyDirCache},
+			expectedUID:     1001,
+			expectedGID:     3003,
+			expectedOK:      true,
+		},
+		{
+			name:            "sidecar container group owns the cache dir without fsGroup",
+			securityContext: &corev1.PodSecurityContext{RunAsUser: &uid},
+			volumes:         []corev1.Volume{emptyDirCache},
+			expectedUID:     1001,
+			expectedGID:     webhook.NobodyGID,
+			expectedOK:      true,
+		},
+		{
+			name:            "sidecar container user owns the cache dir without runAsUser",
+			securityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+			volumes:         []corev1.Volume{emptyDirCache},
+			expectedUID:     webhook.NobodyUID,
+			expectedGID:     3003,
+			expectedOK:      true,
+		},
+		{
+			name:            "custom cache volumes are left as is",
+			securityContext: &corev1.PodSecurityContext{RunAsUser: &uid, FSGroup: &fsGroup},
+			volumes:         []corev1.Volume{pvcCache},
+		},
+		{
+			name:            "Pods without the cache volume are left as is",
+			securityContext: &corev1.PodSecurityContext{RunAsUser: &uid, FSGroup: &fsGroup},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("test case: %s", tc.name)
+		pod := &corev1.Pod{Spec: corev1.PodSpec{SecurityContext: tc.securityContext, Volumes: tc.volumes}}
+		uid, gid, ok := cacheDirOwner(pod)
+		if ok != tc.expectedOK {
+			t.Errorf("Got ok %v, but expected %v", ok, tc.expectedOK)
+		}
+		if uid != tc.expectedUID || gid != tc.expectedGID {
+			t.Errorf("Got owner %d:%d, but expected %d:%d", uid, gid, tc.expectedUID, tc.expectedGID)
+		}
+	}
+}
+
+func TestSupplementalGroupMountOptions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name            string
+		securityContext *corev1.PodSecurityContext
+		options         []string
+		expectedOptions []string
+	}{
+		{
+			name: "Pods without a security context are left as is",
+		},
+		{
+			name:            "Pods without supplemental groups are left as is",
+			securityContext: &corev1.PodSecurityContext{},
+		},
+		{
+			name:            "the first supplemental group owns the files",
+			securityContext: &corev1.PodSecurityContext{SupplementalGroups: []int64{2002, 3003}},
+			options:         []string{"ro"},
+			expectedOptions: []string{"gid=2002", "file-mode=664", "dir-mode=775"},
+		},
+		{
+			name:            "the gid mount option takes precedence",
+			securityContext: &corev1.PodSecurityContext{SupplementalGroups: []int64{2002}},
+			options:         []string{"gid=3003", "file-mode=664", "dir-mode=775"},
+		},
+		{
+			name:            "the file-mode and dir-mode mount options are kept",
+			securityContext: &corev1.PodSecurityContext{SupplementalGroups: []int64{2002}},
+			options:         []string{"file-mode=640", "dir-mode=750"},
+			expectedOptions: []string{"gid=2002"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("test case: %s", tc.name)
+		pod := &corev1.Pod{Spec: corev1.PodSpec{SecurityContext: tc.securityContext}}
+		options := supplementalGroupMountOptions(pod, tc.options)
+		if diff := cmp.Diff(tc.expectedOptions, options); diff != "" {
+			t.Errorf("unexpected mount options (-want +got): %s", diff)
+		}
+	}
+}
+
+func TestSELinuxContextMountOptions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name            string
+		securityContext *corev1.PodSecurityContext
+		options         []string
+		expectedOptions []string
+	}{
+		{
+			name: "Pods without a security context are left as is",
+		},
+		{
+			name:            "Pods without SELinux options are left as is",
+			securityContext: &corev1.PodSecurityContext{},
+		},
+		{
+			name:            "Pods without an SELinux level are left as is",
+			securityContext: &corev1.PodSecurityContext{SELinuxOptions: &corev1.SELinuxOptions{Type: "spc_t"}},
+		},
+		{
+			name:            "the SELinux level of the Pod labels the mount",
+			securityContext: &corev1.PodSecurityContext{SELinuxOptions: &corev1.SELinuxOptions{Level: "s0:c1,c2"}},
+			options:         []string{"ro"},
+			expectedOptions: []string{`context="system_u:object_r:container_file_t:s0:c1,c2"`},
+		},
+		{
+			name: "the SELinux user, role and type of the Pod are kept",
+			securityContext: &corev1.PodSecurityContext{SELinuxOptions: &corev1.SELinuxOptions{
+				User:  "user_u",
+				Role:  "user_r",
+				Type:  "svirt_sandbox_file_t",
+				Level: "s0:c3",
+			}},
+			expectedOptions: []string{`context="user_u:user_r:svirt_sandbox_file_t:s0:c3"`},
+		},
+		{
+			name:            "the context mount option set by kubelet takes precedence",
+			securityContext: &corev1.PodSecurityContext{SELinuxOptions: &corev1.SELinuxOptions{Level: "s0:c1,c2"}},
+			options:         []string{`context="system_u:object_r:container_file_t:s0:c4,c5"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("test case: %s", tc.name)
+		pod := &corev1.Pod{Spec: corev1.PodSpec{SecurityContext: tc.securityContext}}
+		options := seLinuxContextMountOptions(pod, tc.options)
+		if diff := cmp.Diff(tc.expectedOptions, options); diff != "" {
+			t.Errorf("unexpected mount options (-want +got): %s", diff)
+		}
+	}
+}
+
+func TestParseRetryOnStatusCodes(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name                string
+		value               string
+		expectedStatusCodes []int
+		expectedErr         bool
+	}{
+		{
+			name:                "single status code",
+			value:               "520",
+			expectedStatusCodes: []int{520},
+		},
+		{
+			name:                "status codes are sorted without duplicates",
+			value:               "529, 520,529",
+			expectedStatusCodes: []int{520, 529},
+		},
+		{
+			name:        "empty value",
+			value:       "",
+			expectedErr: true,
+		},
+		{
+			name:        "non-numeric status code",
+			value:       "520,server-error",
+			expectedErr: true,
+		},
+		{
+			name:        "status code below the HTTP range",
+			value:       "99",
+			expectedErr: true,
+		},
+		{
+			name:        "status code above the HTTP range",
+			value:       "600",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("test case: %s", tc.name)
+		statusCodes, err := parseRetryOnStatusCodes(tc.value)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Got error %v, but expected error %v", err, tc.expectedErr)
+		}
+		if diff := cmp.Diff(tc.expectedStatusCodes, statusCodes); diff != "" {
+			t.Errorf("unexpected status codes (-want +got): %s", diff)
+		}
+	}
+}
+
+func TestValidateSidecarVersionForMountOptions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		imageName   string
+		options     []string
+		expectedErr bool
+	}{
+		{
+			name:      "should pass for supported sidecar version",
+			imageName: "gcr.io/gke-release/gcs-fuse-csi-driver-sidecar-mounter:v1.14.1-gke.0@sha256:abcd",
+			options:   []string{readStallRetryMountOptionPrefix + "enable:true", readStallRetryMountOptionPrefix + "initial-req-timeout:200ms"},
+		},
+		{
+			name:        "should fail for unsupported sidecar version",
+			imageName:   "gcr.io/gke-release/gcs-fuse-csi-driver-sidecar-mounter:v1.12.3-gke.2@sha256:abcd",
+			options:     []string{readStallRetryMountOptionPrefix + "enable:true"},
+			expectedErr: true,
+		},
+		{
+			name:      "should pass for unsupported sidecar version without guarded options",
+			imageName: "gcr.io/gke-release/gcs-fuse-csi-driver-sidecar-mounter:v1.12.3-gke.2@sha256:abcd",
+			options:   []string{"implicit-dirs", "metadata-cache:ttl-secs:0"},
+		},
+		{
+			name:      "should pass for the o-direct file cache option with supported sidecar version",
+			imageName: "gcr.io/gke-release/gcs-fuse-csi-driver-sidecar-mounter:v1.15.0-gke.0@sha256:abcd",
+			options:   []string{fileCacheODirectMountOption + ":true"},
+		},
+		{
+			name:        "should fail for the o-direct file cache option with unsupported sidecar version",
+			imageName:   "gcr.io/gke-release/gcs-fuse-csi-driver-sidecar-mounter:v1.14.1-gke.0@sha256:abcd",
+			options:     []string{fileCacheODirectMountOption + ":true"},
+			expectedErr: true,
+		},
+		{
+			name:      "should pass for the options only validated by the sidecar mounter",
+			imageName: "gcr.io/gke-release/gcs-fuse-csi-driver-sidecar-mounter:v1.12.3-gke.2@sha256:abcd",
+			options:   []string{"write:enable-streaming-writes:true", "read:enable-buffered-read:true"},
+		},
+		{
+			name:      "should pass for private sidecar",
+			imageName: "customer.gcr.io/dir/gcs-fuse-csi-driver-sidecar-mounter:v1.0.0-gke.0@sha256:abcd",
+			options:   []string{readStallRetryMountOptionPrefix + "enable:true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		err := validateSidecarVersionForMountOptions(tc.imageName, tc.options)
+		if tc.expectedErr != (err != nil) {
+			t.Errorf("test %q failed: expected error %v, got error: %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
 func TestParseVolumeAttributes(t *testing.T) {
 	t.Parallel()
 	t.Run("parsing volume attributes into mount options", func(t *testing.T) {
@@ -431,32 +779,1242 @@ func TestParseVolumeAttributes(t *testing.T) {
 				expectedMountOptions:            []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyDisableMetrics] + util.FalseStr},
 				expectedEnableMetricsCollection: true,
 			},
-		}
-
-		for _, tc := range testCases {
-			t.Run(tc.name, func(t *testing.T) {
-				t.Logf("test case: %s", tc.name)
-				output, skipCSIBucketAccessCheck, disableMetricsCollection, err := parseVolumeAttributes([]string{}, tc.volumeContext)
-				if (err != nil) != tc.expectedErr {
-					t.Errorf("Got error %v, but expected error %v", err, tc.expectedErr)
-				}
-				enableMetricsCollection := !disableMetricsCollection
-
-				if tc.expectedErr {
-					return
-				}
-				if tc.expectedSkipBucketAccessCheck != skipCSIBucketAccessCheck {
-					t.Errorf("Got skipBucketAccessCheck %v, but expected %v", skipCSIBucketAccessCheck, tc.expectedSkipBucketAccessCheck)
-				}
-				if tc.expectedEnableMetricsCollection != enableMetricsCollection {
-					t.Errorf("Got disableMetricsCollection %v, but expected %v", enableMetricsCollection, tc.expectedEnableMetricsCollection)
-				}
-
-				less := func(a, b string) bool { return a > b }
-				if diff := cmp.Diff(output, tc.expectedMountOptions, cmpopts.SortSlices(less)); diff != "" {
-					t.Errorf("unexpected options args (-got, +want)\n%s", diff)
-				}
-			})
-		}
-	})
+			{
+				name:                 "should return correct statCacheTtlSeconds 1",
+				volumeContext:        map[string]string{VolumeContextKeyStatCacheTTLSeconds: "3600"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyStatCacheTTLSeconds] + "3600"},
+			},
+			{
+				name:                 "should return correct statCacheTtlSeconds 2",
+				volumeContext:        map[string]string{VolumeContextKeyStatCacheTTLSeconds: "0"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyStatCacheTTLSeconds] + "0"},
+			},
+			{
+				name:          "should throw error for invalid statCacheTtlSeconds 1",
+				volumeContext: map[string]string{VolumeContextKeyStatCacheTTLSeconds: "abc"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should throw error for invalid statCacheTtlSeconds 2",
+				volumeContext: map[string]string{VolumeContextKeyStatCacheTTLSeconds: "-1"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct negativeStatCacheTtlSeconds 1",
+				volumeContext:        map[string]string{VolumeContextKeyNegativeStatCacheTTLSeconds: "5"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyNegativeStatCacheTTLSeconds] + "5"},
+			},
+			{
+				name:                 "should return correct negativeStatCacheTtlSeconds 2",
+				volumeContext:        map[string]string{VolumeContextKeyNegativeStatCacheTTLSeconds: "-100"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyNegativeStatCacheTTLSeconds] + "-1"},
+			},
+			{
+				name:          "should throw error for invalid negativeStatCacheTtlSeconds",
+				volumeContext: map[string]string{VolumeContextKeyNegativeStatCacheTTLSeconds: "0.5"},
+				expectedErr:   true,
+			},
+			{
+				name: "should return the metadata cache TTL and the negative TTL for statCacheTtlSeconds and negativeStatCacheTtlSeconds",
+				volumeContext: map[string]string{
+					VolumeContextKeyStatCacheTTLSeconds:         "3600",
+					VolumeContextKeyNegativeStatCacheTTLSeconds: "10",
+				},
+				expectedMountOptions: []string{
+					"metadata-cache:ttl-secs:3600",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyNegativeStatCacheTTLSeconds] + "10",
+				},
+			},
+			{
+				name: "statCacheTtlSeconds conflicts with metadataCacheTTLSeconds",
+				volumeContext: map[string]string{
+					VolumeContextKeyStatCacheTTLSeconds:     "3600",
+					VolumeContextKeyMetadataCacheTTLSeconds: "60",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "statCacheTtlSeconds conflicts with cacheValidationMode",
+				volumeContext: map[string]string{
+					VolumeContextKeyStatCacheTTLSeconds: "3600",
+					VolumeContextKeyCacheValidationMode: "etag",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "statCacheTtlSeconds with the ttl cacheValidationMode",
+				volumeContext: map[string]string{
+					VolumeContextKeyStatCacheTTLSeconds: "3600",
+					VolumeContextKeyCacheValidationMode: "ttl",
+				},
+				expectedMountOptions: []string{"metadata-cache:ttl-secs:3600"},
+			},
+			{
+				name: "statCacheTtlSeconds conflicts with pinGeneration",
+				volumeContext: map[string]string{
+					VolumeContextKeyStatCacheTTLSeconds: "3600",
+					VolumeContextKeyPinGeneration:       "true",
+				},
+				expectedErr: true,
+			},
+			{
+				name:                 "should return correct fuseMaxRead",
+				volumeContext:        map[string]string{VolumeContextKeyFuseMaxRead: "131072"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyFuseMaxRead] + "131072"},
+			},
+			{
+				name:          "should throw error for invalid fuseMaxRead",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxRead: "abc"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should throw error for too small fuseMaxRead",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxRead: "1024"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should throw error for too large fuseMaxRead",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxRead: "2097152"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should return correct fuseMaxBackground and fuseCongestionThreshold",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxBackground: "64", VolumeContextKeyFuseCongestionThreshold: "48"},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyFuseMaxBackground] + "64",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyFuseCongestionThreshold] + "48",
+				},
+			},
+			{
+				name:          "should return correct fuseCongestionThreshold equal to fuseMaxBackground",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxBackground: "32", VolumeContextKeyFuseCongestionThreshold: "32"},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyFuseMaxBackground] + "32",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyFuseCongestionThreshold] + "32",
+				},
+			},
+			{
+				name:                 "should return correct fuseCongestionThreshold within the default max background",
+				volumeContext:        map[string]string{VolumeContextKeyFuseCongestionThreshold: "9"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyFuseCongestionThreshold] + "9"},
+			},
+			{
+				name:          "should throw error for fuseCongestionThreshold larger than fuseMaxBackground",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxBackground: "16", VolumeContextKeyFuseCongestionThreshold: "17"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should throw error for fuseCongestionThreshold larger than the default max background",
+				volumeContext: map[string]string{VolumeContextKeyFuseCongestionThreshold: "13"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should throw error for zero fuseMaxBackground",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxBackground: "0"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should throw error for invalid fuseCongestionThreshold",
+				volumeContext: map[string]string{VolumeContextKeyFuseMaxBackground: "64", VolumeContextKeyFuseCongestionThreshold: "-1"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct cacheValidationMode none",
+				volumeContext:        map[string]string{VolumeContextKeyCacheValidationMode: "none"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCacheValidationMode] + "-1"},
+			},
+			{
+				name:                 "should return correct cacheValidationMode etag",
+				volumeContext:        map[string]string{VolumeContextKeyCacheValidationMode: "etag"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCacheValidationMode] + "0"},
+			},
+			{
+				name:                 "should return correct cacheValidationMode ttl",
+				volumeContext:        map[string]string{VolumeContextKeyCacheValidationMode: "ttl"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name: "should return correct cacheValidationMode ttl with metadataCacheTTLSeconds",
+				volumeContext: map[string]string{
+					VolumeContextKeyCacheValidationMode:     "ttl",
+					VolumeContextKeyMetadataCacheTTLSeconds: "3600",
+				},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyMetadataCacheTTLSeconds] + "3600"},
+			},
+			{
+				name: "should throw error for cacheValidationMode etag with metadataCacheTTLSeconds",
+				volumeContext: map[string]string{
+					VolumeContextKeyCacheValidationMode:     "etag",
+					VolumeContextKeyMetadataCacheTTLSeconds: "3600",
+				},
+				expectedErr: true,
+			},
+			{
+				name:          "should throw error for invalid cacheValidationMode",
+				volumeContext: map[string]string{VolumeContextKeyCacheValidationMode: "always"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "value set to true for VolumeContextKeyEnableReadStallRetry",
+				volumeContext:        map[string]string{VolumeContextKeyEnableReadStallRetry: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyEnableReadStallRetry] + util.TrueStr},
+			},
+			{
+				name:                 "value set to false for VolumeContextKeyEnableReadStallRetry",
+				volumeContext:        map[string]string{VolumeContextKeyEnableReadStallRetry: util.FalseStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyEnableReadStallRetry] + util.FalseStr},
+			},
+			{
+				name:          "invalid value for VolumeContextKeyEnableReadStallRetry",
+				volumeContext: map[string]string{VolumeContextKeyEnableReadStallRetry: "invalid"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct readStallInitialTimeoutMs",
+				volumeContext:        map[string]string{VolumeContextKeyReadStallInitialTimeoutMs: "200"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyReadStallInitialTimeoutMs] + "200ms"},
+			},
+			{
+				name:          "zero readStallInitialTimeoutMs",
+				volumeContext: map[string]string{VolumeContextKeyReadStallInitialTimeoutMs: "0"},
+				expectedErr:   true,
+			},
+			{
+				name:          "negative readStallInitialTimeoutMs",
+				volumeContext: map[string]string{VolumeContextKeyReadStallInitialTimeoutMs: "-100"},
+				expectedErr:   true,
+			},
+			{
+				name:          "invalid readStallInitialTimeoutMs",
+				volumeContext: map[string]string{VolumeContextKeyReadStallInitialTimeoutMs: "1s"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct billingProject",
+				volumeContext:        map[string]string{VolumeContextKeyBillingProject: "my-billing-project-1"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyBillingProject] + "my-billing-project-1"},
+			},
+			{
+				name:          "billingProject too short",
+				volumeContext: map[string]string{VolumeContextKeyBillingProject: "proj"},
+				expectedErr:   true,
+			},
+			{
+				name:          "billingProject with uppercase letters",
+				volumeContext: map[string]string{VolumeContextKeyBillingProject: "My-Project"},
+				expectedErr:   true,
+			},
+			{
+				name:          "billingProject ending with a hyphen",
+				volumeContext: map[string]string{VolumeContextKeyBillingProject: "my-project-"},
+				expectedErr:   true,
+			},
+			{
+				name:          "billingProject with an injected mount option",
+				volumeContext: map[string]string{VolumeContextKeyBillingProject: "my-project,implicit-dirs"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct userAgentSuffix",
+				volumeContext:        map[string]string{VolumeContextKeyUserAgentSuffix: "team-a/v1.2_3"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyUserAgentSuffix] + "team-a/v1.2_3"},
+			},
+			{
+				name:          "userAgentSuffix with a header injection",
+				volumeContext: map[string]string{VolumeContextKeyUserAgentSuffix: "team-a\r\nX-Injected: true"},
+				expectedErr:   true,
+			},
+			{
+				name:          "userAgentSuffix with a space",
+				volumeContext: map[string]string{VolumeContextKeyUserAgentSuffix: "team a"},
+				expectedErr:   true,
+			},
+			{
+				name:          "userAgentSuffix with an injected mount option",
+				volumeContext: map[string]string{VolumeContextKeyUserAgentSuffix: "team-a,implicit-dirs"},
+				expectedErr:   true,
+			},
+			{
+				name:          "userAgentSuffix too long",
+				volumeContext: map[string]string{VolumeContextKeyUserAgentSuffix: "a123456789b123456789c123456789d123456789e123456789f123456789g1234"},
+				expectedErr:   true,
+			},
+			{
+				name:          "userAgentSuffix conflicts with the app-name mount option",
+				volumeContext: map[string]string{VolumeContextKeyMountOptions: "app-name=team-b", VolumeContextKeyUserAgentSuffix: "team-a"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct allowRoot",
+				volumeContext:        map[string]string{VolumeContextKeyAllowRoot: "true"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyAllowRoot]},
+			},
+			{
+				name:                 "should not return allowRoot when it is false",
+				volumeContext:        map[string]string{VolumeContextKeyAllowRoot: "false"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "allowRoot conflicts with the allow_other mount option",
+				volumeContext: map[string]string{VolumeContextKeyMountOptions: "allow_other", VolumeContextKeyAllowRoot: "true"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct authMode anonymous",
+				volumeContext:        map[string]string{VolumeContextKeyAuthMode: "anonymous"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyAuthMode] + util.TrueStr},
+			},
+			{
+				name:                 "authMode driver adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyAuthMode: "driver"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "authMode gcsfuse adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyAuthMode: "gcsfuse"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid authMode",
+				volumeContext: map[string]string{VolumeContextKeyAuthMode: "workload-identity"},
+				expectedErr:   true,
+			},
+			{
+				name: "authMode conflicts with key-file mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyAuthMode:     "gcsfuse",
+					VolumeContextKeyMountOptions: "implicit-dirs,key-file=/tmp/key.json",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "authMode conflicts with gcs-auth config mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyAuthMode:     "driver",
+					VolumeContextKeyMountOptions: "gcs-auth:anonymous-access:true",
+				},
+				expectedErr: true,
+			},
+			{
+				name:                 "should return correct maxOpenFiles",
+				volumeContext:        map[string]string{VolumeContextKeyMaxOpenFiles: "65536"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyMaxOpenFiles] + "65536"},
+			},
+			{
+				name:          "maxOpenFiles set to zero",
+				volumeContext: map[string]string{VolumeContextKeyMaxOpenFiles: "0"},
+				expectedErr:   true,
+			},
+			{
+				name:          "invalid maxOpenFiles",
+				volumeContext: map[string]string{VolumeContextKeyMaxOpenFiles: "unlimited"},
+				expectedErr:   true,
+			},
+			{
+				name: "should return correct log rotation options",
+				volumeContext: map[string]string{
+					VolumeContextKeyLogMaxFileSizeMb: "256",
+					VolumeContextKeyLogBackupCount:   "0",
+					VolumeContextKeyLogCompress:      "true",
+				},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyLogMaxFileSizeMb] + "256",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyLogBackupCount] + "0",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyLogCompress] + "true",
+				},
+			},
+			{
+				name:          "logMaxFileSizeMb set to zero",
+				volumeContext: map[string]string{VolumeContextKeyLogMaxFileSizeMb: "0"},
+				expectedErr:   true,
+			},
+			{
+				name:          "negative logBackupCount",
+				volumeContext: map[string]string{VolumeContextKeyLogBackupCount: "-1"},
+				expectedErr:   true,
+			},
+			{
+				name:          "invalid logCompress",
+				volumeContext: map[string]string{VolumeContextKeyLogCompress: "gzip"},
+				expectedErr:   true,
+			},
+			{
+				name: "should return correct access log options with log rotation",
+				volumeContext: map[string]string{
+					VolumeContextKeyAccessLogPath:    "/gcsfuse-cache/access-logs/../logs/access.log",
+					VolumeContextKeyLogMaxFileSizeMb: "100",
+					VolumeContextKeyLogBackupCount:   "5",
+				},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyAccessLogPath] + "/gcsfuse-cache/logs/access.log",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyLogMaxFileSizeMb] + "100",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyLogBackupCount] + "5",
+				},
+			},
+			{
+				name:          "relative accessLogPath",
+				volumeContext: map[string]string{VolumeContextKeyAccessLogPath: "gcsfuse-cache/access.log"},
+				expectedErr:   true,
+			},
+			{
+				name:          "accessLogPath not on a writable sidecar volume",
+				volumeContext: map[string]string{VolumeContextKeyAccessLogPath: "/var/log/access.log"},
+				expectedErr:   true,
+			},
+			{
+				name:          "accessLogPath escaping the sidecar volume",
+				volumeContext: map[string]string{VolumeContextKeyAccessLogPath: "/gcsfuse-buffer/../etc/access.log"},
+				expectedErr:   true,
+			},
+			{
+				name:          "accessLogPath set to the sidecar volume mount path",
+				volumeContext: map[string]string{VolumeContextKeyAccessLogPath: "/gcsfuse-tmp"},
+				expectedErr:   true,
+			},
+			{
+				name:          "accessLogPath in the reserved volumes dir",
+				volumeContext: map[string]string{VolumeContextKeyAccessLogPath: "/gcsfuse-cache/.volumes/access.log"},
+				expectedErr:   true,
+			},
+			{
+				name:          "accessLogPath with a colon",
+				volumeContext: map[string]string{VolumeContextKeyAccessLogPath: "/gcsfuse-cache/access:log"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct fileCacheMinFreePercent",
+				volumeContext:        map[string]string{VolumeContextKeyFileCacheMinFreePercent: "10"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyFileCacheMinFreePercent] + "10"},
+			},
+			{
+				name:          "fileCacheMinFreePercent out of range",
+				volumeContext: map[string]string{VolumeContextKeyFileCacheMinFreePercent: "100"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct sourceReadOnly",
+				volumeContext:        map[string]string{VolumeContextKeySourceReadOnly: "true"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeySourceReadOnly] + "true"},
+			},
+			{
+				name:          "invalid sourceReadOnly",
+				volumeContext: map[string]string{VolumeContextKeySourceReadOnly: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct typeCacheMaxEntries rounded up to MiB",
+				volumeContext:        map[string]string{VolumeContextKeyTypeCacheMaxEntries: "100000"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyTypeCacheMaxEntries] + "20"},
+			},
+			{
+				name:                 "should return at least 1 MiB for typeCacheMaxEntries",
+				volumeContext:        map[string]string{VolumeContextKeyTypeCacheMaxEntries: "1"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyTypeCacheMaxEntries] + "1"},
+			},
+			{
+				name:          "invalid typeCacheMaxEntries",
+				volumeContext: map[string]string{VolumeContextKeyTypeCacheMaxEntries: "0"},
+				expectedErr:   true,
+			},
+			{
+				name: "typeCacheMaxEntries conflicts with metadataTypeCacheCapacity",
+				volumeContext: map[string]string{
+					VolumeContextKeyTypeCacheMaxEntries:       "100000",
+					VolumeContextKeyMetadataTypeCacheCapacity: "32Mi",
+				},
+				expectedErr: true,
+			},
+			{
+				name:                 "should return the read-ahead tuning opt-out option for disableReadAheadTuning",
+				volumeContext:        map[string]string{VolumeContextKeyDisableReadAheadTuning: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyDisableReadAheadTuning]},
+			},
+			{
+				name:                 "should return the writeback throttle opt-out option for disableWritebackThrottle",
+				volumeContext:        map[string]string{VolumeContextKeyDisableWritebackThrottle: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyDisableWritebackThrottle]},
+			},
+			{
+				name:                 "should return the defer permissions option for deferPermissions",
+				volumeContext:        map[string]string{VolumeContextKeyDeferPermissions: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyDeferPermissions]},
+			},
+			{
+				name:                 "deferPermissions false adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyDeferPermissions: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "should return the disable read-ahead option for disableReadAhead",
+				volumeContext:        map[string]string{VolumeContextKeyDisableReadAhead: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyDisableReadAhead]},
+			},
+			{
+				name:                 "disableReadAhead false adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyDisableReadAhead: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid disableReadAhead",
+				volumeContext: map[string]string{VolumeContextKeyDisableReadAhead: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return the gcsfuse binary path option for gcsfuseBinaryPath",
+				volumeContext:        map[string]string{VolumeContextKeyGCSFuseBinaryPath: "/gcsfuse-binaries/gcsfuse-v3"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyGCSFuseBinaryPath] + "/gcsfuse-binaries/gcsfuse-v3"},
+			},
+			{
+				name:          "relative gcsfuseBinaryPath",
+				volumeContext: map[string]string{VolumeContextKeyGCSFuseBinaryPath: "gcsfuse-binaries/gcsfuse-v3"},
+				expectedErr:   true,
+			},
+			{
+				name:          "unclean gcsfuseBinaryPath",
+				volumeContext: map[string]string{VolumeContextKeyGCSFuseBinaryPath: "/gcsfuse-binaries/../tmp/gcsfuse"},
+				expectedErr:   true,
+			},
+			{
+				name:          "gcsfuseBinaryPath with a comma",
+				volumeContext: map[string]string{VolumeContextKeyGCSFuseBinaryPath: "/gcsfuse-binaries/gcsfuse,implicit-dirs"},
+				expectedErr:   true,
+			},
+			{
+				name:          "should return the trace severity and the sample rate option for requestLogSampleRate",
+				volumeContext: map[string]string{VolumeContextKeyRequestLogSampleRate: "0.25"},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyGcsfuseLoggingSeverity] + TraceStr,
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyRequestLogSampleRate] + "0.25",
+				},
+			},
+			{
+				name: "should keep the trace gcsfuseLoggingSeverity for requestLogSampleRate",
+				volumeContext: map[string]string{
+					VolumeContextKeyRequestLogSampleRate:   "1",
+					VolumeContextKeyGcsfuseLoggingSeverity: TraceStr,
+				},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyGcsfuseLoggingSeverity] + TraceStr,
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyRequestLogSampleRate] + "1",
+				},
+			},
+			{
+				name:                 "requestLogSampleRate 0 does not change the logging severity",
+				volumeContext:        map[string]string{VolumeContextKeyRequestLogSampleRate: "0.0"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyRequestLogSampleRate] + "0"},
+			},
+			{
+				name: "requestLogSampleRate conflicts with the info gcsfuseLoggingSeverity",
+				volumeContext: map[string]string{
+					VolumeContextKeyRequestLogSampleRate:   "0.5",
+					VolumeContextKeyGcsfuseLoggingSeverity: "info",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "requestLogSampleRate conflicts with the logging severity mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyRequestLogSampleRate: "0.5",
+					VolumeContextKeyMountOptions:         "logging:severity:warning",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "requestLogSampleRate conflicts with accessLogPath",
+				volumeContext: map[string]string{
+					VolumeContextKeyRequestLogSampleRate: "0.5",
+					VolumeContextKeyAccessLogPath:        "/gcsfuse-cache/logs/access.log",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "requestLogSampleRate conflicts with the log file path mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyRequestLogSampleRate: "0.5",
+					VolumeContextKeyMountOptions:         "logging:file-path:/gcsfuse-cache/logs/access.log",
+				},
+				expectedErr: true,
+			},
+			{
+				name:          "requestLogSampleRate above 1",
+				volumeContext: map[string]string{VolumeContextKeyRequestLogSampleRate: "1.5"},
+				expectedErr:   true,
+			},
+			{
+				name:          "negative requestLogSampleRate",
+				volumeContext: map[string]string{VolumeContextKeyRequestLogSampleRate: "-0.1"},
+				expectedErr:   true,
+			},
+			{
+				name:          "NaN requestLogSampleRate",
+				volumeContext: map[string]string{VolumeContextKeyRequestLogSampleRate: "NaN"},
+				expectedErr:   true,
+			},
+			{
+				name: "should return the tracing config file options for tracingMode and tracingSamplingRatio",
+				volumeContext: map[string]string{
+					VolumeContextKeyTracingMode:          "gcptrace",
+					VolumeContextKeyTracingSamplingRatio: "0.1",
+				},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyTracingMode] + "gcptrace",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyTracingSamplingRatio] + "0.1",
+				},
+			},
+			{
+				name: "should keep a decimal point in the tracingSamplingRatio",
+				volumeContext: map[string]string{
+					VolumeContextKeyTracingMode:          "stdout",
+					VolumeContextKeyTracingSamplingRatio: "1",
+				},
+				expectedMountOptions: []string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyTracingMode] + "stdout",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyTracingSamplingRatio] + "1.0",
+				},
+			},
+			{
+				name:          "invalid tracingMode",
+				volumeContext: map[string]string{VolumeContextKeyTracingMode: "otlp"},
+				expectedErr:   true,
+			},
+			{
+				name: "tracingSamplingRatio above 1",
+				volumeContext: map[string]string{
+					VolumeContextKeyTracingMode:          "stdout",
+					VolumeContextKeyTracingSamplingRatio: "1.5",
+				},
+				expectedErr: true,
+			},
+			{
+				name:          "tracingSamplingRatio without tracingMode",
+				volumeContext: map[string]string{VolumeContextKeyTracingSamplingRatio: "0.5"},
+				expectedErr:   true,
+			},
+			{
+				name:          "invalid deferPermissions",
+				volumeContext: map[string]string{VolumeContextKeyDeferPermissions: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "autoWriteStrategy without a file size hint enables the streaming writes",
+				volumeContext:        map[string]string{VolumeContextKeyAutoWriteStrategy: util.TrueStr},
+				expectedMountOptions: []string{"write:enable-streaming-writes:true"},
+			},
+			{
+				name: "autoWriteStrategy with a small file size hint stages the writes in the temp dir",
+				volumeContext: map[string]string{
+					VolumeContextKeyAutoWriteStrategy:   util.TrueStr,
+					VolumeContextKeyWriteFileSizeHintMb: "4",
+				},
+				expectedMountOptions: []string{"write:enable-streaming-writes:false"},
+			},
+			{
+				name: "autoWriteStrategy with a large file size hint enables the streaming writes",
+				volumeContext: map[string]string{
+					VolumeContextKeyAutoWriteStrategy:   util.TrueStr,
+					VolumeContextKeyWriteFileSizeHintMb: "10240",
+				},
+				expectedMountOptions: []string{"write:enable-streaming-writes:true"},
+			},
+			{
+				name:                 "autoWriteStrategy false adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyAutoWriteStrategy: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid autoWriteStrategy",
+				volumeContext: map[string]string{VolumeContextKeyAutoWriteStrategy: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name: "autoWriteStrategy conflicting with the streaming writes mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyMountOptions:      "write:enable-streaming-writes:true",
+					VolumeContextKeyAutoWriteStrategy: util.TrueStr,
+				},
+				expectedErr: true,
+			},
+			{
+				name: "cacheBarrierFile should not be passed to gcsfuse",
+				volumeContext: map[string]string{
+					VolumeContextKeyCacheBarrierFile:  "models/model.bin",
+					VolumeContextKeyFileCacheCapacity: "-1",
+				},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyFileCacheCapacity] + "-1"},
+			},
+			{
+				name:          "cacheBarrierFile without the file cache",
+				volumeContext: map[string]string{VolumeContextKeyCacheBarrierFile: "models/model.bin"},
+				expectedErr:   true,
+			},
+			{
+				name: "cacheBarrierFile escaping the volume root",
+				volumeContext: map[string]string{
+					VolumeContextKeyCacheBarrierFile:  "../model.bin",
+					VolumeContextKeyFileCacheCapacity: "-1",
+				},
+				expectedErr: true,
+			},
+			{
+				name:          "writeFileSizeHintMb without autoWriteStrategy",
+				volumeContext: map[string]string{VolumeContextKeyWriteFileSizeHintMb: "64"},
+				expectedErr:   true,
+			},
+			{
+				name: "negative writeFileSizeHintMb",
+				volumeContext: map[string]string{
+					VolumeContextKeyAutoWriteStrategy:   util.TrueStr,
+					VolumeContextKeyWriteFileSizeHintMb: "-1",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "invalid writeFileSizeHintMb",
+				volumeContext: map[string]string{
+					VolumeContextKeyAutoWriteStrategy:   util.TrueStr,
+					VolumeContextKeyWriteFileSizeHintMb: "1Gi",
+				},
+				expectedErr: true,
+			},
+			{
+				name:          "invalid disableWritebackThrottle",
+				volumeContext: map[string]string{VolumeContextKeyDisableWritebackThrottle: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "disableReadAheadTuning false adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyDisableReadAheadTuning: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "should return the pinned metadata cache mount options for pinGeneration",
+				volumeContext:        map[string]string{VolumeContextKeyPinGeneration: util.TrueStr},
+				expectedMountOptions: pinGenerationMountOptions,
+			},
+			{
+				name:                 "pinGeneration false adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyPinGeneration: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid pinGeneration",
+				volumeContext: map[string]string{VolumeContextKeyPinGeneration: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name: "pinGeneration conflicts with metadataCacheTTLSeconds",
+				volumeContext: map[string]string{
+					VolumeContextKeyPinGeneration:           util.TrueStr,
+					VolumeContextKeyMetadataCacheTTLSeconds: "60",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "should return the offline cache serving mount options for a read-only volume with the file cache",
+				volumeContext: map[string]string{
+					VolumeContextKeyOfflineCacheServing: util.TrueStr,
+					VolumeContextKeySourceReadOnly:      util.TrueStr,
+					VolumeContextKeyFileCacheCapacity:   "100Mi",
+				},
+				expectedMountOptions: append([]string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeySourceReadOnly] + util.TrueStr,
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyFileCacheCapacity] + "100",
+				}, offlineCacheServingMountOptions...),
+			},
+			{
+				name: "offlineCacheServing keeps the negative stat cache TTL tunable",
+				volumeContext: map[string]string{
+					VolumeContextKeyOfflineCacheServing:         util.TrueStr,
+					VolumeContextKeySourceReadOnly:              util.TrueStr,
+					VolumeContextKeyFileCacheCapacity:           "100Mi",
+					VolumeContextKeyNegativeStatCacheTTLSeconds: "10",
+				},
+				expectedMountOptions: append([]string{
+					volumeAttributesToMountOptionsMapping[VolumeContextKeySourceReadOnly] + util.TrueStr,
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyFileCacheCapacity] + "100",
+					volumeAttributesToMountOptionsMapping[VolumeContextKeyNegativeStatCacheTTLSeconds] + "10",
+				}, offlineCacheServingMountOptions...),
+			},
+			{
+				name:                 "offlineCacheServing false adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyOfflineCacheServing: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name: "offlineCacheServing is rejected on a writable volume",
+				volumeContext: map[string]string{
+					VolumeContextKeyOfflineCacheServing: util.TrueStr,
+					VolumeContextKeyFileCacheCapacity:   "100Mi",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "offlineCacheServing requires the file cache",
+				volumeContext: map[string]string{
+					VolumeContextKeyOfflineCacheServing: util.TrueStr,
+					VolumeContextKeySourceReadOnly:      util.TrueStr,
+					VolumeContextKeyFileCacheCapacity:   "0",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "offlineCacheServing conflicts with metadataCacheTTLSeconds",
+				volumeContext: map[string]string{
+					VolumeContextKeyOfflineCacheServing:     util.TrueStr,
+					VolumeContextKeySourceReadOnly:          util.TrueStr,
+					VolumeContextKeyFileCacheCapacity:       "100Mi",
+					VolumeContextKeyMetadataCacheTTLSeconds: "60",
+				},
+				expectedErr: true,
+			},
+			{
+				name:                 "should return correct enableBufferedRead",
+				volumeContext:        map[string]string{VolumeContextKeyEnableBufferedRead: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyEnableBufferedRead] + util.TrueStr},
+			},
+			{
+				name:          "invalid enableBufferedRead",
+				volumeContext: map[string]string{VolumeContextKeyEnableBufferedRead: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct readBufferSizeMb",
+				volumeContext:        map[string]string{VolumeContextKeyReadBufferSizeMb: "32"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyReadBufferSizeMb] + "32"},
+			},
+			{
+				name:          "readBufferSizeMb should be positive",
+				volumeContext: map[string]string{VolumeContextKeyReadBufferSizeMb: "0"},
+				expectedErr:   true,
+			},
+			{
+				name:          "readBufferSizeMb should be an int",
+				volumeContext: map[string]string{VolumeContextKeyReadBufferSizeMb: "16Mi"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "prefetchManifestConfigMap should not be passed to gcsfuse",
+				volumeContext:        map[string]string{VolumeContextKeyPrefetchManifestConfigMap: "hot-objects"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "should pass the delete cacheCleanupOnUnmount to the sidecar mounter",
+				volumeContext:        map[string]string{VolumeContextKeyCacheCleanupOnUnmount: "delete"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCacheCleanupOnUnmount] + "delete"},
+			},
+			{
+				name:                 "the retain cacheCleanupOnUnmount should not be passed to the sidecar mounter",
+				volumeContext:        map[string]string{VolumeContextKeyCacheCleanupOnUnmount: "retain"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "cacheMedium should not be passed to gcsfuse",
+				volumeContext:        map[string]string{VolumeContextKeyCacheMedium: "memory"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid cacheMedium",
+				volumeContext: map[string]string{VolumeContextKeyCacheMedium: "tape"},
+				expectedErr:   true,
+			},
+			{
+				name:          "invalid cacheCleanupOnUnmount",
+				volumeContext: map[string]string{VolumeContextKeyCacheCleanupOnUnmount: "purge"},
+				expectedErr:   true,
+			},
+			{
+				name:          "prefetchManifestConfigMap should be a valid ConfigMap name",
+				volumeContext: map[string]string{VolumeContextKeyPrefetchManifestConfigMap: "Hot_Objects"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should apply createUmask to the gcsfuse default file mode",
+				volumeContext:        map[string]string{VolumeContextKeyCreateUmask: "027"},
+				expectedMountOptions: []string{"file-mode=640"},
+			},
+			{
+				name:                 "should apply createUmask to the file mode in the mount options",
+				volumeContext:        map[string]string{VolumeContextKeyMountOptions: "file-mode=666", VolumeContextKeyCreateUmask: "0002"},
+				expectedMountOptions: []string{"file-mode=664"},
+			},
+			{
+				name:          "createUmask should be octal",
+				volumeContext: map[string]string{VolumeContextKeyCreateUmask: "089"},
+				expectedErr:   true,
+			},
+			{
+				name:          "createUmask should not be larger than 0777",
+				volumeContext: map[string]string{VolumeContextKeyCreateUmask: "1777"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct maxConcurrentOperations",
+				volumeContext:        map[string]string{VolumeContextKeyMaxConcurrentOperations: "200"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyMaxConcurrentOperations] + "200"},
+			},
+			{
+				name:                 "maxConcurrentOperations should be clamped to the ceiling",
+				volumeContext:        map[string]string{VolumeContextKeyMaxConcurrentOperations: "100000"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyMaxConcurrentOperations] + "1024"},
+			},
+			{
+				name:          "maxConcurrentOperations should be positive",
+				volumeContext: map[string]string{VolumeContextKeyMaxConcurrentOperations: "0"},
+				expectedErr:   true,
+			},
+			{
+				name:          "maxConcurrentOperations conflicts with the max-conns-per-host mount option",
+				volumeContext: map[string]string{VolumeContextKeyMountOptions: "max-conns-per-host=10", VolumeContextKeyMaxConcurrentOperations: "200"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct enableEmptyManagedFolders",
+				volumeContext:        map[string]string{VolumeContextKeyEnableEmptyManagedFolders: util.TrueStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyEnableEmptyManagedFolders] + util.TrueStr},
+			},
+			{
+				name:                 "should return correct enableEmptyManagedFolders false",
+				volumeContext:        map[string]string{VolumeContextKeyEnableEmptyManagedFolders: util.FalseStr},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyEnableEmptyManagedFolders] + util.FalseStr},
+			},
+			{
+				name:          "invalid enableEmptyManagedFolders",
+				volumeContext: map[string]string{VolumeContextKeyEnableEmptyManagedFolders: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct chunkTransferTimeoutSeconds",
+				volumeContext:        map[string]string{VolumeContextKeyChunkTransferTimeoutSeconds: "20"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyChunkTransferTimeoutSeconds] + "20"},
+			},
+			{
+				name:          "chunkTransferTimeoutSeconds should be positive",
+				volumeContext: map[string]string{VolumeContextKeyChunkTransferTimeoutSeconds: "-1"},
+				expectedErr:   true,
+			},
+			{
+				name:          "chunkTransferTimeoutSeconds should be an int",
+				volumeContext: map[string]string{VolumeContextKeyChunkTransferTimeoutSeconds: "10s"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct collectDiagnosticsOnFailure",
+				volumeContext:        map[string]string{VolumeContextKeyCollectDiagnosticsOnFailure: "true"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCollectDiagnosticsOnFailure] + "true"},
+			},
+			{
+				name:          "invalid collectDiagnosticsOnFailure",
+				volumeContext: map[string]string{VolumeContextKeyCollectDiagnosticsOnFailure: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct maxRetryAttempts",
+				volumeContext:        map[string]string{VolumeContextKeyMaxRetryAttempts: "3"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyMaxRetryAttempts] + "3"},
+			},
+			{
+				name:                 "should return correct zero maxRetryAttempts",
+				volumeContext:        map[string]string{VolumeContextKeyMaxRetryAttempts: "0"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyMaxRetryAttempts] + "0"},
+			},
+			{
+				name:          "negative maxRetryAttempts",
+				volumeContext: map[string]string{VolumeContextKeyMaxRetryAttempts: "-1"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct writeDurability synchronous",
+				volumeContext:        map[string]string{VolumeContextKeyWriteDurability: "synchronous"},
+				expectedMountOptions: []string{"o=sync"},
+			},
+			{
+				name:                 "writeDurability buffered adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyWriteDurability: "buffered"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid writeDurability",
+				volumeContext: map[string]string{VolumeContextKeyWriteDurability: "sync"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "localFileCacheMode default adds no mount options",
+				volumeContext:        map[string]string{VolumeContextKeyLocalFileCacheMode: "default"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "should return correct localFileCacheMode direct",
+				volumeContext:        map[string]string{VolumeContextKeyLocalFileCacheMode: "direct"},
+				expectedMountOptions: []string{"file-cache:enable-o-direct:true"},
+			},
+			{
+				name:                 "should return correct localFileCacheMode parallel",
+				volumeContext:        map[string]string{VolumeContextKeyLocalFileCacheMode: "parallel"},
+				expectedMountOptions: []string{"file-cache:enable-parallel-downloads:true"},
+			},
+			{
+				name:          "localFileCacheMode not in the allowlist",
+				volumeContext: map[string]string{VolumeContextKeyLocalFileCacheMode: "max-size-mb:100"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "value set to true for VolumeContextKeyDisableAtime",
+				volumeContext:        map[string]string{VolumeContextKeyDisableAtime: util.TrueStr},
+				expectedMountOptions: []string{"o=noatime"},
+			},
+			{
+				name:                 "value set to false for VolumeContextKeyDisableAtime",
+				volumeContext:        map[string]string{VolumeContextKeyDisableAtime: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:                 "value set to true for VolumeContextKeyEnableParallelDirops",
+				volumeContext:        map[string]string{VolumeContextKeyEnableParallelDirops: util.TrueStr},
+				expectedMountOptions: []string{"file-system:enable-parallel-dirops:true"},
+			},
+			{
+				name:                 "value set to false for VolumeContextKeyEnableParallelDirops",
+				volumeContext:        map[string]string{VolumeContextKeyEnableParallelDirops: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "unexpected value for VolumeContextKeyEnableParallelDirops",
+				volumeContext: map[string]string{VolumeContextKeyEnableParallelDirops: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "value set to true for VolumeContextKeyMountOverNonEmpty",
+				volumeContext:        map[string]string{VolumeContextKeyMountOverNonEmpty: util.TrueStr},
+				expectedMountOptions: []string{"nonempty"},
+			},
+			{
+				name:                 "value set to false for VolumeContextKeyMountOverNonEmpty",
+				volumeContext:        map[string]string{VolumeContextKeyMountOverNonEmpty: util.FalseStr},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "unexpected value for VolumeContextKeyMountOverNonEmpty",
+				volumeContext: map[string]string{VolumeContextKeyMountOverNonEmpty: "yes"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct fsName",
+				volumeContext:        map[string]string{VolumeContextKeyFsName: "team-a/models"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyFsName] + "team-a/models"},
+			},
+			{
+				name:          "fsName with a space",
+				volumeContext: map[string]string{VolumeContextKeyFsName: "team a"},
+				expectedErr:   true,
+			},
+			{
+				name:          "fsName with a comma",
+				volumeContext: map[string]string{VolumeContextKeyFsName: "team-a,rw"},
+				expectedErr:   true,
+			},
+			{
+				name: "fsName conflicting with the fsname mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyMountOptions: "fsname=team-b",
+					VolumeContextKeyFsName:       "team-a",
+				},
+				expectedErr: true,
+			},
+			{
+				name: "should return correct security mount options",
+				volumeContext: map[string]string{
+					VolumeContextKeyAllowSuid:   util.TrueStr,
+					VolumeContextKeyAllowDev:    util.TrueStr,
+					VolumeContextKeyDisableExec: util.TrueStr,
+				},
+				expectedMountOptions: []string{"suid", "dev", "o=noexec"},
+			},
+			{
+				name: "should keep the secure defaults when the security attributes are false",
+				volumeContext: map[string]string{
+					VolumeContextKeyAllowSuid:   util.FalseStr,
+					VolumeContextKeyAllowDev:    util.FalseStr,
+					VolumeContextKeyDisableExec: util.FalseStr,
+				},
+				expectedMountOptions: []string{},
+			},
+			{
+				name: "allowDev false conflicting with the dev mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyMountOptions: "dev",
+					VolumeContextKeyAllowDev:     util.FalseStr,
+				},
+				expectedErr: true,
+			},
+			{
+				name: "allowSuid false conflicting with the suid mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyMountOptions: "suid",
+					VolumeContextKeyAllowSuid:    util.FalseStr,
+				},
+				expectedErr: true,
+			},
+			{
+				name: "allowDev true with the dev mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyMountOptions: "dev",
+					VolumeContextKeyAllowDev:     util.TrueStr,
+				},
+				expectedMountOptions: []string{"dev"},
+			},
+			{
+				name:          "unexpected value for allowDev",
+				volumeContext: map[string]string{VolumeContextKeyAllowDev: "blah"},
+				expectedErr:   true,
+			},
+			{
+				name:          "unexpected value for allowSuid",
+				volumeContext: map[string]string{VolumeContextKeyAllowSuid: "blah"},
+				expectedErr:   true,
+			},
+			{
+				name:                 "should return correct customEndpoint with a hostname",
+				volumeContext:        map[string]string{VolumeContextKeyCustomEndpoint: "storage.example.com"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCustomEndpoint] + "https://storage.example.com"},
+			},
+			{
+				name:                 "should return correct customEndpoint with an IP and a port",
+				volumeContext:        map[string]string{VolumeContextKeyCustomEndpoint: "199.36.153.8:443"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCustomEndpoint] + "https://199.36.153.8:443"},
+			},
+			{
+				name:                 "should return correct customEndpoint with an IPv6 address",
+				volumeContext:        map[string]string{VolumeContextKeyCustomEndpoint: "2001:db8::1"},
+				expectedMountOptions: []string{volumeAttributesToMountOptionsMapping[VolumeContextKeyCustomEndpoint] + "https://[2001:db8::1]"},
+			},
+			{
+				name:          "customEndpoint with a URL scheme",
+				volumeContext: map[string]string{VolumeContextKeyCustomEndpoint: "https://storage.example.com"},
+				expectedErr:   true,
+			},
+			{
+				name:          "customEndpoint with an invalid port",
+				volumeContext: map[string]string{VolumeContextKeyCustomEndpoint: "199.36.153.8:99999"},
+				expectedErr:   true,
+			},
+			{
+				name: "customEndpoint conflicting with the custom-endpoint mount option",
+				volumeContext: map[string]string{
+					VolumeContextKeyMountOptions:   "custom-endpoint=https://storage.googleapis.com",
+					VolumeContextKeyCustomEndpoint: "199.36.153.8",
+				},
+				expectedErr: true,
+			},
+			{
+				name:                 "retryOnStatusCodes should not be passed to gcsfuse",
+				volumeContext:        map[string]string{VolumeContextKeyRetryOnStatusCodes: "520, 529"},
+				expectedMountOptions: []string{},
+			},
+			{
+				name:          "invalid retryOnStatusCodes",
+				volumeContext: map[string]string{VolumeContextKeyRetryOnStatusCodes: "520,abc"},
+				expectedErr:   true,
+			},
+			{
+				name:          "unexpected value for VolumeContextKeyDisableAtime",
+				volumeContext: map[string]string{VolumeContextKeyDisableAtime: "blah"},
+				expectedErr:   true,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Logf("test case: %s", tc.name)
+				output, skipCSIBucketAccessCheck, disableMetricsCollection, err := parseVolumeAttributes([]string{}, tc.volumeContext)
+				if (err != nil) != tc.expectedErr {
+					t.Errorf("Got error %v, but expected error %v", err, tc.expectedErr)
+				}
+				enableMetricsCollection := !disableMetricsCollection
+
+				if tc.expectedErr {
+					return
+				}
+				if tc.expectedSkipBucketAccessCheck != skipCSIBucketAccessCheck {
+					t.Errorf("Got skipBucketAccessCheck %v, but expected %v", skipCSIBucketAccessCheck, tc.expectedSkipBucketAccessCheck)
+				}
+				if tc.expectedEnableMetricsCollection != enableMetricsCollection {
+					t.Errorf("Got disableMetricsCollection %v, but expected %v", enableMetricsCollection, tc.expectedEnableMetricsCollection)
+				}
+
+				less := func(a, b string) bool { return a > b }
+				if diff := cmp.Diff(output, tc.expectedMountOptions, cmpopts.SortSlices(less)); diff != "" {
+					t.Errorf("unexpected options args (-got, +want)\n%s", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestAutoWriteStrategyMountOptions(t *testing.T) {
+	t.Parallel()
+
+	sizeMb := func(v int64) *int64 { return &v }
+	testCases := []struct {
+		name            string
+		fileSizeHintMb  *int64
+		expectedOptions []string
+	}{
+		{
+			name:            "should stream the writes without a file size hint",
+			expectedOptions: []string{"write:enable-streaming-writes:true"},
+		},
+		{
+			name:            "should stage the writes of the empty files",
+			fileSizeHintMb:  sizeMb(0),
+			expectedOptions: []string{"write:enable-streaming-writes:false"},
+		},
+		{
+			name:            "should stage the writes of the files smaller than a streaming block",
+			fileSizeHintMb:  sizeMb(streamingWritesMinFileSizeMb - 1),
+			expectedOptions: []string{"write:enable-streaming-writes:false"},
+		},
+		{
+			name:            "should stream the writes of the files of a streaming block",
+			fileSizeHintMb:  sizeMb(streamingWritesMinFileSizeMb),
+			expectedOptions: []string{"write:enable-streaming-writes:true"},
+		},
+		{
+			name:            "should stream the writes of the large files",
+			fileSizeHintMb:  sizeMb(100 * 1024),
+			expectedOptions: []string{"write:enable-streaming-writes:true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(autoWriteStrategyMountOptions(tc.fileSizeHintMb), tc.expectedOptions); diff != "" {
+				t.Errorf("unexpected options (-got, +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseVolumeAttributesOfflineCacheServingOnReadOnlyMount(t *testing.T) {
+	t.Parallel()
+
+	volumeContext := map[string]string{
+		VolumeContextKeyOfflineCacheServing: util.TrueStr,
+		VolumeContextKeyFileCacheCapacity:   "1Gi",
+	}
+
+	if _, _, _, err := parseVolumeAttributes([]string{}, volumeContext); err == nil {
+		t.Errorf("expected an error for the writable mount, got nil")
+	}
+
+	output, _, _, err := parseVolumeAttributes([]string{"ro"}, volumeContext)
+	if err != nil {
+		t.Fatalf("unexpected error for the read-only mount: %v", err)
+	}
+	for _, o := range offlineCacheServingMountOptions {
+		if !slices.Contains(output, o) {
+			t.Errorf("got mount options %v, expected %q", output, o)
+		}
+	}
 }
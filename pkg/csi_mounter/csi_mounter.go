@@ -27,6 +27,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -45,39 +46,141 @@ const (
 	socketName                       = "socket"
 	readAheadKBMountFlagRegexPattern = "^read_ahead_kb=(.+)$"
 	readAheadKBMountFlag             = "read_ahead_kb"
+	// disableReadAheadTuningMountOption opts a volume out of the read_ahead_kb bdi adjustment.
+	disableReadAheadTuningMountOption = "disable_read_ahead_tuning"
+	// disableReadAheadMountOption turns off the kernel read-ahead of the mount and shrinks the gcsfuse sequential reads to the minimum,
+	// so the random reads do not fetch the data that is never read.
+	disableReadAheadMountOption         = "disable_read_ahead"
+	sequentialReadSizeMountOptionPrefix = "sequential-read-size-mb="
+	minSequentialReadSizeMbMountOption  = sequentialReadSizeMountOptionPrefix + "1"
+	// maxReadMountOptionPrefix bounds the size of the fuse read requests, it is a kernel mount option.
+	maxReadMountOptionPrefix = "max_read="
+	// allowRootMountOption restricts the access to the mount owner, which is root, instead of all the users.
+	allowRootMountOption  = "allow_root"
+	allowOtherMountOption = "allow_other"
+	// deferPermissionsMountOption leaves out the default_permissions kernel mount option,
+	// so the kernel skips the mode bit checks and the access is only authorized by the bucket IAM.
+	deferPermissionsMountOption   = "defer_permissions"
+	defaultPermissionsMountOption = "default_permissions"
+	// nonemptyMountOption permits mounting over a target path with existing files.
+	// It is risky, the existing files are hidden while the volume is mounted and reappear after it is unmounted.
+	nonemptyMountOption = "nonempty"
+	// seLinuxContextMountOptionPrefix labels the fuse mount with an SELinux context, it is handled by the kernel.
+	seLinuxContextMountOptionPrefix = "context="
+	// disableWritebackThrottleMountOption lifts the fuse bdi writeback limits, so the dirty pages of the mount are not throttled early.
+	disableWritebackThrottleMountOption = "disable_writeback_throttle"
+	// The kernel limits the dirty pages of a fuse bdi to its max_ratio share of the dirty threshold, which is 1% for fuse,
+	// and applies the limit strictly. The strict_limit knob is only exposed by the kernel 6.2 and later.
+	maxRatioSysfsBDIKnob    = "max_ratio"
+	strictLimitSysfsBDIKnob = "strict_limit"
+	// The max_background and congestion_threshold knobs of the fuse connection bound the background requests, e.g. the read-ahead,
+	// that the kernel queues to the fuse daemon. They are set in the fuse control filesystem after the mount.
+	maxBackgroundSysfsFuseKnob       = "max_background"
+	congestionThresholdSysfsFuseKnob = "congestion_threshold"
+	// fsNameMountOptionPrefix sets the mount source shown in the mount table, which defaults to the bucket name.
+	fsNameMountOptionPrefix = "fsname="
+	// suidMountOption and devMountOption relax the default nosuid and nodev kernel mount options,
+	// which are only permitted on the nodes whose policy allowlists them.
+	suidMountOption = "suid"
+	devMountOption  = "dev"
 )
 
-var readAheadKBMountFlagRegex = regexp.MustCompile(readAheadKBMountFlagRegexPattern)
+// securityRelaxations maps the mount options relaxing the secure defaults to the kernel mount options they remove.
+var securityRelaxations = map[string]string{
+	suidMountOption: "nosuid",
+	devMountOption:  "nodev",
+}
+
+// nodePolicyMountOptions are the other mount options that are only permitted on the nodes whose policy allowlists them,
+// in the same allowlist as the security relaxations. disable_writeback_throttle lets a mount hold the dirty pages of the whole node,
+// and defer_permissions lets every user of the mount access the files regardless of their mode bits.
+var nodePolicyMountOptions = sets.NewString(disableWritebackThrottleMountOption, deferPermissionsMountOption)
+
+var (
+	readAheadKBMountFlagRegex = regexp.MustCompile(readAheadKBMountFlagRegexPattern)
+
+	sysfsBDIBasePath = "/sys/class/bdi/"
+	// sysfsFuseConnectionsBasePath is the fuse control filesystem, which has a directory per fuse connection.
+	sysfsFuseConnectionsBasePath = "/sys/fs/fuse/connections/"
+	// optionalSysfsBDIKnobs are skipped on the kernels that do not expose them.
+	optionalSysfsBDIKnobs = map[string]bool{strictLimitSysfsBDIKnob: true}
+	// The kernel may reset the bdi settings after the mount,
+	// the settings are re-asserted every sysfsReconcileInterval for sysfsReconcileDuration.
+	sysfsReconcileInterval = time.Second * 10
+	sysfsReconcileDuration = time.Minute * 5
+)
 
 // Mounter provides the Cloud Storage FUSE CSI implementation of mount.Interface
 // for the linux platform.
 type Mounter struct {
 	mount.MounterForceUnmounter
-	mux           sync.Mutex
-	fuseSocketDir string
+	mux                    sync.Mutex
+	fuseSocketDir          string
+	disableReadAheadTuning bool
+	allowRoot              bool
+	allowedRelaxations     sets.String
+	fuseProtocolMinor      int
 }
 
 // New returns a mount.MounterForceUnmounter for the current system.
 // It provides options to override the default mounter behavior.
 // mounterPath allows using an alternative to `/bin/mount` for mounting.
-func New(mounterPath, fuseSocketDir string) (mount.Interface, error) {
+// disableReadAheadTuning skips the read_ahead_kb bdi adjustment for all the volumes.
+// allowRoot permits the volumes to use the allow_root mount option.
+// allowedRelaxations is a comma separated list of the suid and dev mount options the volumes may use to relax the nosuid and nodev defaults,
+// and of the other node policy mount options the volumes may use.
+// fuseProtocolVersion overrides the FUSE protocol version detected from the kernel release, which decides the supported fuse options.
+func New(mounterPath, fuseSocketDir string, disableReadAheadTuning, allowRoot bool, allowedRelaxations, fuseProtocolVersion string) (mount.Interface, error) {
 	m, ok := mount.New(mounterPath).(mount.MounterForceUnmounter)
 	if !ok {
 		return nil, errors.New("failed to cast mounter to MounterForceUnmounter")
 	}
 
+	relaxations := sets.NewString()
+	for _, r := range strings.Split(allowedRelaxations, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if _, ok := securityRelaxations[r]; !ok && !nodePolicyMountOptions.Has(r) {
+			supported := append(sets.StringKeySet(securityRelaxations).List(), nodePolicyMountOptions.List()...)
+
+			return nil, fmt.Errorf("invalid security relaxation %q, the supported values are %v", r, strings.Join(supported, ", "))
+		}
+		relaxations.Insert(r)
+	}
+
+	fuseProtocolMinor, err := detectFuseProtocolMinorVersion(fuseProtocolVersion)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Mounter{
 		m,
 		sync.Mutex{},
 		fuseSocketDir,
+		disableReadAheadTuning,
+		allowRoot,
+		relaxations,
+		fuseProtocolMinor,
 	}, nil
 }
 
 func (m *Mounter) Mount(source string, target string, fstype string, options []string) error {
+	// Bind mounts share an existing gcsfuse mount and do not need a new gcsfuse process.
+	if slices.Contains(options, "bind") {
+		return m.MounterForceUnmounter.Mount(source, target, fstype, options)
+	}
+
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
-	csiMountOptions, sidecarMountOptions, sysfsBDI, err := prepareMountOptions(options)
+	options = stripNonemptyOption(options)
+	fsName, options := mountSource(source, options)
+
+	options = filterFuseOptions(options, m.fuseProtocolMinor)
+
+	csiMountOptions, sidecarMountOptions, sysfsBDI, sysfsFuseConnection, err := prepareMountOptions(options, m.disableReadAheadTuning, m.allowRoot, m.allowedRelaxations)
 	if err != nil {
 		return err
 	}
@@ -104,17 +207,17 @@ func (m *Mounter) Mount(source string, target string, fstype string, options []s
 	csiMountOptions = append(csiMountOptions, fmt.Sprintf("fd=%v", fd))
 
 	klog.V(4).Infof("%v mounting the fuse filesystem", logPrefix)
-	err = m.MountSensitiveWithoutSystemdWithMountFlags(source, target, fstype, csiMountOptions, nil, []string{"--internal-only"})
+	err = m.MountSensitiveWithoutSystemdWithMountFlags(fsName, target, fstype, csiMountOptions, nil, []string{"--internal-only"})
 	if err != nil {
 		return fmt.Errorf("failed to mount the fuse filesystem: %w", err)
 	}
 
-	if len(sysfsBDI) != 0 {
+	if len(sysfsBDI) != 0 || len(sysfsFuseConnection) != 0 {
 		go func() {
 			// updateSysfsConfig may hang until the file descriptor (fd) is either consumed or canceled.
 			// It will succeed once dfuse finishes the mount process, or it will fail if dfuse fails
 			// or the mount point is cleaned up due to mounting failures.
-			if err := updateSysfsConfig(target, sysfsBDI); err != nil {
+			if err := updateSysfsConfig(target, sysfsBDI, sysfsFuseConnection); err != nil {
 				klog.Errorf("%v failed to update kernel parameters: %v", logPrefix, err)
 			}
 		}()
@@ -150,8 +253,9 @@ func (m *Mounter) Mount(source string, target string, fstype string, options []s
 }
 
 // updateSysfsConfig modifies the kernel page cache settings based on the read_ahead_kb provided in the mountOption,
-// and verifies that the values are successfully updated after the operation completes.
-func updateSysfsConfig(targetMountPath string, sysfsBDI map[string]int64) error {
+// and the fuse connection settings, and verifies that the values are successfully updated after the operation completes.
+// The page cache settings are then periodically re-applied for a bounded time in case the kernel resets them.
+func updateSysfsConfig(targetMountPath string, sysfsBDI, sysfsFuseConnection map[string]int64) error {
 	// Command will hang until mount completes.
 	cmd := exec.Command("mountpoint", "-d", targetMountPath)
 	output, err := cmd.CombinedOutput()
@@ -168,24 +272,106 @@ func updateSysfsConfig(targetMountPath string, sysfsBDI map[string]int64) error
 	targetDevice := strings.TrimSpace(string(output))
 	klog.Infof("Output of mountpoint for target mount path %s: %s", targetMountPath, output)
 
-	for key, value := range sysfsBDI {
-		// Update the target value.
-		sysfsBDIPath := filepath.Join("/sys/class/bdi/", targetDevice, key)
-		file, err := os.OpenFile(sysfsBDIPath, os.O_WRONLY|os.O_TRUNC, 0o644)
+	// The kernel only changes the fuse connection settings on writes, so they are applied once.
+	if len(sysfsFuseConnection) != 0 {
+		connection, err := fuseConnectionName(targetDevice)
 		if err != nil {
-			return fmt.Errorf("failed to open file %q: %w", sysfsBDIPath, err)
+			return err
 		}
-		defer file.Close()
 
-		_, err = file.WriteString(fmt.Sprintf("%d\n", value))
-		if err != nil {
-			return fmt.Errorf("failed to write to file %q: %w", "echo", err)
+		if _, err := reapplySysfsConfig(filepath.Join(sysfsFuseConnectionsBasePath, connection), sysfsFuseConnection, true); err != nil {
+			return err
+		}
+	}
+
+	if len(sysfsBDI) == 0 {
+		return nil
+	}
+
+	sysfsBDIDir := filepath.Join(sysfsBDIBasePath, targetDevice)
+	if _, err := reapplySysfsConfig(sysfsBDIDir, sysfsBDI, true); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sysfsReconcileDuration)
+	defer cancel()
+
+	return reconcileSysfsConfig(ctx, sysfsBDIDir, sysfsBDI, sysfsReconcileInterval)
+}
+
+// fuseConnectionName returns the fuse control filesystem directory name of the device in the form of <major>:<minor>,
+// which is the kernel internal device number.
+func fuseConnectionName(device string) (string, error) {
+	major, minor, found := strings.Cut(device, ":")
+	majorNum, majorErr := strconv.ParseUint(major, 10, 12)
+	minorNum, minorErr := strconv.ParseUint(minor, 10, 20)
+	if !found || majorErr != nil || minorErr != nil {
+		return "", fmt.Errorf("invalid device number %q", device)
+	}
+
+	return strconv.FormatUint(majorNum<<20|minorNum, 10), nil
+}
+
+// reconcileSysfsConfig re-applies the kernel page cache settings every interval until the context is done.
+// It stops early if the bdi directory is gone, which means the mount point was cleaned up.
+func reconcileSysfsConfig(ctx context.Context, sysfsBDIDir string, sysfsBDI map[string]int64, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := os.Stat(sysfsBDIDir); os.IsNotExist(err) {
+				klog.V(4).Infof("Stop reconciling kernel parameters because %s does not exist", sysfsBDIDir)
+
+				return nil
+			}
+
+			if _, err := reapplySysfsConfig(sysfsBDIDir, sysfsBDI, false); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// reapplySysfsConfig writes the sysfs values to the bdi or fuse connection directory.
+// Unless force is true, a value is only written when the current value differs.
+// It returns whether any value was written.
+func reapplySysfsConfig(sysfsBDIDir string, sysfsBDI map[string]int64, force bool) (bool, error) {
+	updated := false
+	for key, value := range sysfsBDI {
+		sysfsBDIPath := filepath.Join(sysfsBDIDir, key)
+		if _, err := os.Stat(sysfsBDIPath); os.IsNotExist(err) && optionalSysfsBDIKnobs[key] {
+			klog.V(4).Infof("Skipping %s, the kernel does not support it", sysfsBDIPath)
+
+			continue
+		}
+
+		if !force {
+			current, err := os.ReadFile(sysfsBDIPath)
+			if err != nil {
+				return updated, fmt.Errorf("failed to read file %q: %w", sysfsBDIPath, err)
+			}
+
+			if strings.TrimSpace(string(current)) == strconv.FormatInt(value, 10) {
+				continue
+			}
+
+			klog.Warningf("%s was reset to %s, re-applying %d", sysfsBDIPath, strings.TrimSpace(string(current)), value)
+		}
+
+		// Update the target value.
+		if err := os.WriteFile(sysfsBDIPath, []byte(fmt.Sprintf("%d\n", value)), 0o644); err != nil {
+			return updated, fmt.Errorf("failed to write to file %q: %w", sysfsBDIPath, err)
 		}
 
+		updated = true
 		klog.Infof("Updated %s to %d", sysfsBDIPath, value)
 	}
 
-	return nil
+	return updated, nil
 }
 
 func (m *Mounter) UnmountWithForce(target string, umountTimeout time.Duration) error {
@@ -279,7 +465,32 @@ func startAcceptConn(l net.Listener, logPrefix string, msg []byte, fd int, cance
 	klog.V(4).Infof("%v exiting the listener goroutine.", logPrefix)
 }
 
-func prepareMountOptions(options []string) ([]string, []string, map[string]int64, error) {
+// stripNonemptyOption returns the options without the nonempty mount option, which is neither a kernel nor a gcsfuse option.
+// The kernel mounts over a target path with existing files regardless of the option.
+func stripNonemptyOption(options []string) []string {
+	return slices.DeleteFunc(slices.Clone(options), func(o string) bool {
+		return o == nonemptyMountOption
+	})
+}
+
+// mountSource returns the fsname mount option value as the mount source, defaulting to the bucket name.
+// It returns the options without the fsname mount option, which is not a gcsfuse option.
+func mountSource(bucketName string, options []string) (string, []string) {
+	source := bucketName
+	options = slices.DeleteFunc(slices.Clone(options), func(o string) bool {
+		if v, ok := strings.CutPrefix(o, fsNameMountOptionPrefix); ok {
+			source = v
+
+			return true
+		}
+
+		return false
+	})
+
+	return source, options
+}
+
+func prepareMountOptions(options []string, disableReadAheadTuning, allowRoot bool, allowedRelaxations sets.String) ([]string, []string, map[string]int64, map[string]int64, error) {
 	allowedOptions := map[string]bool{
 		"exec":    true,
 		"noexec":  true,
@@ -293,8 +504,8 @@ func prepareMountOptions(options []string) ([]string, []string, map[string]int64
 	csiMountOptions := []string{
 		"nodev",
 		"nosuid",
-		"allow_other",
-		"default_permissions",
+		allowOtherMountOption,
+		defaultPermissionsMountOption,
 		"rootmode=40000",
 		fmt.Sprintf("user_id=%d", os.Getuid()),
 		fmt.Sprintf("group_id=%d", os.Getgid()),
@@ -312,6 +523,7 @@ func prepareMountOptions(options []string) ([]string, []string, map[string]int64
 	}
 
 	sysfsBDI := make(map[string]int64)
+	sysfsFuseConnection := make(map[string]int64)
 	for _, o := range optionSet.List() {
 		if strings.HasPrefix(o, "o=") {
 			v := o[2:]
@@ -323,20 +535,113 @@ func prepareMountOptions(options []string) ([]string, []string, map[string]int64
 			optionSet.Delete(o)
 		}
 
+		if strings.HasPrefix(o, seLinuxContextMountOptionPrefix) || strings.HasPrefix(o, maxReadMountOptionPrefix) {
+			csiMountOptions = append(csiMountOptions, o)
+			optionSet.Delete(o)
+		}
+
 		if readAheadKB := readAheadKBMountFlagRegex.FindStringSubmatch(o); len(readAheadKB) == 2 {
 			// There is only one matching pattern in readAheadKBMountFlagRegex
 			// If found, it will be at index 1
 			readAheadKBInt, err := strconv.ParseInt(readAheadKB[1], 10, 0)
 			if err != nil {
-				return nil, nil, nil, fmt.Errorf("invalid read_ahead_kb mount flag %q: %w", o, err)
+				return nil, nil, nil, nil, fmt.Errorf("invalid read_ahead_kb mount flag %q: %w", o, err)
 			}
 			if readAheadKBInt < 0 {
-				return nil, nil, nil, fmt.Errorf("invalid negative value for read_ahead_kb mount flag: %q", o)
+				return nil, nil, nil, nil, fmt.Errorf("invalid negative value for read_ahead_kb mount flag: %q", o)
 			}
 			sysfsBDI[readAheadKBMountFlag] = readAheadKBInt
 			optionSet.Delete(o)
 		}
+
+		if knob, value, found := strings.Cut(o, "="); found && (knob == maxBackgroundSysfsFuseKnob || knob == congestionThresholdSysfsFuseKnob) {
+			intVal, err := strconv.ParseInt(value, 10, 0)
+			if err != nil || intVal <= 0 {
+				return nil, nil, nil, nil, fmt.Errorf("invalid %v mount option %q, must be a positive integer", knob, o)
+			}
+			sysfsFuseConnection[knob] = intVal
+			optionSet.Delete(o)
+		}
+	}
+
+	// The kernel does not support allow_root, the mount owner is root,
+	// so the access is restricted to root by leaving out allow_other.
+	if optionSet.Has(allowRootMountOption) {
+		if !allowRoot {
+			return nil, nil, nil, nil, fmt.Errorf("the %v mount option is not permitted on this node, the driver must be started with --fuse-allow-root", allowRootMountOption)
+		}
+		if optionSet.Has(allowOtherMountOption) {
+			return nil, nil, nil, nil, fmt.Errorf("the %v mount option conflicts with the %v mount option", allowRootMountOption, allowOtherMountOption)
+		}
+
+		csiMountOptions = slices.DeleteFunc(csiMountOptions, func(o string) bool {
+			return o == allowOtherMountOption
+		})
+		optionSet.Delete(allowRootMountOption)
+	}
+
+	for _, o := range nodePolicyMountOptions.List() {
+		if optionSet.Has(o) && !allowedRelaxations.Has(o) {
+			return nil, nil, nil, nil, fmt.Errorf("the %v mount option is not permitted on this node, the driver must be started with --fuse-allowed-security-relaxations=%v", o, o)
+		}
+	}
+
+	if optionSet.Has(deferPermissionsMountOption) {
+		csiMountOptions = slices.DeleteFunc(csiMountOptions, func(o string) bool {
+			return o == defaultPermissionsMountOption
+		})
+		optionSet.Delete(deferPermissionsMountOption)
+	}
+
+	// The mounts are nosuid and nodev by default, a volume can only relax them on the nodes that allowlist the relaxation.
+	for relaxation, secureOption := range securityRelaxations {
+		if !optionSet.Has(relaxation) {
+			continue
+		}
+		if !allowedRelaxations.Has(relaxation) {
+			return nil, nil, nil, nil, fmt.Errorf("the %v mount option is not permitted on this node, the driver must be started with --fuse-allowed-security-relaxations=%v", relaxation, relaxation)
+		}
+
+		csiMountOptions = slices.DeleteFunc(csiMountOptions, func(o string) bool {
+			return o == secureOption
+		})
+		optionSet.Delete(relaxation)
+	}
+
+	if optionSet.Has(disableWritebackThrottleMountOption) {
+		sysfsBDI[maxRatioSysfsBDIKnob] = 100
+		sysfsBDI[strictLimitSysfsBDIKnob] = 0
+		optionSet.Delete(disableWritebackThrottleMountOption)
+	}
+
+	if optionSet.Has(disableReadAheadMountOption) {
+		if _, ok := sysfsBDI[readAheadKBMountFlag]; ok {
+			return nil, nil, nil, nil, fmt.Errorf("the %v mount option conflicts with the %v mount option", disableReadAheadMountOption, readAheadKBMountFlag)
+		}
+		if optionSet.Has(disableReadAheadTuningMountOption) {
+			return nil, nil, nil, nil, fmt.Errorf("the %v mount option conflicts with the %v mount option", disableReadAheadMountOption, disableReadAheadTuningMountOption)
+		}
+		for _, o := range optionSet.List() {
+			if strings.HasPrefix(o, sequentialReadSizeMountOptionPrefix) {
+				return nil, nil, nil, nil, fmt.Errorf("the %v mount option conflicts with the %q mount option", disableReadAheadMountOption, o)
+			}
+		}
+
+		sysfsBDI[readAheadKBMountFlag] = 0
+		optionSet.Delete(disableReadAheadMountOption)
+		optionSet.Insert(minSequentialReadSizeMbMountOption)
+	}
+
+	if optionSet.Has(disableReadAheadTuningMountOption) {
+		disableReadAheadTuning = true
+		optionSet.Delete(disableReadAheadTuningMountOption)
+	}
+
+	// Some kernel policies forbid writing the bdi knobs, skip the adjustment instead of logging errors.
+	if _, ok := sysfsBDI[readAheadKBMountFlag]; ok && disableReadAheadTuning {
+		klog.Infof("the read-ahead tuning is disabled, ignoring the %v mount option", readAheadKBMountFlag)
+		delete(sysfsBDI, readAheadKBMountFlag)
 	}
 
-	return csiMountOptions, optionSet.List(), sysfsBDI, nil
+	return csiMountOptions, optionSet.List(), sysfsBDI, sysfsFuseConnection, nil
 }
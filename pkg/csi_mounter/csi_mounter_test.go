@@ -18,10 +18,17 @@ limitations under the License.
 package csimounter
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 var defaultCsiMountOptions = []string{
@@ -42,7 +49,11 @@ func TestPrepareMountArgs(t *testing.T) {
 		inputMountOptions          []string
 		expecteCsiMountOptions     []string
 		expecteSidecarMountOptions []string
+		disableReadAheadTuning     bool
+		allowRoot                  bool
+		allowedRelaxations         []string
 		expectedSysfsBDI           map[string]int64
+		expectedSysfsFuseConn      map[string]int64
 		expectErr                  bool
 	}{
 		{
@@ -66,6 +77,13 @@ func TestPrepareMountArgs(t *testing.T) {
 			expecteSidecarMountOptions: []string{"implicit-dirs", "max-conns-per-host=10"},
 			expectedSysfsBDI:           map[string]int64{},
 		},
+		{
+			name:                       "should pass the SELinux context mount option to the kernel",
+			inputMountOptions:          []string{"implicit-dirs", `context="system_u:object_r:container_file_t:s0:c1,c2"`},
+			expecteCsiMountOptions:     append(defaultCsiMountOptions, `context="system_u:object_r:container_file_t:s0:c1,c2"`),
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
 		{
 			name:                       "should return valid options correctly with CSI and sidecar mount options",
 			inputMountOptions:          []string{"ro", "implicit-dirs", "max-conns-per-host=10", "o=noexec", "o=noatime", "o=invalid"},
@@ -80,6 +98,162 @@ func TestPrepareMountArgs(t *testing.T) {
 			expecteSidecarMountOptions: []string{"implicit-dirs", "max-conns-per-host=10"},
 			expectedSysfsBDI:           map[string]int64{"read_ahead_kb": 4096},
 		},
+		{
+			name:                       "should pass the max_read mount option to the kernel",
+			inputMountOptions:          []string{"implicit-dirs", "max_read=131072"},
+			expecteCsiMountOptions:     append(defaultCsiMountOptions, "max_read=131072"),
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:                       "should return valid options correctly with the sync mount option",
+			inputMountOptions:          []string{"implicit-dirs", "o=sync"},
+			expecteCsiMountOptions:     append(defaultCsiMountOptions, "sync"),
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:                       "should skip the read ahead configs when the read-ahead tuning is disabled by the driver",
+			inputMountOptions:          []string{"implicit-dirs", "read_ahead_kb=4096"},
+			disableReadAheadTuning:     true,
+			expecteCsiMountOptions:     defaultCsiMountOptions,
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:                       "should skip the read ahead configs when the volume opts out of the read-ahead tuning",
+			inputMountOptions:          []string{"implicit-dirs", "read_ahead_kb=4096", "disable_read_ahead_tuning"},
+			expecteCsiMountOptions:     defaultCsiMountOptions,
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:                       "should lift the bdi writeback limits when the writeback throttle is disabled",
+			inputMountOptions:          []string{"implicit-dirs", "read_ahead_kb=4096", "disable_writeback_throttle"},
+			allowedRelaxations:         []string{"disable_writeback_throttle"},
+			expecteCsiMountOptions:     defaultCsiMountOptions,
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{"read_ahead_kb": 4096, "max_ratio": 100, "strict_limit": 0},
+		},
+		{
+			name:              "disable_writeback_throttle is not permitted without a node policy",
+			inputMountOptions: []string{"implicit-dirs", "disable_writeback_throttle"},
+			expectErr:         true,
+		},
+		{
+			name:                       "should set the fuse connection background request limits",
+			inputMountOptions:          []string{"implicit-dirs", "max_background=64", "congestion_threshold=48"},
+			expecteCsiMountOptions:     defaultCsiMountOptions,
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+			expectedSysfsFuseConn:      map[string]int64{"max_background": 64, "congestion_threshold": 48},
+		},
+		{
+			name:              "should throw error for invalid max_background",
+			inputMountOptions: []string{"max_background=0"},
+			expectErr:         true,
+		},
+		{
+			name:              "should throw error for invalid congestion_threshold",
+			inputMountOptions: []string{"congestion_threshold=high"},
+			expectErr:         true,
+		},
+		{
+			name:                       "should turn off the gcsfuse and kernel read-ahead with the disable_read_ahead mount option",
+			inputMountOptions:          []string{"implicit-dirs", "disable_read_ahead"},
+			expecteCsiMountOptions:     defaultCsiMountOptions,
+			expecteSidecarMountOptions: []string{"implicit-dirs", "sequential-read-size-mb=1"},
+			expectedSysfsBDI:           map[string]int64{"read_ahead_kb": 0},
+		},
+		{
+			name:                       "should only shrink the gcsfuse sequential reads when the read-ahead tuning is disabled by the driver",
+			inputMountOptions:          []string{"implicit-dirs", "disable_read_ahead"},
+			disableReadAheadTuning:     true,
+			expecteCsiMountOptions:     defaultCsiMountOptions,
+			expecteSidecarMountOptions: []string{"implicit-dirs", "sequential-read-size-mb=1"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:              "disable_read_ahead conflicts with read_ahead_kb",
+			inputMountOptions: []string{"disable_read_ahead", "read_ahead_kb=4096"},
+			expectErr:         true,
+		},
+		{
+			name:              "disable_read_ahead conflicts with disable_read_ahead_tuning",
+			inputMountOptions: []string{"disable_read_ahead", "disable_read_ahead_tuning"},
+			expectErr:         true,
+		},
+		{
+			name:              "disable_read_ahead conflicts with sequential-read-size-mb",
+			inputMountOptions: []string{"disable_read_ahead", "sequential-read-size-mb=200"},
+			expectErr:         true,
+		},
+		{
+			name:                       "should leave out default_permissions with the defer_permissions mount option",
+			inputMountOptions:          []string{"implicit-dirs", "defer_permissions"},
+			allowedRelaxations:         []string{"defer_permissions"},
+			expecteCsiMountOptions:     []string{"nodev", "nosuid", "allow_other", "rootmode=40000", fmt.Sprintf("user_id=%d", os.Getuid()), fmt.Sprintf("group_id=%d", os.Getgid())},
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:               "defer_permissions is not permitted by the node policy",
+			inputMountOptions:  []string{"implicit-dirs", "defer_permissions"},
+			allowedRelaxations: []string{"disable_writeback_throttle"},
+			expectErr:          true,
+		},
+		{
+			name:                       "should leave out allow_other with the allow_root mount option",
+			inputMountOptions:          []string{"implicit-dirs", "allow_root"},
+			allowRoot:                  true,
+			expecteCsiMountOptions:     []string{"nodev", "nosuid", "default_permissions", "rootmode=40000", fmt.Sprintf("user_id=%d", os.Getuid()), fmt.Sprintf("group_id=%d", os.Getgid())},
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:              "allow_root is not permitted by the driver",
+			inputMountOptions: []string{"allow_root"},
+			expectErr:         true,
+		},
+		{
+			name:              "allow_root conflicts with allow_other",
+			inputMountOptions: []string{"allow_root", "allow_other"},
+			allowRoot:         true,
+			expectErr:         true,
+		},
+		{
+			name:                       "should lift nosuid and nodev with the allowlisted relaxations",
+			inputMountOptions:          []string{"implicit-dirs", "suid", "dev", "o=noexec"},
+			allowedRelaxations:         []string{"suid", "dev"},
+			expecteCsiMountOptions:     []string{"noexec", allowOtherMountOption, "default_permissions", "rootmode=40000", fmt.Sprintf("user_id=%d", os.Getuid()), fmt.Sprintf("group_id=%d", os.Getgid())},
+			expecteSidecarMountOptions: []string{"implicit-dirs"},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:                       "should only lift nosuid when dev is not requested",
+			inputMountOptions:          []string{"suid"},
+			allowedRelaxations:         []string{"suid", "dev"},
+			expecteCsiMountOptions:     []string{"nodev", allowOtherMountOption, "default_permissions", "rootmode=40000", fmt.Sprintf("user_id=%d", os.Getuid()), fmt.Sprintf("group_id=%d", os.Getgid())},
+			expecteSidecarMountOptions: []string{},
+			expectedSysfsBDI:           map[string]int64{},
+		},
+		{
+			name:               "suid is not permitted by the node policy",
+			inputMountOptions:  []string{"suid"},
+			allowedRelaxations: []string{"dev"},
+			expectErr:          true,
+		},
+		{
+			name:               "dev is not permitted by the node policy",
+			inputMountOptions:  []string{"dev"},
+			allowedRelaxations: []string{"suid"},
+			expectErr:          true,
+		},
+		{
+			name:              "dev is not permitted without a node policy",
+			inputMountOptions: []string{"dev"},
+			expectErr:         true,
+		},
 		{
 			name:              "invalid read ahead - not int",
 			inputMountOptions: append(defaultCsiMountOptions, "read_ahead_kb=abc"),
@@ -97,7 +271,7 @@ func TestPrepareMountArgs(t *testing.T) {
 			t.Parallel()
 			t.Logf("test case: %s", tc.name)
 
-			c, s, sysfsBDI, err := prepareMountOptions(tc.inputMountOptions)
+			c, s, sysfsBDI, sysfsFuseConn, err := prepareMountOptions(tc.inputMountOptions, tc.disableReadAheadTuning, tc.allowRoot, sets.NewString(tc.allowedRelaxations...))
 
 			if tc.expectErr && err == nil {
 				t.Errorf("test %q failed: expected an error, but got nil", tc.name)
@@ -124,10 +298,222 @@ func TestPrepareMountArgs(t *testing.T) {
 			if !reflect.DeepEqual(sysfsBDI, tc.expectedSysfsBDI) {
 				t.Errorf("Got sysfsBDI %v, expected %v", sysfsBDI, tc.expectedSysfsBDI)
 			}
+
+			if (len(sysfsFuseConn) != 0 || len(tc.expectedSysfsFuseConn) != 0) && !reflect.DeepEqual(sysfsFuseConn, tc.expectedSysfsFuseConn) {
+				t.Errorf("Got sysfs fuse connection %v, expected %v", sysfsFuseConn, tc.expectedSysfsFuseConn)
+			}
+		})
+	}
+}
+
+func TestNewSecurityRelaxations(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                string
+		allowedRelaxations  string
+		expectedRelaxations []string
+		expectErr           bool
+	}{
+		{
+			name:                "should keep the secure defaults without relaxations",
+			expectedRelaxations: []string{},
+		},
+		{
+			name:                "should parse the comma separated relaxations",
+			allowedRelaxations:  "suid, dev,",
+			expectedRelaxations: []string{"dev", "suid"},
+		},
+		{
+			name:                "should parse the node policy mount options",
+			allowedRelaxations:  "disable_writeback_throttle,defer_permissions",
+			expectedRelaxations: []string{"defer_permissions", "disable_writeback_throttle"},
+		},
+		{
+			name:               "should reject an unsupported relaxation",
+			allowedRelaxations: "suid,exec",
+			expectErr:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New("", "", false, false, tc.allowedRelaxations, "")
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			mounter, ok := m.(*Mounter)
+			if !ok {
+				t.Fatalf("failed to cast the mounter")
+			}
+			if !reflect.DeepEqual(mounter.allowedRelaxations.List(), tc.expectedRelaxations) {
+				t.Errorf("got relaxations %v, expected %v", mounter.allowedRelaxations.List(), tc.expectedRelaxations)
+			}
+		})
+	}
+}
+
+func TestStripNonemptyOption(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		inputOptions    []string
+		expectedOptions []string
+	}{
+		{
+			name:            "should keep the options without the nonempty mount option",
+			inputOptions:    []string{"implicit-dirs"},
+			expectedOptions: []string{"implicit-dirs"},
+		},
+		{
+			name:            "should strip the nonempty mount option",
+			inputOptions:    []string{"implicit-dirs", "nonempty"},
+			expectedOptions: []string{"implicit-dirs"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if options := stripNonemptyOption(tc.inputOptions); !reflect.DeepEqual(options, tc.expectedOptions) {
+				t.Errorf("Got options %v, but expected %v", options, tc.expectedOptions)
+			}
+		})
+	}
+}
+
+func TestMountSource(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		inputOptions    []string
+		expectedSource  string
+		expectedOptions []string
+	}{
+		{
+			name:            "should use the bucket name as the mount source by default",
+			inputOptions:    []string{"implicit-dirs"},
+			expectedSource:  "test-bucket",
+			expectedOptions: []string{"implicit-dirs"},
+		},
+		{
+			name:            "should use the fsname as the mount source",
+			inputOptions:    []string{"implicit-dirs", "fsname=team-a/models"},
+			expectedSource:  "team-a/models",
+			expectedOptions: []string{"implicit-dirs"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			source, options := mountSource("test-bucket", tc.inputOptions)
+			if source != tc.expectedSource {
+				t.Errorf("Got mount source %q, but expected %q", source, tc.expectedSource)
+			}
+			if !reflect.DeepEqual(options, tc.expectedOptions) {
+				t.Errorf("Got options %v, but expected %v", options, tc.expectedOptions)
+			}
 		})
 	}
 }
 
+func TestReconcileSysfsConfig(t *testing.T) {
+	t.Parallel()
+
+	sysfsBDIDir := t.TempDir()
+	sysfsBDIPath := filepath.Join(sysfsBDIDir, readAheadKBMountFlag)
+	sysfsBDI := map[string]int64{readAheadKBMountFlag: 4096}
+
+	if _, err := reapplySysfsConfig(sysfsBDIDir, sysfsBDI, true); err != nil {
+		t.Fatalf("failed to apply sysfs config: %v", err)
+	}
+
+	// Simulate the kernel resetting the value to the default.
+	if err := os.WriteFile(sysfsBDIPath, []byte("128\n"), 0o644); err != nil {
+		t.Fatalf("failed to reset %q: %v", sysfsBDIPath, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	if err := reconcileSysfsConfig(ctx, sysfsBDIDir, sysfsBDI, time.Millisecond*10); err != nil {
+		t.Fatalf("failed to reconcile sysfs config: %v", err)
+	}
+
+	got, err := os.ReadFile(sysfsBDIPath)
+	if err != nil {
+		t.Fatalf("failed to read %q: %v", sysfsBDIPath, err)
+	}
+	if strings.TrimSpace(string(got)) != "4096" {
+		t.Errorf("got %s %q, expected it to be re-applied to 4096", readAheadKBMountFlag, strings.TrimSpace(string(got)))
+	}
+
+	// No update is expected when the value is unchanged.
+	updated, err := reapplySysfsConfig(sysfsBDIDir, sysfsBDI, false)
+	if err != nil {
+		t.Fatalf("failed to reapply sysfs config: %v", err)
+	}
+	if updated {
+		t.Errorf("expected no update when %s is unchanged", readAheadKBMountFlag)
+	}
+}
+
+func TestReconcileSysfsConfigStopsWhenMountIsGone(t *testing.T) {
+	t.Parallel()
+
+	sysfsBDIDir := filepath.Join(t.TempDir(), "not-exist")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- reconcileSysfsConfig(ctx, sysfsBDIDir, map[string]int64{readAheadKBMountFlag: 4096}, time.Millisecond*10)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Errorf("expected reconciliation to stop when the bdi directory does not exist")
+	}
+}
+
+func TestFuseConnectionName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		device       string
+		expectedName string
+		expectErr    bool
+	}{
+		{device: "0:52", expectedName: "52"},
+		{device: "8:1", expectedName: "8388609"},
+		{device: "52", expectErr: true},
+		{device: "0:x", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		name, err := fuseConnectionName(tc.device)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("device %q: got error %v, expected error %v", tc.device, err, tc.expectErr)
+		}
+		if name != tc.expectedName {
+			t.Errorf("device %q: got connection name %q, expected %q", tc.device, name, tc.expectedName)
+		}
+	}
+}
+
 func countOptionOccurrence(options []string) map[string]int {
 	dict := make(map[string]int)
 	for _, o := range options {
@@ -136,3 +522,40 @@ func countOptionOccurrence(options []string) map[string]int {
 
 	return dict
 }
+
+func TestReapplySysfsConfigWritebackThrottle(t *testing.T) {
+	t.Parallel()
+
+	sysfsBDI := map[string]int64{maxRatioSysfsBDIKnob: 100, strictLimitSysfsBDIKnob: 0}
+
+	// The kernels before 6.2 do not expose the strict_limit knob.
+	for _, knobs := range [][]string{{maxRatioSysfsBDIKnob, strictLimitSysfsBDIKnob}, {maxRatioSysfsBDIKnob}} {
+		sysfsBDIDir := t.TempDir()
+		for _, knob := range knobs {
+			if err := os.WriteFile(filepath.Join(sysfsBDIDir, knob), []byte("1\n"), 0o644); err != nil {
+				t.Fatalf("failed to create %q: %v", knob, err)
+			}
+		}
+
+		if _, err := reapplySysfsConfig(sysfsBDIDir, sysfsBDI, true); err != nil {
+			t.Fatalf("failed to apply sysfs config with the knobs %v: %v", knobs, err)
+		}
+		if _, err := reapplySysfsConfig(sysfsBDIDir, sysfsBDI, false); err != nil {
+			t.Fatalf("failed to re-apply sysfs config with the knobs %v: %v", knobs, err)
+		}
+
+		for _, knob := range knobs {
+			got, err := os.ReadFile(filepath.Join(sysfsBDIDir, knob))
+			if err != nil {
+				t.Fatalf("failed to read %q: %v", knob, err)
+			}
+			if expected := strconv.FormatInt(sysfsBDI[knob], 10); strings.TrimSpace(string(got)) != expected {
+				t.Errorf("got %s %q, expected %q", knob, strings.TrimSpace(string(got)), expected)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(sysfsBDIDir, strictLimitSysfsBDIKnob)); len(knobs) == 1 && !os.IsNotExist(err) {
+			t.Errorf("expected %s to be skipped when the kernel does not expose it", strictLimitSysfsBDIKnob)
+		}
+	}
+}
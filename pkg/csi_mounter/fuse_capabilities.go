@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csimounter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// fuseProtocolMajorVersion is the FUSE kernel protocol major version of all the supported kernels.
+	fuseProtocolMajorVersion = 7
+	// fuseMaxPagesProtocolMinorVersion introduced FUSE_MAX_PAGES, which lifts the request size limit of 32 pages.
+	fuseMaxPagesProtocolMinorVersion = 28
+	// fuseLatestProtocolMinorVersion is assumed when the kernel capabilities cannot be detected, so no option is filtered.
+	fuseLatestProtocolMinorVersion = 1<<31 - 1
+)
+
+// kernelFuseProtocolMinorVersions maps the kernel releases to the FUSE protocol minor versions they support,
+// in the descending order of the kernel releases. Only the versions the mount options depend on are listed.
+var kernelFuseProtocolMinorVersions = []struct {
+	kernelRelease string
+	minorVersion  int
+}{
+	{"4.20", fuseMaxPagesProtocolMinorVersion},
+	{"4.7", 25},
+	{"2.6.32", 13},
+}
+
+// fuseOptionMinProtocolMinorVersions are the FUSE protocol minor versions required by the fuse and gcsfuse options.
+var fuseOptionMinProtocolMinorVersions = map[string]int{
+	"max_background":                     13,
+	"congestion_threshold":               13,
+	"file-system:enable-parallel-dirops": 25,
+}
+
+// fuseProtocolMinorVersion returns the FUSE protocol minor version set by the fuseProtocolVersion in the form of 7.<minor>,
+// or the version supported by the kernel release when fuseProtocolVersion is empty.
+func fuseProtocolMinorVersion(fuseProtocolVersion, kernelRelease string) (int, error) {
+	if fuseProtocolVersion != "" {
+		major, minor, found := strings.Cut(fuseProtocolVersion, ".")
+		minorVersion, err := strconv.Atoi(minor)
+		if !found || major != strconv.Itoa(fuseProtocolMajorVersion) || err != nil || minorVersion < 0 {
+			return 0, fmt.Errorf("invalid FUSE protocol version %q, must be in the form of %v.<minor>", fuseProtocolVersion, fuseProtocolMajorVersion)
+		}
+
+		return minorVersion, nil
+	}
+
+	kernelVersion, err := version.ParseGeneric(kernelRelease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the kernel release %q: %w", kernelRelease, err)
+	}
+
+	for _, v := range kernelFuseProtocolMinorVersions {
+		if kernelVersion.AtLeast(version.MustParseGeneric(v.kernelRelease)) {
+			return v.minorVersion, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// detectFuseProtocolMinorVersion returns the FUSE protocol minor version set by the fuseProtocolVersion,
+// or detects the version from the node kernel release. The latest version is assumed if the detection fails.
+func detectFuseProtocolMinorVersion(fuseProtocolVersion string) (int, error) {
+	if fuseProtocolVersion != "" {
+		return fuseProtocolMinorVersion(fuseProtocolVersion, "")
+	}
+
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		klog.Warningf("failed to get the kernel release, assuming the latest FUSE protocol version: %v", err)
+
+		return fuseLatestProtocolMinorVersion, nil
+	}
+
+	kernelRelease := unix.ByteSliceToString(uts.Release[:])
+	minorVersion, err := fuseProtocolMinorVersion("", kernelRelease)
+	if err != nil {
+		klog.Warningf("assuming the latest FUSE protocol version: %v", err)
+
+		return fuseLatestProtocolMinorVersion, nil
+	}
+	klog.Infof("detected FUSE protocol version %v.%v from the kernel release %q", fuseProtocolMajorVersion, minorVersion, kernelRelease)
+
+	return minorVersion, nil
+}
+
+// filterFuseOptions drops the sidecar mount options the FUSE protocol version does not support.
+func filterFuseOptions(options []string, minorVersion int) []string {
+	filtered := []string{}
+	for _, o := range options {
+		name := o
+		if i := strings.Index(o, "="); i >= 0 {
+			name = o[:i]
+		} else if i := strings.LastIndex(o, ":"); i >= 0 {
+			name = o[:i]
+		}
+
+		if required, ok := fuseOptionMinProtocolMinorVersions[name]; ok && minorVersion < required {
+			klog.Warningf("the mount option %q requires FUSE protocol version %v.%v, which the node does not support, discarding the option", o, fuseProtocolMajorVersion, required)
+
+			continue
+		}
+
+		filtered = append(filtered, o)
+	}
+
+	return filtered
+}
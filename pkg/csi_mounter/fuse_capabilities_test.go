@@ -0,0 +1,139 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csimounter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuseProtocolMinorVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                 string
+		fuseProtocolVersion  string
+		kernelRelease        string
+		expectedMinorVersion int
+		expectErr            bool
+	}{
+		{
+			name:                 "should detect FUSE_MAX_PAGES on a COS kernel",
+			kernelRelease:        "6.1.100+",
+			expectedMinorVersion: 28,
+		},
+		{
+			name:                 "should detect parallel dirops on an Ubuntu kernel",
+			kernelRelease:        "4.15.0-1057-gke",
+			expectedMinorVersion: 25,
+		},
+		{
+			name:                 "should detect max_background on an old kernel",
+			kernelRelease:        "4.4.0",
+			expectedMinorVersion: 13,
+		},
+		{
+			name:                 "should not detect any capability on a kernel older than the table",
+			kernelRelease:        "2.6.18",
+			expectedMinorVersion: 0,
+		},
+		{
+			name:                 "should prefer the FUSE protocol version flag",
+			fuseProtocolVersion:  "7.23",
+			kernelRelease:        "6.1.100+",
+			expectedMinorVersion: 23,
+		},
+		{
+			name:                "invalid FUSE protocol major version",
+			fuseProtocolVersion: "8.1",
+			expectErr:           true,
+		},
+		{
+			name:                "invalid FUSE protocol minor version",
+			fuseProtocolVersion: "7.x",
+			expectErr:           true,
+		},
+		{
+			name:          "invalid kernel release",
+			kernelRelease: "unknown",
+			expectErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			minorVersion, err := fuseProtocolMinorVersion(tc.fuseProtocolVersion, tc.kernelRelease)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error %v", err, tc.expectErr)
+			}
+			if minorVersion != tc.expectedMinorVersion {
+				t.Errorf("got minor version %v, expected %v", minorVersion, tc.expectedMinorVersion)
+			}
+		})
+	}
+}
+
+func TestFilterFuseOptions(t *testing.T) {
+	t.Parallel()
+
+	options := []string{"implicit-dirs", "max_background=64", "congestion_threshold=48", "file-system:enable-parallel-dirops:true"}
+
+	testCases := []struct {
+		name            string
+		minorVersion    int
+		expectedOptions []string
+	}{
+		{
+			name:            "should keep all the options on the latest kernel",
+			minorVersion:    fuseLatestProtocolMinorVersion,
+			expectedOptions: options,
+		},
+		{
+			name:            "should keep all the options with FUSE_MAX_PAGES",
+			minorVersion:    28,
+			expectedOptions: options,
+		},
+		{
+			name:            "should keep all the options with FUSE_PARALLEL_DIROPS",
+			minorVersion:    25,
+			expectedOptions: options,
+		},
+		{
+			name:            "should discard parallel dirops without FUSE_PARALLEL_DIROPS",
+			minorVersion:    13,
+			expectedOptions: []string{"implicit-dirs", "max_background=64", "congestion_threshold=48"},
+		},
+		{
+			name:            "should discard the background options on an old kernel",
+			minorVersion:    12,
+			expectedOptions: []string{"implicit-dirs"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := filterFuseOptions(options, tc.minorVersion); !reflect.DeepEqual(got, tc.expectedOptions) {
+				t.Errorf("got options %v, expected %v", got, tc.expectedOptions)
+			}
+		})
+	}
+}
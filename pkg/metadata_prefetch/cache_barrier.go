@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadataprefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// CacheBarrierPollInterval is the interval between two reads of a missing or unreadable cache barrier file.
+const CacheBarrierPollInterval = 5 * time.Second
+
+// waitForCacheBarrier reads the cache barrier file, at the given path relative to the objects dir, e.g. my-volume/models/model.bin,
+// until the whole file is read. gcsfuse serves a sequential read of a file from its file cache once the object is downloaded up to
+// the read offset, so the file is cached when the read completes.
+// A missing or failed read is retried every interval, e.g. the object is uploaded after the Pod starts.
+func waitForCacheBarrier(ctx context.Context, objectsDir, filePath string, interval time.Duration, fetch objectFetcher) error {
+	cleanPath := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	if !strings.Contains(cleanPath, "/") || slices.Contains(strings.Split(filePath, "/"), "..") {
+		return fmt.Errorf("invalid cache barrier file %q, must be a file path under a volume dir", filePath)
+	}
+
+	klog.Infof("waiting for the cache barrier file %q to be cached", cleanPath)
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		if _, err := fetch(ctx, cleanPath); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				klog.Warningf("failed to read the cache barrier file %q: %v", cleanPath, err)
+			}
+
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("stopped waiting for the cache barrier file %q: %w", cleanPath, err)
+	}
+
+	klog.Infof("the cache barrier file %q is cached", cleanPath)
+
+	return nil
+}
+
+// WaitForCacheBarriers waits for all the cache barrier files to be cached, reading them through the volumes under the objects dir.
+// It returns when all the files are cached, or the context is done.
+func WaitForCacheBarriers(ctx context.Context, objectsDir string, filePaths []string, interval time.Duration) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(filePaths))
+	for i, filePath := range filePaths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = waitForCacheBarrier(ctx, objectsDir, filePath, interval, volumeObjectFetcher(objectsDir))
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadataprefetch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitForCacheBarrier(t *testing.T) {
+	t.Parallel()
+
+	objectsDir := t.TempDir()
+	modelsDir := filepath.Join(objectsDir, "my-volume", "models")
+	if err := os.MkdirAll(modelsDir, 0o755); err != nil {
+		t.Fatalf("failed to create the volume dir: %v", err)
+	}
+
+	reads := make(chan string, 100)
+	fetcher := volumeObjectFetcher(objectsDir)
+	fetch := func(ctx context.Context, objectPath string) (int64, error) {
+		reads <- objectPath
+
+		return fetcher(ctx, objectPath)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- waitForCacheBarrier(context.Background(), objectsDir, "/my-volume//models/model.bin", 10*time.Millisecond, fetch)
+	}()
+
+	// The missing file is read again until the object is uploaded.
+	for range 2 {
+		if got := <-reads; got != "my-volume/models/model.bin" {
+			t.Errorf("got read of %q, expected %q", got, "my-volume/models/model.bin")
+		}
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("the barrier was released before the file exists: %v", err)
+	default:
+	}
+
+	if err := os.WriteFile(filepath.Join(modelsDir, "model.bin"), make([]byte, 16), 0o600); err != nil {
+		t.Fatalf("failed to create the barrier file: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the barrier was not released after the file is read")
+	}
+}
+
+func TestWaitForCacheBarrierInvalidPath(t *testing.T) {
+	t.Parallel()
+
+	for _, filePath := range []string{"", "model.bin", "my-volume/../model.bin", "../my-volume/model.bin"} {
+		if err := waitForCacheBarrier(context.Background(), t.TempDir(), filePath, 10*time.Millisecond, volumeObjectFetcher(t.TempDir())); err == nil {
+			t.Errorf("expected an error for the cache barrier file %q, got nil", filePath)
+		}
+	}
+}
+
+func TestWaitForCacheBarriersCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := WaitForCacheBarriers(ctx, t.TempDir(), []string{"my-volume/model.bin"}, 10*time.Millisecond); err == nil {
+		t.Errorf("expected an error after the context is done, got nil")
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadataprefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ObjectsPath is the dir of the metadata prefetch sidecar container where the volumes prefetching objects are mounted,
+	// e.g. /objects/my-volume.
+	ObjectsPath = "/objects/"
+	// ManifestsPath is the dir of the metadata prefetch sidecar container where the prefetch manifest ConfigMaps are mounted,
+	// e.g. /manifests/my-volume.
+	ManifestsPath = "/manifests/"
+
+	// prefetchProgressInterval is the number of objects between two prefetch progress logs.
+	prefetchProgressInterval = 100
+)
+
+// objectFetcher reads the object at the given path relative to the volume, and returns the number of bytes read.
+type objectFetcher func(ctx context.Context, objectPath string) (int64, error)
+
+// parsePrefetchManifest returns the object paths listed in the manifest files, in the order of the sorted file names.
+// Each file lists one object path per line, the empty lines and the lines starting with # are ignored.
+// The paths escaping the volume root are skipped.
+func parsePrefetchManifest(manifestDir string, files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	objects := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, line := range strings.Split(files[name], "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			objectPath := strings.TrimPrefix(path.Clean("/"+line), "/")
+			if objectPath == "" || slices.Contains(strings.Split(line, "/"), "..") {
+				klog.Warningf("skipping the prefetch manifest entry %q in %q, the object path must be relative to the bucket root", line, filepath.Join(manifestDir, name))
+
+				continue
+			}
+
+			if seen[objectPath] {
+				continue
+			}
+			seen[objectPath] = true
+			objects = append(objects, objectPath)
+		}
+	}
+
+	return objects
+}
+
+// readPrefetchManifest reads the files of the ConfigMap mounted at the manifest dir.
+// The hidden entries kubelet uses to update the ConfigMap atomically, e.g. ..data, are skipped.
+func readPrefetchManifest(manifestDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(manifestDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the prefetch manifest dir %q: %w", manifestDir, err)
+	}
+
+	files := map[string]string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(manifestDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the prefetch manifest file %q: %w", entry.Name(), err)
+		}
+		files[entry.Name()] = string(data)
+	}
+
+	return files, nil
+}
+
+// prefetchObjects reads the objects through the gcsfuse mount to warm the gcsfuse cache,
+// logging the progress every prefetchProgressInterval objects. The missing objects are skipped.
+// It stops when the context is done. Returns the number of the fetched and the missing objects.
+func prefetchObjects(ctx context.Context, volumeDir string, objects []string, fetch objectFetcher) (int, int) {
+	klog.Infof("start to prefetch %v objects for %q", len(objects), volumeDir)
+
+	var fetched, missing int
+	var fetchedBytes int64
+	for i, objectPath := range objects {
+		if ctx.Err() != nil {
+			klog.Warningf("the prefetch for %q is canceled after %v/%v objects", volumeDir, i, len(objects))
+
+			return fetched, missing
+		}
+
+		n, err := fetch(ctx, objectPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			klog.Warningf("skipping the prefetch of object %q for %q, the object does not exist", objectPath, volumeDir)
+			missing++
+		case err != nil:
+			klog.Errorf("failed to prefetch object %q for %q: %v", objectPath, volumeDir, err)
+		default:
+			fetched++
+			fetchedBytes += n
+		}
+
+		if (i+1)%prefetchProgressInterval == 0 && i+1 < len(objects) {
+			klog.Infof("prefetched %v/%v objects (%v bytes) for %q", i+1, len(objects), fetchedBytes, volumeDir)
+		}
+	}
+
+	klog.Infof("prefetch completed for %q: %v fetched (%v bytes), %v missing, %v failed", volumeDir, fetched, fetchedBytes, missing, len(objects)-fetched-missing)
+
+	return fetched, missing
+}
+
+// contextReader stops the read when the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
+
+// volumeObjectFetcher returns an objectFetcher reading the objects through the volume mounted at the volume dir.
+func volumeObjectFetcher(volumeDir string) objectFetcher {
+	return func(ctx context.Context, objectPath string) (int64, error) {
+		f, err := os.Open(filepath.Join(volumeDir, objectPath))
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+
+		n, err := io.Copy(io.Discard, &contextReader{ctx: ctx, r: f})
+		if err != nil {
+			return n, fmt.Errorf("failed to read object: %w", err)
+		}
+
+		return n, nil
+	}
+}
+
+// PrefetchObjects prefetches the objects listed in the prefetch manifest of each volume under the manifests dir,
+// reading them through the volume of the same name under the objects dir. The volumes are prefetched in parallel.
+// It returns when all the prefetches complete, or the context is done.
+func PrefetchObjects(ctx context.Context, objectsDir, manifestsDir string) {
+	entries, err := os.ReadDir(manifestsDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			klog.Errorf("failed to read the prefetch manifests dir %q: %v", manifestsDir, err)
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		manifestDir := filepath.Join(manifestsDir, entry.Name())
+		volumeDir := filepath.Join(objectsDir, entry.Name())
+
+		files, err := readPrefetchManifest(manifestDir)
+		if err != nil {
+			klog.Errorf("skipping the prefetch for %q: %v", volumeDir, err)
+
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prefetchObjects(ctx, volumeDir, parsePrefetchManifest(manifestDir, files), volumeObjectFetcher(volumeDir))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadataprefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePrefetchManifest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		files           map[string]string
+		expectedObjects []string
+	}{
+		{
+			name:            "empty ConfigMap",
+			expectedObjects: []string{},
+		},
+		{
+			name: "should list the objects in the sorted file name order",
+			files: map[string]string{
+				"models":   "models/a.bin\n\n# tokenizer\n  models/tokenizer.json  \n",
+				"datasets": "/datasets/train/part-0\ndatasets/train/part-1",
+			},
+			expectedObjects: []string{"datasets/train/part-0", "datasets/train/part-1", "models/a.bin", "models/tokenizer.json"},
+		},
+		{
+			name: "should skip the duplicated objects",
+			files: map[string]string{
+				"a": "models/a.bin\n./models/a.bin",
+				"b": "models//a.bin",
+			},
+			expectedObjects: []string{"models/a.bin"},
+		},
+		{
+			name: "should skip the paths escaping the volume root",
+			files: map[string]string{
+				"a": "../etc/passwd\nmodels/../../secret\n/\nmodels/a.bin",
+			},
+			expectedObjects: []string{"models/a.bin"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.expectedObjects, parsePrefetchManifest("/manifests/my-volume", tc.files)); diff != "" {
+				t.Errorf("unexpected objects (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadPrefetchManifest(t *testing.T) {
+	t.Parallel()
+
+	// kubelet projects the ConfigMap keys as symlinks to the files in a hidden timestamped dir.
+	manifestDir := t.TempDir()
+	dataDir := filepath.Join(manifestDir, "..2024_01_01_00_00_00.000000000")
+	if err := os.Mkdir(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create the data dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "models"), []byte("models/a.bin\n"), 0o600); err != nil {
+		t.Fatalf("failed to create the manifest file: %v", err)
+	}
+	if err := os.Symlink(filepath.Base(dataDir), filepath.Join(manifestDir, "..data")); err != nil {
+		t.Fatalf("failed to create the data symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "models"), filepath.Join(manifestDir, "models")); err != nil {
+		t.Fatalf("failed to create the key symlink: %v", err)
+	}
+
+	files, err := readPrefetchManifest(manifestDir)
+	if err != nil {
+		t.Fatalf("failed to read the prefetch manifest: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"models": "models/a.bin\n"}, files); diff != "" {
+		t.Errorf("unexpected manifest files (-want, +got)\n%s", diff)
+	}
+}
+
+func TestPrefetchObjects(t *testing.T) {
+	t.Parallel()
+
+	objects := []string{"models/a.bin", "models/missing.bin", "models/broken.bin", "models/b.bin"}
+
+	fetchedObjects := []string{}
+	fetch := func(_ context.Context, objectPath string) (int64, error) {
+		fetchedObjects = append(fetchedObjects, objectPath)
+		switch objectPath {
+		case "models/missing.bin":
+			return 0, fmt.Errorf("failed to open: %w", fs.ErrNotExist)
+		case "models/broken.bin":
+			return 0, errors.New("input/output error")
+		default:
+			return 8, nil
+		}
+	}
+
+	fetched, missing := prefetchObjects(context.Background(), "/objects/my-volume", objects, fetch)
+	if fetched != 2 {
+		t.Errorf("Got %v fetched objects, but expected 2", fetched)
+	}
+	if missing != 1 {
+		t.Errorf("Got %v missing objects, but expected 1", missing)
+	}
+	if diff := cmp.Diff(objects, fetchedObjects); diff != "" {
+		t.Errorf("unexpected fetched objects (-want, +got)\n%s", diff)
+	}
+}
+
+func TestPrefetchObjectsStopsWhenCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fetchedObjects := []string{}
+	fetch := func(_ context.Context, objectPath string) (int64, error) {
+		fetchedObjects = append(fetchedObjects, objectPath)
+		cancel()
+
+		return 8, nil
+	}
+
+	prefetchObjects(ctx, "/objects/my-volume", []string{"models/a.bin", "models/b.bin"}, fetch)
+	if diff := cmp.Diff([]string{"models/a.bin"}, fetchedObjects); diff != "" {
+		t.Errorf("unexpected fetched objects (-want, +got)\n%s", diff)
+	}
+}
+
+func TestVolumeObjectFetcher(t *testing.T) {
+	t.Parallel()
+
+	volumeDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(volumeDir, "models"), 0o755); err != nil {
+		t.Fatalf("failed to create the object dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(volumeDir, "models", "a.bin"), make([]byte, 8), 0o600); err != nil {
+		t.Fatalf("failed to create the object: %v", err)
+	}
+
+	fetch := volumeObjectFetcher(volumeDir)
+
+	n, err := fetch(context.Background(), "models/a.bin")
+	if err != nil || n != 8 {
+		t.Errorf("Got %v bytes and error %v, but expected 8 bytes and no error", n, err)
+	}
+
+	if _, err := fetch(context.Background(), "models/missing.bin"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Got error %v, but expected a not exist error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetch(ctx, "models/a.bin"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Got error %v, but expected a canceled error", err)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// CacheDebugPath is the path of the debug endpoint listing the file cache of the volumes.
+const CacheDebugPath = "/debug/cache"
+
+// cachedObject is a file in the gcsfuse file cache of a volume.
+type cachedObject struct {
+	// Path is the path of the cache file relative to the volume cache dir.
+	Path           string    `json:"path"`
+	SizeBytes      uint64    `json:"sizeBytes"`
+	LastAccessTime time.Time `json:"lastAccessTime"`
+}
+
+// volumeCacheState is the file cache state of a volume.
+type volumeCacheState struct {
+	VolumeName     string         `json:"volumeName"`
+	TotalSizeBytes uint64         `json:"totalSizeBytes"`
+	Objects        []cachedObject `json:"objects"`
+}
+
+// listVolumeCacheStates returns the file cache state of the volumes under the cache root, sorted by the volume name.
+// The objects of each volume are sorted from the most recently accessed.
+// When volumeName is not empty, only the state of the volume is returned.
+func listVolumeCacheStates(cacheRoot, volumeName string) ([]volumeCacheState, error) {
+	entries, err := os.ReadDir(cacheRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []volumeCacheState{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to list the volume cache dirs: %w", err)
+	}
+
+	states := []volumeCacheState{}
+	for _, e := range entries {
+		if !e.IsDir() || (volumeName != "" && e.Name() != volumeName) {
+			continue
+		}
+
+		volumeCacheDir := filepath.Join(cacheRoot, e.Name())
+		files, err := listCacheFiles(volumeCacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("volume %q: %w", e.Name(), err)
+		}
+
+		state := volumeCacheState{VolumeName: e.Name(), Objects: make([]cachedObject, 0, len(files))}
+		for i := len(files) - 1; i >= 0; i-- {
+			rel, err := filepath.Rel(volumeCacheDir, files[i].path)
+			if err != nil {
+				rel = files[i].path
+			}
+			state.Objects = append(state.Objects, cachedObject{Path: rel, SizeBytes: files[i].size, LastAccessTime: files[i].accessTime.UTC()})
+			state.TotalSizeBytes += files[i].size
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].VolumeName < states[j].VolumeName
+	})
+
+	return states, nil
+}
+
+// cacheDebugHandler serves the file cache state of the volumes under the cache root as JSON,
+// the optional "volume" query parameter selects a single volume.
+func cacheDebugHandler(cacheRoot string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		states, err := listVolumeCacheStates(cacheRoot, r.URL.Query().Get("volume"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string][]volumeCacheState{"volumes": states}); err != nil {
+			klog.Errorf("failed to write the cache debug response: %v", err)
+		}
+	}
+}
+
+// ServeCacheDebug serves the debug endpoint listing the file cache of the volumes under the cache root
+// on the loopback port until the context is cancelled.
+// The endpoint is only reachable from the containers of the Pod, or via kubectl port-forward.
+func ServeCacheDebug(ctx context.Context, port int, cacheRoot string) {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	mux := http.NewServeMux()
+	mux.HandleFunc(CacheDebugPath, cacheDebugHandler(cacheRoot))
+
+	server := http.Server{
+		Addr:         address,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := server.Close(); err != nil {
+			klog.Errorf("failed to close the cache debug server: %v", err)
+		}
+	}()
+
+	klog.Infof("serving the file cache debug endpoint on http://%v%v", address, CacheDebugPath)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		klog.Errorf("failed to serve the cache debug endpoint on %q: %v", address, err)
+	}
+}
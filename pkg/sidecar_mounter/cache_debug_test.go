@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCacheDebugHandler(t *testing.T) {
+	t.Parallel()
+
+	cacheRoot := t.TempDir()
+	accessTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, f := range []struct {
+		path string
+		size int
+	}{
+		{"volume-b/gcsfuse-file-cache/bucket-b/dir/object", 16},
+		{"volume-a/gcsfuse-file-cache/bucket-a/object-1", 8},
+		{"volume-a/gcsfuse-file-cache/bucket-a/object-2", 4},
+	} {
+		path := filepath.Join(cacheRoot, f.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create the cache dir: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, f.size), 0o600); err != nil {
+			t.Fatalf("failed to create the cache file: %v", err)
+		}
+		if err := os.Chtimes(path, accessTime, accessTime); err != nil {
+			t.Fatalf("failed to set the cache file times: %v", err)
+		}
+		accessTime = accessTime.Add(time.Hour)
+	}
+	if err := os.MkdirAll(filepath.Join(cacheRoot, "volume-c"), 0o755); err != nil {
+		t.Fatalf("failed to create the cache dir: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		query            string
+		expectedResponse map[string]any
+	}{
+		{
+			name: "should list the cached objects of all the volumes",
+			expectedResponse: map[string]any{"volumes": []any{
+				map[string]any{
+					"volumeName":     "volume-a",
+					"totalSizeBytes": float64(12),
+					"objects": []any{
+						map[string]any{"path": "gcsfuse-file-cache/bucket-a/object-2", "sizeBytes": float64(4), "lastAccessTime": "2024-01-02T05:04:05Z"},
+						map[string]any{"path": "gcsfuse-file-cache/bucket-a/object-1", "sizeBytes": float64(8), "lastAccessTime": "2024-01-02T04:04:05Z"},
+					},
+				},
+				map[string]any{
+					"volumeName":     "volume-b",
+					"totalSizeBytes": float64(16),
+					"objects": []any{
+						map[string]any{"path": "gcsfuse-file-cache/bucket-b/dir/object", "sizeBytes": float64(16), "lastAccessTime": "2024-01-02T03:04:05Z"},
+					},
+				},
+				map[string]any{
+					"volumeName":     "volume-c",
+					"totalSizeBytes": float64(0),
+					"objects":        []any{},
+				},
+			}},
+		},
+		{
+			name:  "should list the cached objects of the selected volume",
+			query: "?volume=volume-b",
+			expectedResponse: map[string]any{"volumes": []any{
+				map[string]any{
+					"volumeName":     "volume-b",
+					"totalSizeBytes": float64(16),
+					"objects": []any{
+						map[string]any{"path": "gcsfuse-file-cache/bucket-b/dir/object", "sizeBytes": float64(16), "lastAccessTime": "2024-01-02T03:04:05Z"},
+					},
+				},
+			}},
+		},
+		{
+			name:             "should list no volume for an unknown volume",
+			query:            "?volume=unknown",
+			expectedResponse: map[string]any{"volumes": []any{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			cacheDebugHandler(cacheRoot)(w, httptest.NewRequest(http.MethodGet, CacheDebugPath+tc.query, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status code %v, expected %v: %v", w.Code, http.StatusOK, w.Body.String())
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("got content type %q, expected application/json", contentType)
+			}
+
+			var response map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode the response %q: %v", w.Body.String(), err)
+			}
+			if diff := cmp.Diff(tc.expectedResponse, response); diff != "" {
+				t.Errorf("unexpected response (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCacheDebugHandlerMethodNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	cacheDebugHandler(t.TempDir())(w, httptest.NewRequest(http.MethodPost, CacheDebugPath, nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status code %v, expected %v", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// freeSpaceFunc returns the available and total bytes of the file system the path is on.
+type freeSpaceFunc func(path string) (uint64, uint64, error)
+
+func statfsFreeSpace(path string) (uint64, uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	//nolint: gosec
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
+
+type cacheFile struct {
+	path       string
+	size       uint64
+	accessTime time.Time
+}
+
+// fileCacheFreeSpaceLimitMb returns the file cache size in MiB the volumes can use together, so that minFreePercent of the cache volume
+// mounted at cacheVolumePath stays free. The cache files already in the cache dir count as available space.
+// gcsfuse evicts its cache files in its own LRU order once the file cache reaches the size.
+func fileCacheFreeSpaceLimitMb(cacheVolumePath, cacheDir string, minFreePercent uint64, freeSpace freeSpaceFunc) (int64, error) {
+	free, total, err := freeSpace(cacheVolumePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the free space: %w", err)
+	}
+
+	files, err := listCacheFiles(cacheDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	for _, f := range files {
+		free += f.size
+	}
+
+	minFree := total / 100 * minFreePercent
+	if free <= minFree {
+		return 0, nil
+	}
+
+	//nolint: gosec
+	return int64((free - minFree) / 1024 / 1024), nil
+}
+
+// listCacheFiles returns the regular files in the cache dir, sorted from the least recently accessed.
+func listCacheFiles(cacheDir string) ([]cacheFile, error) {
+	files := []cacheFile{}
+	err := filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			// the file is removed by gcsfuse
+			return nil
+		}
+
+		accessTime := info.ModTime()
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			accessTime = time.Unix(st.Atim.Unix())
+		}
+		files = append(files, cacheFile{path: path, size: uint64(info.Size()), accessTime: accessTime})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the cache files: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].accessTime.Before(files[j].accessTime)
+	})
+
+	return files, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoundFileCacheToFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	const mib = 1024 * 1024
+
+	testCases := []struct {
+		name                 string
+		maxSizeMb            string
+		cachedBytes          int
+		otherCachedBytes     int
+		cachedVolumes        int
+		freeBytes            uint64
+		expectedMaxSizeMb    string
+		expectedCacheEnabled bool
+	}{
+		{
+			name:                 "should bound the unlimited file cache to the free space above the min free percent",
+			maxSizeMb:            "-1",
+			freeBytes:            50 * mib,
+			expectedMaxSizeMb:    "30",
+			expectedCacheEnabled: true,
+		},
+		{
+			name:                 "should keep the file cache max size under the free space",
+			maxSizeMb:            "10",
+			freeBytes:            50 * mib,
+			expectedMaxSizeMb:    "10",
+			expectedCacheEnabled: true,
+		},
+		{
+			name:                 "should count the cache files of the volume as free space",
+			maxSizeMb:            "-1",
+			cachedBytes:          4 * mib,
+			freeBytes:            50 * mib,
+			expectedMaxSizeMb:    "34",
+			expectedCacheEnabled: true,
+		},
+		{
+			name:                 "should split the free space between the cached volumes",
+			maxSizeMb:            "-1",
+			cachedVolumes:        3,
+			freeBytes:            50 * mib,
+			expectedMaxSizeMb:    "10",
+			expectedCacheEnabled: true,
+		},
+		{
+			name:                 "should count the cache files of the other volumes as free space",
+			maxSizeMb:            "-1",
+			otherCachedBytes:     6 * mib,
+			cachedVolumes:        2,
+			freeBytes:            50 * mib,
+			expectedMaxSizeMb:    "18",
+			expectedCacheEnabled: true,
+		},
+		{
+			name:              "should disable the file cache when the free space is below the min free percent",
+			maxSizeMb:         "-1",
+			freeBytes:         10 * mib,
+			expectedMaxSizeMb: "0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cacheRoot := t.TempDir()
+			cacheDir := filepath.Join(cacheRoot, "test-volume")
+			for volumeDir, cachedBytes := range map[string]int{cacheDir: tc.cachedBytes, filepath.Join(cacheRoot, "other-volume"): tc.otherCachedBytes} {
+				if cachedBytes == 0 {
+					continue
+				}
+				fileDir := filepath.Join(volumeDir, "gcsfuse-file-cache", "test-bucket")
+				if err := os.MkdirAll(fileDir, 0o755); err != nil {
+					t.Fatalf("failed to create the cache dir: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(fileDir, "object"), make([]byte, cachedBytes), 0o600); err != nil {
+					t.Fatalf("failed to create the cache file: %v", err)
+				}
+			}
+
+			mc := &MountConfig{
+				VolumeName:              "test-volume",
+				FileCacheMinFreePercent: 20,
+				ConfigFileFlagMap: map[string]string{
+					"cache-dir":              cacheDir,
+					"file-cache:max-size-mb": tc.maxSizeMb,
+				},
+			}
+
+			// the cache volume has 100 MiB, and the min free space is 20 MiB.
+			freeSpace := func(string) (uint64, uint64, error) {
+				return tc.freeBytes, 100 * mib, nil
+			}
+
+			if err := mc.boundFileCacheToFreeSpace("/gcsfuse-cache", tc.cachedVolumes, freeSpace); err != nil {
+				t.Fatalf("failed to bound the file cache: %v", err)
+			}
+			if got := mc.ConfigFileFlagMap["file-cache:max-size-mb"]; got != tc.expectedMaxSizeMb {
+				t.Errorf("Got file cache max size %q, but expected %q", got, tc.expectedMaxSizeMb)
+			}
+			if got := mc.ConfigFileFlagMap["cache-dir"] != ""; got != tc.expectedCacheEnabled {
+				t.Errorf("Got file cache enabled %v, but expected %v", got, tc.expectedCacheEnabled)
+			}
+		})
+	}
+}
+
+func TestBoundFileCacheToFreeSpaceWithoutMinFreePercent(t *testing.T) {
+	t.Parallel()
+
+	mc := &MountConfig{
+		ConfigFileFlagMap: map[string]string{
+			"cache-dir":              "test-cache-dir",
+			"file-cache:max-size-mb": "-1",
+		},
+	}
+
+	freeSpace := func(string) (uint64, uint64, error) {
+		t.Errorf("the free space should not be checked without the min free percent")
+
+		return 0, 0, nil
+	}
+
+	if err := mc.boundFileCacheToFreeSpace("/gcsfuse-cache", 1, freeSpace); err != nil {
+		t.Fatalf("failed to bound the file cache: %v", err)
+	}
+	if got := mc.ConfigFileFlagMap["file-cache:max-size-mb"]; got != "-1" {
+		t.Errorf("Got file cache max size %q, but expected %q", got, "-1")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DiagnosticsFileName is the name of the diagnostic bundle written to the volume temp dir when gcsfuse fails.
+	DiagnosticsFileName = "diagnostics"
+
+	diagnosticsLogTailBytes        = 64 * 1024
+	diagnosticsNetworkCheckTimeout = 5 * time.Second
+	gcsEndpointAddress             = "storage.googleapis.com:443"
+)
+
+// sensitiveEnvKeywords are the env var name keywords whose values are redacted in the diagnostic bundle.
+var sensitiveEnvKeywords = []string{"TOKEN", "SECRET", "PASSWORD", "KEY", "CREDENTIAL"}
+
+// logTail is an io.Writer keeping the last bytes written to it.
+type logTail struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+func newLogTail(size int) *logTail {
+	return &logTail{size: size}
+}
+
+func (t *logTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.size {
+		t.buf = t.buf[len(t.buf)-t.size:]
+	}
+
+	return len(p), nil
+}
+
+func (t *logTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.buf)
+}
+
+// networkCheckFunc checks that the address is reachable.
+type networkCheckFunc func(ctx context.Context, address string) error
+
+func dialNetworkCheck(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// collectDiagnostics writes the diagnostic bundle of the failed gcsfuse process of the given version to the volume temp dir.
+func collectDiagnostics(mc *MountConfig, version string, mountErr error, logs *logTail) {
+	path := filepath.Join(mc.TempDir, DiagnosticsFileName)
+	if err := writeDiagnostics(path, mc, version, mountErr, logs.String(), os.Environ(), dialNetworkCheck); err != nil {
+		klog.Errorf("[%v] failed to write the diagnostic bundle to %q: %v", mc.VolumeName, path, err)
+
+		return
+	}
+
+	klog.Infof("[%v] the diagnostic bundle of the gcsfuse failure is written to %q", mc.VolumeName, path)
+}
+
+// writeDiagnostics writes a diagnostic bundle with the mount error, the gcsfuse version and flags,
+// the environment with the sensitive values redacted, the recent gcsfuse logs and a GCS endpoint network check.
+func writeDiagnostics(path string, mc *MountConfig, version string, mountErr error, logs string, env []string, networkCheck networkCheckFunc) error {
+	var b strings.Builder
+
+	writeSection := func(title, content string) {
+		fmt.Fprintf(&b, "=== %v ===\n%v\n\n", title, strings.TrimRight(content, "\n"))
+	}
+
+	if version == "" {
+		version = "unknown"
+	}
+
+	writeSection("mount error", fmt.Sprintf("bucket %q, volume %q: %v", mc.BucketName, mc.VolumeName, mountErr))
+	writeSection("gcsfuse version", version)
+	writeSection("gcsfuse flags", formatFlagMap(mc.FlagMap))
+	writeSection("gcsfuse config file flags", formatFlagMap(mc.ConfigFileFlagMap))
+	writeSection("environment", strings.Join(redactEnv(env), "\n"))
+	writeSection("recent logs", logs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsNetworkCheckTimeout)
+	defer cancel()
+	networkResult := fmt.Sprintf("%v: reachable", gcsEndpointAddress)
+	if err := networkCheck(ctx, gcsEndpointAddress); err != nil {
+		networkResult = fmt.Sprintf("%v: unreachable: %v", gcsEndpointAddress, err)
+	}
+	writeSection("network check", networkResult)
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// formatFlagMap returns the flags sorted by name, one per line.
+func formatFlagMap(flags map[string]string) string {
+	lines := make([]string, 0, len(flags))
+	for k, v := range flags {
+		lines = append(lines, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
+
+// redactEnv returns the sorted env vars with the values of the sensitive env vars redacted.
+func redactEnv(env []string) []string {
+	redacted := make([]string, 0, len(env))
+	for _, e := range env {
+		name, _, _ := strings.Cut(e, "=")
+		for _, keyword := range sensitiveEnvKeywords {
+			if strings.Contains(strings.ToUpper(name), keyword) {
+				e = name + "=***redacted***"
+
+				break
+			}
+		}
+		redacted = append(redacted, e)
+	}
+	sort.Strings(redacted)
+
+	return redacted
+}
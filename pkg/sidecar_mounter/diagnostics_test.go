@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		version          string
+		networkErr       error
+		expectedContains []string
+	}{
+		{
+			name:    "should write all the sections when the GCS endpoint is reachable",
+			version: "v2.11.1",
+			expectedContains: []string{
+				"=== gcsfuse version ===\nv2.11.1\n",
+				"=== network check ===\nstorage.googleapis.com:443: reachable\n",
+			},
+		},
+		{
+			name:       "should write the network check error and the unknown gcsfuse version",
+			networkErr: errors.New("connection refused"),
+			expectedContains: []string{
+				"=== gcsfuse version ===\nunknown\n",
+				"=== network check ===\nstorage.googleapis.com:443: unreachable: connection refused\n",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), DiagnosticsFileName)
+			mc := &MountConfig{
+				BucketName:        "test-bucket",
+				VolumeName:        "test-volume",
+				FlagMap:           map[string]string{"uid": "100", "app-name": "gke-gcs-fuse-csi"},
+				ConfigFileFlagMap: map[string]string{"logging:severity": "info"},
+			}
+			env := []string{"HOME=/root", "GOOGLE_APPLICATION_CREDENTIALS=/etc/key.json", "ACCESS_TOKEN=secret-token"}
+			networkCheck := func(context.Context, string) error {
+				return tc.networkErr
+			}
+
+			logs := newLogTail(len("mount failed\n"))
+			if _, err := logs.Write([]byte("dropped log line\nmount failed\n")); err != nil {
+				t.Fatalf("failed to write the logs: %v", err)
+			}
+
+			if err := writeDiagnostics(path, mc, tc.version, errors.New("exit status 1"), logs.String(), env, networkCheck); err != nil {
+				t.Fatalf("failed to write the diagnostics: %v", err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read the diagnostics: %v", err)
+			}
+
+			expectedContains := append([]string{
+				"=== mount error ===\nbucket \"test-bucket\", volume \"test-volume\": exit status 1\n",
+				"=== gcsfuse flags ===\napp-name=gke-gcs-fuse-csi\nuid=100\n",
+				"=== gcsfuse config file flags ===\nlogging:severity=info\n",
+				"=== environment ===\nACCESS_TOKEN=***redacted***\nGOOGLE_APPLICATION_CREDENTIALS=***redacted***\nHOME=/root\n",
+				"=== recent logs ===\nmount failed\n",
+			}, tc.expectedContains...)
+			for _, expected := range expectedContains {
+				if !strings.Contains(string(content), expected) {
+					t.Errorf("Expected the diagnostics to contain %q, got:\n%v", expected, string(content))
+				}
+			}
+
+			if strings.Contains(string(content), "secret-token") || strings.Contains(string(content), "dropped log line") {
+				t.Errorf("Expected the diagnostics to redact the secrets and drop the old logs, got:\n%v", string(content))
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/klog/v2"
+)
+
+const (
+	fileCacheReadCountMetric = "file_cache_read_count"
+	fileCacheHitsMetric      = "gcsfuse_file_cache_hits_total"
+	fileCacheMissesMetric    = "gcsfuse_file_cache_misses_total"
+	cacheHitLabel            = "cache_hit"
+)
+
+// fileCacheHitMissCounts returns the file cache hits and misses, summed over the read types of the gcsfuse file_cache_read_count metric.
+// It returns false if gcsfuse has not reported the metric, e.g. the file cache is disabled or no file has been read.
+func fileCacheHitMissCounts(families map[string]*dto.MetricFamily) (float64, float64, bool) {
+	mf, ok := families[fileCacheReadCountMetric]
+	if !ok {
+		return 0, 0, false
+	}
+
+	var hits, misses float64
+	for _, m := range mf.GetMetric() {
+		val := m.GetCounter().GetValue()
+		if mf.GetType() == dto.MetricType_UNTYPED {
+			val = m.GetUntyped().GetValue()
+		}
+
+		for _, label := range m.GetLabel() {
+			if label.GetName() != cacheHitLabel {
+				continue
+			}
+
+			if label.GetValue() == "true" {
+				hits += val
+			} else {
+				misses += val
+			}
+		}
+	}
+
+	return hits, misses, true
+}
+
+// writeFileCacheHitMissMetrics writes the gcsfuse metrics followed by the file cache hit and miss counters derived from them,
+// the CSI driver adds the volume labels when it collects the metrics.
+func writeFileCacheHitMissMetrics(w io.Writer, gcsfuseMetrics []byte) error {
+	if _, err := w.Write(gcsfuseMetrics); err != nil {
+		return err
+	}
+
+	families, err := metrics.ProcessMetricsData(bytes.NewReader(gcsfuseMetrics))
+	if err != nil {
+		// The gcsfuse metrics are still served as is.
+		klog.Warningf("failed to derive the file cache hit and miss counters: %v", err)
+
+		return nil
+	}
+
+	hits, misses, ok := fileCacheHitMissCounts(families)
+	if !ok {
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "# HELP %v The number of file reads served from the file cache.\n# TYPE %v counter\n%v %v\n"+
+		"# HELP %v The number of file reads not served from the file cache.\n# TYPE %v counter\n%v %v\n",
+		fileCacheHitsMetric, fileCacheHitsMetric, fileCacheHitsMetric, hits,
+		fileCacheMissesMetric, fileCacheMissesMetric, fileCacheMissesMetric, misses)
+
+	return err
+}
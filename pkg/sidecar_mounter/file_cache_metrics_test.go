@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/metrics"
+)
+
+func gcsfuseFileCacheMetrics(sequentialHits, randomHits, misses int) string {
+	return fmt.Sprintf(`# HELP file_cache_read_count Specifies the number of read requests made via file cache.
+# TYPE file_cache_read_count counter
+file_cache_read_count{cache_hit="true",read_type="Sequential"} %v
+file_cache_read_count{cache_hit="true",read_type="Random"} %v
+file_cache_read_count{cache_hit="false",read_type="Sequential"} %v
+`, sequentialHits, randomHits, misses)
+}
+
+func TestWriteFileCacheHitMissMetrics(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		gcsfuseMetrics string
+		expectedHits   float64
+		expectedMisses float64
+		expectedFound  bool
+		invalid        bool
+	}{
+		{
+			name:           "should not emit the counters before any file cache read",
+			gcsfuseMetrics: "# TYPE fs_ops_count counter\nfs_ops_count{fs_op=\"LookUpInode\"} 3\n",
+		},
+		{
+			name:           "should count the first read of a file as a miss",
+			gcsfuseMetrics: gcsfuseFileCacheMetrics(0, 0, 1),
+			expectedMisses: 1,
+			expectedFound:  true,
+		},
+		{
+			name:           "should count the hits of all the read types",
+			gcsfuseMetrics: gcsfuseFileCacheMetrics(5, 2, 1),
+			expectedHits:   7,
+			expectedMisses: 1,
+			expectedFound:  true,
+		},
+		{
+			name:           "should serve the gcsfuse metrics when they cannot be parsed",
+			gcsfuseMetrics: "invalid metrics{\n",
+			invalid:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			if err := writeFileCacheHitMissMetrics(&w, []byte(tc.gcsfuseMetrics)); err != nil {
+				t.Fatalf("failed to write the metrics: %v", err)
+			}
+
+			if !strings.HasPrefix(w.String(), tc.gcsfuseMetrics) {
+				t.Errorf("Expected the output to start with the gcsfuse metrics, got:\n%v", w.String())
+			}
+
+			if tc.invalid {
+				return
+			}
+
+			families, err := metrics.ProcessMetricsData(&w)
+			if err != nil {
+				t.Fatalf("failed to parse the output: %v", err)
+			}
+
+			for metric, expected := range map[string]float64{fileCacheHitsMetric: tc.expectedHits, fileCacheMissesMetric: tc.expectedMisses} {
+				mf, ok := families[metric]
+				if ok != tc.expectedFound {
+					t.Fatalf("Got metric %v present %v, but expected %v", metric, ok, tc.expectedFound)
+				}
+				if !ok {
+					continue
+				}
+
+				if got := mf.GetMetric()[0].GetCounter().GetValue(); got != expected {
+					t.Errorf("Got metric %v value %v, but expected %v", metric, got, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// DefaultGCSFuseBinaryPathAllowlist is the dir in the sidecar container image holding the alternate gcsfuse binaries.
+const DefaultGCSFuseBinaryPathAllowlist = "/gcsfuse-binaries"
+
+// gcsfuseBinary returns the path and the features of the gcsfuse binary launched for the volume,
+// which is the alternate binary selected by the gcsfuseBinaryPath volume attribute, or the default binary.
+func (m *Mounter) gcsfuseBinary(mc *MountConfig) (string, *gcsfuseFeatures, error) {
+	if mc.GCSFuseBinaryPath == "" {
+		return m.mounterPath, m.features, nil
+	}
+
+	if err := validateGCSFuseBinaryPath(mc.GCSFuseBinaryPath, m.BinaryPathAllowlist); err != nil {
+		return "", nil, err
+	}
+
+	klog.Infof("[%v] using the alternate gcsfuse binary %q", mc.VolumeName, mc.GCSFuseBinaryPath)
+
+	return mc.GCSFuseBinaryPath, detectGCSFuseFeatures(&binaryVersionReporter{mounterPath: mc.GCSFuseBinaryPath}), nil
+}
+
+// validateGCSFuseBinaryPath returns an error unless the path, with the symlinks resolved,
+// is an executable regular file under one of the allowlisted dirs.
+func validateGCSFuseBinaryPath(path string, allowlist []string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("invalid gcsfuse binary path %q: %w", path, err)
+	}
+
+	allowed := false
+	for _, dir := range allowlist {
+		if resolvedDir, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolvedDir
+		}
+		if dir = filepath.Clean(dir); filepath.IsAbs(dir) && strings.HasPrefix(resolved, dir+string(filepath.Separator)) {
+			allowed = true
+
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("gcsfuse binary path %q is not under the allowlisted dirs %v", path, allowlist)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return fmt.Errorf("invalid gcsfuse binary path %q: %w", path, err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("gcsfuse binary path %q is not an executable file", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createFakeGCSFuseBinaries creates an allowlisted dir with the fake gcsfuse binaries, and a dir outside the allowlist.
+func createFakeGCSFuseBinaries(t *testing.T) (string, string) {
+	t.Helper()
+
+	allowedDir := t.TempDir()
+	otherDir := t.TempDir()
+	script := []byte("#!/bin/sh\necho 'gcsfuse version 3.1.0 (Go version go1.24.1)'\n")
+	for path, mode := range map[string]os.FileMode{
+		filepath.Join(allowedDir, "gcsfuse-v3"):        0o755,
+		filepath.Join(allowedDir, "gcsfuse-not-exec"):  0o644,
+		filepath.Join(otherDir, "gcsfuse-not-allowed"): 0o755,
+	} {
+		if err := os.WriteFile(path, script, mode); err != nil {
+			t.Fatalf("failed to create the fake gcsfuse binary: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(allowedDir, "dir"), 0o755); err != nil {
+		t.Fatalf("failed to create the dir: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(otherDir, "gcsfuse-not-allowed"), filepath.Join(allowedDir, "gcsfuse-link")); err != nil {
+		t.Fatalf("failed to create the symlink: %v", err)
+	}
+
+	return allowedDir, otherDir
+}
+
+func TestValidateGCSFuseBinaryPath(t *testing.T) {
+	t.Parallel()
+
+	allowedDir, otherDir := createFakeGCSFuseBinaries(t)
+	testCases := []struct {
+		name      string
+		path      string
+		allowlist []string
+		expectErr bool
+	}{
+		{
+			name:      "should accept an executable binary in the allowlisted dir",
+			path:      filepath.Join(allowedDir, "gcsfuse-v3"),
+			allowlist: []string{otherDir + "/sub", allowedDir + "/"},
+		},
+		{
+			name:      "should reject a binary outside the allowlisted dirs",
+			path:      filepath.Join(otherDir, "gcsfuse-not-allowed"),
+			allowlist: []string{allowedDir},
+			expectErr: true,
+		},
+		{
+			name:      "should reject a symlink escaping the allowlisted dirs",
+			path:      filepath.Join(allowedDir, "gcsfuse-link"),
+			allowlist: []string{allowedDir},
+			expectErr: true,
+		},
+		{
+			name:      "should reject a binary when the allowlist is empty",
+			path:      filepath.Join(allowedDir, "gcsfuse-v3"),
+			expectErr: true,
+		},
+		{
+			name:      "should reject a non-executable file",
+			path:      filepath.Join(allowedDir, "gcsfuse-not-exec"),
+			allowlist: []string{allowedDir},
+			expectErr: true,
+		},
+		{
+			name:      "should reject a dir",
+			path:      filepath.Join(allowedDir, "dir"),
+			allowlist: []string{allowedDir},
+			expectErr: true,
+		},
+		{
+			name:      "should reject a missing binary",
+			path:      filepath.Join(allowedDir, "gcsfuse-missing"),
+			allowlist: []string{allowedDir},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := validateGCSFuseBinaryPath(tc.path, tc.allowlist); (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func TestGCSFuseBinary(t *testing.T) {
+	t.Parallel()
+
+	allowedDir, otherDir := createFakeGCSFuseBinaries(t)
+	m := &Mounter{
+		mounterPath:         "/gcsfuse",
+		features:            &gcsfuseFeatures{version: "v2.11.1", unavailable: map[string]string{}},
+		BinaryPathAllowlist: []string{allowedDir},
+	}
+
+	path, features, err := m.gcsfuseBinary(&MountConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error for the default binary: %v", err)
+	}
+	if path != "/gcsfuse" || features != m.features {
+		t.Errorf("Got binary %q with gcsfuse version %q, but expected the default binary", path, features.version)
+	}
+
+	alternate := filepath.Join(allowedDir, "gcsfuse-v3")
+	path, features, err = m.gcsfuseBinary(&MountConfig{GCSFuseBinaryPath: alternate})
+	if err != nil {
+		t.Fatalf("unexpected error for the alternate binary: %v", err)
+	}
+	// The features are detected by running the alternate binary.
+	if path != alternate || features.version != "v3.1.0" {
+		t.Errorf("Got binary %q with gcsfuse version %q, but expected %q with gcsfuse version v3.1.0", path, features.version, alternate)
+	}
+
+	if _, _, err := m.gcsfuseBinary(&MountConfig{GCSFuseBinaryPath: filepath.Join(otherDir, "gcsfuse-not-allowed")}); err == nil {
+		t.Errorf("Expected error for the binary outside the allowlisted dirs")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
+	"golang.org/x/mod/semver"
+	"k8s.io/klog/v2"
+)
+
+var gcsfuseVersionRegex = regexp.MustCompile(`gcsfuse version (\d+\.\d+\.\d+)`)
+
+// VersionReporter reports the version of the gcsfuse binary.
+type VersionReporter interface {
+	GCSFuseVersion() (string, error)
+}
+
+type binaryVersionReporter struct {
+	mounterPath string
+}
+
+// GCSFuseVersion runs gcsfuse --version and returns the semantic version, e.g. v2.11.1.
+func (r *binaryVersionReporter) GCSFuseVersion() (string, error) {
+	//nolint: gosec
+	output, err := exec.Command(r.mounterPath, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %v --version: %w, output: %s", r.mounterPath, err, output)
+	}
+
+	matches := gcsfuseVersionRegex.FindStringSubmatch(string(output))
+	if len(matches) != 2 {
+		return "", fmt.Errorf("failed to parse the gcsfuse version from %q", strings.TrimSpace(string(output)))
+	}
+
+	return "v" + matches[1], nil
+}
+
+// gcsfuseFeatures records the optional features available in the gcsfuse binary.
+type gcsfuseFeatures struct {
+	version string
+	// unavailable maps the unavailable mount option prefixes to the minimum gcsfuse version supporting them.
+	unavailable map[string]string
+}
+
+// detectGCSFuseFeatures queries the gcsfuse version and records the available optional features.
+// If the version is unknown, all the features are considered available and gcsfuse validates the options itself.
+func detectGCSFuseFeatures(reporter VersionReporter) *gcsfuseFeatures {
+	f := &gcsfuseFeatures{unavailable: map[string]string{}}
+
+	version, err := reporter.GCSFuseVersion()
+	if err != nil || !semver.IsValid(version) {
+		klog.Warningf("failed to detect the gcsfuse version, skipping the gcsfuse feature validation: %v", err)
+
+		return f
+	}
+
+	f.version = version
+	for prefix, minVersion := range util.GCSFuseOptionPrefixesToMinVersion {
+		if semver.Compare(version, minVersion.GCSFuse) < 0 {
+			f.unavailable[prefix] = minVersion.GCSFuse
+		}
+	}
+
+	klog.Infof("detected gcsfuse version %v, unavailable optional features: %v", version, f.unavailable)
+
+	return f
+}
+
+// validateOptions returns an error if a mount option requires a newer gcsfuse version.
+func (f *gcsfuseFeatures) validateOptions(options []string) error {
+	for _, o := range options {
+		for prefix, minVersion := range f.unavailable {
+			if strings.HasPrefix(o, prefix) {
+				return fmt.Errorf("mount option %q requires gcsfuse version %v or later, but the sidecar container runs gcsfuse %v, please upgrade the sidecar container image", o, minVersion, f.version)
+			}
+		}
+	}
+
+	return nil
+}
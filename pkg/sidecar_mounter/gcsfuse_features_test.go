@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeVersionReporter struct {
+	version string
+	err     error
+}
+
+func (r *fakeVersionReporter) GCSFuseVersion() (string, error) {
+	return r.version, r.err
+}
+
+func TestGCSFuseFeatures(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		reporter    *fakeVersionReporter
+		options     []string
+		expectedErr bool
+	}{
+		{
+			name:     "new gcsfuse supports all the features",
+			reporter: &fakeVersionReporter{version: "v2.11.1"},
+			options:  []string{"file-cache:enable-parallel-downloads:true", "gcs-retries:read-stall:enable:true", "write:enable-streaming-writes:true"},
+		},
+		{
+			name:     "old gcsfuse supports the features released before its version",
+			reporter: &fakeVersionReporter{version: "v2.5.0"},
+			options:  []string{"file-cache:enable-parallel-downloads:true", "gcs-retries:read-stall:enable:true", "implicit-dirs"},
+		},
+		{
+			name:        "old gcsfuse does not support the features released after its version",
+			reporter:    &fakeVersionReporter{version: "v2.4.0"},
+			options:     []string{"file-cache:enable-parallel-downloads:true", "gcs-retries:read-stall:enable:true"},
+			expectedErr: true,
+		},
+		{
+			name:        "gcsfuse 2 does not support the parallel directory operations",
+			reporter:    &fakeVersionReporter{version: "v2.11.1"},
+			options:     []string{"file-system:enable-parallel-dirops:true"},
+			expectedErr: true,
+		},
+		{
+			name:     "gcsfuse 3 supports the parallel directory operations",
+			reporter: &fakeVersionReporter{version: "v3.0.0"},
+			options:  []string{"file-system:enable-parallel-dirops:true"},
+		},
+		{
+			name:        "gcsfuse 3.0 does not support the buffered read",
+			reporter:    &fakeVersionReporter{version: "v3.0.0"},
+			options:     []string{"read:enable-buffered-read:true"},
+			expectedErr: true,
+		},
+		{
+			name:        "old gcsfuse does not support the o-direct file cache",
+			reporter:    &fakeVersionReporter{version: "v2.5.0"},
+			options:     []string{"file-cache:enable-o-direct:true"},
+			expectedErr: true,
+		},
+		{
+			name:     "unknown gcsfuse version skips the validation",
+			reporter: &fakeVersionReporter{err: errors.New("exec format error")},
+			options:  []string{"write:enable-streaming-writes:true"},
+		},
+		{
+			name:     "invalid gcsfuse version skips the validation",
+			reporter: &fakeVersionReporter{version: "unknown"},
+			options:  []string{"write:enable-streaming-writes:true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			features := detectGCSFuseFeatures(tc.reporter)
+			err := features.validateOptions(tc.options)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestBinaryVersionReporter(t *testing.T) {
+	t.Parallel()
+
+	gcsfusePath := filepath.Join(t.TempDir(), "gcsfuse")
+	script := "#!/bin/sh\necho 'gcsfuse version 2.11.1-gke.0 (Go version go1.23.5)'\n"
+	if err := os.WriteFile(gcsfusePath, []byte(script), 0o700); err != nil {
+		t.Fatalf("failed to create the fake gcsfuse binary: %v", err)
+	}
+
+	version, err := (&binaryVersionReporter{mounterPath: gcsfusePath}).GCSFuseVersion()
+	if err != nil {
+		t.Fatalf("failed to get the gcsfuse version: %v", err)
+	}
+	if version != "v2.11.1" {
+		t.Errorf("Got gcsfuse version %q, but expected %q", version, "v2.11.1")
+	}
+
+	if _, err := (&binaryVersionReporter{mounterPath: "/non-existing-gcsfuse"}).GCSFuseVersion(); err == nil {
+		t.Errorf("Expected error for a non-existing gcsfuse binary")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"regexp"
+)
+
+// requestLogMaxLineBytes bounds the buffered partial line, a longer line is passed through without sampling.
+const requestLogMaxLineBytes = 1 << 20
+
+// requestLogIDPattern matches the GCS request ID in the gcsfuse trace logs, e.g. "gcs: Req              0x2a: <- StatObject(...)".
+// The request and the response of a GCS request share the ID.
+var requestLogIDPattern = regexp.MustCompile(`gcs: Req\s+(0x[0-9a-f]+):`)
+
+// requestLogSampler is a writer keeping a sampled fraction of the GCS request logs of gcsfuse,
+// all the other log lines are passed through. The sampling is keyed on the request ID,
+// so the request and the response lines, which carry the latency, are kept or dropped together.
+type requestLogSampler struct {
+	w         io.Writer
+	threshold uint64
+	buf       []byte
+}
+
+func newRequestLogSampler(w io.Writer, rate float64) *requestLogSampler {
+	return &requestLogSampler{
+		w:         w,
+		threshold: uint64(rate * (1 << 32)),
+	}
+}
+
+// Write buffers the partial lines and writes the complete lines kept by the sampling.
+func (s *requestLogSampler) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+
+	out := []byte{}
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := s.buf[:i+1]
+		if s.keep(line) {
+			out = append(out, line...)
+		}
+		s.buf = s.buf[i+1:]
+	}
+
+	if len(s.buf) > requestLogMaxLineBytes {
+		out = append(out, s.buf...)
+		s.buf = nil
+	}
+	// Copy the partial line so the consumed lines are released.
+	s.buf = append([]byte{}, s.buf...)
+
+	if len(out) > 0 {
+		if _, err := s.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// keep reports whether the log line is kept, the lines without a GCS request ID are always kept.
+func (s *requestLogSampler) keep(line []byte) bool {
+	m := requestLogIDPattern.FindSubmatch(line)
+	if m == nil {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write(m[1])
+
+	return uint64(h.Sum32()) < s.threshold
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// requestIDRegex matches the request ID of a gcsfuse request log line.
+var requestIDRegex = regexp.MustCompile(`Req +(0x[0-9a-f]+):`)
+
+func requestLogLines(id int) string {
+	return fmt.Sprintf(`{"severity":"TRACE","message":"gcs: Req            0x%x: <- StatObject(\"foo\")"}`+"\n"+
+		`{"severity":"TRACE","message":"gcs: Req            0x%x: -> StatObject(\"foo\") (12.3ms): OK"}`+"\n", id, id)
+}
+
+func TestRequestLogSampler(t *testing.T) {
+	t.Parallel()
+
+	const requests = 10000
+	otherLine := `{"severity":"INFO","message":"File system has been successfully mounted."}` + "\n"
+
+	testCases := []struct {
+		name        string
+		rate        float64
+		minRequests int
+		maxRequests int
+	}{
+		{
+			name:        "should drop all the requests at rate 0",
+			rate:        0,
+			minRequests: 0,
+			maxRequests: 0,
+		},
+		{
+			name:        "should keep all the requests at rate 1",
+			rate:        1,
+			minRequests: requests,
+			maxRequests: requests,
+		},
+		{
+			name:        "should keep about half of the requests at rate 0.5",
+			rate:        0.5,
+			minRequests: requests * 45 / 100,
+			maxRequests: requests * 55 / 100,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			s := newRequestLogSampler(&out, tc.rate)
+			for i := range requests {
+				if _, err := s.Write([]byte(requestLogLines(i) + otherLine)); err != nil {
+					t.Fatalf("failed to write the logs: %v", err)
+				}
+			}
+
+			if got := strings.Count(out.String(), otherLine); got != requests {
+				t.Errorf("got %v other log lines, expected %v", got, requests)
+			}
+
+			lines := map[string]int{}
+			for _, line := range strings.Split(out.String(), "\n") {
+				if m := requestIDRegex.FindStringSubmatch(line); m != nil {
+					lines[m[1]]++
+				}
+			}
+
+			kept := 0
+			for id, count := range lines {
+				if count != 2 {
+					t.Fatalf("the request %v and its response were not sampled together", id)
+				}
+				kept++
+			}
+			if kept < tc.minRequests || kept > tc.maxRequests {
+				t.Errorf("got %v sampled requests, expected between %v and %v", kept, tc.minRequests, tc.maxRequests)
+			}
+		})
+	}
+}
+
+func TestRequestLogSamplerPartialWrites(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	s := newRequestLogSampler(&out, 1)
+	logs := requestLogLines(42) + "gcsfuse started\n"
+	for i := range len(logs) {
+		if n, err := s.Write([]byte{logs[i]}); n != 1 || err != nil {
+			t.Fatalf("got %v, %v writing a byte, expected 1, nil", n, err)
+		}
+	}
+
+	if out.String() != logs {
+		t.Errorf("got logs %q, expected %q", out.String(), logs)
+	}
+
+	if _, err := s.Write([]byte("partial")); err != nil {
+		t.Fatalf("failed to write the logs: %v", err)
+	}
+	if out.String() != logs {
+		t.Errorf("got logs %q, expected the partial line to be buffered", out.String())
+	}
+}
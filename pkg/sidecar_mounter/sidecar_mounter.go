@@ -37,11 +37,13 @@ import (
 	"cloud.google.com/go/compute/metadata"
 	credentials "cloud.google.com/go/iam/credentials/apiv1"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/metrics"
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sts/v1"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 )
 
 const metricEndpointFmt = "http://localhost:%v/metrics"
@@ -49,7 +51,11 @@ const metricEndpointFmt = "http://localhost:%v/metrics"
 // Mounter will be used in the sidecar container to invoke gcsfuse.
 type Mounter struct {
 	mounterPath string
+	features    *gcsfuseFeatures
 	WaitGroup   sync.WaitGroup
+	// BinaryPathAllowlist lists the dirs in the sidecar container image holding the alternate gcsfuse binaries
+	// the volumes may select with the gcsfuseBinaryPath volume attribute.
+	BinaryPathAllowlist []string
 }
 
 // New returns a Mounter for the current system.
@@ -57,10 +63,21 @@ type Mounter struct {
 func New(mounterPath string) *Mounter {
 	return &Mounter{
 		mounterPath: mounterPath,
+		features:    detectGCSFuseFeatures(&binaryVersionReporter{mounterPath: mounterPath}),
 	}
 }
 
 func (m *Mounter) Mount(ctx context.Context, mc *MountConfig) error {
+	mounterPath, features, err := m.gcsfuseBinary(mc)
+	if err != nil {
+		return err
+	}
+
+	// Fail fast with a precise message if the gcsfuse binary does not support the options.
+	if err := features.validateOptions(mc.Options); err != nil {
+		return err
+	}
+
 	// Start the token server for HostNetwork enabled pods.
 	if mc.TokenServerIdentityProvider != "" {
 		tp := filepath.Join(mc.TempDir, TokenFileName)
@@ -74,6 +91,13 @@ func (m *Mounter) Mount(ctx context.Context, mc *MountConfig) error {
 		return fmt.Errorf("failed to create temp dir %q: %w", mc.BufferDir+TempDir, err)
 	}
 
+	// gcsfuse does not create the parent dir of the log file set by the accessLogPath volume attribute.
+	if logFile := mc.ConfigFileFlagMap["logging:file-path"]; logFile != "" && !strings.HasPrefix(logFile, "/dev/fd/") {
+		if err := os.MkdirAll(filepath.Dir(logFile), 0o750); err != nil {
+			return fmt.Errorf("failed to create the log file dir %q: %w", filepath.Dir(logFile), err)
+		}
+	}
+
 	args := []string{}
 	for k, v := range mc.FlagMap {
 		args = append(args, "--"+k)
@@ -89,10 +113,20 @@ func (m *Mounter) Mount(ctx context.Context, mc *MountConfig) error {
 
 	klog.Infof("gcsfuse mounting with args %v...", args)
 	//nolint: gosec
-	cmd := exec.CommandContext(ctx, m.mounterPath, args...)
+	cmd := exec.CommandContext(ctx, mounterPath, args...)
 	cmd.ExtraFiles = []*os.File{os.NewFile(uintptr(mc.FileDescriptor), "/dev/fuse")}
 	cmd.Stdout = os.Stdout
+	if mc.RequestLogSampleRate != nil {
+		klog.Infof("[%v] logging %v of the GCS requests", mc.VolumeName, *mc.RequestLogSampleRate)
+		cmd.Stdout = newRequestLogSampler(os.Stdout, *mc.RequestLogSampleRate)
+	}
 	cmd.Stderr = io.MultiWriter(os.Stderr, mc.ErrWriter)
+	var logs *logTail
+	if mc.CollectDiagnosticsOnFailure {
+		logs = newLogTail(diagnosticsLogTailBytes)
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, logs)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, logs)
+	}
 	cmd.Cancel = func() error {
 		klog.V(4).Infof("sending SIGTERM to gcsfuse process: %v", cmd)
 
@@ -116,14 +150,22 @@ func (m *Mounter) Mount(ctx context.Context, mc *MountConfig) error {
 	m.WaitGroup.Add(1)
 	go func() {
 		defer m.WaitGroup.Done()
-		if err := cmd.Start(); err != nil {
+		if err := startWithMaxOpenFiles(cmd, mc.MaxOpenFiles); err != nil {
 			mc.ErrWriter.WriteMsg(fmt.Sprintf("failed to start gcsfuse with error: %v\n", err))
+			if mc.CollectDiagnosticsOnFailure {
+				collectDiagnostics(mc, features.version, err, logs)
+			}
 
 			return
 		}
 
 		klog.Infof("gcsfuse for bucket %q, volume %q started with process id %v", mc.BucketName, mc.VolumeName, cmd.Process.Pid)
 
+		// Expose the file cache layout so that the tooling inspecting the cache dir can locate the cached objects.
+		if cacheDir := mc.ConfigFileFlagMap["cache-dir"]; cacheDir != "" {
+			klog.Infof("[%v] gcsfuse caches the objects in %q with the file cache layout %v", mc.VolumeName, cacheDir, util.FileCacheLayoutVersion(features.version))
+		}
+
 		loggingSeverity := mc.ConfigFileFlagMap["logging:severity"]
 		if loggingSeverity == "debug" || loggingSeverity == "trace" {
 			go logMemoryUsage(ctx, cmd.Process.Pid)
@@ -143,14 +185,74 @@ func (m *Mounter) Mount(ctx context.Context, mc *MountConfig) error {
 			errMsg := fmt.Sprintf("gcsfuse exited with error: %v\n", err)
 			if strings.Contains(errMsg, "signal: terminated") {
 				klog.Infof("[%v] gcsfuse was terminated.", mc.VolumeName)
+				removeCacheDir(mc)
 			} else {
 				mc.ErrWriter.WriteMsg(errMsg)
+				if mc.CollectDiagnosticsOnFailure {
+					collectDiagnostics(mc, features.version, err, logs)
+				}
 			}
 		} else {
 			klog.Infof("[%v] gcsfuse exited normally.", mc.VolumeName)
+			removeCacheDir(mc)
+		}
+	}()
+
+	return nil
+}
+
+// removeCacheDir removes the file cache dir of the volume after gcsfuse exits, if the volume cache cleanup policy is delete.
+// gcsfuse exits when its last mount is unmounted or the Pod terminates, so a cache dir shared by several mounts is removed
+// with the last one. The cache dir is kept when gcsfuse fails, so that a restarted gcsfuse reuses the cache.
+func removeCacheDir(mc *MountConfig) {
+	cacheDir := mc.ConfigFileFlagMap["cache-dir"]
+	if !mc.CacheCleanupOnUnmount || cacheDir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(cacheDir); err != nil {
+		klog.Warningf("[%v] failed to remove the cache dir %q: %v", mc.VolumeName, cacheDir, err)
+	} else {
+		klog.Infof("[%v] removed the cache dir %q", mc.VolumeName, cacheDir)
+	}
+}
+
+// rlimitMutex serializes the start of the gcsfuse processes, so that a process does not inherit the open files limit set for another one.
+var rlimitMutex sync.Mutex
+
+// startWithMaxOpenFiles starts the command with the given RLIMIT_NOFILE soft limit, raising the hard limit if needed.
+// A zero limit starts the command with the open files limit of the sidecar mounter.
+// The process inherits the limits of the sidecar mounter when it starts, so the limit of the sidecar mounter is set before the start
+// and restored after it. The command is not started if the limit cannot be set.
+func startWithMaxOpenFiles(cmd *exec.Cmd, limit uint64) error {
+	rlimitMutex.Lock()
+	defer rlimitMutex.Unlock()
+
+	if limit == 0 {
+		return cmd.Start()
+	}
+
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return fmt.Errorf("failed to get the open files limit: %w", err)
+	}
+
+	newRlimit := syscall.Rlimit{Cur: limit, Max: max(rlimit.Max, limit)}
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &newRlimit); err != nil {
+		return fmt.Errorf("failed to set the open files limit to %v, hard limit %v: %w", newRlimit.Cur, newRlimit.Max, err)
+	}
+	defer func() {
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+			klog.Warningf("failed to restore the open files limit to %v, hard limit %v: %v", rlimit.Cur, rlimit.Max, err)
 		}
 	}()
 
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	klog.Infof("process with id %v started with the open files limit %v, hard limit %v", cmd.Process.Pid, newRlimit.Cur, newRlimit.Max)
+
 	return nil
 }
 
@@ -267,7 +369,7 @@ func collectMetrics(ctx context.Context, port, tempDir string) {
 }
 
 // scrapeMetrics connects to the metrics endpoint and scrapes latest metrics sample.
-// The response is written to a new http.ResponseWriter.
+// The response, with the file cache hit and miss counters derived from it, is written to a new http.ResponseWriter.
 func scrapeMetrics(ctx context.Context, metricEndpoint string, w http.ResponseWriter) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metricEndpoint, nil)
 	if err != nil {
@@ -284,7 +386,12 @@ func scrapeMetrics(ctx context.Context, metricEndpoint string, w http.ResponseWr
 		return fmt.Errorf("unexpected HTTP status: %v", resp.Status)
 	}
 
-	if _, err := io.Copy(w, resp.Body); err != nil {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := writeFileCacheHitMissMetrics(w, body); err != nil {
 		return fmt.Errorf("failed to copy response: %w", err)
 	}
 
@@ -348,31 +455,74 @@ func getAudienceFromContextAndIdentityProvider(ctx context.Context, identityProv
 	return audience, nil
 }
 
-func StartTokenServer(ctx context.Context, tokenURLSocketPath string, identityProvider string) {
-	// Create a unix domain socket and listen for incoming connections.
-	tokenSocketListener, err := net.Listen("unix", tokenURLSocketPath)
+// tokenRefreshMargin is how long before the expiry a cached token is rotated.
+const tokenRefreshMargin = time.Minute * 5
+
+// rotatingTokenSource caches the identity binding token and rotates it ahead of its expiry.
+// The gcsfuse process fetches tokens from the token server whenever its token expires,
+// so a rotated token is picked up without remounting the bucket.
+type rotatingTokenSource struct {
+	mu            sync.Mutex
+	token         *oauth2.Token
+	clock         clock.PassiveClock
+	refreshMargin time.Duration
+	fetchToken    func(ctx context.Context) (*oauth2.Token, error)
+}
+
+func newRotatingTokenSource(identityProvider string) *rotatingTokenSource {
+	return &rotatingTokenSource{
+		clock:         clock.RealClock{},
+		refreshMargin: tokenRefreshMargin,
+		fetchToken: func(ctx context.Context) (*oauth2.Token, error) {
+			k8stoken, err := getK8sTokenFromFile(webhook.SidecarContainerSATokenVolumeMountPath + "/" + webhook.K8STokenPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get k8s token from path: %w", err)
+			}
+
+			return fetchIdentityBindingToken(ctx, k8stoken, identityProvider)
+		},
+	}
+}
+
+// Token returns the cached token, or rotates it if it expires within the refresh margin.
+// If the rotation fails, the cached token is returned as long as it has not expired,
+// so in-flight gcsfuse operations are not interrupted by a transient failure.
+func (ts *rotatingTokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != nil && ts.token.Expiry.Sub(ts.clock.Now()) > ts.refreshMargin {
+		return ts.token, nil
+	}
+
+	token, err := ts.fetchToken(ctx)
 	if err != nil {
-		klog.Errorf("failed to create socket %q: %v", tokenURLSocketPath, err)
+		if ts.token != nil && ts.clock.Now().Before(ts.token.Expiry) {
+			klog.Warningf("failed to rotate token, serving the cached token that expires at %v: %v", ts.token.Expiry, err)
 
-		return
+			return ts.token, nil
+		}
+
+		return nil, err
 	}
-	klog.Infof("created a listener using the socket path %s", tokenURLSocketPath)
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+
+	if ts.token != nil {
+		klog.V(4).Infof("rotated token, new token expires at %v", token.Expiry)
+	}
+	ts.token = token
+
+	return token, nil
+}
+
+// tokenHandler serves the token from the token source as JSON.
+func tokenHandler(ctx context.Context, ts *rotatingTokenSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 
-		k8stoken, err := getK8sTokenFromFile(webhook.SidecarContainerSATokenVolumeMountPath + "/" + webhook.K8STokenPath)
-		var stsToken *oauth2.Token
+		stsToken, err := ts.Token(ctx)
 		if err != nil {
-			klog.Errorf("failed to get k8s token from path %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-
-			return
-		}
-		stsToken, err = fetchIdentityBindingToken(ctx, k8stoken, identityProvider)
-		if err != nil {
-			klog.Errorf("failed to get sts token from path %v", err)
+			klog.Errorf("failed to get sts token: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 
 			return
@@ -388,7 +538,25 @@ func StartTokenServer(ctx context.Context, tokenURLSocketPath string, identityPr
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, string(jsonToken))
-	})
+	}
+}
+
+func StartTokenServer(ctx context.Context, tokenURLSocketPath string, identityProvider string) {
+	serveTokens(ctx, tokenURLSocketPath, newRotatingTokenSource(identityProvider))
+}
+
+// serveTokens serves the tokens from the token source on the unix domain socket until the context is cancelled.
+func serveTokens(ctx context.Context, tokenURLSocketPath string, ts *rotatingTokenSource) {
+	// Create a unix domain socket and listen for incoming connections.
+	tokenSocketListener, err := net.Listen("unix", tokenURLSocketPath)
+	if err != nil {
+		klog.Errorf("failed to create socket %q: %v", tokenURLSocketPath, err)
+
+		return
+	}
+	klog.Infof("created a listener using the socket path %s", tokenURLSocketPath)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", tokenHandler(ctx, ts))
 
 	server := http.Server{
 		Handler:      mux,
@@ -396,6 +564,11 @@ func StartTokenServer(ctx context.Context, tokenURLSocketPath string, identityPr
 		WriteTimeout: 10 * time.Second,
 	}
 
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
 	if err := server.Serve(tokenSocketListener); !errors.Is(err, http.ErrServerClosed) {
 		klog.Errorf("Server for %q returns unexpected error: %v", tokenURLSocketPath, err)
 	}
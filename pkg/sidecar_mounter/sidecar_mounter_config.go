@@ -24,6 +24,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -40,6 +42,14 @@ const (
 	unixSocketBasePath   = "unix://"
 	TokenFileName        = "token.sock" // #nosec G101
 	identityProviderFlag = "token-server-identity-provider"
+	maxOpenFilesFlag     = "max-open-files"
+
+	fileCacheMinFreePercentFlag = "file-cache-min-free-percent"
+	sourceReadOnlyFlag          = "source-read-only"
+	collectDiagnosticsFlag      = "collect-diagnostics-on-failure"
+	gcsfuseBinaryPathFlag       = "gcsfuse-binary-path"
+	requestLogSampleRateFlag    = "request-log-sample-rate"
+	cacheCleanupOnUnmountFlag   = "cache-cleanup-on-unmount"
 )
 
 // MountConfig contains the information gcsfuse needs.
@@ -56,28 +66,54 @@ type MountConfig struct {
 	FlagMap                     map[string]string     `json:"-"`
 	ConfigFileFlagMap           map[string]string     `json:"-"`
 	TokenServerIdentityProvider string                `json:"-"`
+	MaxOpenFiles                uint64                `json:"-"`
+	FileCacheMinFreePercent     uint64                `json:"-"`
+	CollectDiagnosticsOnFailure bool                  `json:"-"`
+	GCSFuseBinaryPath           string                `json:"-"`
+	RequestLogSampleRate        *float64              `json:"-"`
+	CacheCleanupOnUnmount       bool                  `json:"-"`
 }
 
 var prometheusPort = 62990
 
 var disallowedFlags = map[string]bool{
-	"temp-dir":                             true,
-	"config-file":                          true,
-	"foreground":                           true,
-	"log-file":                             true,
-	"log-format":                           true,
-	"key-file":                             true,
-	"token-url":                            true,
-	"reuse-token-from-url":                 true,
-	"o":                                    true,
-	"logging:log-rotate:max-file-size-mb":  true,
-	"logging:log-rotate:backup-file-count": true,
-	"logging:log-rotate:compress":          true,
-	"cache-dir":                            true,
-	"experimental-local-file-cache":        true,
-	"prometheus-port":                      true,
+	"temp-dir":                      true,
+	"config-file":                   true,
+	"foreground":                    true,
+	"log-file":                      true,
+	"log-format":                    true,
+	"key-file":                      true,
+	"token-url":                     true,
+	"reuse-token-from-url":          true,
+	"o":                             true,
+	"cache-dir":                     true,
+	"experimental-local-file-cache": true,
+	"prometheus-port":               true,
 }
 
+// logRotateFlags validate the values of the gcsfuse log rotation config file flags.
+var logRotateFlags = map[string]func(string) bool{
+	"logging:log-rotate:max-file-size-mb": func(v string) bool {
+		i, err := strconv.Atoi(v)
+
+		return err == nil && i > 0
+	},
+	"logging:log-rotate:backup-file-count": func(v string) bool {
+		i, err := strconv.Atoi(v)
+
+		return err == nil && i >= 0
+	},
+	"logging:log-rotate:compress": func(v string) bool {
+		return v == util.TrueStr || v == util.FalseStr
+	},
+}
+
+// podMetadataEnvRefRegex matches the references to the Pod metadata env vars injected by the webhook, e.g. ${GCSFUSE_POD_LABEL_APP}.
+var podMetadataEnvRefRegex = regexp.MustCompile(`\$\{(` + webhook.PodMetadataEnvPrefix + `[A-Z0-9_]+)\}`)
+
+// decimalRegex matches the decimal numbers with a decimal point, e.g. 0.25, which are written to the gcsfuse config file as floats.
+var decimalRegex = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`)
+
 var boolFlags = map[string]bool{
 	"implicit-dirs":                 true,
 	"enable-nonexistent-type-cache": true,
@@ -95,6 +131,7 @@ var boolFlags = map[string]bool{
 // 2. The file descriptor
 // 3. GCS bucket name
 // 4. Mount options passing to gcsfuse (passed by the csi mounter).
+// The gcsfuse config file is written by Prepare, once the file cache settings of all the volumes are known.
 func NewMountConfig(sp string) *MountConfig {
 	// socket path pattern: /gcsfuse-tmp/.volumes/<volume-name>/socket
 	tempDir := filepath.Dir(sp)
@@ -142,16 +179,68 @@ func NewMountConfig(sp string) *MountConfig {
 		return nil
 	}
 
-	mc.prepareMountArgs()
-	if err := mc.prepareConfigFile(); err != nil {
-		mc.ErrWriter.WriteMsg(fmt.Sprintf("failed to create config file %q: %v", mc.ConfigFile, err))
+	if err := mc.expandPodMetadataEnv(); err != nil {
+		mc.ErrWriter.WriteMsg(fmt.Sprintf("failed to expand the Pod metadata in the mount options: %v", err))
 
 		return nil
 	}
+	mc.prepareMountArgs()
 
 	return &mc
 }
 
+// FileCacheEnabled returns true if the volume enables the gcsfuse file cache.
+func (mc *MountConfig) FileCacheEnabled() bool {
+	return mc.ConfigFileFlagMap["cache-dir"] != ""
+}
+
+// CountFileCacheVolumes returns the number of the volumes enabling the file cache.
+func CountFileCacheVolumes(mcs []*MountConfig) int {
+	cachedVolumes := 0
+	for _, mc := range mcs {
+		if mc.FileCacheEnabled() {
+			cachedVolumes++
+		}
+	}
+
+	return cachedVolumes
+}
+
+// FileCacheSizeLimitShareMb splits the Pod total cache size limit in MiB evenly between the volumes enabling the file cache,
+// the volumes without the file cache do not take a share. It returns 0 if there is no limit.
+func FileCacheSizeLimitShareMb(totalCacheSizeLimitMb int64, cachedVolumes int) int64 {
+	if totalCacheSizeLimitMb <= 0 || cachedVolumes <= 0 {
+		return 0
+	}
+
+	return max(totalCacheSizeLimitMb/int64(cachedVolumes), 1)
+}
+
+// Prepare bounds the file cache of the volume to fileCacheSizeLimitMb if it is positive, and to its share of the free space
+// of the cache volume shared by the cachedVolumes volumes enabling the file cache, then writes the gcsfuse config file.
+func (mc *MountConfig) Prepare(fileCacheSizeLimitMb int64, cachedVolumes int) error {
+	mc.boundFileCacheToSizeLimit(fileCacheSizeLimitMb)
+	if err := mc.boundFileCacheToFreeSpace(webhook.SidecarContainerCacheVolumeMountPath, cachedVolumes, statfsFreeSpace); err != nil {
+		return fmt.Errorf("failed to bound the file cache size to the free space of the cache volume: %w", err)
+	}
+	if err := mc.prepareConfigFile(); err != nil {
+		return fmt.Errorf("failed to create config file %q: %w", mc.ConfigFile, err)
+	}
+
+	return nil
+}
+
+// boundFileCacheToSizeLimit bounds the file cache max size of the volume to its share of the Pod total cache size limit.
+func (mc *MountConfig) boundFileCacheToSizeLimit(limitMb int64) {
+	if limitMb <= 0 || !mc.FileCacheEnabled() {
+		return
+	}
+
+	if boundFileCacheMaxSize(mc.ConfigFileFlagMap, limitMb) {
+		klog.Infof("bounded the file cache max size of volume %q to its share %v MiB of the Pod total cache size limit", mc.VolumeName, limitMb)
+	}
+}
+
 func (mc *MountConfig) prepareMountArgs() {
 	flagMap := map[string]string{
 		"app-name":    GCSFuseAppName,
@@ -169,6 +258,7 @@ func (mc *MountConfig) prepareMountArgs() {
 	}
 
 	invalidArgs := []string{}
+	fuseMountOptions := []string{}
 
 	for _, arg := range mc.Options {
 		if strings.Contains(arg, ":") && !strings.Contains(arg, "https") {
@@ -185,6 +275,12 @@ func (mc *MountConfig) prepareMountArgs() {
 				continue
 			}
 
+			if isValid, ok := logRotateFlags[f]; ok && !isValid(v) {
+				invalidArgs = append(invalidArgs, arg)
+
+				continue
+			}
+
 			if disallowedFlags[f] {
 				invalidArgs = append(invalidArgs, arg)
 			} else {
@@ -223,6 +319,79 @@ func (mc *MountConfig) prepareMountArgs() {
 			continue
 		}
 
+		if flag == maxOpenFilesFlag {
+			if limit, err := strconv.ParseUint(value, 10, 64); err == nil && limit > 0 {
+				mc.MaxOpenFiles = limit
+			} else {
+				invalidArgs = append(invalidArgs, arg)
+			}
+
+			continue
+		}
+
+		// gcsfuse refuses all the writes with the ro fuse option, even if the kernel mount is writable.
+		if flag == sourceReadOnlyFlag {
+			switch value {
+			case util.TrueStr:
+				fuseMountOptions = append(fuseMountOptions, "ro")
+			case util.FalseStr:
+			default:
+				invalidArgs = append(invalidArgs, arg)
+			}
+
+			continue
+		}
+
+		if flag == collectDiagnosticsFlag {
+			switch value {
+			case util.TrueStr:
+				mc.CollectDiagnosticsOnFailure = true
+			case util.FalseStr:
+			default:
+				invalidArgs = append(invalidArgs, arg)
+			}
+
+			continue
+		}
+
+		// The binary path is validated against the allowlist by the Mounter.
+		if flag == gcsfuseBinaryPathFlag {
+			mc.GCSFuseBinaryPath = value
+
+			continue
+		}
+
+		// The CSI driver only passes the delete policy, the cache dir is retained by default.
+		if flag == cacheCleanupOnUnmountFlag {
+			if value == "delete" {
+				mc.CacheCleanupOnUnmount = true
+			} else {
+				invalidArgs = append(invalidArgs, arg)
+			}
+
+			continue
+		}
+
+		if flag == requestLogSampleRateFlag {
+			if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 && rate <= 1 {
+				mc.RequestLogSampleRate = &rate
+			} else {
+				invalidArgs = append(invalidArgs, arg)
+			}
+
+			continue
+		}
+
+		if flag == fileCacheMinFreePercentFlag {
+			if percent, err := strconv.ParseUint(value, 10, 64); err == nil && percent > 0 && percent < 100 {
+				mc.FileCacheMinFreePercent = percent
+			} else {
+				invalidArgs = append(invalidArgs, arg)
+			}
+
+			continue
+		}
+
 		switch {
 		case boolFlags[flag] && value != "":
 			flag = flag + "=" + value
@@ -240,6 +409,11 @@ func (mc *MountConfig) prepareMountArgs() {
 		flagMap[flag] = value
 	}
 
+	if len(fuseMountOptions) > 0 {
+		sort.Strings(fuseMountOptions)
+		flagMap["o"] = strings.Join(fuseMountOptions, ",")
+	}
+
 	if len(invalidArgs) > 0 {
 		klog.Warningf("got invalid arguments for volume %q: %v. Will discard invalid args and continue to mount.",
 			invalidArgs, mc.VolumeName)
@@ -247,6 +421,71 @@ func (mc *MountConfig) prepareMountArgs() {
 	mc.FlagMap, mc.ConfigFileFlagMap = flagMap, configFileFlagMap
 }
 
+// boundFileCacheMaxSize lowers the file cache max size to limitMb if the size is unlimited, invalid or larger than limitMb.
+// It returns true if the size is changed.
+func boundFileCacheMaxSize(configFileFlagMap map[string]string, limitMb int64) bool {
+	if size, err := strconv.ParseInt(configFileFlagMap["file-cache:max-size-mb"], 10, 64); err == nil && size >= 0 && size <= limitMb {
+		return false
+	}
+	configFileFlagMap["file-cache:max-size-mb"] = strconv.FormatInt(limitMb, 10)
+
+	return true
+}
+
+// boundFileCacheToFreeSpace bounds the file cache max size of the volume to its share of the free space of the cache volume
+// mounted at cacheVolumePath, keeping FileCacheMinFreePercent of the cache volume free. The free space is split evenly
+// between the cachedVolumes volumes enabling the file cache, which all keep their cache dirs on the cache volume.
+// The bound is fixed when the volume is mounted, so that gcsfuse evicts the cache files itself instead of having them deleted behind its LRU.
+// It does not follow the free space changes after the mount, e.g. other files written to a custom cache volume.
+// The file cache is disabled if no space is left.
+func (mc *MountConfig) boundFileCacheToFreeSpace(cacheVolumePath string, cachedVolumes int, freeSpace freeSpaceFunc) error {
+	cacheDir := mc.ConfigFileFlagMap["cache-dir"]
+	if mc.FileCacheMinFreePercent == 0 || cacheDir == "" {
+		return nil
+	}
+
+	// The cache files of all the volumes count as available space, each volume is bounded to its share of it.
+	limitMb, err := fileCacheFreeSpaceLimitMb(cacheVolumePath, filepath.Dir(cacheDir), mc.FileCacheMinFreePercent, freeSpace)
+	if err != nil {
+		return err
+	}
+	limitMb /= int64(max(cachedVolumes, 1))
+
+	if limitMb == 0 {
+		klog.Warningf("[%v] disabling the file cache, the free space of the cache volume is below %v%%", mc.VolumeName, mc.FileCacheMinFreePercent)
+		mc.ConfigFileFlagMap["file-cache:max-size-mb"] = "0"
+		mc.ConfigFileFlagMap["cache-dir"] = ""
+
+		return nil
+	}
+
+	if boundFileCacheMaxSize(mc.ConfigFileFlagMap, limitMb) {
+		klog.Infof("[%v] bounded the file cache max size to %v MiB, its share of the %v volumes keeping %v%% of the cache volume free", mc.VolumeName, limitMb, max(cachedVolumes, 1), mc.FileCacheMinFreePercent)
+	}
+
+	return nil
+}
+
+// expandPodMetadataEnv replaces the Pod metadata env var references in the mount options with the env var values.
+// The Pod labels and annotations are set by the Pod author, so a value containing the mount option separator ',' or
+// the config file flag separator ':' is rejected, instead of turning into another mount option or config file flag.
+func (mc *MountConfig) expandPodMetadataEnv() error {
+	var errs []error
+	for i, arg := range mc.Options {
+		mc.Options[i] = podMetadataEnvRefRegex.ReplaceAllStringFunc(arg, func(ref string) string {
+			name := podMetadataEnvRefRegex.FindStringSubmatch(ref)[1]
+			value := os.Getenv(name)
+			if strings.ContainsAny(value, ",:") {
+				errs = append(errs, fmt.Errorf("the value %q of the Pod metadata env var %q in the mount option %q must not contain ',' or ':'", value, name, arg))
+			}
+
+			return value
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
 func (mc *MountConfig) prepareConfigFile() error {
 	if mc.ConfigFileFlagMap == nil {
 		return errors.New("got empty config file flag map")
@@ -267,6 +506,8 @@ func (mc *MountConfig) prepareConfigFile() error {
 					curLevel[t] = intVal
 				} else if boolVal, err := strconv.ParseBool(v); err == nil {
 					curLevel[t] = boolVal
+				} else if floatVal, err := strconv.ParseFloat(v, 64); err == nil && decimalRegex.MatchString(v) {
+					curLevel[t] = floatVal
 				} else {
 					curLevel[t] = v
 				}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
 	"gopkg.in/yaml.v3"
+	"k8s.io/utils/ptr"
 )
 
 var (
@@ -65,10 +66,16 @@ func TestPrepareMountArgs(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		name                  string
-		mc                    *MountConfig
-		expectedArgs          map[string]string
-		expectedConfigMapArgs map[string]string
+		name                            string
+		mc                              *MountConfig
+		expectedArgs                    map[string]string
+		expectedConfigMapArgs           map[string]string
+		expectedMaxOpenFiles            uint64
+		expectedFileCacheMinFreePercent uint64
+		expectedCollectDiagnostics      bool
+		expectedGCSFuseBinaryPath       string
+		expectedRequestLogSampleRate    *float64
+		expectedCacheCleanupOnUnmount   bool
 	}{
 		{
 			name: "should return valid args correctly",
@@ -109,6 +116,26 @@ func TestPrepareMountArgs(t *testing.T) {
 				"cache-dir":               "",
 			},
 		},
+		{
+			name: "should pass the custom endpoint as a flag",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"custom-endpoint=https://[2001:db8::1]:443"},
+			},
+			expectedArgs: map[string]string{
+				"app-name":        GCSFuseAppName,
+				"temp-dir":        "test-buffer-dir/temp-dir",
+				"config-file":     "test-config-file",
+				"foreground":      "",
+				"uid":             "0",
+				"gid":             "0",
+				"custom-endpoint": "https://[2001:db8::1]:443",
+			},
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
 		{
 			name: "should return valid args with bool options correctly",
 			mc: &MountConfig{
@@ -162,6 +189,25 @@ func TestPrepareMountArgs(t *testing.T) {
 			},
 			expectedConfigMapArgs: defaultConfigFileFlagMap,
 		},
+		{
+			name: "should return valid args with the user agent suffix",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"app-name=team-a/v1.2_3"},
+			},
+			expectedArgs: map[string]string{
+				"app-name":    GCSFuseAppName + "-team-a/v1.2_3",
+				"temp-dir":    "test-buffer-dir/temp-dir",
+				"config-file": "test-config-file",
+				"foreground":  "",
+				"uid":         "0",
+				"gid":         "0",
+			},
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
 		{
 			name: "should return valid args when file cache is disabled",
 			mc: &MountConfig{
@@ -233,6 +279,246 @@ func TestPrepareMountArgs(t *testing.T) {
 			},
 			expectedConfigMapArgs: defaultConfigFileFlagMap,
 		},
+		{
+			name: "should pass the billing project to gcsfuse",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"billing-project=my-quota-project"},
+			},
+			expectedArgs: map[string]string{
+				"app-name":        GCSFuseAppName,
+				"temp-dir":        "test-buffer-dir/temp-dir",
+				"config-file":     "test-config-file",
+				"foreground":      "",
+				"uid":             "0",
+				"gid":             "0",
+				"billing-project": "my-quota-project",
+			},
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should return valid args with log rotation options",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"logging:log-rotate:max-file-size-mb:256", "logging:log-rotate:backup-file-count:0", "logging:log-rotate:compress:false"},
+			},
+			expectedArgs: defaultFlagMap,
+			expectedConfigMapArgs: map[string]string{
+				"logging:file-path":                    "/dev/fd/1",
+				"logging:format":                       "json",
+				"logging:log-rotate:max-file-size-mb":  "256",
+				"logging:log-rotate:backup-file-count": "0",
+				"logging:log-rotate:compress":          "false",
+				"cache-dir":                            "",
+			},
+		},
+		{
+			name: "should return valid args with the access log file and log rotation options",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"logging:file-path:/gcsfuse-cache/logs/access.log", "logging:log-rotate:max-file-size-mb:100", "logging:log-rotate:backup-file-count:5", "logging:log-rotate:compress:true"},
+			},
+			expectedArgs: defaultFlagMap,
+			expectedConfigMapArgs: map[string]string{
+				"logging:file-path":                    "/gcsfuse-cache/logs/access.log",
+				"logging:format":                       "json",
+				"logging:log-rotate:max-file-size-mb":  "100",
+				"logging:log-rotate:backup-file-count": "5",
+				"logging:log-rotate:compress":          "true",
+				"cache-dir":                            "",
+			},
+		},
+		{
+			name: "should discard invalid log rotation options",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"logging:log-rotate:max-file-size-mb:0", "logging:log-rotate:backup-file-count:-1", "logging:log-rotate:compress:yes"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should return valid args with file cache min free percent",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"file-cache-min-free-percent=10"},
+			},
+			expectedArgs:                    defaultFlagMap,
+			expectedConfigMapArgs:           defaultConfigFileFlagMap,
+			expectedFileCacheMinFreePercent: 10,
+		},
+		{
+			name: "should discard invalid file cache min free percent",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"file-cache-min-free-percent=100"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should return valid args with source read only",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"source-read-only=true"},
+			},
+			expectedArgs: map[string]string{
+				"app-name":    GCSFuseAppName,
+				"temp-dir":    "test-buffer-dir/temp-dir",
+				"config-file": "test-config-file",
+				"foreground":  "",
+				"uid":         "0",
+				"gid":         "0",
+				"o":           "ro",
+			},
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should not set the read only fuse option when source read only is false",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"source-read-only=false"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should collect the diagnostics on failure",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"collect-diagnostics-on-failure=true"},
+			},
+			expectedArgs:               defaultFlagMap,
+			expectedConfigMapArgs:      defaultConfigFileFlagMap,
+			expectedCollectDiagnostics: true,
+		},
+		{
+			name: "should discard invalid collect diagnostics on failure",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"collect-diagnostics-on-failure=yes"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should select the gcsfuse binary path",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"gcsfuse-binary-path=/gcsfuse-binaries/gcsfuse-v3"},
+			},
+			expectedArgs:              defaultFlagMap,
+			expectedConfigMapArgs:     defaultConfigFileFlagMap,
+			expectedGCSFuseBinaryPath: "/gcsfuse-binaries/gcsfuse-v3",
+		},
+		{
+			name: "should set the request log sample rate",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"request-log-sample-rate=0.25"},
+			},
+			expectedArgs:                 defaultFlagMap,
+			expectedConfigMapArgs:        defaultConfigFileFlagMap,
+			expectedRequestLogSampleRate: ptr.To(0.25),
+		},
+		{
+			name: "should set the cache cleanup on unmount",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"cache-cleanup-on-unmount=delete"},
+			},
+			expectedArgs:                  defaultFlagMap,
+			expectedConfigMapArgs:         defaultConfigFileFlagMap,
+			expectedCacheCleanupOnUnmount: true,
+		},
+		{
+			name: "should discard invalid cache cleanup on unmount",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"cache-cleanup-on-unmount=retain"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should discard invalid request log sample rate",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"request-log-sample-rate=2"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
+		{
+			name: "should return valid args with max open files",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"max-open-files=65536"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+			expectedMaxOpenFiles:  65536,
+		},
+		{
+			name: "should discard invalid max open files",
+			mc: &MountConfig{
+				BucketName: "test-bucket",
+				BufferDir:  "test-buffer-dir",
+				CacheDir:   "test-cache-dir",
+				ConfigFile: "test-config-file",
+				Options:    []string{"max-open-files=0"},
+			},
+			expectedArgs:          defaultFlagMap,
+			expectedConfigMapArgs: defaultConfigFileFlagMap,
+		},
 	}
 
 	prometheusPort := 62990
@@ -261,6 +547,25 @@ func TestPrepareMountArgs(t *testing.T) {
 			if !reflect.DeepEqual(tc.mc.ConfigFileFlagMap, tc.expectedConfigMapArgs) {
 				t.Errorf("Got config file args %v, but expected %v", tc.mc.ConfigFileFlagMap, tc.expectedConfigMapArgs)
 			}
+
+			if tc.mc.MaxOpenFiles != tc.expectedMaxOpenFiles {
+				t.Errorf("Got max open files %v, but expected %v", tc.mc.MaxOpenFiles, tc.expectedMaxOpenFiles)
+			}
+			if tc.mc.FileCacheMinFreePercent != tc.expectedFileCacheMinFreePercent {
+				t.Errorf("Got file cache min free percent %v, but expected %v", tc.mc.FileCacheMinFreePercent, tc.expectedFileCacheMinFreePercent)
+			}
+			if tc.mc.CollectDiagnosticsOnFailure != tc.expectedCollectDiagnostics {
+				t.Errorf("Got collect diagnostics on failure %v, but expected %v", tc.mc.CollectDiagnosticsOnFailure, tc.expectedCollectDiagnostics)
+			}
+			if tc.mc.GCSFuseBinaryPath != tc.expectedGCSFuseBinaryPath {
+				t.Errorf("Got gcsfuse binary path %q, but expected %q", tc.mc.GCSFuseBinaryPath, tc.expectedGCSFuseBinaryPath)
+			}
+			if tc.mc.CacheCleanupOnUnmount != tc.expectedCacheCleanupOnUnmount {
+				t.Errorf("Got cache cleanup on unmount %v, but expected %v", tc.mc.CacheCleanupOnUnmount, tc.expectedCacheCleanupOnUnmount)
+			}
+			if !reflect.DeepEqual(tc.mc.RequestLogSampleRate, tc.expectedRequestLogSampleRate) {
+				t.Errorf("Got request log sample rate %v, but expected %v", ptr.Deref(tc.mc.RequestLogSampleRate, -1), ptr.Deref(tc.expectedRequestLogSampleRate, -1))
+			}
 		})
 	}
 }
@@ -350,6 +655,39 @@ func TestPrepareConfigFile(t *testing.T) {
 				"gcs-auth":  map[string]interface{}{"token-url": "unix:///gcsfuse-tmp/.volumes/vol1/token.sock"},
 			},
 		},
+		{
+			name: "should create valid config file with anonymous access",
+			mc: &MountConfig{
+				ConfigFile: "./test-config-file.yaml",
+				TempDir:    "/gcsfuse-tmp/.volumes/vol1",
+				ConfigFileFlagMap: map[string]string{
+					"logging:file-path":         "/dev/fd/1",
+					"gcs-auth:anonymous-access": "true",
+				},
+			},
+			expectedConfig: map[string]interface{}{
+				"logging": map[string]interface{}{
+					"file-path": "/dev/fd/1",
+				},
+				"gcs-auth": map[string]interface{}{"anonymous-access": true},
+			},
+		},
+		{
+			name: "should create valid config file with the tracing settings",
+			mc: &MountConfig{
+				ConfigFile: "./test-config-file.yaml",
+				ConfigFileFlagMap: map[string]string{
+					"monitoring:experimental-tracing-mode":           "gcptrace",
+					"monitoring:experimental-tracing-sampling-ratio": "0.25",
+				},
+			},
+			expectedConfig: map[string]interface{}{
+				"monitoring": map[string]interface{}{
+					"experimental-tracing-mode":           "gcptrace",
+					"experimental-tracing-sampling-ratio": 0.25,
+				},
+			},
+		},
 		{
 			name: "should throw error when incorrect flag is passed",
 			mc: &MountConfig{
@@ -396,3 +734,140 @@ func TestPrepareConfigFile(t *testing.T) {
 		os.Remove(tc.mc.ConfigFile)
 	}
 }
+
+func TestExpandPodMetadataEnv(t *testing.T) {
+	t.Setenv("GCSFUSE_POD_LABEL_APP", "my-app")
+	t.Setenv("GCSFUSE_POD_ANNOTATION_DIR", "data:file-cache:max-size-mb:-1")
+	t.Setenv("GCSFUSE_POD_ANNOTATION_OPTIONS", "data,implicit-dirs")
+
+	testCases := []struct {
+		arg       string
+		expected  string
+		expectErr bool
+	}{
+		{arg: "only-dir=${GCSFUSE_POD_LABEL_APP}", expected: "only-dir=my-app"},
+		{arg: "only-dir=${GCSFUSE_POD_LABEL_UNSET}/data", expected: "only-dir=/data"},
+		{arg: "only-dir=${HOME}", expected: "only-dir=${HOME}"},
+		{arg: "implicit-dirs", expected: "implicit-dirs"},
+		{arg: "only-dir=${GCSFUSE_POD_ANNOTATION_DIR}", expectErr: true},
+		{arg: "only-dir=${GCSFUSE_POD_ANNOTATION_OPTIONS}", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		mc := &MountConfig{Options: []string{tc.arg}}
+		err := mc.expandPodMetadataEnv()
+		if (err != nil) != tc.expectErr {
+			t.Errorf("Got error %v for %q, but expected error %v", err, tc.arg, tc.expectErr)
+		}
+		if !tc.expectErr && mc.Options[0] != tc.expected {
+			t.Errorf("Got %q for %q, but expected %q", mc.Options[0], tc.arg, tc.expected)
+		}
+	}
+}
+
+func TestBoundFileCacheToSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		cacheDir          string
+		maxSizeMb         string
+		limitMb           int64
+		expectedMaxSizeMb string
+	}{
+		{
+			name:              "should bound the unlimited file cache max size to the volume share",
+			cacheDir:          "test-cache-dir",
+			maxSizeMb:         "-1",
+			limitMb:           2048,
+			expectedMaxSizeMb: "2048",
+		},
+		{
+			name:              "should keep the file cache max size under the volume share",
+			cacheDir:          "test-cache-dir",
+			maxSizeMb:         "100",
+			limitMb:           2048,
+			expectedMaxSizeMb: "100",
+		},
+		{
+			name:              "should not enable the file cache for the volume share",
+			maxSizeMb:         "0",
+			limitMb:           2048,
+			expectedMaxSizeMb: "0",
+		},
+		{
+			name:              "should keep the file cache max size without a limit",
+			cacheDir:          "test-cache-dir",
+			maxSizeMb:         "-1",
+			expectedMaxSizeMb: "-1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mc := &MountConfig{
+				ConfigFileFlagMap: map[string]string{
+					"cache-dir":              tc.cacheDir,
+					"file-cache:max-size-mb": tc.maxSizeMb,
+				},
+			}
+
+			mc.boundFileCacheToSizeLimit(tc.limitMb)
+			if got := mc.ConfigFileFlagMap["file-cache:max-size-mb"]; got != tc.expectedMaxSizeMb {
+				t.Errorf("Got file cache max size %q, but expected %q", got, tc.expectedMaxSizeMb)
+			}
+		})
+	}
+}
+
+func TestFileCacheSizeLimitShareMb(t *testing.T) {
+	t.Parallel()
+
+	cachedVolume := &MountConfig{ConfigFileFlagMap: map[string]string{"cache-dir": "test-cache-dir"}}
+	uncachedVolume := &MountConfig{ConfigFileFlagMap: map[string]string{}}
+
+	testCases := []struct {
+		name                  string
+		totalCacheSizeLimitMb int64
+		mcs                   []*MountConfig
+		expectedShareMb       int64
+	}{
+		{
+			name:                  "should give the whole limit to the only cached volume",
+			totalCacheSizeLimitMb: 4096,
+			mcs:                   []*MountConfig{cachedVolume, uncachedVolume, uncachedVolume, uncachedVolume},
+			expectedShareMb:       4096,
+		},
+		{
+			name:                  "should split the limit between the cached volumes",
+			totalCacheSizeLimitMb: 4096,
+			mcs:                   []*MountConfig{cachedVolume, uncachedVolume, cachedVolume},
+			expectedShareMb:       2048,
+		},
+		{
+			name:                  "should give at least 1 MiB to each cached volume",
+			totalCacheSizeLimitMb: 1,
+			mcs:                   []*MountConfig{cachedVolume, cachedVolume},
+			expectedShareMb:       1,
+		},
+		{
+			name:                  "should return no limit without cached volumes",
+			totalCacheSizeLimitMb: 4096,
+			mcs:                   []*MountConfig{uncachedVolume},
+		},
+		{
+			name: "should return no limit without the total limit",
+			mcs:  []*MountConfig{cachedVolume},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := FileCacheSizeLimitShareMb(tc.totalCacheSizeLimitMb, CountFileCacheVolumes(tc.mcs)); got != tc.expectedShareMb {
+				t.Errorf("Got file cache size limit share %v MiB, but expected %v MiB", got, tc.expectedShareMb)
+			}
+		})
+	}
+}
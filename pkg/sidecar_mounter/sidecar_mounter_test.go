@@ -0,0 +1,353 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarmounter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/sys/unix"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestRotatingTokenSource(t *testing.T) {
+	t.Parallel()
+
+	fetchCount := 0
+	var fetchErr error
+	ts := &rotatingTokenSource{
+		clock:         clock.RealClock{},
+		refreshMargin: time.Minute,
+		fetchToken: func(_ context.Context) (*oauth2.Token, error) {
+			if fetchErr != nil {
+				return nil, fetchErr
+			}
+			fetchCount++
+
+			return &oauth2.Token{
+				AccessToken: "token-" + strconv.Itoa(fetchCount),
+				// The first token is about to expire, the following tokens are long-lived.
+				Expiry: time.Now().Add(time.Duration(fetchCount-1)*time.Hour + time.Second*30),
+			}, nil
+		},
+	}
+	handler := tokenHandler(context.Background(), ts)
+
+	getToken := func() (*oauth2.Token, int) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			return nil, w.Code
+		}
+
+		token := &oauth2.Token{}
+		if err := json.Unmarshal(w.Body.Bytes(), token); err != nil {
+			t.Fatalf("failed to unmarshal token: %v", err)
+		}
+
+		return token, w.Code
+	}
+
+	// The first token expires within the refresh margin, so the next request rotates it.
+	if token, _ := getToken(); token == nil || token.AccessToken != "token-1" {
+		t.Fatalf("expected token-1, got %v", token)
+	}
+	if token, _ := getToken(); token == nil || token.AccessToken != "token-2" {
+		t.Fatalf("expected the expiring token to be rotated to token-2, got %v", token)
+	}
+
+	// The rotated token is long-lived and served from the cache.
+	if token, _ := getToken(); token == nil || token.AccessToken != "token-2" {
+		t.Fatalf("expected the cached token-2, got %v", token)
+	}
+	if fetchCount != 2 {
+		t.Errorf("expected 2 token fetches, got %d", fetchCount)
+	}
+
+	// A failed rotation keeps serving the cached token while it is still valid.
+	ts.token.Expiry = time.Now().Add(time.Second * 30)
+	fetchErr = errors.New("sts unavailable")
+	if token, _ := getToken(); token == nil || token.AccessToken != "token-2" {
+		t.Fatalf("expected the cached token-2 to be served after a failed rotation, got %v", token)
+	}
+
+	// Once the cached token has expired, the error is surfaced.
+	ts.token.Expiry = time.Now().Add(-time.Second)
+	if _, code := getToken(); code != http.StatusInternalServerError {
+		t.Errorf("expected status %d after the cached token expired, got %d", http.StatusInternalServerError, code)
+	}
+}
+
+func TestTokenServerRefreshesExpiredToken(t *testing.T) {
+	t.Parallel()
+
+	const (
+		tokenLifetime = time.Hour
+		// gcsfuse reuses a token until it expires within the oauth2 default expiry delta.
+		gcsfuseExpiryDelta = 10 * time.Second
+	)
+	fakeClock := testingclock.NewFakeClock(time.Now())
+
+	// The fake GCS server only accepts the issued tokens before they expire.
+	var mu sync.Mutex
+	issuedTokens := map[string]time.Time{}
+	gcs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		expiry, ok := issuedTokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		mu.Unlock()
+		if !ok || fakeClock.Now().After(expiry) {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		fmt.Fprint(w, "object content")
+	}))
+	defer gcs.Close()
+
+	fetchCount := 0
+	ts := &rotatingTokenSource{
+		clock:         fakeClock,
+		refreshMargin: tokenRefreshMargin,
+		fetchToken: func(_ context.Context) (*oauth2.Token, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			fetchCount++
+			token := &oauth2.Token{
+				AccessToken: "token-" + strconv.Itoa(fetchCount),
+				TokenType:   "Bearer",
+				Expiry:      fakeClock.Now().Add(tokenLifetime),
+			}
+			issuedTokens[token.AccessToken] = token.Expiry
+
+			return token, nil
+		},
+	}
+
+	// The unix domain socket path length is limited, so the socket is not created in t.TempDir().
+	socketDir, err := os.MkdirTemp("", "token")
+	if err != nil {
+		t.Fatalf("failed to create the socket dir: %v", err)
+	}
+	defer os.RemoveAll(socketDir)
+	socketPath := filepath.Join(socketDir, TokenFileName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go serveTokens(ctx, socketPath, ts)
+
+	// gcsfuse fetches the token from the token-url socket and reuses it until it expires.
+	socketClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	var gcsfuseToken *oauth2.Token
+	gcsfuseTokenSource := tokenSourceFunc(func() (*oauth2.Token, error) {
+		if gcsfuseToken != nil && gcsfuseToken.Expiry.Sub(fakeClock.Now()) > gcsfuseExpiryDelta {
+			return gcsfuseToken, nil
+		}
+
+		resp, err := socketClient.Get("http://unix/")
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("token server returned status %d", resp.StatusCode)
+		}
+
+		token := &oauth2.Token{}
+		if err := json.NewDecoder(resp.Body).Decode(token); err != nil {
+			return nil, err
+		}
+		gcsfuseToken = token
+
+		return token, nil
+	})
+	// oauth2.NewClient would wrap the token source with a reuse token source using the real clock.
+	gcsClient := &http.Client{Transport: &oauth2.Transport{Source: gcsfuseTokenSource}}
+
+	read := func() {
+		t.Helper()
+		resp, err := gcsClient.Get(gcs.URL)
+		if err != nil {
+			t.Fatalf("failed to read the object: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d reading the object, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+	expectFetchCount := func(expected int) {
+		t.Helper()
+		mu.Lock()
+		defer mu.Unlock()
+		if fetchCount != expected {
+			t.Errorf("expected %d token fetches, got %d", expected, fetchCount)
+		}
+	}
+
+	// Wait for the token server to listen on the socket.
+	for i := 0; ; i++ {
+		if _, err := os.Stat(socketPath); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("the token server did not create the socket %q", socketPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	read()
+	expectFetchCount(1)
+
+	// The token is reused before it expires.
+	fakeClock.Step(tokenLifetime / 2)
+	read()
+	expectFetchCount(1)
+
+	// The ongoing reads keep working after the token used by the mount expires.
+	fakeClock.Step(tokenLifetime/2 + time.Second)
+	read()
+	expectFetchCount(2)
+}
+
+// tokenSourceFunc adapts a function to oauth2.TokenSource.
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+// The tests change the open files limit of the test process, so they do not run in parallel.
+func TestStartWithMaxOpenFiles(t *testing.T) {
+	var rlimit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &rlimit); err != nil {
+		t.Fatalf("failed to get the open files limit: %v", err)
+	}
+
+	// Lowering the soft limit is always allowed for an unprivileged user.
+	limit := min(rlimit.Cur, 1024) - 1
+	var out strings.Builder
+	cmd := exec.Command("sh", "-c", "ulimit -n")
+	cmd.Stdout = &out
+	if err := startWithMaxOpenFiles(cmd, limit); err != nil {
+		t.Fatalf("failed to start the process: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("the process failed: %v", err)
+	}
+
+	if got := strings.TrimSpace(out.String()); got != strconv.FormatUint(limit, 10) {
+		t.Errorf("Got open files limit %v, but expected %v", got, limit)
+	}
+
+	var restored unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &restored); err != nil {
+		t.Fatalf("failed to get the open files limit: %v", err)
+	}
+	if restored != rlimit {
+		t.Errorf("Got open files limit %+v after the start, but expected %+v", restored, rlimit)
+	}
+}
+
+func TestStartWithMaxOpenFilesAboveSystemLimit(t *testing.T) {
+	// No process can raise the limit above fs.nr_open, which is at most 2^30.
+	cmd := exec.Command("true")
+	if err := startWithMaxOpenFiles(cmd, 1<<40); err == nil {
+		_ = cmd.Wait()
+		t.Fatalf("expected an error for an open files limit above the system limit, got nil")
+	}
+	if cmd.Process != nil {
+		t.Errorf("expected the process not to start when the open files limit cannot be set")
+	}
+}
+
+func TestRemoveCacheDir(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                  string
+		cacheCleanupOnUnmount bool
+		fileCacheEnabled      bool
+		expectCacheDirExists  bool
+	}{
+		{
+			name:                 "should retain the cache dir by default",
+			fileCacheEnabled:     true,
+			expectCacheDirExists: true,
+		},
+		{
+			name:                  "should remove the cache dir in delete mode",
+			cacheCleanupOnUnmount: true,
+			fileCacheEnabled:      true,
+		},
+		{
+			name:                  "should not remove the dir when the file cache is disabled",
+			cacheCleanupOnUnmount: true,
+			expectCacheDirExists:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := filepath.Join(t.TempDir(), ".volumes", "test-volume")
+			if err := os.MkdirAll(cacheDir, 0o750); err != nil {
+				t.Fatalf("failed to create the cache dir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(cacheDir, "cached-object"), []byte("data"), 0o600); err != nil {
+				t.Fatalf("failed to write the cache file: %v", err)
+			}
+
+			mc := &MountConfig{
+				VolumeName:            "test-volume",
+				CacheDir:              cacheDir,
+				ConfigFileFlagMap:     map[string]string{},
+				CacheCleanupOnUnmount: tc.cacheCleanupOnUnmount,
+			}
+			if tc.fileCacheEnabled {
+				mc.ConfigFileFlagMap["cache-dir"] = cacheDir
+			}
+			removeCacheDir(mc)
+
+			_, err := os.Stat(cacheDir)
+			if exists := err == nil; exists != tc.expectCacheDirExists {
+				t.Errorf("Got cache dir exists %v, but expected %v", exists, tc.expectCacheDirExists)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/mod/semver"
+)
+
+// The gcsfuse file cache layout versions, which define where gcsfuse stores the cached objects in the volume cache dir.
+const (
+	// FileCacheLayoutV1 stores an object at <cache-dir>/gcsfuse-file-cache/<bucket-name>/<object-name>.
+	FileCacheLayoutV1 = "v1"
+)
+
+// fileCacheLayoutsByMinVersion maps the minimum gcsfuse versions to the file cache layout versions they use,
+// sorted by the gcsfuse version.
+var fileCacheLayoutsByMinVersion = []struct {
+	minVersion    string
+	layoutVersion string
+}{
+	{minVersion: "v0.0.0", layoutVersion: FileCacheLayoutV1},
+}
+
+// fileCacheLayoutSubpaths maps the file cache layout versions to the subpath of a cached object in the volume cache dir.
+var fileCacheLayoutSubpaths = map[string]func(bucketName, objectName string) string{
+	FileCacheLayoutV1: func(bucketName, objectName string) string {
+		return filepath.Join("gcsfuse-file-cache", bucketName, objectName)
+	},
+}
+
+// FileCacheLayoutVersion returns the file cache layout version of the gcsfuse version, e.g. v2.11.1.
+// The latest layout version is returned if the gcsfuse version is unknown.
+func FileCacheLayoutVersion(gcsfuseVersion string) string {
+	layoutVersion := fileCacheLayoutsByMinVersion[len(fileCacheLayoutsByMinVersion)-1].layoutVersion
+	if !semver.IsValid(gcsfuseVersion) {
+		return layoutVersion
+	}
+
+	for _, l := range fileCacheLayoutsByMinVersion {
+		if semver.Compare(gcsfuseVersion, l.minVersion) >= 0 {
+			layoutVersion = l.layoutVersion
+		}
+	}
+
+	return layoutVersion
+}
+
+// FileCacheObjectPath returns the path of a cached object in the volume cache dir for the file cache layout version.
+func FileCacheObjectPath(cacheDir, layoutVersion, bucketName, objectName string) (string, error) {
+	subpath, ok := fileCacheLayoutSubpaths[layoutVersion]
+	if !ok {
+		return "", fmt.Errorf("unknown file cache layout version %q", layoutVersion)
+	}
+
+	return filepath.Join(cacheDir, subpath(bucketName, objectName)), nil
+}
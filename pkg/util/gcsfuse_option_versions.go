@@ -0,0 +1,40 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// GCSFuseOptionMinVersion is the minimum version of gcsfuse supporting an optional gcsfuse mount option,
+// and the minimum version of the managed sidecar image shipping that gcsfuse.
+type GCSFuseOptionMinVersion struct {
+	GCSFuse string
+	// Sidecar is empty when the CSI driver does not check the option against the managed sidecar version,
+	// the sidecar mounter still checks the option against its gcsfuse binary.
+	Sidecar string
+}
+
+// GCSFuseOptionPrefixesToMinVersion maps the optional gcsfuse mount option prefixes to the minimum versions supporting them.
+// The CSI driver validates the options against the managed sidecar image version before mounting,
+// and the sidecar mounter validates them against the gcsfuse binary version.
+var GCSFuseOptionPrefixesToMinVersion = map[string]GCSFuseOptionMinVersion{
+	"file-cache:enable-parallel-downloads":    {GCSFuse: "v2.2.0"},
+	"gcs-retries:read-stall:":                 {GCSFuse: "v2.5.0", Sidecar: "v1.14.0-gke.0"},
+	"file-cache:enable-o-direct":              {GCSFuse: "v2.6.0", Sidecar: "v1.15.0-gke.0"},
+	"write:enable-streaming-writes":           {GCSFuse: "v2.9.0"},
+	"gcs-retries:chunk-transfer-timeout-secs": {GCSFuse: "v2.10.0"},
+	"file-system:enable-parallel-dirops":      {GCSFuse: "v3.0.0"},
+	"read:enable-buffered-read":               {GCSFuse: "v3.1.0"},
+}
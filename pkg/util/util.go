@@ -159,6 +159,36 @@ func PrepareEmptyDir(targetPath string, createEmptyDir bool) (string, error) {
 	return emptyDirBasePath, nil
 }
 
+// CacheDirPath returns the per-volume cache directory in the sidecar cache emptyDir.
+func CacheDirPath(targetPath string) string {
+	return emptyReplacementRegexp.ReplaceAllString(targetPath, fmt.Sprintf("kubernetes.io~empty-dir/%v/.volumes/$1", webhook.SidecarContainerCacheVolumeName))
+}
+
+// PrepareCacheDir creates the per-volume cache directory in the sidecar cache emptyDir,
+// and sets the owner so both the Pod user and the sidecar container can use it.
+func PrepareCacheDir(targetPath string, uid, gid int) (string, error) {
+	_, _, err := ParsePodIDVolumeFromTargetpath(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse volume name from target path %q: %w", targetPath, err)
+	}
+
+	cacheDirPath := CacheDirPath(targetPath)
+	if err := os.MkdirAll(cacheDirPath, 0o770); err != nil {
+		return "", fmt.Errorf("mkdir failed for path %q: %w", cacheDirPath, err)
+	}
+
+	if err := os.Chown(cacheDirPath, uid, gid); err != nil {
+		return "", fmt.Errorf("chown failed for path %q: %w", cacheDirPath, err)
+	}
+
+	// The setgid bit makes the files created by gcsfuse inherit the group.
+	if err := os.Chmod(cacheDirPath, 0o770|os.ModeSetgid); err != nil {
+		return "", fmt.Errorf("chmod failed for path %q: %w", cacheDirPath, err)
+	}
+
+	return cacheDirPath, nil
+}
+
 func GetSocketBasePath(targetPath, fuseSocketDir string) string {
 	podID, volumeName, _ := ParsePodIDVolumeFromTargetpath(targetPath)
 
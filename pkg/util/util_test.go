@@ -19,7 +19,10 @@ package util
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"syscall"
 	"testing"
 
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
@@ -348,3 +351,95 @@ func TestPrepareEmptyDir(t *testing.T) {
 		}
 	}
 }
+
+func TestPrepareCacheDir(t *testing.T) {
+	t.Parallel()
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of the cache directory requires root")
+	}
+
+	podPath, err := os.MkdirTemp("", "prepare-cache-dir-")
+	if err != nil {
+		t.Fatalf("failed to setup testdir: %v", err)
+	}
+	defer os.RemoveAll(podPath)
+
+	targetPath := filepath.Join(podPath, "var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/test-volume/mount")
+	expectedCacheDirPath := filepath.Join(podPath, "var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~empty-dir", webhook.SidecarContainerCacheVolumeName, ".volumes/test-volume")
+
+	cacheDirPath, err := PrepareCacheDir(targetPath, 1001, 3003)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if cacheDirPath != expectedCacheDirPath {
+		t.Errorf("Got cacheDirPath %v, but expected %v", cacheDirPath, expectedCacheDirPath)
+	}
+
+	fi, err := os.Stat(cacheDirPath)
+	if err != nil {
+		t.Fatalf("failed to stat the cache dir: %v", err)
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Fatalf("failed to get the owner of the cache dir")
+	}
+	if stat.Uid != 1001 || stat.Gid != 3003 {
+		t.Errorf("Got owner %v:%v, but expected 1001:3003", stat.Uid, stat.Gid)
+	}
+	if fi.Mode().Perm() != 0o770 || fi.Mode()&os.ModeSetgid == 0 {
+		t.Errorf("Got mode %v, but expected the setgid bit and permission 0770", fi.Mode())
+	}
+
+	if _, err := PrepareCacheDir("/foo/bar/volumes", 1001, 3003); err == nil {
+		t.Errorf("Expected error for an invalid target path but got none")
+	}
+}
+
+func TestFileCacheObjectPath(t *testing.T) {
+	t.Parallel()
+
+	targetPath := "/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~csi/test-volume/mount"
+	cacheDir := CacheDirPath(targetPath)
+
+	testCases := []struct {
+		name           string
+		gcsfuseVersion string
+		layoutVersion  string
+		expectedPath   string
+		expectErr      bool
+	}{
+		{
+			name:           "should resolve the v1 layout for a released gcsfuse version",
+			gcsfuseVersion: "v2.11.1",
+			expectedPath:   "/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~empty-dir/" + webhook.SidecarContainerCacheVolumeName + "/.volumes/test-volume/gcsfuse-file-cache/test-bucket/dir/object",
+		},
+		{
+			name:           "should resolve the latest layout for an unknown gcsfuse version",
+			gcsfuseVersion: "unknown",
+			expectedPath:   "/var/lib/kubelet/pods/test-pod-id/volumes/kubernetes.io~empty-dir/" + webhook.SidecarContainerCacheVolumeName + "/.volumes/test-volume/gcsfuse-file-cache/test-bucket/dir/object",
+		},
+		{
+			name:          "should return error for an unknown layout version",
+			layoutVersion: "v0",
+			expectErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			layoutVersion := tc.layoutVersion
+			if layoutVersion == "" {
+				layoutVersion = FileCacheLayoutVersion(tc.gcsfuseVersion)
+			}
+
+			path, err := FileCacheObjectPath(cacheDir, layoutVersion, "test-bucket", "dir/object")
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if path != tc.expectedPath {
+				t.Errorf("Got path %v, but expected %v", path, tc.expectedPath)
+			}
+		})
+	}
+}
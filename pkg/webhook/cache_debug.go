@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cacheDebugArgs returns the sidecar mounter args enabling the debug endpoint listing the file cache of the volumes
+// on the given loopback port, or no args if the value is empty.
+// The endpoint must be enabled by the cluster admin, and it is refused for the Pods using the host network,
+// where the loopback interface is the node one, shared with all the other host network Pods of the node.
+func cacheDebugArgs(value string, enabled, hostNetwork bool) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if !enabled {
+		return nil, fmt.Errorf("the annotation %q is not permitted in this cluster, the webhook must be started with --enable-cache-debug-endpoint", cacheDebugPortAnnotation)
+	}
+
+	if hostNetwork {
+		return nil, fmt.Errorf("the annotation %q is not supported for the Pods using the host network", cacheDebugPortAnnotation)
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("the acceptable values for %q are port numbers between 1 and 65535, got %q", cacheDebugPortAnnotation, value)
+	}
+
+	return []string{"--cache-debug-port=" + strconv.Itoa(port)}, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCacheDebugArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName     string
+		value        string
+		disabled     bool
+		hostNetwork  bool
+		expectedArgs []string
+		expectErr    bool
+	}{
+		{
+			testName: "no value",
+		},
+		{
+			testName: "no value when disabled",
+			disabled: true,
+		},
+		{
+			testName:     "valid value",
+			value:        " 9921 ",
+			expectedArgs: []string{"--cache-debug-port=9921"},
+		},
+		{
+			testName:  "valid value when disabled",
+			value:     "9921",
+			disabled:  true,
+			expectErr: true,
+		},
+		{
+			testName:    "valid value with the host network",
+			value:       "9921",
+			hostNetwork: true,
+			expectErr:   true,
+		},
+		{
+			testName:  "zero port",
+			value:     "0",
+			expectErr: true,
+		},
+		{
+			testName:  "port out of range",
+			value:     "65536",
+			expectErr: true,
+		},
+		{
+			testName:  "address value",
+			value:     "0.0.0.0:9921",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			args, err := cacheDebugArgs(tc.value, !tc.disabled, tc.hostNetwork)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if diff := cmp.Diff(tc.expectedArgs, args); diff != "" {
+				t.Errorf("unexpected args (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithCacheDebugPort(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName    string
+		enabled     bool
+		hostNetwork bool
+		expectErr   bool
+	}{
+		{
+			testName: "endpoint enabled by the admin",
+			enabled:  true,
+		},
+		{
+			testName:  "endpoint not enabled by the admin",
+			expectErr: true,
+		},
+		{
+			testName:    "endpoint enabled by the admin with the host network",
+			enabled:     true,
+			hostNetwork: true,
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{cacheDebugPortAnnotation: "9921"}},
+				Spec: corev1.PodSpec{
+					HostNetwork: tc.hostNetwork,
+					Containers:  []corev1.Container{{Name: "workload"}},
+				},
+			}
+
+			si := SidecarInjector{Config: FakeConfig(), EnableCacheDebugEndpoint: tc.enabled}
+			err := si.injectSidecarContainer(GcsFuseSidecarName, pod, false)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			if !slices.Contains(pod.Spec.Containers[0].Args, "--cache-debug-port=9921") {
+				t.Errorf("expected the sidecar container args to contain the cache debug port, got %v", pod.Spec.Containers[0].Args)
+			}
+		})
+	}
+}
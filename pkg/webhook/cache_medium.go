@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// Supported values of the cacheMedium volume attribute.
+const (
+	cacheMediumDisk   = "disk"
+	cacheMediumMemory = "memory"
+	cacheMediumSSD    = "ssd"
+)
+
+// localSSDEphemeralStorageNodeLabel selects the GKE nodes whose emptyDir volumes are backed by local SSDs.
+const localSSDEphemeralStorageNodeLabel = "cloud.google.com/gke-ephemeral-storage-local-ssd"
+
+// podCacheMedium returns the cacheMedium of the gcsfuse csi driver volumes of the Pod,
+// or an empty string if no volume sets it. All the volumes share the sidecar cache volume,
+// so the volumes setting the cacheMedium must agree on the value.
+func (si *SidecarInjector) podCacheMedium(pod *corev1.Pod) (string, error) {
+	medium, mediumVolume := "", ""
+	for _, v := range pod.Spec.Volumes {
+		isGcsFuseCSIVolume, _, volumeAttributes, _, err := si.getGcsFuseCSIVolumeOptions(v, pod.Namespace)
+		if err != nil || !isGcsFuseCSIVolume {
+			continue
+		}
+
+		value, ok := volumeAttributes[volumeAttributeCacheMedium]
+		if !ok {
+			continue
+		}
+
+		if value != cacheMediumDisk && value != cacheMediumMemory && value != cacheMediumSSD {
+			return "", fmt.Errorf("volume %q has an invalid volume attribute %v %q, the acceptable values are %q, %q or %q", v.Name, volumeAttributeCacheMedium, value, cacheMediumDisk, cacheMediumMemory, cacheMediumSSD)
+		}
+
+		if medium != "" && medium != value {
+			return "", fmt.Errorf("volumes %q and %q set different values %q and %q for the volume attribute %v, but the volumes share the same cache volume", mediumVolume, v.Name, medium, value, volumeAttributeCacheMedium)
+		}
+		medium, mediumVolume = value, v.Name
+	}
+
+	return medium, nil
+}
+
+// applyCacheMedium backs the injected sidecar cache volume by the given medium:
+// the disk medium uses the node boot disk, the memory medium uses a tmpfs emptyDir,
+// and the ssd medium schedules the Pod on the nodes whose emptyDir volumes are backed by local SSDs.
+// A custom cache volume is left as is.
+func applyCacheMedium(pod *corev1.Pod, sidecarVolumes []corev1.Volume, medium string) error {
+	if medium == "" {
+		return nil
+	}
+
+	index := -1
+	for i, v := range sidecarVolumes {
+		if v.Name == SidecarContainerCacheVolumeName {
+			index = i
+
+			break
+		}
+	}
+	if index < 0 {
+		klog.Warningf("the Pod has a custom cache volume %q, ignoring the volume attribute %v %q", SidecarContainerCacheVolumeName, volumeAttributeCacheMedium, medium)
+
+		return nil
+	}
+
+	switch medium {
+	case cacheMediumMemory:
+		sidecarVolumes[index] = corev1.Volume{
+			Name: SidecarContainerCacheVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		}
+	case cacheMediumSSD:
+		if value, ok := pod.Spec.NodeSelector[localSSDEphemeralStorageNodeLabel]; ok && value != "true" {
+			return fmt.Errorf("the volume attribute %v %q requires the node selector %v=true, but the Pod selects %v=%v", volumeAttributeCacheMedium, medium, localSSDEphemeralStorageNodeLabel, localSSDEphemeralStorageNodeLabel, value)
+		}
+
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		pod.Spec.NodeSelector[localSSDEphemeralStorageNodeLabel] = "true"
+	}
+
+	return nil
+}
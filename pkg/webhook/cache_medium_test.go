@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func cacheMediumVolume(name, medium string) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			CSI: &corev1.CSIVolumeSource{
+				Driver:           gcsFuseCsiDriverName,
+				VolumeAttributes: map[string]string{"bucketName": "test-bucket", volumeAttributeCacheMedium: medium},
+			},
+		},
+	}
+}
+
+func TestPodCacheMedium(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		volumes        []corev1.Volume
+		expectedMedium string
+		expectErr      bool
+	}{
+		{
+			name:    "no volume sets the cache medium",
+			volumes: []corev1.Volume{{Name: "empty-dir", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		},
+		{
+			name:           "volumes with the same cache medium",
+			volumes:        []corev1.Volume{cacheMediumVolume("vol-1", cacheMediumMemory), cacheMediumVolume("vol-2", cacheMediumMemory)},
+			expectedMedium: cacheMediumMemory,
+		},
+		{
+			name:      "volumes with different cache mediums should be rejected",
+			volumes:   []corev1.Volume{cacheMediumVolume("vol-1", cacheMediumMemory), cacheMediumVolume("vol-2", cacheMediumSSD)},
+			expectErr: true,
+		},
+		{
+			name:      "invalid cache medium should be rejected",
+			volumes:   []corev1.Volume{cacheMediumVolume("vol-1", "tape")},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			si := &SidecarInjector{}
+			medium, err := si.podCacheMedium(&corev1.Pod{Spec: corev1.PodSpec{Volumes: tc.volumes}})
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if medium != tc.expectedMedium {
+				t.Errorf("Got cache medium %q, but expected %q", medium, tc.expectedMedium)
+			}
+		})
+	}
+}
+
+func TestApplyCacheMedium(t *testing.T) {
+	t.Parallel()
+
+	customCacheVolume := corev1.Volume{
+		Name: SidecarContainerCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "cache-pvc"},
+		},
+	}
+
+	testCases := []struct {
+		name                 string
+		medium               string
+		existingVolumes      []corev1.Volume
+		nodeSelector         map[string]string
+		expectedCacheVolume  *corev1.Volume
+		expectedNodeSelector map[string]string
+		expectErr            bool
+	}{
+		{
+			name:                "no cache medium keeps the default cache volume",
+			expectedCacheVolume: &cacheVolume,
+		},
+		{
+			name:                "disk cache medium uses the default cache volume",
+			medium:              cacheMediumDisk,
+			expectedCacheVolume: &cacheVolume,
+		},
+		{
+			name:   "memory cache medium uses a tmpfs cache volume",
+			medium: cacheMediumMemory,
+			expectedCacheVolume: &corev1.Volume{
+				Name: SidecarContainerCacheVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+				},
+			},
+		},
+		{
+			name:                 "ssd cache medium selects the local SSD nodes",
+			medium:               cacheMediumSSD,
+			nodeSelector:         map[string]string{"pool": "gpu"},
+			expectedCacheVolume:  &cacheVolume,
+			expectedNodeSelector: map[string]string{"pool": "gpu", localSSDEphemeralStorageNodeLabel: "true"},
+		},
+		{
+			name:         "ssd cache medium conflicting with the Pod node selector should be rejected",
+			medium:       cacheMediumSSD,
+			nodeSelector: map[string]string{localSSDEphemeralStorageNodeLabel: "false"},
+			expectErr:    true,
+		},
+		{
+			name:            "custom cache volume is kept",
+			medium:          cacheMediumMemory,
+			existingVolumes: []corev1.Volume{customCacheVolume},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: tc.nodeSelector, Volumes: tc.existingVolumes}}
+			sidecarVolumes := GetSidecarContainerVolumeSpec(tc.existingVolumes...)
+
+			err := applyCacheMedium(pod, sidecarVolumes, tc.medium)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			var gotCacheVolume *corev1.Volume
+			for i := range sidecarVolumes {
+				if sidecarVolumes[i].Name == SidecarContainerCacheVolumeName {
+					gotCacheVolume = &sidecarVolumes[i]
+				}
+			}
+			if diff := cmp.Diff(tc.expectedCacheVolume, gotCacheVolume); diff != "" {
+				t.Errorf("unexpected cache volume (-want +got):\n%s", diff)
+			}
+
+			expectedNodeSelector := tc.expectedNodeSelector
+			if expectedNodeSelector == nil {
+				expectedNodeSelector = tc.nodeSelector
+			}
+			if diff := cmp.Diff(expectedNodeSelector, pod.Spec.NodeSelector); diff != "" {
+				t.Errorf("unexpected node selector (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -46,17 +46,68 @@ type Config struct {
 	EphemeralStorageLimit resource.Quantity `json:"ephemeral-storage-limit,omitempty"`
 }
 
+// LoadConfig is like ParseConfig but panics if the settings are invalid.
+// It simplifies the initialization of the configs from constant settings.
 func LoadConfig(containerImage, imagePullPolicy, cpuRequest, cpuLimit, memoryRequest, memoryLimit, ephemeralStorageRequest, ephemeralStorageLimit string) *Config {
-	return &Config{
-		ContainerImage:          containerImage,
-		ImagePullPolicy:         imagePullPolicy,
-		CPURequest:              resource.MustParse(cpuRequest),
-		CPULimit:                resource.MustParse(cpuLimit),
-		MemoryRequest:           resource.MustParse(memoryRequest),
-		MemoryLimit:             resource.MustParse(memoryLimit),
-		EphemeralStorageRequest: resource.MustParse(ephemeralStorageRequest),
-		EphemeralStorageLimit:   resource.MustParse(ephemeralStorageLimit),
+	config, err := ParseConfig(containerImage, imagePullPolicy, cpuRequest, cpuLimit, memoryRequest, memoryLimit, ephemeralStorageRequest, ephemeralStorageLimit)
+	if err != nil {
+		panic(err)
+	}
+
+	return config
+}
+
+// ParseConfig builds a Config from the cluster-wide default sidecar resource settings,
+// returning an error instead of panicking when a quantity is malformed or inconsistent.
+func ParseConfig(containerImage, imagePullPolicy, cpuRequest, cpuLimit, memoryRequest, memoryLimit, ephemeralStorageRequest, ephemeralStorageLimit string) (*Config, error) {
+	config := &Config{
+		ContainerImage:  containerImage,
+		ImagePullPolicy: imagePullPolicy,
 	}
+
+	for _, q := range []struct {
+		name  string
+		value string
+		dst   *resource.Quantity
+	}{
+		{"cpu request", cpuRequest, &config.CPURequest},
+		{"cpu limit", cpuLimit, &config.CPULimit},
+		{"memory request", memoryRequest, &config.MemoryRequest},
+		{"memory limit", memoryLimit, &config.MemoryLimit},
+		{"ephemeral storage request", ephemeralStorageRequest, &config.EphemeralStorageRequest},
+		{"ephemeral storage limit", ephemeralStorageLimit, &config.EphemeralStorageLimit},
+	} {
+		parsed, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s %q: %w", q.name, q.value, err)
+		}
+		if parsed.Sign() < 0 {
+			return nil, fmt.Errorf("%s %q must not be negative", q.name, q.value)
+		}
+		*q.dst = parsed
+	}
+
+	if err := validateRequestNotAboveLimit("cpu", config.CPURequest, config.CPULimit); err != nil {
+		return nil, err
+	}
+	if err := validateRequestNotAboveLimit("memory", config.MemoryRequest, config.MemoryLimit); err != nil {
+		return nil, err
+	}
+	if err := validateRequestNotAboveLimit("ephemeral storage", config.EphemeralStorageRequest, config.EphemeralStorageLimit); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// validateRequestNotAboveLimit returns an error when a non-zero limit is lower than the request.
+// A zero limit means unlimited and is always valid.
+func validateRequestNotAboveLimit(resourceName string, request, limit resource.Quantity) error {
+	if !limit.IsZero() && request.Cmp(limit) > 0 {
+		return fmt.Errorf("%s request %q must not be greater than %s limit %q", resourceName, request.String(), resourceName, limit.String())
+	}
+
+	return nil
 }
 
 func FakeConfig() *Config {
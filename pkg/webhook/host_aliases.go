@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// hostAliases returns the Pod host aliases of the comma separated <HOSTNAME>=<IP> pairs,
+// e.g. storage.googleapis.com=199.36.153.8. The hostnames resolving to the same IP are grouped in one host alias.
+// The kubelet writes the host aliases to the /etc/hosts file shared by all the containers of the Pod,
+// so the gcsfuse process resolves the GCS endpoints to the pinned IPs regardless of the DNS configuration.
+func hostAliases(pairs string) ([]corev1.HostAlias, error) {
+	aliases := []corev1.HostAlias{}
+	ipIndex := map[string]int{}
+	for _, pair := range strings.Split(pairs, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		hostname, ip, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("the host alias %q in the annotation %q must be in the format <HOSTNAME>=<IP>", pair, hostAliasesAnnotation)
+		}
+
+		hostname, ip = strings.TrimSpace(hostname), strings.TrimSpace(ip)
+		if errs := validation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+			return nil, fmt.Errorf("the host alias %q in the annotation %q has an invalid hostname %q: %v", pair, hostAliasesAnnotation, hostname, strings.Join(errs, ", "))
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("the host alias %q in the annotation %q has an invalid IP %q", pair, hostAliasesAnnotation, ip)
+		}
+
+		if i, ok := ipIndex[ip]; ok {
+			aliases[i].Hostnames = append(aliases[i].Hostnames, hostname)
+
+			continue
+		}
+		ipIndex[ip] = len(aliases)
+		aliases = append(aliases, corev1.HostAlias{IP: ip, Hostnames: []string{hostname}})
+	}
+
+	return aliases, nil
+}
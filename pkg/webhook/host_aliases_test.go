@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHostAliases(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName        string
+		pairs           string
+		expectedAliases []corev1.HostAlias
+		expectErr       bool
+	}{
+		{
+			testName:        "no pairs",
+			expectedAliases: []corev1.HostAlias{},
+		},
+		{
+			testName: "valid pairs",
+			pairs:    "storage.googleapis.com=199.36.153.8, oauth2.googleapis.com=199.36.153.8,metadata.google.internal=2001:db8::1",
+			expectedAliases: []corev1.HostAlias{
+				{IP: "199.36.153.8", Hostnames: []string{"storage.googleapis.com", "oauth2.googleapis.com"}},
+				{IP: "2001:db8::1", Hostnames: []string{"metadata.google.internal"}},
+			},
+		},
+		{
+			testName:  "pair without IP",
+			pairs:     "storage.googleapis.com",
+			expectErr: true,
+		},
+		{
+			testName:  "invalid hostname",
+			pairs:     "storage_googleapis.com=199.36.153.8",
+			expectErr: true,
+		},
+		{
+			testName:  "invalid IP",
+			pairs:     "storage.googleapis.com=199.36.153",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			aliases, err := hostAliases(tc.pairs)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if diff := cmp.Diff(tc.expectedAliases, aliases); diff != "" {
+				t.Errorf("unexpected host aliases (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithHostAliases(t *testing.T) {
+	t.Parallel()
+
+	userAlias := corev1.HostAlias{IP: "10.0.0.1", Hostnames: []string{"workload.internal"}}
+
+	testCases := []struct {
+		testName        string
+		annotations     map[string]string
+		expectedAliases []corev1.HostAlias
+		expectErr       bool
+	}{
+		{
+			testName:        "no annotation",
+			expectedAliases: []corev1.HostAlias{userAlias},
+		},
+		{
+			testName:    "annotation with valid pairs",
+			annotations: map[string]string{hostAliasesAnnotation: "storage.googleapis.com=199.36.153.8"},
+			expectedAliases: []corev1.HostAlias{
+				userAlias,
+				{IP: "199.36.153.8", Hostnames: []string{"storage.googleapis.com"}},
+			},
+		},
+		{
+			testName:        "annotation with invalid IP",
+			annotations:     map[string]string{hostAliasesAnnotation: "storage.googleapis.com=invalid"},
+			expectedAliases: []corev1.HostAlias{userAlias},
+			expectErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec: corev1.PodSpec{
+					Containers:  []corev1.Container{{Name: "workload"}},
+					HostAliases: []corev1.HostAlias{userAlias},
+				},
+			}
+
+			si := SidecarInjector{Config: FakeConfig()}
+			err := si.injectSidecarContainer(GcsFuseSidecarName, pod, true)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if diff := cmp.Diff(tc.expectedAliases, pod.Spec.HostAliases); diff != "" {
+				t.Errorf("unexpected Pod host aliases (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
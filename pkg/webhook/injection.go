@@ -108,6 +108,20 @@ func (si *SidecarInjector) injectSidecarContainer(containerName string, pod *cor
 	}
 	config.PodHostNetworkSetting = pod.Spec.HostNetwork
 
+	// Account for the memory of the gcsfuse read buffers on top of the default sidecar memory.
+	// The memory set by the Pod annotations is kept as is, the user sizes it for the read buffers.
+	_, memoryLimitAnnotated := pod.Annotations[memoryLimitAnnotation]
+	_, memoryRequestAnnotated := pod.Annotations[memoryRequestAnnotation]
+	if containerName == GcsFuseSidecarName && !memoryLimitAnnotated && !memoryRequestAnnotated {
+		if bufferMemory := si.bufferedReadMemory(pod); !bufferMemory.IsZero() {
+			config.MemoryRequest = addQuantity(config.MemoryRequest, bufferMemory)
+			// A zero limit means unlimited.
+			if !config.MemoryLimit.IsZero() {
+				config.MemoryLimit = addQuantity(config.MemoryLimit, bufferMemory)
+			}
+		}
+	}
+
 	// Extract user provided metadata prefetch sidecar image.
 	if userProvidedSidecarImage, err := ExtractImageAndDeleteContainer(&pod.Spec, containerName); err == nil {
 		if userProvidedSidecarImage != "" {
@@ -121,14 +135,57 @@ func (si *SidecarInjector) injectSidecarContainer(containerName string, pod *cor
 	if injectAsNativeSidecar {
 		containerSpec = si.getNativeContainerSpec(containerName, pod, config)
 		index = getInjectIndexAfterContainer(pod.Spec.InitContainers, containerIndexOrderMap[containerName])
+		if position, ok := pod.Annotations[sidecarPositionAnnotation]; ok && containerName == GcsFuseSidecarName {
+			if index, err = sidecarInjectIndex(pod.Spec.InitContainers, position); err != nil {
+				return err
+			}
+		}
 	} else {
 		containerSpec = si.getContainerSpec(containerName, pod, config)
 		index = getInjectIndexAfterContainer(pod.Spec.Containers, containerIndexOrderMap[containerName])
+		if _, ok := pod.Annotations[sidecarPositionAnnotation]; ok && containerName == GcsFuseSidecarName {
+			klog.Warningf("the annotation %q only applies to the native sidecar container, ignoring it", sidecarPositionAnnotation)
+		}
 	}
 
-	// Skip metadata prefetch sidecar injection if no volumes are requesting metadata prefetch.
+	// Expose the selected Pod metadata to the sidecar mounter via the downward API.
+	if containerName == GcsFuseSidecarName {
+		env, err := podMetadataEnv(pod.Annotations[podMetadataEnvAnnotation])
+		if err != nil {
+			return err
+		}
+		containerSpec.Env = append(containerSpec.Env, env...)
+
+		args, err := totalCacheSizeLimitArgs(pod.Annotations[totalCacheSizeLimitMbAnnotation])
+		if err != nil {
+			return err
+		}
+		containerSpec.Args = append(containerSpec.Args, args...)
+
+		args, err = cacheDebugArgs(pod.Annotations[cacheDebugPortAnnotation], si.EnableCacheDebugEndpoint, pod.Spec.HostNetwork)
+		if err != nil {
+			return err
+		}
+		containerSpec.Args = append(containerSpec.Args, args...)
+
+		mounts, err := sidecarVolumeMounts(pod, pod.Annotations[sidecarVolumeMountsAnnotation], si.SidecarVolumeMountPathAllowlist)
+		if err != nil {
+			return err
+		}
+		containerSpec.VolumeMounts = append(containerSpec.VolumeMounts, mounts...)
+	}
+
+	// Pin the resolution of the GCS endpoints in the Pod /etc/hosts shared with the sidecar container.
+	var aliases []corev1.HostAlias
+	if containerName == GcsFuseSidecarName {
+		if aliases, err = hostAliases(pod.Annotations[hostAliasesAnnotation]); err != nil {
+			return err
+		}
+	}
+
+	// Skip metadata prefetch sidecar injection if no volumes are requesting metadata or object prefetch.
 	if containerName == MetadataPrefetchSidecarName && len(containerSpec.VolumeMounts) == 0 {
-		klog.Info("no volumes are requesting metadata prefetch, skipping metadata prefetch sidecar injection")
+		klog.Info("no volumes are requesting metadata or object prefetch, skipping metadata prefetch sidecar injection")
 
 		return nil
 	}
@@ -140,17 +197,82 @@ func (si *SidecarInjector) injectSidecarContainer(containerName string, pod *cor
 		return fmt.Errorf("%s", (containerIndexOrderMap[containerName] + "not found when attempting to inject metadata prefetch. skipping injection"))
 	}
 
+	// Project the prefetch manifest ConfigMaps mounted to the metadata prefetch sidecar container.
+	if containerName == MetadataPrefetchSidecarName {
+		volumes, _, _ := si.objectPrefetchSpec(pod)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
+	}
+
 	if injectAsNativeSidecar {
 		pod.Spec.InitContainers = insert(pod.Spec.InitContainers, containerSpec, index)
 	} else {
 		pod.Spec.Containers = insert(pod.Spec.Containers, containerSpec, index)
 	}
+	pod.Spec.HostAliases = append(pod.Spec.HostAliases, aliases...)
 	// Log pod mutation after fuse sidecar injection.
 	LogPodMutation(pod, config)
 
 	return nil
 }
 
+// injectBucketAccessCheckContainer injects an init container that validates the access to
+// the buckets of the gcsfuse csi driver volumes, so that the Pod fails fast before the workload starts.
+// The container checks the access with the Pod identity, so the volumes that skip the bucket access check,
+// or that do not mount the bucket with the Pod identity, are left out.
+func (si *SidecarInjector) injectBucketAccessCheckContainer(pod *corev1.Pod) error {
+	if si.BucketAccessCheckImage == "" {
+		return fmt.Errorf("the annotation %q is not supported in this cluster, the webhook must be started with --bucket-access-check-image", GcsFuseBucketAccessCheckAnnotation)
+	}
+
+	if _, present := containerPresent(pod.Spec.InitContainers, BucketAccessCheckContainerName); present {
+		return nil
+	}
+
+	checks := []BucketAccessCheck{}
+	for _, v := range pod.Spec.Volumes {
+		bucketName, volumeAttributes, err := si.getGcsFuseCSIVolumeBucketName(v, pod.Namespace)
+		if err != nil {
+			return fmt.Errorf("failed to get bucket name of volume %q: %w", v.Name, err)
+		}
+
+		if bucketName == "" {
+			continue
+		}
+
+		if skip, err := ParseBool(volumeAttributes[skipCSIBucketAccessCheckVolumeAttribute]); err == nil && skip {
+			continue
+		}
+
+		// The anonymous mode mounts the bucket without credentials, and the gcsfuse mode with the credentials set in the mount options.
+		if authMode := volumeAttributes[authModeVolumeAttribute]; authMode != "" && authMode != authModeDriver {
+			continue
+		}
+
+		if !bucketNameRegex.MatchString(bucketName) {
+			return fmt.Errorf("volume %q has an invalid bucket name %q", v.Name, bucketName)
+		}
+
+		billingProject := volumeAttributes[billingProjectVolumeAttribute]
+		if billingProject != "" && !projectIDRegex.MatchString(billingProject) {
+			return fmt.Errorf("volume %q has an invalid billing project %q", v.Name, billingProject)
+		}
+
+		checks = append(checks, BucketAccessCheck{BucketName: bucketName, BillingProject: billingProject})
+	}
+
+	if len(checks) == 0 {
+		klog.Info("no volumes are using a static bucket with the Pod identity, skipping bucket access check container injection")
+
+		return nil
+	}
+
+	// The access check requires network, inject the container after the istio-proxy native sidecar.
+	index := getInjectIndexAfterContainer(pod.Spec.InitContainers, IstioSidecarName)
+	pod.Spec.InitContainers = insert(pod.Spec.InitContainers, GetBucketAccessCheckContainerSpec(si.BucketAccessCheckImage, checks), index)
+
+	return nil
+}
+
 func (si *SidecarInjector) getNativeContainerSpec(containerName string, pod *corev1.Pod, config *Config) corev1.Container {
 	containerSpec := si.getContainerSpec(containerName, pod, config)
 	containerSpec.Env = append(containerSpec.Env, corev1.EnvVar{Name: "NATIVE_SIDECAR", Value: "TRUE"})
@@ -1550,6 +1550,166 @@ func TestInjectMetadataPrefetchSidecar(t *testing.T) {
 				},
 			},
 		},
+		{
+			testName: "fuse sidecar present, injection with prefetch manifest successful",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name: GcsFuseSidecarName,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name: "workload-one",
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "my-volume",
+							VolumeSource: corev1.VolumeSource{
+								CSI: &corev1.CSIVolumeSource{
+									Driver: gcsFuseCsiDriverName,
+									VolumeAttributes: map[string]string{
+										prefetchManifestConfigMapVolumeAttribute: "hot-objects",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name: GcsFuseSidecarName,
+						},
+						{
+							Name:            MetadataPrefetchSidecarName,
+							Env:             []corev1.EnvVar{{Name: "NATIVE_SIDECAR", Value: "TRUE"}},
+							RestartPolicy:   ptr.To(corev1.ContainerRestartPolicyAlways),
+							SecurityContext: GetSecurityContext(),
+							Image:           FakePrefetchConfig().ContainerImage,
+							ImagePullPolicy: corev1.PullPolicy(FakePrefetchConfig().ImagePullPolicy),
+							Resources: corev1.ResourceRequirements{
+								Requests: requests,
+								Limits:   limits,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "my-volume", ReadOnly: true, MountPath: "/objects/my-volume"},
+								{Name: prefetchManifestVolumeName("my-volume"), ReadOnly: true, MountPath: "/manifests/my-volume"},
+								TmpVolumeMount,
+							},
+							Args: []string{"--ready-file=/gcsfuse-tmp/prefetch-complete"},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									Exec: &corev1.ExecAction{Command: []string{"ls", "/gcsfuse-tmp/prefetch-complete"}},
+								},
+								PeriodSeconds: 5,
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name: "workload-one",
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "my-volume",
+							VolumeSource: corev1.VolumeSource{
+								CSI: &corev1.CSIVolumeSource{
+									Driver: gcsFuseCsiDriverName,
+									VolumeAttributes: map[string]string{
+										prefetchManifestConfigMapVolumeAttribute: "hot-objects",
+									},
+								},
+							},
+						},
+						{
+							Name: prefetchManifestVolumeName("my-volume"),
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "hot-objects"},
+									Optional:             ptr.To(true),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			testName: "fuse sidecar present, injection with cache barrier file successful",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name: GcsFuseSidecarName,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "my-volume",
+							VolumeSource: corev1.VolumeSource{
+								CSI: &corev1.CSIVolumeSource{
+									Driver: gcsFuseCsiDriverName,
+									VolumeAttributes: map[string]string{
+										cacheBarrierFileVolumeAttribute: "models/model.bin",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedPod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name: GcsFuseSidecarName,
+						},
+						{
+							Name:            MetadataPrefetchSidecarName,
+							Env:             []corev1.EnvVar{{Name: "NATIVE_SIDECAR", Value: "TRUE"}},
+							RestartPolicy:   ptr.To(corev1.ContainerRestartPolicyAlways),
+							SecurityContext: GetSecurityContext(),
+							Image:           FakePrefetchConfig().ContainerImage,
+							ImagePullPolicy: corev1.PullPolicy(FakePrefetchConfig().ImagePullPolicy),
+							Resources: corev1.ResourceRequirements{
+								Requests: requests,
+								Limits:   limits,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "my-volume", ReadOnly: true, MountPath: "/objects/my-volume"},
+								TmpVolumeMount,
+							},
+							Args: []string{"--cache-barrier-file=my-volume/models/model.bin", "--ready-file=/gcsfuse-tmp/prefetch-complete"},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									Exec: &corev1.ExecAction{Command: []string{"ls", "/gcsfuse-tmp/prefetch-complete"}},
+								},
+								PeriodSeconds: 5,
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "my-volume",
+							VolumeSource: corev1.VolumeSource{
+								CSI: &corev1.CSIVolumeSource{
+									Driver: gcsFuseCsiDriverName,
+									VolumeAttributes: map[string]string{
+										cacheBarrierFileVolumeAttribute: "models/model.bin",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
@@ -1567,6 +1727,168 @@ func TestInjectMetadataPrefetchSidecar(t *testing.T) {
 	}
 }
 
+func TestInjectBucketAccessCheckContainer(t *testing.T) {
+	t.Parallel()
+
+	gcsFuseVolume := func(name, bucketName string, attributes ...string) corev1.Volume {
+		volumeAttributes := map[string]string{"bucketName": bucketName}
+		for i := 0; i+1 < len(attributes); i += 2 {
+			volumeAttributes[attributes[i]] = attributes[i+1]
+		}
+
+		return corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				CSI: &corev1.CSIVolumeSource{
+					Driver:           gcsFuseCsiDriverName,
+					VolumeAttributes: volumeAttributes,
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		testName               string
+		image                  string
+		pod                    *corev1.Pod
+		expectedInitContainers []corev1.Container
+		expectErr              bool
+	}{
+		{
+			testName: "inject with multiple buckets",
+			image:    "fake-image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "one"}},
+					Volumes: []corev1.Volume{
+						gcsFuseVolume("volume-one", "bucket-one", "authMode", "driver"),
+						gcsFuseVolume("volume-two", "bucket-two", "billingProject", "billing-project"),
+						{
+							Name: "other-volume",
+							VolumeSource: corev1.VolumeSource{
+								CSI: &corev1.CSIVolumeSource{Driver: "other-csi"},
+							},
+						},
+					},
+				},
+			},
+			expectedInitContainers: []corev1.Container{
+				GetBucketAccessCheckContainerSpec("fake-image", []BucketAccessCheck{{BucketName: "bucket-one"}, {BucketName: "bucket-two", BillingProject: "billing-project"}}),
+				{Name: "one"},
+			},
+		},
+		{
+			testName: "inject after istio",
+			image:    "fake-image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: IstioSidecarName}, {Name: "one"}},
+					Volumes:        []corev1.Volume{gcsFuseVolume("volume-one", "bucket-one")},
+				},
+			},
+			expectedInitContainers: []corev1.Container{
+				{Name: IstioSidecarName},
+				GetBucketAccessCheckContainerSpec("fake-image", []BucketAccessCheck{{BucketName: "bucket-one"}}),
+				{Name: "one"},
+			},
+		},
+		{
+			testName: "no injection for dynamic mount",
+			image:    "fake-image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "one"}},
+					Volumes:        []corev1.Volume{gcsFuseVolume("volume-one", "_")},
+				},
+			},
+			expectedInitContainers: []corev1.Container{{Name: "one"}},
+		},
+		{
+			testName: "no injection for volumes skipping the check or not using the Pod identity",
+			image:    "fake-image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "one"}},
+					Volumes: []corev1.Volume{
+						gcsFuseVolume("volume-one", "bucket-one", "skipCSIBucketAccessCheck", "true"),
+						gcsFuseVolume("volume-two", "bucket-two", "authMode", "anonymous"),
+						gcsFuseVolume("volume-three", "bucket-three", "authMode", "gcsfuse"),
+					},
+				},
+			},
+			expectedInitContainers: []corev1.Container{{Name: "one"}},
+		},
+		{
+			testName: "error without image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{gcsFuseVolume("volume-one", "bucket-one")},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			testName: "invalid billing project",
+			image:    "fake-image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{gcsFuseVolume("volume-one", "bucket-one", "billingProject", "project; rm -rf /")},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			testName: "invalid bucket name",
+			image:    "fake-image",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{gcsFuseVolume("volume-one", "bucket; rm -rf /")},
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			si := SidecarInjector{BucketAccessCheckImage: tc.image}
+			err := si.injectBucketAccessCheckContainer(tc.pod)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if diff := cmp.Diff(tc.expectedInitContainers, tc.pod.Spec.InitContainers); diff != "" {
+				t.Errorf("unexpected init containers (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetBucketAccessCheckContainerSpec(t *testing.T) {
+	t.Parallel()
+
+	container := GetBucketAccessCheckContainerSpec("fake-image", []BucketAccessCheck{{BucketName: "bucket-one"}, {BucketName: "bucket-two", BillingProject: "billing-project"}})
+	expectedCommand := []string{
+		"/bin/sh",
+		"-c",
+		"gcloud storage objects list gs://bucket-one --limit=1 --format='value(name)' > /dev/null || { echo 'failed to access bucket bucket-one' >&2; exit 1; } && " +
+			"gcloud storage objects list gs://bucket-two --billing-project=billing-project --limit=1 --format='value(name)' > /dev/null || { echo 'failed to access bucket bucket-two' >&2; exit 1; }",
+	}
+
+	if container.Name != BucketAccessCheckContainerName {
+		t.Errorf("Got container name %q, but expected %q", container.Name, BucketAccessCheckContainerName)
+	}
+	if container.Image != "fake-image" {
+		t.Errorf("Got container image %q, but expected %q", container.Image, "fake-image")
+	}
+	if diff := cmp.Diff(expectedCommand, container.Command); diff != "" {
+		t.Errorf("unexpected command (-want, +got)\n%s", diff)
+	}
+}
+
 func generateAnnotationsFromConfig(config *Config, prefix string) map[string]string {
 	annotations := make(map[string]string)
 	if config.ImagePullPolicy != "" {
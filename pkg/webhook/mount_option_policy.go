@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// The keys of the mount option policy ConfigMap, each value lists the forbidden entries
+// separated by new lines or commas, the empty entries and the entries starting with # are ignored.
+const (
+	// mountOptionPolicyMountOptionsKey lists the forbidden mount options, e.g. allow_other or file-cache:max-size-mb.
+	// The mount options with a value, e.g. file-cache:max-size-mb:-1, are matched by the option name.
+	mountOptionPolicyMountOptionsKey = "mountOptions"
+	// mountOptionPolicyVolumeAttributesKey lists the forbidden volume attributes, e.g. skipCSIBucketAccessCheck,
+	// or the forbidden volume attribute values, e.g. authMode=anonymous.
+	mountOptionPolicyVolumeAttributesKey = "volumeAttributes"
+)
+
+// mountOptionPolicy is the cluster policy forbidding mount options and volume attributes of the gcsfuse csi driver volumes.
+type mountOptionPolicy struct {
+	forbiddenMountOptions     []string
+	forbiddenVolumeAttributes []string
+}
+
+// parseMountOptionPolicy returns the mount option policy defined by the ConfigMap.
+func parseMountOptionPolicy(configMap *corev1.ConfigMap) *mountOptionPolicy {
+	return &mountOptionPolicy{
+		forbiddenMountOptions:     splitPolicyEntries(configMap.Data[mountOptionPolicyMountOptionsKey]),
+		forbiddenVolumeAttributes: splitPolicyEntries(configMap.Data[mountOptionPolicyVolumeAttributesKey]),
+	}
+}
+
+func splitPolicyEntries(value string) []string {
+	entries := []string{}
+	for _, line := range strings.Split(value, "\n") {
+		for _, entry := range strings.Split(line, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" && !strings.HasPrefix(entry, "#") {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries
+}
+
+// violations returns the volume attributes and mount options of a volume forbidden by the policy.
+func (p *mountOptionPolicy) violations(volumeAttributes map[string]string, mountOptions []string) []string {
+	violations := []string{}
+	for _, forbidden := range p.forbiddenVolumeAttributes {
+		name, value, hasValue := strings.Cut(forbidden, "=")
+		if v, ok := volumeAttributes[name]; ok && (!hasValue || v == value) {
+			violations = append(violations, fmt.Sprintf("volume attribute %q", name+"="+v))
+		}
+	}
+
+	for _, forbidden := range p.forbiddenMountOptions {
+		for _, o := range mountOptions {
+			if o == forbidden || strings.HasPrefix(o, forbidden+"=") || strings.HasPrefix(o, forbidden+":") {
+				violations = append(violations, fmt.Sprintf("mount option %q", o))
+			}
+		}
+	}
+
+	return violations
+}
+
+// validateMountOptionPolicy returns an error if any gcsfuse csi driver volume of the Pod uses a mount option
+// or a volume attribute forbidden by the cluster policy. A missing policy ConfigMap forbids nothing.
+func (si *SidecarInjector) validateMountOptionPolicy(pod *corev1.Pod) error {
+	if si.ConfigMapLister == nil || si.MountOptionPolicyConfigMap == "" {
+		return nil
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(si.MountOptionPolicyConfigMap)
+	if err != nil {
+		return fmt.Errorf("invalid mount option policy ConfigMap %q: %w", si.MountOptionPolicyConfigMap, err)
+	}
+
+	configMap, err := si.ConfigMapLister.ConfigMaps(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the mount option policy ConfigMap %q: %w", si.MountOptionPolicyConfigMap, err)
+	}
+
+	policy := parseMountOptionPolicy(configMap)
+	for _, v := range pod.Spec.Volumes {
+		isGcsFuseCSIVolume, _, volumeAttributes, mountOptions, err := si.getGcsFuseCSIVolumeOptions(v, pod.Namespace)
+		if err != nil {
+			// The PVC may be created after the Pod, same as the volume options validation.
+			klog.Warningf("failed to get the options of volume %q, skipping the mount option policy: %v", v.Name, err)
+
+			continue
+		}
+
+		if !isGcsFuseCSIVolume {
+			continue
+		}
+
+		if violations := policy.violations(volumeAttributes, mountOptions); len(violations) > 0 {
+			return fmt.Errorf("volume %q uses the %v forbidden by the cluster policy in ConfigMap %q", v.Name, strings.Join(violations, ", "), si.MountOptionPolicyConfigMap)
+		}
+	}
+
+	return nil
+}
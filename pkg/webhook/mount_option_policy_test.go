@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMountOptionPolicyViolations(t *testing.T) {
+	t.Parallel()
+
+	policy := parseMountOptionPolicy(&corev1.ConfigMap{
+		Data: map[string]string{
+			mountOptionPolicyMountOptionsKey:     "allow_other\n# gcsfuse can fill the node disk without a cap\nfile-cache:max-size-mb, uid",
+			mountOptionPolicyVolumeAttributesKey: "authMode=anonymous\nskipCSIBucketAccessCheck",
+		},
+	})
+
+	testCases := []struct {
+		name               string
+		volumeAttributes   map[string]string
+		mountOptions       []string
+		expectedViolations []string
+	}{
+		{
+			name:               "allowed options should pass",
+			volumeAttributes:   map[string]string{"bucketName": "test-bucket", "authMode": "driver"},
+			mountOptions:       []string{"implicit-dirs", "gid=1000", "file-cache:enable-parallel-downloads:true"},
+			expectedViolations: []string{},
+		},
+		{
+			name:               "forbidden mount options should be reported",
+			mountOptions:       []string{"allow_other", "file-cache:max-size-mb:-1", "uid=0"},
+			expectedViolations: []string{`mount option "allow_other"`, `mount option "file-cache:max-size-mb:-1"`, `mount option "uid=0"`},
+		},
+		{
+			name:               "forbidden volume attribute values should be reported",
+			volumeAttributes:   map[string]string{"authMode": "anonymous"},
+			expectedViolations: []string{`volume attribute "authMode=anonymous"`},
+		},
+		{
+			name:               "forbidden volume attributes should be reported with any value",
+			volumeAttributes:   map[string]string{"skipCSIBucketAccessCheck": "false"},
+			expectedViolations: []string{`volume attribute "skipCSIBucketAccessCheck=false"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		violations := policy.violations(tc.volumeAttributes, tc.mountOptions)
+		if diff := cmp.Diff(tc.expectedViolations, violations); diff != "" {
+			t.Errorf("for %q, unexpected violations (-want +got): %s", tc.name, diff)
+		}
+	}
+}
+
+func TestValidateMountOptionPolicy(t *testing.T) {
+	t.Parallel()
+
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "mount-option-policy", Namespace: "gcs-fuse-csi-driver"},
+		Data: map[string]string{
+			mountOptionPolicyMountOptionsKey:     "allow_other",
+			mountOptionPolicyVolumeAttributesKey: "authMode=anonymous",
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeClient := fake.NewSimpleClientset(&configMap)
+	informer := informers.NewSharedInformerFactoryWithOptions(fakeClient, resyncDuration, informers.WithNamespace(metav1.NamespaceAll))
+	configMapLister := informer.Core().V1().ConfigMaps().Lister()
+	informer.Start(ctx.Done())
+	informer.WaitForCacheSync(ctx.Done())
+
+	csiVolume := func(volumeAttributes map[string]string) corev1.Volume {
+		return corev1.Volume{
+			Name: "gcs-fuse-csi-ephemeral",
+			VolumeSource: corev1.VolumeSource{
+				CSI: &corev1.CSIVolumeSource{Driver: gcsFuseCsiDriverName, VolumeAttributes: volumeAttributes},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		policyConfigMap    string
+		volumes            []corev1.Volume
+		expectErrSubstring string
+	}{
+		{
+			name:            "allowed options should pass",
+			policyConfigMap: "gcs-fuse-csi-driver/mount-option-policy",
+			volumes:         []corev1.Volume{csiVolume(map[string]string{"bucketName": "test-bucket", volumeAttributeMountOptions: "implicit-dirs"})},
+		},
+		{
+			name:               "forbidden mount option should be rejected",
+			policyConfigMap:    "gcs-fuse-csi-driver/mount-option-policy",
+			volumes:            []corev1.Volume{csiVolume(map[string]string{"bucketName": "test-bucket", volumeAttributeMountOptions: "implicit-dirs,allow_other"})},
+			expectErrSubstring: `volume "gcs-fuse-csi-ephemeral" uses the mount option "allow_other" forbidden by the cluster policy`,
+		},
+		{
+			name:               "forbidden volume attribute value should be rejected",
+			policyConfigMap:    "gcs-fuse-csi-driver/mount-option-policy",
+			volumes:            []corev1.Volume{csiVolume(map[string]string{"bucketName": "test-bucket", "authMode": "anonymous"})},
+			expectErrSubstring: `volume attribute "authMode=anonymous"`,
+		},
+		{
+			name:            "a missing policy ConfigMap should forbid nothing",
+			policyConfigMap: "gcs-fuse-csi-driver/missing",
+			volumes:         []corev1.Volume{csiVolume(map[string]string{"bucketName": "test-bucket", volumeAttributeMountOptions: "allow_other"})},
+		},
+		{
+			name:    "a disabled policy should forbid nothing",
+			volumes: []corev1.Volume{csiVolume(map[string]string{"bucketName": "test-bucket", volumeAttributeMountOptions: "allow_other"})},
+		},
+	}
+
+	for _, tc := range testCases {
+		si := &SidecarInjector{ConfigMapLister: configMapLister, MountOptionPolicyConfigMap: tc.policyConfigMap}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+			Spec:       corev1.PodSpec{Volumes: tc.volumes},
+		}
+
+		err := si.validateMountOptionPolicy(pod)
+		if tc.expectErrSubstring == "" && err != nil {
+			t.Errorf("for %q, expected no error, got: %v", tc.name, err)
+		}
+		if tc.expectErrSubstring != "" && (err == nil || !strings.Contains(err.Error(), tc.expectErrSubstring)) {
+			t.Errorf("for %q, expected an error containing %q, got: %v", tc.name, tc.expectErrSubstring, err)
+		}
+	}
+}
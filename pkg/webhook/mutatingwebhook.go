@@ -36,6 +36,7 @@ import (
 const (
 	GcsFuseVolumeEnableAnnotation           = "gke-gcsfuse/volumes"
 	GcsFuseNativeSidecarEnableAnnotation    = "gke-gcsfuse/enable-native-sidecar"
+	GcsFuseBucketAccessCheckAnnotation      = "gke-gcsfuse/enable-bucket-access-check"
 	cpuLimitAnnotation                      = "gke-gcsfuse/cpu-limit"
 	cpuRequestAnnotation                    = "gke-gcsfuse/cpu-request"
 	memoryLimitAnnotation                   = "gke-gcsfuse/memory-limit"
@@ -44,6 +45,12 @@ const (
 	ephemeralStorageRequestAnnotation       = "gke-gcsfuse/ephemeral-storage-request"
 	metadataPrefetchMemoryLimitAnnotation   = "gke-gcsfuse/metadata-prefetch/memory-limit"
 	metadataPrefetchMemoryRequestAnnotation = "gke-gcsfuse/metadata-prefetch/memory-request"
+	podMetadataEnvAnnotation                = "gke-gcsfuse/pod-metadata-env"
+	hostAliasesAnnotation                   = "gke-gcsfuse/host-aliases"
+	sidecarPositionAnnotation               = "gke-gcsfuse/sidecar-position"
+	totalCacheSizeLimitMbAnnotation         = "gke-gcsfuse/total-cache-size-limit-mb"
+	sidecarVolumeMountsAnnotation           = "gke-gcsfuse/sidecar-volume-mounts"
+	cacheDebugPortAnnotation                = "gke-gcsfuse/cache-debug-port"
 )
 
 type SidecarInjector struct {
@@ -56,6 +63,19 @@ type SidecarInjector struct {
 	PvcLister              listersv1.PersistentVolumeClaimLister
 	PvLister               listersv1.PersistentVolumeLister
 	ServerVersion          *version.Version
+	// container image of the init container that validates bucket access
+	BucketAccessCheckImage string
+	// ConfigMapLister lists the mount option policy ConfigMap, the policy is disabled when it is nil.
+	ConfigMapLister listersv1.ConfigMapLister
+	// MountOptionPolicyConfigMap is the namespace/name of the ConfigMap listing the mount options
+	// and volume attributes forbidden by the cluster policy.
+	MountOptionPolicyConfigMap string
+	// SidecarVolumeMountPathAllowlist lists the path prefixes the Pod Secret and ConfigMap volumes
+	// can be mounted at in the gcsfuse sidecar container, the sidecar volume mounts are rejected when it is empty.
+	SidecarVolumeMountPathAllowlist []string
+	// EnableCacheDebugEndpoint permits the Pods to enable the file cache debug endpoint of the gcsfuse sidecar container,
+	// the cache debug port annotation is rejected when it is false.
+	EnableCacheDebugEndpoint bool
 }
 
 // Handle injects a gcsfuse sidecar container and a emptyDir to incoming qualified pods.
@@ -89,6 +109,19 @@ func (si *SidecarInjector) Handle(ctx context.Context, req admission.Request) ad
 		return admission.Allowed(fmt.Sprintf("found annotation '%v: false' for Pod: Name %q, GenerateName %q, Namespace %q, no injection required.", GcsFuseVolumeEnableAnnotation, pod.Name, pod.GenerateName, pod.Namespace))
 	}
 
+	if err := si.validateGcsFuseVolumes(pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if err := si.validateMountOptionPolicy(pod); err != nil {
+		return admission.Errored(http.StatusForbidden, err)
+	}
+
+	cacheMedium, err := si.podCacheMedium(pod)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
 	sidecarInjected, _ := ValidatePodHasSidecarContainerInjected(pod)
 	if sidecarInjected {
 		return admission.Allowed("The sidecar container was injected, no injection required.")
@@ -116,7 +149,11 @@ func (si *SidecarInjector) Handle(ctx context.Context, req admission.Request) ad
 		pod.Spec.Volumes = append(pod.Spec.Volumes, GetSATokenVolume(projectID))
 	}
 
-	pod.Spec.Volumes = append(GetSidecarContainerVolumeSpec(pod.Spec.Volumes...), pod.Spec.Volumes...)
+	sidecarVolumes := GetSidecarContainerVolumeSpec(pod.Spec.Volumes...)
+	if err := applyCacheMedium(pod, sidecarVolumes, cacheMedium); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	pod.Spec.Volumes = append(sidecarVolumes, pod.Spec.Volumes...)
 
 	// Inject metadata prefetch sidecar.
 	injected, _ = validatePodHasSidecarContainerInjected(MetadataPrefetchSidecarName, pod, []corev1.Volume{}, []corev1.VolumeMount{})
@@ -127,6 +164,20 @@ func (si *SidecarInjector) Handle(ctx context.Context, req admission.Request) ad
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	// Inject bucket access check init container.
+	if enable, ok := pod.Annotations[GcsFuseBucketAccessCheckAnnotation]; ok {
+		enableBucketAccessCheck, err := ParseBool(enable)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, fmt.Errorf("the acceptable values for %q are 'True', 'true', 'false' or 'False'", GcsFuseBucketAccessCheckAnnotation))
+		}
+
+		if enableBucketAccessCheck {
+			if err := si.injectBucketAccessCheckContainer(pod); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+	}
+
 	marshaledPod, err := json.Marshal(pod)
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, fmt.Errorf("failed to marshal pod: %w", err))
@@ -338,6 +338,170 @@ func TestPrepareConfig(t *testing.T) {
 	}
 }
 
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                                           string
+		cpuRequest, cpuLimit                           string
+		memoryRequest, memoryLimit                     string
+		ephemeralStorageRequest, ephemeralStorageLimit string
+		wantConfig                                     *Config
+		expectErr                                      bool
+	}{
+		{
+			name:                    "valid quantities",
+			cpuRequest:              "100m",
+			cpuLimit:                "500m",
+			memoryRequest:           "128Mi",
+			memoryLimit:             "1Gi",
+			ephemeralStorageRequest: "1Gi",
+			ephemeralStorageLimit:   "5Gi",
+			wantConfig: &Config{
+				ContainerImage:          "fake-image",
+				ImagePullPolicy:         "Always",
+				CPURequest:              resource.MustParse("100m"),
+				CPULimit:                resource.MustParse("500m"),
+				MemoryRequest:           resource.MustParse("128Mi"),
+				MemoryLimit:             resource.MustParse("1Gi"),
+				EphemeralStorageRequest: resource.MustParse("1Gi"),
+				EphemeralStorageLimit:   resource.MustParse("5Gi"),
+			},
+		},
+		{
+			name:                    "zero limits are treated as unlimited",
+			cpuRequest:              "100m",
+			cpuLimit:                "0",
+			memoryRequest:           "128Mi",
+			memoryLimit:             "0",
+			ephemeralStorageRequest: "1Gi",
+			ephemeralStorageLimit:   "0",
+			wantConfig: &Config{
+				ContainerImage:          "fake-image",
+				ImagePullPolicy:         "Always",
+				CPURequest:              resource.MustParse("100m"),
+				CPULimit:                resource.MustParse("0"),
+				MemoryRequest:           resource.MustParse("128Mi"),
+				MemoryLimit:             resource.MustParse("0"),
+				EphemeralStorageRequest: resource.MustParse("1Gi"),
+				EphemeralStorageLimit:   resource.MustParse("0"),
+			},
+		},
+		{
+			name:                    "malformed quantity",
+			cpuRequest:              "invalid",
+			cpuLimit:                "500m",
+			memoryRequest:           "128Mi",
+			memoryLimit:             "1Gi",
+			ephemeralStorageRequest: "1Gi",
+			ephemeralStorageLimit:   "5Gi",
+			expectErr:               true,
+		},
+		{
+			name:                    "negative quantity",
+			cpuRequest:              "100m",
+			cpuLimit:                "500m",
+			memoryRequest:           "-128Mi",
+			memoryLimit:             "1Gi",
+			ephemeralStorageRequest: "1Gi",
+			ephemeralStorageLimit:   "5Gi",
+			expectErr:               true,
+		},
+		{
+			name:                    "request greater than limit",
+			cpuRequest:              "1",
+			cpuLimit:                "500m",
+			memoryRequest:           "128Mi",
+			memoryLimit:             "1Gi",
+			ephemeralStorageRequest: "1Gi",
+			ephemeralStorageLimit:   "5Gi",
+			expectErr:               true,
+		},
+	}
+
+	for _, tc := range testCases {
+		gotConfig, gotErr := ParseConfig("fake-image", "Always", tc.cpuRequest, tc.cpuLimit, tc.memoryRequest, tc.memoryLimit, tc.ephemeralStorageRequest, tc.ephemeralStorageLimit)
+		if tc.expectErr != (gotErr != nil) {
+			t.Errorf(`for "%s", expect error: %v, but got error: %v`, tc.name, tc.expectErr, gotErr)
+		}
+
+		if diff := cmp.Diff(gotConfig, tc.wantConfig); diff != "" {
+			t.Errorf(`for "%s", config differ (-got, +want)\n%s`, tc.name, diff)
+		}
+	}
+}
+
+func TestPrepareConfigWithClusterDefaults(t *testing.T) {
+	t.Parallel()
+
+	clusterDefaults, err := ParseConfig("fake-image", "Always", "1", "2", "1Gi", "2Gi", "10Gi", "20Gi")
+	if err != nil {
+		t.Fatalf("failed to parse cluster defaults: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		wantConfig  *Config
+	}{
+		{
+			name: "cluster defaults apply when no annotation is found",
+			annotations: map[string]string{
+				GcsFuseVolumeEnableAnnotation: "true",
+			},
+			wantConfig: &Config{
+				ContainerImage:          "fake-image",
+				ImagePullPolicy:         "Always",
+				CPURequest:              resource.MustParse("1"),
+				CPULimit:                resource.MustParse("2"),
+				MemoryRequest:           resource.MustParse("1Gi"),
+				MemoryLimit:             resource.MustParse("2Gi"),
+				EphemeralStorageRequest: resource.MustParse("10Gi"),
+				EphemeralStorageLimit:   resource.MustParse("20Gi"),
+			},
+		},
+		{
+			name: "annotations override cluster defaults",
+			annotations: map[string]string{
+				GcsFuseVolumeEnableAnnotation: "true",
+				cpuRequestAnnotation:          "250m",
+				cpuLimitAnnotation:            "500m",
+				memoryLimitAnnotation:         "512Mi",
+			},
+			wantConfig: &Config{
+				ContainerImage:          "fake-image",
+				ImagePullPolicy:         "Always",
+				CPURequest:              resource.MustParse("250m"),
+				CPULimit:                resource.MustParse("500m"),
+				MemoryRequest:           resource.MustParse("512Mi"),
+				MemoryLimit:             resource.MustParse("512Mi"),
+				EphemeralStorageRequest: resource.MustParse("10Gi"),
+				EphemeralStorageLimit:   resource.MustParse("20Gi"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		si := SidecarInjector{
+			Config:                 clusterDefaults,
+			MetadataPrefetchConfig: FakePrefetchConfig(),
+		}
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: tc.annotations,
+			},
+		}
+		gotConfig, gotErr := si.prepareConfig(sidecarPrefixMap[GcsFuseSidecarName], pod)
+		if gotErr != nil {
+			t.Errorf(`for "%s", got unexpected error: %v`, tc.name, gotErr)
+		}
+
+		if diff := cmp.Diff(gotConfig, tc.wantConfig); diff != "" {
+			t.Errorf(`for "%s", config differ (-got, +want)\n%s`, tc.name, diff)
+		}
+	}
+}
+
 func TestValidateMutatingWebhookResponse(t *testing.T) {
 	t.Parallel()
 
@@ -568,6 +732,27 @@ func TestValidateMutatingWebhookResponse(t *testing.T) {
 			wantResponse: wantResponseWithIstio(t, true, false, true),
 			nodes:        nativeSupportNodes(),
 		},
+		{
+			name:         "bucket access check container injection successful test.",
+			operation:    admissionv1.Create,
+			inputPod:     validInputPodWithBucketAccessCheckAnnotation("true"),
+			wantResponse: wantResponseWithBucketAccessCheck(t, "true"),
+			nodes:        skewVersionNodes(),
+		},
+		{
+			name:         "bucket access check container disabled via annotation test.",
+			operation:    admissionv1.Create,
+			inputPod:     validInputPodWithBucketAccessCheckAnnotation("false"),
+			wantResponse: wantResponseWithBucketAccessCheck(t, "false"),
+			nodes:        skewVersionNodes(),
+		},
+		{
+			name:         "bucket access check container set via invalid annotation test.",
+			operation:    admissionv1.Create,
+			inputPod:     validInputPodWithBucketAccessCheckAnnotation("maybe"),
+			wantResponse: admission.Errored(http.StatusBadRequest, fmt.Errorf("the acceptable values for %q are 'True', 'true', 'false' or 'False'", GcsFuseBucketAccessCheckAnnotation)),
+			nodes:        skewVersionNodes(),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -594,6 +779,7 @@ func TestValidateMutatingWebhookResponse(t *testing.T) {
 				MetadataPrefetchConfig: FakePrefetchConfig(),
 				Decoder:                admission.NewDecoder(runtime.NewScheme()),
 				NodeLister:             lister,
+				BucketAccessCheckImage: "fake-gcloud-image",
 			}
 
 			stopCh := make(<-chan struct{})
@@ -772,6 +958,33 @@ func validInputPodWithPrefetchIncluded() *corev1.Pod {
 	return pod
 }
 
+func validInputPodWithBucketAccessCheckAnnotation(enableBucketAccessCheckAnnotation string) *corev1.Pod {
+	pod := validInputPod()
+	pod.ObjectMeta.Annotations[GcsFuseBucketAccessCheckAnnotation] = enableBucketAccessCheckAnnotation
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: "gcs-volume",
+		VolumeSource: corev1.VolumeSource{
+			CSI: &corev1.CSIVolumeSource{
+				Driver:           gcsFuseCsiDriverName,
+				VolumeAttributes: map[string]string{"bucketName": "test-bucket"},
+			},
+		},
+	})
+
+	return pod
+}
+
+func wantResponseWithBucketAccessCheck(t *testing.T, enableBucketAccessCheckAnnotation string) admission.Response {
+	t.Helper()
+	pod := validInputPodWithBucketAccessCheckAnnotation(enableBucketAccessCheckAnnotation)
+	newPod := modifySpec(*validInputPodWithBucketAccessCheckAnnotation(enableBucketAccessCheckAnnotation), false, false, false)
+	if enableBucketAccessCheckAnnotation == "true" {
+		newPod.Spec.InitContainers = append([]corev1.Container{GetBucketAccessCheckContainerSpec("fake-gcloud-image", []BucketAccessCheck{{BucketName: "test-bucket"}})}, newPod.Spec.InitContainers...)
+	}
+
+	return generatePatch(t, pod, newPod)
+}
+
 func getWorkloadSpec(name string) corev1.Container {
 	return corev1.Container{
 		Name:  name,
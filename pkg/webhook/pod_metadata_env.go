@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodMetadataEnvPrefix is the prefix of the sidecar container env vars holding the Pod metadata.
+// The sidecar mounter expands the ${GCSFUSE_POD_...} references in the mount options using these env vars.
+const PodMetadataEnvPrefix = "GCSFUSE_POD_"
+
+// podMetadataFields are the supported Pod metadata fields besides the labels and annotations,
+// mapped to the env var name suffixes.
+var podMetadataFields = map[string]string{
+	"metadata.name":      "NAME",
+	"metadata.namespace": "NAMESPACE",
+	"metadata.uid":       "UID",
+}
+
+var (
+	podMetadataKeyFieldRegex = regexp.MustCompile(`^metadata\.(labels|annotations)\['([^']+)'\]$`)
+	envVarNameInvalidRegex   = regexp.MustCompile(`[^A-Z0-9_]`)
+)
+
+// podMetadataEnv returns the downward API env vars of the comma separated Pod metadata field paths.
+// The supported field paths are metadata.name, metadata.namespace, metadata.uid,
+// metadata.labels['<KEY>'] and metadata.annotations['<KEY>'], e.g. metadata.labels['app'] is exposed as GCSFUSE_POD_LABEL_APP.
+func podMetadataEnv(fieldPaths string) ([]corev1.EnvVar, error) {
+	env := []corev1.EnvVar{}
+	names := map[string]string{}
+	for _, fieldPath := range strings.Split(fieldPaths, ",") {
+		fieldPath = strings.TrimSpace(fieldPath)
+		if fieldPath == "" {
+			continue
+		}
+
+		suffix, ok := podMetadataFields[fieldPath]
+		if !ok {
+			matches := podMetadataKeyFieldRegex.FindStringSubmatch(fieldPath)
+			if len(matches) != 3 {
+				return nil, fmt.Errorf("the Pod metadata field path %q in the annotation %q is not supported, the supported field paths are metadata.name, metadata.namespace, metadata.uid, metadata.labels['<KEY>'] and metadata.annotations['<KEY>']", fieldPath, podMetadataEnvAnnotation)
+			}
+
+			suffix = strings.TrimSuffix(strings.ToUpper(matches[1]), "S") + "_" + envVarNameInvalidRegex.ReplaceAllString(strings.ToUpper(matches[2]), "_")
+		}
+
+		name := PodMetadataEnvPrefix + suffix
+		if existing, ok := names[name]; ok {
+			if existing == fieldPath {
+				continue
+			}
+
+			return nil, fmt.Errorf("the Pod metadata field paths %q and %q in the annotation %q map to the same env var %q", existing, fieldPath, podMetadataEnvAnnotation, name)
+		}
+		names[name] = fieldPath
+
+		env = append(env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath},
+			},
+		})
+	}
+
+	return env, nil
+}
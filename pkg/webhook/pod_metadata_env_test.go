@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func fieldRefEnvVar(name, fieldPath string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath},
+		},
+	}
+}
+
+func TestPodMetadataEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName    string
+		fieldPaths  string
+		expectedEnv []corev1.EnvVar
+		expectErr   bool
+	}{
+		{
+			testName:    "no field paths",
+			expectedEnv: []corev1.EnvVar{},
+		},
+		{
+			testName:   "supported field paths",
+			fieldPaths: "metadata.name, metadata.namespace,metadata.uid,metadata.labels['app.kubernetes.io/name'],metadata.annotations['cache-key']",
+			expectedEnv: []corev1.EnvVar{
+				fieldRefEnvVar("GCSFUSE_POD_NAME", "metadata.name"),
+				fieldRefEnvVar("GCSFUSE_POD_NAMESPACE", "metadata.namespace"),
+				fieldRefEnvVar("GCSFUSE_POD_UID", "metadata.uid"),
+				fieldRefEnvVar("GCSFUSE_POD_LABEL_APP_KUBERNETES_IO_NAME", "metadata.labels['app.kubernetes.io/name']"),
+				fieldRefEnvVar("GCSFUSE_POD_ANNOTATION_CACHE_KEY", "metadata.annotations['cache-key']"),
+			},
+		},
+		{
+			testName:    "duplicated field paths",
+			fieldPaths:  "metadata.name,metadata.name",
+			expectedEnv: []corev1.EnvVar{fieldRefEnvVar("GCSFUSE_POD_NAME", "metadata.name")},
+		},
+		{
+			testName:   "field paths mapping to the same env var",
+			fieldPaths: "metadata.labels['cache-key'],metadata.labels['cache.key']",
+			expectErr:  true,
+		},
+		{
+			testName:   "unsupported field path",
+			fieldPaths: "spec.nodeName",
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			env, err := podMetadataEnv(tc.fieldPaths)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if diff := cmp.Diff(tc.expectedEnv, env); diff != "" {
+				t.Errorf("unexpected env (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithPodMetadataEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName    string
+		annotations map[string]string
+		expectedEnv []corev1.EnvVar
+		expectErr   bool
+	}{
+		{
+			testName:    "no annotation",
+			expectedEnv: GetNativeSidecarContainerSpec(FakeConfig()).Env,
+		},
+		{
+			testName:    "annotation with supported field paths",
+			annotations: map[string]string{podMetadataEnvAnnotation: "metadata.namespace,metadata.labels['app']"},
+			expectedEnv: append(GetNativeSidecarContainerSpec(FakeConfig()).Env,
+				fieldRefEnvVar("GCSFUSE_POD_NAMESPACE", "metadata.namespace"),
+				fieldRefEnvVar("GCSFUSE_POD_LABEL_APP", "metadata.labels['app']"),
+			),
+		},
+		{
+			testName:    "annotation with unsupported field path",
+			annotations: map[string]string{podMetadataEnvAnnotation: "status.podIP"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "workload"}},
+				},
+			}
+
+			si := SidecarInjector{Config: FakeConfig()}
+			err := si.injectSidecarContainer(GcsFuseSidecarName, pod, true)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				if len(pod.Spec.InitContainers) != 0 {
+					t.Errorf("Expected no sidecar container injected, got %v", pod.Spec.InitContainers)
+				}
+
+				return
+			}
+
+			if len(pod.Spec.InitContainers) != 1 {
+				t.Fatalf("Expected the sidecar container injected, got %v", pod.Spec.InitContainers)
+			}
+			if diff := cmp.Diff(tc.expectedEnv, pod.Spec.InitContainers[0].Env); diff != "" {
+				t.Errorf("unexpected sidecar container env (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
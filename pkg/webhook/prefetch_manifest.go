@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path"
+	"path/filepath"
+
+	metadataprefetch "github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/metadata_prefetch"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	prefetchManifestConfigMapVolumeAttribute = "prefetchManifestConfigMap"
+	cacheBarrierFileVolumeAttribute          = "cacheBarrierFile"
+	// prefetchManifestVolumeNamePrefix prefixes the Pod volumes projecting the prefetch manifest ConfigMaps into the metadata prefetch sidecar container.
+	prefetchManifestVolumeNamePrefix = "gke-gcsfuse-prefetch-manifest-"
+	// prefetchReadyFile is created by the metadata prefetch sidecar container once the objects are prefetched,
+	// the readiness probe of the container checks the file.
+	prefetchReadyFile = SidecarContainerTmpVolumeMountPath + "/prefetch-complete"
+	// prefetchReadinessProbePeriodSeconds is the interval between two checks of the prefetch ready file.
+	prefetchReadinessProbePeriodSeconds = 5
+)
+
+// prefetchManifestVolumeName returns the name of the Pod volume projecting the prefetch manifest ConfigMap of the gcsfuse volume.
+// The gcsfuse volume name is hashed, so the name fits in the volume name length limit.
+func prefetchManifestVolumeName(volumeName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(volumeName))
+
+	return fmt.Sprintf("%v%08x", prefetchManifestVolumeNamePrefix, h.Sum32())
+}
+
+// objectPrefetchSpec returns the Pod volumes projecting the prefetch manifest ConfigMaps of the gcsfuse volumes,
+// the metadata prefetch sidecar container volume mounts of the gcsfuse volumes and their manifests,
+// and the container args naming the cache barrier files of the gcsfuse volumes.
+// The ConfigMaps are optional, the Pod starts without the prefetch if a ConfigMap is missing.
+func (si *SidecarInjector) objectPrefetchSpec(pod *corev1.Pod) ([]corev1.Volume, []corev1.VolumeMount, []string) {
+	volumes := []corev1.Volume{}
+	mounts := []corev1.VolumeMount{}
+	args := []string{}
+	for _, v := range pod.Spec.Volumes {
+		isGcsFuseCSIVolume, _, volumeAttributes, err := si.isGcsFuseCSIVolume(v, pod.Namespace)
+		if err != nil {
+			klog.Errorf("failed to determine if %s is a GcsFuseCSI backed volume: %v", v.Name, err)
+		}
+
+		configMapName := volumeAttributes[prefetchManifestConfigMapVolumeAttribute]
+		cacheBarrierFile := volumeAttributes[cacheBarrierFileVolumeAttribute]
+		if !isGcsFuseCSIVolume || (configMapName == "" && cacheBarrierFile == "") {
+			continue
+		}
+
+		mounts = append(mounts, corev1.VolumeMount{Name: v.Name, MountPath: filepath.Join(metadataprefetch.ObjectsPath, v.Name), ReadOnly: true})
+		if cacheBarrierFile != "" {
+			args = append(args, "--cache-barrier-file="+path.Join(v.Name, cacheBarrierFile))
+		}
+		if configMapName == "" {
+			continue
+		}
+
+		manifestVolumeName := prefetchManifestVolumeName(v.Name)
+		volumes = append(volumes, corev1.Volume{
+			Name: manifestVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+					Optional:             ptr.To(true),
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: manifestVolumeName, MountPath: filepath.Join(metadataprefetch.ManifestsPath, v.Name), ReadOnly: true})
+	}
+
+	return volumes, mounts, args
+}
+
+// objectPrefetchReadiness sets the metadata prefetch sidecar container to report ready once the objects are prefetched
+// and the cache barrier files are cached, so the Pod does not become ready until the gcsfuse file cache is warm. The status survives a restart of the CSI driver.
+// The probe runs ls, which the container image ships for the metadata prefetch.
+func objectPrefetchReadiness(container *corev1.Container) {
+	container.VolumeMounts = append(container.VolumeMounts, TmpVolumeMount)
+	container.Args = append(container.Args, "--ready-file="+prefetchReadyFile)
+	container.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"ls", prefetchReadyFile}},
+		},
+		PeriodSeconds: prefetchReadinessProbePeriodSeconds,
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The supported values of the sidecar position annotation.
+const (
+	sidecarPositionFirst       = "first"
+	sidecarPositionLast        = "last"
+	sidecarPositionAfterPrefix = "after:"
+)
+
+// sidecarInjectIndex returns the index of the init containers the native sidecar container is inserted at.
+// The position is "first", "last", or "after:<CONTAINER_NAME>" to insert the sidecar right after the named init container,
+// so that the init containers injected by other webhooks and using the volumes run after the sidecar.
+func sidecarInjectIndex(initContainers []corev1.Container, position string) (int, error) {
+	switch {
+	case position == sidecarPositionFirst:
+		return 0, nil
+	case position == sidecarPositionLast:
+		return len(initContainers), nil
+	case strings.HasPrefix(position, sidecarPositionAfterPrefix):
+		name := strings.TrimPrefix(position, sidecarPositionAfterPrefix)
+		idx, present := containerPresent(initContainers, name)
+		if !present {
+			return 0, fmt.Errorf("the init container %q referenced in the annotation %q is not found in the Pod spec", name, sidecarPositionAnnotation)
+		}
+
+		return idx + 1, nil
+	default:
+		return 0, fmt.Errorf("the acceptable values for %q are %q, %q or %q", sidecarPositionAnnotation, sidecarPositionFirst, sidecarPositionLast, sidecarPositionAfterPrefix+"<CONTAINER_NAME>")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSidecarInjectIndex(t *testing.T) {
+	t.Parallel()
+
+	initContainers := []corev1.Container{{Name: IstioSidecarName}, {Name: "init-a"}, {Name: "init-b"}}
+
+	testCases := []struct {
+		testName      string
+		position      string
+		expectedIndex int
+		expectErr     bool
+	}{
+		{
+			testName:      "first",
+			position:      sidecarPositionFirst,
+			expectedIndex: 0,
+		},
+		{
+			testName:      "last",
+			position:      sidecarPositionLast,
+			expectedIndex: 3,
+		},
+		{
+			testName:      "after a named init container",
+			position:      "after:init-a",
+			expectedIndex: 2,
+		},
+		{
+			testName:  "after a missing init container",
+			position:  "after:init-c",
+			expectErr: true,
+		},
+		{
+			testName:  "after without a container name",
+			position:  "after:",
+			expectErr: true,
+		},
+		{
+			testName:  "unsupported position",
+			position:  "middle",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			index, err := sidecarInjectIndex(initContainers, tc.position)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if !tc.expectErr && index != tc.expectedIndex {
+				t.Errorf("Got index %v, but expected %v", index, tc.expectedIndex)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithPosition(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName      string
+		annotations   map[string]string
+		expectedIndex int
+		expectErr     bool
+	}{
+		{
+			testName:      "no annotation injects after the istio-proxy",
+			expectedIndex: 1,
+		},
+		{
+			testName:      "first",
+			annotations:   map[string]string{sidecarPositionAnnotation: sidecarPositionFirst},
+			expectedIndex: 0,
+		},
+		{
+			testName:      "last",
+			annotations:   map[string]string{sidecarPositionAnnotation: sidecarPositionLast},
+			expectedIndex: 3,
+		},
+		{
+			testName:      "after a named init container",
+			annotations:   map[string]string{sidecarPositionAnnotation: "after:init-a"},
+			expectedIndex: 2,
+		},
+		{
+			testName:    "after a missing init container",
+			annotations: map[string]string{sidecarPositionAnnotation: "after:init-c"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: IstioSidecarName}, {Name: "init-a"}, {Name: "init-b"}},
+					Containers:     []corev1.Container{{Name: "workload"}},
+				},
+			}
+
+			si := SidecarInjector{Config: FakeConfig()}
+			err := si.injectSidecarContainer(GcsFuseSidecarName, pod, true)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				if len(pod.Spec.InitContainers) != 3 {
+					t.Errorf("Expected no sidecar container injected, got %v", pod.Spec.InitContainers)
+				}
+
+				return
+			}
+
+			index, present := containerPresent(pod.Spec.InitContainers, GcsFuseSidecarName)
+			if !present || index != tc.expectedIndex {
+				t.Errorf("Got the sidecar container at index %v (present: %v), but expected %v", index, present, tc.expectedIndex)
+			}
+		})
+	}
+}
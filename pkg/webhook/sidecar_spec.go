@@ -18,7 +18,10 @@ limitations under the License.
 package webhook
 
 import (
+	"fmt"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -28,6 +31,7 @@ import (
 const (
 	GcsFuseSidecarName                     = "gke-gcsfuse-sidecar"
 	MetadataPrefetchSidecarName            = "gke-gcsfuse-metadata-prefetch"
+	BucketAccessCheckContainerName         = "gke-gcsfuse-bucket-access-check"
 	SidecarContainerTmpVolumeName          = "gke-gcsfuse-tmp"
 	SidecarContainerTmpVolumeMountPath     = "/gcsfuse-tmp"
 	SidecarContainerBufferVolumeName       = "gke-gcsfuse-buffer"
@@ -40,6 +44,11 @@ const (
 
 	// Webhook relevant volume attributes.
 	gcsFuseMetadataPrefetchOnMountVolumeAttribute = "gcsfuseMetadataPrefetchOnMount"
+	skipCSIBucketAccessCheckVolumeAttribute       = "skipCSIBucketAccessCheck"
+	authModeVolumeAttribute                       = "authMode"
+	billingProjectVolumeAttribute                 = "billingProject"
+	// authModeDriver is the authMode value mounting the bucket with the Pod identity.
+	authModeDriver = "driver"
 
 	// See the nonroot user discussion: https://github.com/GoogleContainerTools/distroless/issues/443
 	NobodyUID           = 65534
@@ -48,6 +57,12 @@ const (
 )
 
 var (
+	// bucketNameRegex matches valid GCS bucket names, see https://cloud.google.com/storage/docs/buckets#naming.
+	bucketNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,220}[a-z0-9]$`)
+
+	// projectIDRegex matches valid GCP project IDs, see https://cloud.google.com/resource-manager/docs/creating-managing-projects#before_you_begin.
+	projectIDRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+
 	// gke-gcsfuse-sidecar volumes.
 	tmpVolume = corev1.Volume{
 		Name: SidecarContainerTmpVolumeName,
@@ -110,6 +125,7 @@ func GetSidecarContainerSpec(c *Config) corev1.Container {
 
 	// The sidecar container follows Restricted Pod Security Standard,
 	// see https://kubernetes.io/docs/concepts/security/pod-security-standards/#restricted
+	// The root filesystem is read-only, gcsfuse writes the temp files, the write buffers and the file cache to the emptyDir volumes.
 	container := corev1.Container{
 		Name:            GcsFuseSidecarName,
 		Image:           c.ContainerImage,
@@ -198,9 +214,51 @@ func (si *SidecarInjector) GetMetadataPrefetchSidecarContainerSpec(pod *corev1.P
 		}
 	}
 
+	// The volumes with a prefetch manifest or a cache barrier file are also mounted to read the objects.
+	_, objectPrefetchMounts, objectPrefetchArgs := si.objectPrefetchSpec(pod)
+	if len(objectPrefetchMounts) > 0 {
+		container.VolumeMounts = append(container.VolumeMounts, objectPrefetchMounts...)
+		container.Args = append(container.Args, objectPrefetchArgs...)
+		objectPrefetchReadiness(&container)
+	}
+
 	return container
 }
 
+// BucketAccessCheck is the bucket checked by the bucket access check init container,
+// and the project billed for the requests to a requester pays bucket.
+type BucketAccessCheck struct {
+	BucketName     string
+	BillingProject string
+}
+
+// GetBucketAccessCheckContainerSpec returns an init container spec that checks the access to the given buckets,
+// the container exits with a non-zero code when any of the buckets is not reachable.
+func GetBucketAccessCheckContainerSpec(image string, bucketChecks []BucketAccessCheck) corev1.Container {
+	checks := make([]string, 0, len(bucketChecks))
+	for _, c := range bucketChecks {
+		billingProjectFlag := ""
+		if c.BillingProject != "" {
+			billingProjectFlag = " --billing-project=" + c.BillingProject
+		}
+		checks = append(checks, fmt.Sprintf("gcloud storage objects list gs://%s%s --limit=1 --format='value(name)' > /dev/null || { echo 'failed to access bucket %s' >&2; exit 1; }", c.BucketName, billingProjectFlag, c.BucketName))
+	}
+
+	// The container follows Restricted Pod Security Standard,
+	// see https://kubernetes.io/docs/concepts/security/pod-security-standards/#restricted
+	return corev1.Container{
+		Name:            BucketAccessCheckContainerName,
+		Image:           image,
+		SecurityContext: GetSecurityContext(),
+		Command:         []string{"/bin/sh", "-c", strings.Join(checks, " && ")},
+		// gcloud requires a writable config directory.
+		Env: []corev1.EnvVar{
+			{Name: "CLOUDSDK_CONFIG", Value: filepath.Join(SidecarContainerTmpVolumeMountPath, ".gcloud")},
+		},
+		VolumeMounts: []corev1.VolumeMount{TmpVolumeMount},
+	}
+}
+
 func GetSATokenVolume(projectID string) corev1.Volume {
 	saTokenVolume := corev1.Volume{
 		Name: SidecarContainerSATokenVolumeName,
@@ -477,3 +477,58 @@ func TestValidatePodHasSidecarContainerInjectedForAutoInjection(t *testing.T) {
 		}
 	}
 }
+
+func TestSidecarContainerSpecReadOnlyRootFilesystem(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		container corev1.Container
+	}{
+		{
+			name:      "regular sidecar container",
+			container: GetSidecarContainerSpec(FakeConfig()),
+		},
+		{
+			name:      "native sidecar container",
+			container: GetNativeSidecarContainerSpec(FakeConfig()),
+		},
+	}
+
+	emptyDirVolumes := map[string]bool{}
+	for _, v := range GetSidecarContainerVolumeSpec() {
+		if v.EmptyDir != nil {
+			emptyDirVolumes[v.Name] = true
+		}
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if tc.container.SecurityContext == nil || !ptr.Deref(tc.container.SecurityContext.ReadOnlyRootFilesystem, false) {
+				t.Errorf("Expected the sidecar container to have a read-only root filesystem, got security context %+v", tc.container.SecurityContext)
+			}
+
+			mountPaths := map[string]string{}
+			for _, m := range tc.container.VolumeMounts {
+				if m.ReadOnly {
+					t.Errorf("Expected the volume mount %q to be writable", m.Name)
+				}
+				mountPaths[m.Name] = m.MountPath
+			}
+
+			for name, mountPath := range map[string]string{
+				SidecarContainerTmpVolumeName:    SidecarContainerTmpVolumeMountPath,
+				SidecarContainerBufferVolumeName: SidecarContainerBufferVolumeMountPath,
+				SidecarContainerCacheVolumeName:  SidecarContainerCacheVolumeMountPath,
+			} {
+				if mountPaths[name] != mountPath {
+					t.Errorf("Expected the volume %q to be mounted at %q, got %q", name, mountPath, mountPaths[name])
+				}
+				if !emptyDirVolumes[name] {
+					t.Errorf("Expected the volume %q to be an emptyDir volume", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// sidecarVolumeMounts returns the read-only sidecar container volume mounts of the comma separated <VOLUME_NAME>:<MOUNT_PATH> pairs,
+// e.g. federation-config:/etc/gcsfuse-secrets/federation. The volumes must be Secret, ConfigMap or projected volumes of the Pod,
+// and the mount paths must be under a path prefix of the allowlist, so the Pod cannot shadow the files the sidecar container depends on.
+func sidecarVolumeMounts(pod *corev1.Pod, pairs string, allowedPathPrefixes []string) ([]corev1.VolumeMount, error) {
+	mounts := []corev1.VolumeMount{}
+	mountPaths := map[string]bool{}
+	for _, pair := range strings.Split(pairs, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		volumeName, mountPath, found := strings.Cut(pair, ":")
+		if !found {
+			return nil, fmt.Errorf("the volume mount %q in the annotation %q must be in the format <VOLUME_NAME>:<MOUNT_PATH>", pair, sidecarVolumeMountsAnnotation)
+		}
+		volumeName, mountPath = strings.TrimSpace(volumeName), strings.TrimSpace(mountPath)
+
+		volume, ok := podVolume(pod, volumeName)
+		if !ok {
+			return nil, fmt.Errorf("the volume %q in the annotation %q is not found in the Pod spec", volumeName, sidecarVolumeMountsAnnotation)
+		}
+		if volume.Secret == nil && volume.ConfigMap == nil && volume.Projected == nil {
+			return nil, fmt.Errorf("the volume %q in the annotation %q must be a Secret, ConfigMap or projected volume", volumeName, sidecarVolumeMountsAnnotation)
+		}
+
+		if !path.IsAbs(mountPath) || path.Clean(mountPath) != mountPath {
+			return nil, fmt.Errorf("the mount path %q of the volume %q in the annotation %q must be a clean absolute path", mountPath, volumeName, sidecarVolumeMountsAnnotation)
+		}
+		if !isPathAllowed(mountPath, allowedPathPrefixes) {
+			return nil, fmt.Errorf("the mount path %q of the volume %q in the annotation %q is not allowed, the allowed path prefixes are %v", mountPath, volumeName, sidecarVolumeMountsAnnotation, allowedPathPrefixes)
+		}
+		if mountPaths[mountPath] {
+			return nil, fmt.Errorf("the mount path %q in the annotation %q is used by more than one volume", mountPath, sidecarVolumeMountsAnnotation)
+		}
+		mountPaths[mountPath] = true
+
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: mountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return mounts, nil
+}
+
+// podVolume returns the Pod volume of the name.
+func podVolume(pod *corev1.Pod, name string) (corev1.Volume, bool) {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == name {
+			return v, true
+		}
+	}
+
+	return corev1.Volume{}, false
+}
+
+// isPathAllowed returns true if the path is one of the allowed path prefixes or under one of them.
+func isPathAllowed(p string, allowedPathPrefixes []string) bool {
+	for _, prefix := range allowedPathPrefixes {
+		prefix = path.Clean(prefix)
+		if p == prefix || strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var testSidecarVolumeMountPathAllowlist = []string{"/etc/gcsfuse-secrets", "/etc/federation/"}
+
+func testSidecarVolumeMountsPod(annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "workload"}},
+			Volumes: []corev1.Volume{
+				{Name: "federation-secret", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "federation"}}},
+				{Name: "federation-config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "federation"}}}},
+				{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+}
+
+func TestSidecarVolumeMounts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName       string
+		pairs          string
+		expectedMounts []corev1.VolumeMount
+		expectErr      bool
+	}{
+		{
+			testName:       "no pairs",
+			expectedMounts: []corev1.VolumeMount{},
+		},
+		{
+			testName: "valid pairs",
+			pairs:    "federation-secret:/etc/gcsfuse-secrets/credentials, federation-config:/etc/federation",
+			expectedMounts: []corev1.VolumeMount{
+				{Name: "federation-secret", MountPath: "/etc/gcsfuse-secrets/credentials", ReadOnly: true},
+				{Name: "federation-config", MountPath: "/etc/federation", ReadOnly: true},
+			},
+		},
+		{
+			testName:  "pair without mount path",
+			pairs:     "federation-secret",
+			expectErr: true,
+		},
+		{
+			testName:  "volume not found",
+			pairs:     "missing:/etc/gcsfuse-secrets/missing",
+			expectErr: true,
+		},
+		{
+			testName:  "volume is not a Secret or ConfigMap",
+			pairs:     "scratch:/etc/gcsfuse-secrets/scratch",
+			expectErr: true,
+		},
+		{
+			testName:  "mount path not in the allowlist",
+			pairs:     "federation-secret:/gcsfuse-tmp",
+			expectErr: true,
+		},
+		{
+			testName:  "mount path sharing a prefix with an allowed path",
+			pairs:     "federation-secret:/etc/gcsfuse-secrets-other",
+			expectErr: true,
+		},
+		{
+			testName:  "mount path escaping the allowlist",
+			pairs:     "federation-secret:/etc/gcsfuse-secrets/../ssl",
+			expectErr: true,
+		},
+		{
+			testName:  "relative mount path",
+			pairs:     "federation-secret:etc/gcsfuse-secrets",
+			expectErr: true,
+		},
+		{
+			testName:  "duplicate mount paths",
+			pairs:     "federation-secret:/etc/gcsfuse-secrets,federation-config:/etc/gcsfuse-secrets",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			mounts, err := sidecarVolumeMounts(testSidecarVolumeMountsPod(nil), tc.pairs, testSidecarVolumeMountPathAllowlist)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if diff := cmp.Diff(tc.expectedMounts, mounts); diff != "" {
+				t.Errorf("unexpected volume mounts (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithSidecarVolumeMounts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName      string
+		annotations   map[string]string
+		allowlist     []string
+		expectedMount *corev1.VolumeMount
+		expectErr     bool
+	}{
+		{
+			testName:  "no annotation",
+			allowlist: testSidecarVolumeMountPathAllowlist,
+		},
+		{
+			testName:      "annotation with an allowed mount path",
+			annotations:   map[string]string{sidecarVolumeMountsAnnotation: "federation-secret:/etc/gcsfuse-secrets/credentials"},
+			allowlist:     testSidecarVolumeMountPathAllowlist,
+			expectedMount: &corev1.VolumeMount{Name: "federation-secret", MountPath: "/etc/gcsfuse-secrets/credentials", ReadOnly: true},
+		},
+		{
+			testName:    "annotation with a disallowed mount path",
+			annotations: map[string]string{sidecarVolumeMountsAnnotation: "federation-secret:/etc/ssl/certs"},
+			allowlist:   testSidecarVolumeMountPathAllowlist,
+			expectErr:   true,
+		},
+		{
+			testName:    "annotation without an allowlist",
+			annotations: map[string]string{sidecarVolumeMountsAnnotation: "federation-secret:/etc/gcsfuse-secrets/credentials"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			pod := testSidecarVolumeMountsPod(tc.annotations)
+
+			si := SidecarInjector{Config: FakeConfig(), SidecarVolumeMountPathAllowlist: tc.allowlist}
+			err := si.injectSidecarContainer(GcsFuseSidecarName, pod, true)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			index, ok := containerPresent(pod.Spec.InitContainers, GcsFuseSidecarName)
+			if !ok {
+				t.Fatalf("the sidecar container is not injected")
+			}
+			var gotMount *corev1.VolumeMount
+			for _, m := range pod.Spec.InitContainers[index].VolumeMounts {
+				if m.Name == "federation-secret" {
+					gotMount = &m
+				}
+			}
+			if diff := cmp.Diff(tc.expectedMount, gotMount); diff != "" {
+				t.Errorf("unexpected sidecar volume mount (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// totalCacheSizeLimitArgs returns the sidecar mounter args bounding the total file cache size
+// of all the volumes of the Pod to the given MiB value, or no args if the value is empty.
+// The sidecar mounter splits the limit evenly between the volumes, and bounds the file cache size of each volume to its share.
+func totalCacheSizeLimitArgs(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	limitMb, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limitMb <= 0 {
+		return nil, fmt.Errorf("the acceptable values for %q are positive integers in MiB, got %q", totalCacheSizeLimitMbAnnotation, value)
+	}
+
+	return []string{"--total-cache-size-limit-mb=" + strconv.FormatInt(limitMb, 10)}, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTotalCacheSizeLimitArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		testName     string
+		value        string
+		expectedArgs []string
+		expectErr    bool
+	}{
+		{
+			testName: "no value",
+		},
+		{
+			testName:     "valid value",
+			value:        " 10240 ",
+			expectedArgs: []string{"--total-cache-size-limit-mb=10240"},
+		},
+		{
+			testName:  "zero value",
+			value:     "0",
+			expectErr: true,
+		},
+		{
+			testName:  "negative value",
+			value:     "-1",
+			expectErr: true,
+		},
+		{
+			testName:  "quantity value",
+			value:     "10Gi",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+			args, err := totalCacheSizeLimitArgs(tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Got error %v, but expected error %v", err, tc.expectErr)
+			}
+			if diff := cmp.Diff(tc.expectedArgs, args); diff != "" {
+				t.Errorf("unexpected args (-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithTotalCacheSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{totalCacheSizeLimitMbAnnotation: "2048"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "workload"}},
+		},
+	}
+
+	si := SidecarInjector{Config: FakeConfig()}
+	if err := si.injectSidecarContainer(GcsFuseSidecarName, pod, true); err != nil {
+		t.Fatalf("failed to inject the sidecar container: %v", err)
+	}
+
+	expectedArgs := []string{"--v=5", "--total-cache-size-limit-mb=2048"}
+	if diff := cmp.Diff(expectedArgs, pod.Spec.InitContainers[0].Args); diff != "" {
+		t.Errorf("unexpected sidecar container args (-want, +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+// The gcsfuse csi driver volume attributes validated at admission.
+const (
+	volumeAttributeMountOptions            = "mountOptions"
+	volumeAttributeFileCacheCapacity       = "fileCacheCapacity"
+	volumeAttributeFileCacheForRangeRead   = "fileCacheForRangeRead"
+	volumeAttributeFileCacheMinFreePercent = "fileCacheMinFreePercent"
+	volumeAttributeLocalFileCacheMode      = "localFileCacheMode"
+	volumeAttributeWriteDurability         = "writeDurability"
+	volumeAttributeSourceReadOnly          = "sourceReadOnly"
+	volumeAttributePinGeneration           = "pinGeneration"
+	volumeAttributeEnableBufferedRead      = "enableBufferedRead"
+	volumeAttributeReadBufferSizeMb        = "readBufferSizeMb"
+	volumeAttributeCacheMedium             = "cacheMedium"
+)
+
+const (
+	fileCacheMaxSizeMountOption     = "file-cache:max-size-mb:"
+	streamingWritesMountOption      = "write:enable-streaming-writes:true"
+	syncMountOption                 = "o=sync"
+	writeDurabilitySynchronousValue = "synchronous"
+	localFileCacheModeDefaultValue  = "default"
+
+	// gcsfuse caps the buffered read blocks of all the file handles of a volume to 40 blocks of 16 MiB by default.
+	bufferedReadGlobalMaxBlocks    = 40
+	bufferedReadDefaultBlockSizeMb = 16
+)
+
+// writeVolumeAttributes are the volume attributes that only take effect when gcsfuse writes to the bucket,
+// mapped to the value enabling them.
+var writeVolumeAttributes = map[string]string{
+	volumeAttributeWriteDurability: writeDurabilitySynchronousValue,
+}
+
+// writeMountOptions are the gcsfuse mount options that only take effect when gcsfuse writes to the bucket.
+var writeMountOptions = []string{streamingWritesMountOption, syncMountOption}
+
+// fileCacheVolumeAttributes are the volume attributes that only take effect when the file cache is enabled,
+// mapped to a function reporting whether the value relies on the file cache.
+var fileCacheVolumeAttributes = map[string]func(string) bool{
+	volumeAttributeFileCacheForRangeRead: func(v string) bool {
+		b, err := ParseBool(v)
+
+		return err == nil && b
+	},
+	volumeAttributeFileCacheMinFreePercent: func(string) bool {
+		return true
+	},
+	volumeAttributeLocalFileCacheMode: func(v string) bool {
+		return v != localFileCacheModeDefaultValue
+	},
+}
+
+// validateGcsFuseVolumes returns an error if any gcsfuse csi driver volume of the Pod uses incompatible options,
+// so that the Pod is rejected at admission instead of launching a gcsfuse process that cannot serve the volume.
+func (si *SidecarInjector) validateGcsFuseVolumes(pod *corev1.Pod) error {
+	for _, v := range pod.Spec.Volumes {
+		isGcsFuseCSIVolume, readOnly, volumeAttributes, mountOptions, err := si.getGcsFuseCSIVolumeOptions(v, pod.Namespace)
+		if err != nil {
+			// The PVC may be created after the Pod, the volume options are then validated by the CSI driver.
+			klog.Warningf("failed to get the options of volume %q, skipping the validation: %v", v.Name, err)
+
+			continue
+		}
+
+		if !isGcsFuseCSIVolume {
+			continue
+		}
+
+		if err := validateVolumeOptions(readOnly, volumeAttributes, mountOptions); err != nil {
+			return fmt.Errorf("volume %q has incompatible options: %w", v.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// getGcsFuseCSIVolumeOptions returns the options of the given gcsfuse csi driver volume.
+//
+// Returns the following (in order):
+//   - isGcsFuseCSIVolume - (bool) whether volume is backed by gcsfuse csi driver.
+//   - readOnly - (bool) whether the volume is mounted read-only.
+//   - volumeAttributes (map[string]string)
+//   - mountOptions ([]string) - the mount options of the volume, including the mountOptions volume attribute.
+//   - error - if check failed
+func (si *SidecarInjector) getGcsFuseCSIVolumeOptions(volume corev1.Volume, namespace string) (bool, bool, map[string]string, []string, error) {
+	if volume.CSI != nil {
+		if volume.CSI.Driver != gcsFuseCsiDriverName {
+			return false, false, nil, nil, nil
+		}
+
+		readOnly := volume.CSI.ReadOnly != nil && *volume.CSI.ReadOnly
+
+		return true, readOnly, volume.CSI.VolumeAttributes, splitMountOptions(volume.CSI.VolumeAttributes), nil
+	}
+
+	pvc := volume.PersistentVolumeClaim
+	if pvc == nil {
+		return false, false, nil, nil, nil
+	}
+	pvcObj, err := si.GetPVC(namespace, pvc.ClaimName)
+	if err != nil {
+		return false, false, nil, nil, err
+	}
+
+	pv, ok, err := si.GetPreprovisionCSIVolume(gcsFuseCsiDriverName, pvcObj)
+	if err != nil {
+		return false, false, nil, nil, fmt.Errorf("unable to determine if PVC %s/%s is a pre-provisioned gcsfuse volume: %w", namespace, pvc.ClaimName, err)
+	}
+
+	if !ok {
+		return false, false, nil, nil, nil
+	}
+
+	readOnly := pvc.ReadOnly || pv.Spec.CSI.ReadOnly
+	mountOptions := append(splitMountOptions(pv.Spec.CSI.VolumeAttributes), pv.Spec.MountOptions...)
+
+	return true, readOnly, pv.Spec.CSI.VolumeAttributes, mountOptions, nil
+}
+
+// bufferedReadMemory returns the memory the gcsfuse read buffers of the Pod volumes can take,
+// which is added to the sidecar container memory request.
+func (si *SidecarInjector) bufferedReadMemory(pod *corev1.Pod) resource.Quantity {
+	var totalMb int64
+	for _, v := range pod.Spec.Volumes {
+		isGcsFuseCSIVolume, _, volumeAttributes, _, err := si.getGcsFuseCSIVolumeOptions(v, pod.Namespace)
+		if err != nil || !isGcsFuseCSIVolume {
+			continue
+		}
+
+		totalMb += volumeBufferedReadMemoryMb(volumeAttributes)
+	}
+
+	return *resource.NewQuantity(totalMb*1024*1024, resource.BinarySI)
+}
+
+// addQuantity returns the sum of the quantities without modifying the shared quantity values.
+func addQuantity(q, y resource.Quantity) resource.Quantity {
+	sum := q.DeepCopy()
+	sum.Add(y)
+
+	return sum
+}
+
+// volumeBufferedReadMemoryMb returns the max size of the read buffers of a volume in MiB,
+// or 0 if the buffered read is not enabled.
+func volumeBufferedReadMemoryMb(volumeAttributes map[string]string) int64 {
+	if enabled, err := ParseBool(volumeAttributes[volumeAttributeEnableBufferedRead]); err != nil || !enabled {
+		return 0
+	}
+
+	blockSizeMb := int64(bufferedReadDefaultBlockSizeMb)
+	if v, err := strconv.ParseInt(volumeAttributes[volumeAttributeReadBufferSizeMb], 10, 64); err == nil && v > 0 {
+		blockSizeMb = v
+	}
+
+	return bufferedReadGlobalMaxBlocks * blockSizeMb
+}
+
+// splitMountOptions returns the comma separated mount options in the mountOptions volume attribute.
+func splitMountOptions(volumeAttributes map[string]string) []string {
+	mountOptions := []string{}
+	for _, o := range strings.Split(volumeAttributes[volumeAttributeMountOptions], ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			mountOptions = append(mountOptions, o)
+		}
+	}
+
+	return mountOptions
+}
+
+// validateVolumeOptions returns an error for the option combinations gcsfuse cannot serve:
+// the write options on a read-only volume, and the file cache options without the file cache.
+func validateVolumeOptions(readOnly bool, volumeAttributes map[string]string, mountOptions []string) error {
+	for _, o := range mountOptions {
+		if o == "ro" {
+			readOnly = true
+		}
+	}
+	for _, attribute := range []string{volumeAttributeSourceReadOnly, volumeAttributePinGeneration} {
+		if v, err := ParseBool(volumeAttributes[attribute]); err == nil && v {
+			readOnly = true
+		}
+	}
+
+	if readOnly {
+		for attribute, value := range writeVolumeAttributes {
+			if volumeAttributes[attribute] == value {
+				return fmt.Errorf("volume attribute %v %q requires a writable volume, but the volume is read-only", attribute, value)
+			}
+		}
+
+		for _, o := range mountOptions {
+			for _, writeOption := range writeMountOptions {
+				if o == writeOption {
+					return fmt.Errorf("mount option %q requires a writable volume, but the volume is read-only", o)
+				}
+			}
+		}
+	}
+
+	fileCacheEnabled := false
+	if capacity, ok := volumeAttributes[volumeAttributeFileCacheCapacity]; ok && capacity != "0" {
+		fileCacheEnabled = true
+	}
+	for _, o := range mountOptions {
+		if strings.HasPrefix(o, fileCacheMaxSizeMountOption) && o != fileCacheMaxSizeMountOption+"0" {
+			fileCacheEnabled = true
+		}
+	}
+
+	if !fileCacheEnabled {
+		for attribute, requiresFileCache := range fileCacheVolumeAttributes {
+			if value, ok := volumeAttributes[attribute]; ok && requiresFileCache(value) {
+				return fmt.Errorf("volume attribute %v %q requires the file cache, but the volume attribute %v is not set or is \"0\"", attribute, value, volumeAttributeFileCacheCapacity)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,373 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestValidateVolumeOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		readOnly         bool
+		volumeAttributes map[string]string
+		mountOptions     []string
+		expectErr        bool
+	}{
+		{
+			name:             "read-only volume with streaming writes should be rejected",
+			readOnly:         true,
+			volumeAttributes: map[string]string{},
+			mountOptions:     []string{"implicit-dirs", "write:enable-streaming-writes:true"},
+			expectErr:        true,
+		},
+		{
+			name:             "ro mount option with synchronous writeDurability should be rejected",
+			volumeAttributes: map[string]string{volumeAttributeWriteDurability: "synchronous"},
+			mountOptions:     []string{"ro"},
+			expectErr:        true,
+		},
+		{
+			name:             "sourceReadOnly with the sync mount option should be rejected",
+			volumeAttributes: map[string]string{volumeAttributeSourceReadOnly: "true"},
+			mountOptions:     []string{"o=sync"},
+			expectErr:        true,
+		},
+		{
+			name:             "pinGeneration with streaming writes should be rejected",
+			volumeAttributes: map[string]string{volumeAttributePinGeneration: "true"},
+			mountOptions:     []string{"write:enable-streaming-writes:true"},
+			expectErr:        true,
+		},
+		{
+			name:             "fileCacheForRangeRead without the file cache should be rejected",
+			volumeAttributes: map[string]string{volumeAttributeFileCacheForRangeRead: "true"},
+			expectErr:        true,
+		},
+		{
+			name: "localFileCacheMode with a zero file cache capacity should be rejected",
+			volumeAttributes: map[string]string{
+				volumeAttributeLocalFileCacheMode: "parallel",
+				volumeAttributeFileCacheCapacity:  "0",
+			},
+			expectErr: true,
+		},
+		{
+			name:             "fileCacheMinFreePercent without the file cache should be rejected",
+			volumeAttributes: map[string]string{volumeAttributeFileCacheMinFreePercent: "10"},
+			expectErr:        true,
+		},
+		{
+			name:             "read-only volume with the file cache should pass",
+			readOnly:         true,
+			volumeAttributes: map[string]string{volumeAttributeFileCacheCapacity: "10Gi", volumeAttributeFileCacheForRangeRead: "true"},
+			mountOptions:     []string{"implicit-dirs"},
+		},
+		{
+			name:             "writable volume with streaming writes and synchronous writeDurability should pass",
+			volumeAttributes: map[string]string{volumeAttributeWriteDurability: "synchronous"},
+			mountOptions:     []string{"write:enable-streaming-writes:true"},
+		},
+		{
+			name:             "read-only volume with buffered writeDurability should pass",
+			readOnly:         true,
+			volumeAttributes: map[string]string{volumeAttributeWriteDurability: "buffered"},
+		},
+		{
+			name:             "localFileCacheMode parallel with the file cache mount option should pass",
+			volumeAttributes: map[string]string{volumeAttributeLocalFileCacheMode: "parallel"},
+			mountOptions:     []string{"file-cache:max-size-mb:-1"},
+		},
+		{
+			name:             "default localFileCacheMode and disabled fileCacheForRangeRead without the file cache should pass",
+			volumeAttributes: map[string]string{volumeAttributeLocalFileCacheMode: "default", volumeAttributeFileCacheForRangeRead: "false"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateVolumeOptions(tc.readOnly, tc.volumeAttributes, tc.mountOptions)
+			if tc.expectErr && err == nil {
+				t.Error("expected an error, got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Errorf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateGcsFuseVolumes(t *testing.T) {
+	t.Parallel()
+
+	pv := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pv"},
+		Spec: corev1.PersistentVolumeSpec{
+			MountOptions: []string{"write:enable-streaming-writes:true"},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:       gcsFuseCsiDriverName,
+					VolumeHandle: "test-bucket",
+				},
+			},
+		},
+	}
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pvc", Namespace: "default"},
+		Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: pv.Name},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeClient := fake.NewSimpleClientset(&pv, &pvc)
+	informer := informers.NewSharedInformerFactoryWithOptions(fakeClient, resyncDuration, informers.WithNamespace(metav1.NamespaceAll))
+	si := &SidecarInjector{
+		PvLister:  informer.Core().V1().PersistentVolumes().Lister(),
+		PvcLister: informer.Core().V1().PersistentVolumeClaims().Lister(),
+	}
+	informer.Start(ctx.Done())
+	informer.WaitForCacheSync(ctx.Done())
+
+	testCases := []struct {
+		name      string
+		volumes   []corev1.Volume
+		expectErr bool
+	}{
+		{
+			name: "read-only ephemeral volume with streaming writes should be rejected",
+			volumes: []corev1.Volume{
+				{
+					Name: "ephemeral",
+					VolumeSource: corev1.VolumeSource{
+						CSI: &corev1.CSIVolumeSource{
+							Driver:           gcsFuseCsiDriverName,
+							ReadOnly:         ptr.To(true),
+							VolumeAttributes: map[string]string{"bucketName": "test-bucket", volumeAttributeMountOptions: "implicit-dirs,write:enable-streaming-writes:true"},
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "read-only PVC with streaming writes in the PV mount options should be rejected",
+			volumes: []corev1.Volume{
+				{
+					Name: "pvc",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.Name, ReadOnly: true},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "read-only ephemeral volume with a writable custom cache PVC should pass",
+			volumes: []corev1.Volume{
+				{
+					Name: "ephemeral",
+					VolumeSource: corev1.VolumeSource{
+						CSI: &corev1.CSIVolumeSource{
+							Driver:           gcsFuseCsiDriverName,
+							ReadOnly:         ptr.To(true),
+							VolumeAttributes: map[string]string{"bucketName": "test-bucket", volumeAttributeFileCacheCapacity: "10Gi"},
+						},
+					},
+				},
+				{
+					Name: SidecarContainerCacheVolumeName,
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "cache-pvc"},
+					},
+				},
+			},
+		},
+		{
+			name: "writable PVC with streaming writes in the PV mount options should pass",
+			volumes: []corev1.Volume{
+				{
+					Name: "pvc",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.Name},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+			Spec:       corev1.PodSpec{Volumes: tc.volumes},
+		}
+		err := si.validateGcsFuseVolumes(pod)
+		if tc.expectErr && err == nil {
+			t.Errorf("for %q, expected an error, got nil", tc.name)
+		}
+		if !tc.expectErr && err != nil {
+			t.Errorf("for %q, expected no error, got: %v", tc.name, err)
+		}
+	}
+}
+
+func TestVolumeBufferedReadMemoryMb(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		volumeAttributes map[string]string
+		expectedMb       int64
+	}{
+		{
+			name:             "buffered read not enabled",
+			volumeAttributes: map[string]string{volumeAttributeReadBufferSizeMb: "32"},
+			expectedMb:       0,
+		},
+		{
+			name:             "buffered read disabled",
+			volumeAttributes: map[string]string{volumeAttributeEnableBufferedRead: "false"},
+			expectedMb:       0,
+		},
+		{
+			name:             "buffered read enabled with the default buffer size",
+			volumeAttributes: map[string]string{volumeAttributeEnableBufferedRead: "true"},
+			expectedMb:       640,
+		},
+		{
+			name:             "buffered read enabled with a custom buffer size",
+			volumeAttributes: map[string]string{volumeAttributeEnableBufferedRead: "true", volumeAttributeReadBufferSizeMb: "8"},
+			expectedMb:       320,
+		},
+		{
+			name:             "buffered read enabled with an invalid buffer size rejected by the CSI driver",
+			volumeAttributes: map[string]string{volumeAttributeEnableBufferedRead: "true", volumeAttributeReadBufferSizeMb: "-8"},
+			expectedMb:       640,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := volumeBufferedReadMemoryMb(tc.volumeAttributes); got != tc.expectedMb {
+				t.Errorf("Got %v MiB, but expected %v MiB", got, tc.expectedMb)
+			}
+		})
+	}
+}
+
+func TestInjectSidecarContainerWithBufferedRead(t *testing.T) {
+	t.Parallel()
+
+	bufferedReadVolume := func(name, readBufferSizeMb string) corev1.Volume {
+		return corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				CSI: &corev1.CSIVolumeSource{
+					Driver: gcsFuseCsiDriverName,
+					VolumeAttributes: map[string]string{
+						"bucketName":                      "test-bucket",
+						volumeAttributeEnableBufferedRead: "true",
+						volumeAttributeReadBufferSizeMb:   readBufferSizeMb,
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		annotations     map[string]string
+		volumes         []corev1.Volume
+		expectedRequest resource.Quantity
+		expectedLimit   *resource.Quantity
+	}{
+		{
+			name:            "no buffered read volumes",
+			volumes:         []corev1.Volume{{Name: "empty-dir", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+			expectedRequest: resource.MustParse("256Mi"),
+			expectedLimit:   ptr.To(resource.MustParse("256Mi")),
+		},
+		{
+			name:            "buffer memory of all the volumes is added to the request and limit",
+			volumes:         []corev1.Volume{bufferedReadVolume("vol-1", "8"), bufferedReadVolume("vol-2", "")},
+			expectedRequest: resource.MustParse("1216Mi"),
+			expectedLimit:   ptr.To(resource.MustParse("1216Mi")),
+		},
+		{
+			name:            "unlimited memory limit annotation is kept",
+			annotations:     map[string]string{memoryLimitAnnotation: "0", memoryRequestAnnotation: "1Gi"},
+			volumes:         []corev1.Volume{bufferedReadVolume("vol-1", "8")},
+			expectedRequest: resource.MustParse("1Gi"),
+		},
+		{
+			name:            "memory limit annotation is kept",
+			annotations:     map[string]string{memoryLimitAnnotation: "2Gi"},
+			volumes:         []corev1.Volume{bufferedReadVolume("vol-1", "8")},
+			expectedRequest: resource.MustParse("2Gi"),
+			expectedLimit:   ptr.To(resource.MustParse("2Gi")),
+		},
+		{
+			name:            "memory request annotation is kept",
+			annotations:     map[string]string{memoryRequestAnnotation: "512Mi"},
+			volumes:         []corev1.Volume{bufferedReadVolume("vol-1", "8")},
+			expectedRequest: resource.MustParse("512Mi"),
+			expectedLimit:   ptr.To(resource.MustParse("512Mi")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "workload"}},
+					Volumes:    tc.volumes,
+				},
+			}
+
+			si := SidecarInjector{Config: FakeConfig()}
+			if err := si.injectSidecarContainer(GcsFuseSidecarName, pod, true); err != nil {
+				t.Fatalf("failed to inject the sidecar container: %v", err)
+			}
+
+			resources := pod.Spec.InitContainers[0].Resources
+			if got := resources.Requests[corev1.ResourceMemory]; got.Cmp(tc.expectedRequest) != 0 {
+				t.Errorf("Got memory request %v, but expected %v", got.String(), tc.expectedRequest.String())
+			}
+			got, ok := resources.Limits[corev1.ResourceMemory]
+			if tc.expectedLimit == nil && ok {
+				t.Errorf("Got memory limit %v, but expected no limit", got.String())
+			}
+			if tc.expectedLimit != nil && got.Cmp(*tc.expectedLimit) != 0 {
+				t.Errorf("Got memory limit %v, but expected %v", got.String(), tc.expectedLimit.String())
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ limitations under the License.
 package webhook
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 )
@@ -80,3 +82,39 @@ func (si *SidecarInjector) isGcsFuseCSIVolume(volume corev1.Volume, namespace st
 
 	return false, false, nil, nil
 }
+
+// getGcsFuseCSIVolumeBucketName returns the bucket name and the volume attributes of the given gcsfuse csi driver volume.
+// An empty string is returned if the volume is not backed by gcsfuse csi driver or is using dynamic mounting.
+func (si *SidecarInjector) getGcsFuseCSIVolumeBucketName(volume corev1.Volume, namespace string) (string, map[string]string, error) {
+	if volume.CSI != nil {
+		if volume.CSI.Driver != gcsFuseCsiDriverName {
+			return "", nil, nil
+		}
+
+		if bucketName := volume.CSI.VolumeAttributes["bucketName"]; bucketName != "_" {
+			return bucketName, volume.CSI.VolumeAttributes, nil
+		}
+
+		return "", nil, nil
+	}
+
+	pvc := volume.PersistentVolumeClaim
+	if pvc == nil {
+		return "", nil, nil
+	}
+	pvcObj, err := si.GetPVC(namespace, pvc.ClaimName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pv, ok, err := si.GetPreprovisionCSIVolume(gcsFuseCsiDriverName, pvcObj)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to determine if PVC %s/%s is a pre-provisioned gcsfuse volume: %w", namespace, pvc.ClaimName, err)
+	}
+
+	if ok && pv != nil && pv.Spec.CSI != nil && pv.Spec.CSI.VolumeHandle != "_" {
+		return pv.Spec.CSI.VolumeHandle, pv.Spec.CSI.VolumeAttributes, nil
+	}
+
+	return "", nil, nil
+}
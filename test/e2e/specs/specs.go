@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -59,6 +60,7 @@ const (
 	NonRootVolumePrefix                                        = "gcsfuse-csi-non-root-volume"
 	InvalidMountOptionsVolumePrefix                            = "gcsfuse-csi-invalid-mount-options-volume"
 	ImplicitDirsVolumePrefix                                   = "gcsfuse-csi-implicit-dirs-volume"
+	ImplicitDirsDisabledVolumePrefix                           = "gcsfuse-csi-implicit-dirs-disabled-volume"
 	ForceNewBucketPrefix                                       = "gcsfuse-csi-force-new-bucket"
 	SubfolderInBucketPrefix                                    = "gcsfuse-csi-subfolder-in-bucket"
 	MultipleBucketsPrefix                                      = "gcsfuse-csi-multiple-buckets"
@@ -67,7 +69,33 @@ const (
 	EnableFileCachePrefix                                      = "gcsfuse-csi-enable-file-cache"
 	EnableFileCacheAndMetricsPrefix                            = "gcsfuse-csi-enable-file-cache-and-metrics"
 	EnableFileCacheWithLargeCapacityPrefix                     = "gcsfuse-csi-enable-file-cache-large-capacity"
+	EnableFileCacheWithEtagValidationPrefix                    = "gcsfuse-csi-enable-file-cache-etag-validation"
+	EnableFileCacheWithTTLValidationPrefix                     = "gcsfuse-csi-enable-file-cache-ttl-validation"
+	EnableFileCacheWithNonRootPrefix                           = "gcsfuse-csi-enable-file-cache-non-root"
+	EnableFileCacheInMemoryPrefix                              = "gcsfuse-csi-enable-file-cache-in-memory"
+	EnableFileCacheWithCacheCleanupPrefix                      = "gcsfuse-csi-enable-file-cache-cleanup-on-unmount"
 	EnableMetadataPrefetchPrefix                               = "gcsfuse-csi-enable-metadata-prefetch"
+	RequesterPaysBucketPrefix                                  = "gcsfuse-csi-requester-pays-bucket"
+	RequesterPaysBucketWithoutBillingProjectPrefix             = "gcsfuse-csi-requester-pays-bucket-without-billing-project"
+	RequesterPaysTestFileName                                  = "requester-pays-test-file"
+	ManySmallFilesPrefix                                       = "gcsfuse-csi-many-small-files"
+	ManySmallFilesWithMetadataCachePrefix                      = "gcsfuse-csi-many-small-files-metadata-cache"
+	ManySmallFilesWithTypeCacheMaxEntriesPrefix                = "gcsfuse-csi-many-small-files-type-cache-max-entries"
+	WriteDurabilitySynchronousPrefix                           = "gcsfuse-csi-write-durability-synchronous"
+	SharedMounterPrefix                                        = "gcsfuse-csi-shared-mounter"
+	SourceReadOnlyPrefix                                       = "gcsfuse-csi-source-read-only"
+	PinGenerationPrefix                                        = "gcsfuse-csi-pin-generation"
+	PinGenerationTestFileName                                  = "pin-generation-test-file"
+	CreateUmaskPrefix                                          = "gcsfuse-csi-create-umask"
+	EnableEmptyManagedFoldersPrefix                            = "gcsfuse-csi-enable-empty-managed-folders"
+	EmptyManagedFolderName                                     = "empty-managed-folder"
+	ChunkTransferTimeoutPrefix                                 = "gcsfuse-csi-chunk-transfer-timeout"
+	MaxRetryAttemptsWithUnreachableEndpointPrefix              = "gcsfuse-csi-max-retry-attempts-with-unreachable-endpoint"
+	BucketDeletedDuringMountPrefix                             = "gcsfuse-csi-bucket-deleted-during-mount"
+	ConcurrentDiropsPrefix                                     = "gcsfuse-csi-concurrent-dirops"
+	ConcurrentDiropsWithParallelDiropsPrefix                   = "gcsfuse-csi-concurrent-dirops-parallel-dirops"
+	MountOverNonEmptyPrefix                                    = "gcsfuse-csi-mount-over-non-empty"
+	FsNamePrefix                                               = "gcsfuse-csi-fs-name"
 	EnableHostNetworkPrefix                                    = "gcsfuse-csi-enable-hostnetwork"
 	EnableCustomReadAhead                                      = "gcsfuse-csi-enable-custom-read-ahead"
 	EnableMetadataPrefetchAndFakeVolumePrefix                  = "gcsfuse-csi-enable-metadata-prefetch-and-fake-volume"
@@ -82,6 +110,10 @@ const (
 	SkipCSIBucketAccessCheckAndNonRootVolumePrefix             = "gcsfuse-csi-skip-bucket-access-check-non-root-volume"
 	SkipCSIBucketAccessCheckAndImplicitDirsVolumePrefix        = "gcsfuse-csi-skip-bucket-access-check-implicit-dirs-volume"
 
+	// The many small files tests populate ManySmallFilesDirCount directories with ManySmallFilesPerDir objects each.
+	ManySmallFilesDirCount = 100
+	ManySmallFilesPerDir   = 1000
+
 	// Read ahead config custom settings to verify testing.
 	ReadAheadCustomReadAheadKb = "15360"
 	ReadAheadCustomMaxRatio    = "100"
@@ -297,6 +329,30 @@ func (t *TestPod) CheckSidecarNeverTerminatedAfterAWhile(ctx context.Context, is
 	gomega.Expect(sidecarContainerStatus.State.Running).ToNot(gomega.BeNil())
 }
 
+// WaitForSidecarOOMKilledAndRestarted waits until the sidecar container has been restarted after an OOM kill.
+func (t *TestPod) WaitForSidecarOOMKilledAndRestarted(ctx context.Context, isNativeSidecar bool) {
+	err := wait.PollUntilContextTimeout(ctx, pollInterval, pollTimeoutSlow, true, func(ctx context.Context) (bool, error) {
+		pod, err := t.client.CoreV1().Pods(t.namespace.Name).Get(ctx, t.pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		containerStatusList := pod.Status.ContainerStatuses
+		if isNativeSidecar {
+			containerStatusList = pod.Status.InitContainerStatuses
+		}
+
+		for _, cs := range containerStatusList {
+			if cs.Name == webhook.GcsFuseSidecarName && cs.RestartCount > 0 && cs.LastTerminationState.Terminated != nil {
+				return cs.LastTerminationState.Terminated.Reason == "OOMKilled", nil
+			}
+		}
+
+		return false, nil
+	})
+	framework.ExpectNoError(err)
+}
+
 func (t *TestPod) SetupVolumeForInitContainer(name, mountPath string, readOnly bool, subPath string) {
 	t.setupVolumeMount(name, mountPath, readOnly, subPath, true)
 }
@@ -471,6 +527,13 @@ func (t *TestPod) SetNonRootSecurityContext(uid, gid, fsgroup int) {
 	t.pod.Spec.SecurityContext = psc
 }
 
+func (t *TestPod) SetSupplementalGroups(groups ...int64) {
+	if t.pod.Spec.SecurityContext == nil {
+		t.pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	t.pod.Spec.SecurityContext.SupplementalGroups = groups
+}
+
 func (t *TestPod) SetCommand(cmd string) {
 	t.pod.Spec.Containers[0].Args = []string{"-c", cmd}
 }
@@ -1157,10 +1220,33 @@ func CreateTestFileInBucket(fileName, bucketName string) {
 	createTestFileInBucket(fileName, bucketName, []byte(fileName))
 }
 
+func CreateTestFileWithContentInBucket(fileName, bucketName, fileContent string) {
+	createTestFileInBucket(fileName, bucketName, []byte(fileContent))
+}
+
 func CreateTestFileWithSizeInBucket(fileName, bucketName string, fileSize int) {
 	createTestFileInBucket(fileName, bucketName, make([]byte, fileSize))
 }
 
+// CreateTestObjectInBucket uploads an object with the content to the bucket, the object name may contain slashes.
+func CreateTestObjectInBucket(objectName, bucketName, content string) {
+	f, err := os.CreateTemp("", bucketName)
+	if err != nil {
+		framework.Failf("Failed to create a test file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(content); err != nil {
+		framework.Failf("Failed to write the test file: %v", err)
+	}
+	f.Close()
+
+	//nolint:gosec
+	if output, err := exec.Command("gsutil", "cp", f.Name(), fmt.Sprintf("gs://%v/%v", bucketName, objectName)).CombinedOutput(); err != nil {
+		framework.Failf("Failed to create a test object in GCS bucket: %v, output: %s", err, output)
+	}
+}
+
 func createTestFileInBucket(fileName, bucketName string, fileContent []byte) {
 	err := os.WriteFile(fileName, fileContent, 0o600)
 	if err != nil {
@@ -1179,6 +1265,85 @@ func createTestFileInBucket(fileName, bucketName string, fileContent []byte) {
 	}
 }
 
+// CreateManySmallFilesInBucket uploads dirCount directories with filesPerDir one-byte objects each to the bucket.
+func CreateManySmallFilesInBucket(bucketName string, dirCount, filesPerDir int) {
+	tempDir, err := os.MkdirTemp("", bucketName)
+	if err != nil {
+		framework.Failf("Failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	args := []string{"-m", "-q", "cp", "-r"}
+	for i := range dirCount {
+		dirPath := filepath.Join(tempDir, fmt.Sprintf("dir-%d", i))
+		args = append(args, dirPath)
+		if err := os.Mkdir(dirPath, 0o700); err != nil {
+			framework.Failf("Failed to create a test dir: %v", err)
+		}
+
+		for j := range filesPerDir {
+			if err := os.WriteFile(filepath.Join(dirPath, fmt.Sprintf("file-%d", j)), []byte("a"), 0o600); err != nil {
+				framework.Failf("Failed to create a test file: %v", err)
+			}
+		}
+	}
+
+	//nolint:gosec
+	if output, err := exec.Command("gsutil", append(args, fmt.Sprintf("gs://%v/", bucketName))...).CombinedOutput(); err != nil {
+		framework.Failf("Failed to upload small files to GCS bucket: %v, output: %s", err, output)
+	}
+}
+
+// ReadTestFileFromBucket returns the content of the object in the GCS bucket.
+func ReadTestFileFromBucket(fileName, bucketName string) string {
+	//nolint:gosec
+	output, err := exec.Command("gsutil", "cat", fmt.Sprintf("gs://%v/%v", bucketName, fileName)).CombinedOutput()
+	if err != nil {
+		framework.Failf("Failed to read the test file from GCS bucket: %v, output: %s", err, output)
+	}
+
+	return string(output)
+}
+
+// DeleteTestFileInBucket deletes the object from the GCS bucket without going through the gcsfuse mount.
+func DeleteTestFileInBucket(fileName, bucketName string) {
+	//nolint:gosec
+	if output, err := exec.Command("gsutil", "rm", fmt.Sprintf("gs://%v/%v", bucketName, fileName)).CombinedOutput(); err != nil {
+		framework.Failf("Failed to delete the test file from GCS bucket: %v, output: %s", err, output)
+	}
+}
+
+// EnableVersioningOnBucket keeps the noncurrent object generations when the objects are overwritten.
+func EnableVersioningOnBucket(bucketName string) {
+	//nolint:gosec
+	if output, err := exec.Command("gsutil", "versioning", "set", "on", "gs://"+bucketName).CombinedOutput(); err != nil {
+		framework.Failf("Failed to enable versioning on GCS bucket: %v, output: %s", err, output)
+	}
+}
+
+// CreateManagedFolderInBucket creates an empty managed folder, which has no backing object in the bucket.
+func CreateManagedFolderInBucket(folderName, bucketName string) {
+	//nolint:gosec
+	if output, err := exec.Command("gcloud", "storage", "managed-folders", "create", fmt.Sprintf("gs://%v/%v/", bucketName, folderName)).CombinedOutput(); err != nil {
+		framework.Failf("Failed to create a managed folder in GCS bucket: %v, output: %s", err, output)
+	}
+}
+
+// DeleteBucketOutOfBand deletes the GCS bucket and its objects without going through the gcsfuse mount.
+func DeleteBucketOutOfBand(bucketName string) {
+	//nolint:gosec
+	if output, err := exec.Command("gsutil", "-m", "rm", "-r", "gs://"+bucketName).CombinedOutput(); err != nil {
+		framework.Failf("Failed to delete GCS bucket: %v, output: %s", err, output)
+	}
+}
+
+func EnableRequesterPaysOnBucket(bucketName string) {
+	//nolint:gosec
+	if output, err := exec.Command("gsutil", "requesterpays", "set", "on", "gs://"+bucketName).CombinedOutput(); err != nil {
+		framework.Failf("Failed to enable requester pays on GCS bucket: %v, output: %s", err, output)
+	}
+}
+
 func GetGCSFuseVersion(ctx context.Context, client clientset.Interface) string {
 	configMaps, err := client.CoreV1().ConfigMaps("").List(ctx, metav1.ListOptions{
 		FieldSelector: "metadata.name=gcsfusecsi-image-config",
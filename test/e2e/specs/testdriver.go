@@ -20,6 +20,7 @@ package specs
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -51,15 +52,32 @@ type GCSFuseCSITestDriver struct {
 }
 
 type gcsVolume struct {
-	bucketName              string
-	serviceAccountNamespace string
-	mountOptions            string
-	fileCacheCapacity       string
-	shared                  bool
-	readOnly                bool
-	skipBucketAccessCheck   bool
-	metadataPrefetch        bool
-	enableMetrics           bool
+	bucketName                string
+	serviceAccountNamespace   string
+	mountOptions              string
+	fileCacheCapacity         string
+	shared                    bool
+	readOnly                  bool
+	skipBucketAccessCheck     bool
+	metadataPrefetch          bool
+	enableMetrics             bool
+	cacheValidationMode       string
+	billingProject            string
+	requesterPays             bool
+	writeDurability           string
+	sharedMounter             bool
+	sourceReadOnly            bool
+	typeCacheMaxEntries       string
+	pinGeneration             bool
+	createUmask               string
+	enableEmptyManagedFolders bool
+	chunkTransferTimeout      string
+	maxRetryAttempts          string
+	cacheMedium               string
+	cacheCleanupOnUnmount     string
+	enableParallelDirops      bool
+	mountOverNonEmpty         bool
+	fsName                    string
 }
 
 // InitGCSFuseCSITestDriver returns GCSFuseCSITestDriver that implements TestDriver interface.
@@ -129,7 +147,11 @@ func (n *GCSFuseCSITestDriver) PrepareTest(ctx context.Context, f *e2eframework.
 
 	ginkgo.DeferCleanup(func() {
 		for _, v := range n.volumeStore {
-			if err := n.deleteBucket(ctx, v.bucketName); err != nil {
+			billingProject := ""
+			if v.requesterPays {
+				billingProject = n.meta.GetProjectID()
+			}
+			if err := n.deleteBucket(ctx, v.bucketName, billingProject); err != nil {
 				e2eframework.Logf("failed to delete bucket: %v", err)
 			}
 		}
@@ -155,7 +177,7 @@ func (n *GCSFuseCSITestDriver) CreateVolume(ctx context.Context, config *storage
 			bucketName = uuid.NewString()
 		case InvalidVolumePrefix, SkipCSIBucketAccessCheckAndInvalidVolumePrefix:
 			bucketName = InvalidVolume
-		case ForceNewBucketPrefix, EnableFileCacheForceNewBucketPrefix, EnableMetadataPrefetchPrefixForceNewBucketPrefix, EnableFileCacheForceNewBucketAndMetricsPrefix:
+		case ForceNewBucketPrefix, EnableFileCacheForceNewBucketPrefix, EnableMetadataPrefetchPrefixForceNewBucketPrefix, EnableFileCacheForceNewBucketAndMetricsPrefix, RequesterPaysBucketPrefix, RequesterPaysBucketWithoutBillingProjectPrefix, ManySmallFilesPrefix, ManySmallFilesWithMetadataCachePrefix, ManySmallFilesWithTypeCacheMaxEntriesPrefix:
 			bucketName = n.createBucket(ctx, config.Framework.Namespace.Name)
 		case MultipleBucketsPrefix:
 			isMultipleBucketsPrefix = true
@@ -208,6 +230,8 @@ func (n *GCSFuseCSITestDriver) CreateVolume(ctx context.Context, config *storage
 		case ImplicitDirsVolumePrefix:
 			CreateImplicitDirInBucket(ImplicitDirsPath, bucketName)
 			mountOptions += ",implicit-dirs"
+		case ImplicitDirsDisabledVolumePrefix:
+			CreateImplicitDirInBucket(ImplicitDirsPath, bucketName)
 		case SubfolderInBucketPrefix:
 			dirPath := uuid.NewString()
 			CreateImplicitDirInBucket(dirPath, bucketName)
@@ -219,6 +243,52 @@ func (n *GCSFuseCSITestDriver) CreateVolume(ctx context.Context, config *storage
 			v.enableMetrics = true
 		case EnableFileCacheWithLargeCapacityPrefix:
 			v.fileCacheCapacity = "2Gi"
+		case EnableFileCacheWithEtagValidationPrefix:
+			v.fileCacheCapacity = "100Mi"
+			v.cacheValidationMode = "etag"
+		case EnableFileCacheWithTTLValidationPrefix:
+			mountOptions += ",metadata-cache:ttl-secs:3600"
+			v.fileCacheCapacity = "100Mi"
+			v.cacheValidationMode = "ttl"
+		case EnableFileCacheWithNonRootPrefix:
+			mountOptions += ",uid=1001,gid=3003"
+			v.fileCacheCapacity = "100Mi"
+		case EnableFileCacheInMemoryPrefix:
+			v.fileCacheCapacity = "100Mi"
+			v.cacheMedium = "memory"
+		case EnableFileCacheWithCacheCleanupPrefix:
+			v.fileCacheCapacity = "100Mi"
+			v.cacheCleanupOnUnmount = "delete"
+		case WriteDurabilitySynchronousPrefix:
+			v.writeDurability = "synchronous"
+		case SharedMounterPrefix:
+			v.sharedMounter = true
+		case SourceReadOnlyPrefix:
+			v.sourceReadOnly = true
+		case PinGenerationPrefix:
+			EnableVersioningOnBucket(bucketName)
+			CreateTestFileWithContentInBucket(PinGenerationTestFileName, bucketName, "original")
+			v.pinGeneration = true
+		case CreateUmaskPrefix:
+			v.createUmask = "027"
+		case ChunkTransferTimeoutPrefix:
+			v.chunkTransferTimeout = "20"
+		case ConcurrentDiropsWithParallelDiropsPrefix:
+			v.enableParallelDirops = true
+		case MountOverNonEmptyPrefix:
+			v.mountOverNonEmpty = true
+		case FsNamePrefix:
+			v.fsName = FsNamePrefix
+		case BucketDeletedDuringMountPrefix:
+			// Send every lookup to GCS, so that the bucket deletion is observed right away.
+			mountOptions += ",metadata-cache:ttl-secs:0"
+		case MaxRetryAttemptsWithUnreachableEndpointPrefix:
+			// Nothing listens on the discard port, every GCS request fails with connection refused.
+			mountOptions += ",custom-endpoint=http://127.0.0.1:9/storage/v1/"
+			v.maxRetryAttempts = "2"
+		case EnableEmptyManagedFoldersPrefix:
+			CreateManagedFolderInBucket(EmptyManagedFolderName, bucketName)
+			v.enableEmptyManagedFolders = true
 		case SkipCSIBucketAccessCheckPrefix, SkipCSIBucketAccessCheckAndFakeVolumePrefix, SkipCSIBucketAccessCheckAndInvalidVolumePrefix:
 			v.skipBucketAccessCheck = true
 		case SkipCSIBucketAccessCheckAndInvalidMountOptionsVolumePrefix:
@@ -236,6 +306,25 @@ func (n *GCSFuseCSITestDriver) CreateVolume(ctx context.Context, config *storage
 			v.metadataPrefetch = true
 		case EnableCustomReadAhead:
 			mountOptions += ",read_ahead_kb=" + ReadAheadCustomReadAheadKb
+		case ManySmallFilesPrefix:
+			CreateManySmallFilesInBucket(bucketName, ManySmallFilesDirCount, ManySmallFilesPerDir)
+			mountOptions += ",implicit-dirs"
+		case ManySmallFilesWithMetadataCachePrefix:
+			CreateManySmallFilesInBucket(bucketName, ManySmallFilesDirCount, ManySmallFilesPerDir)
+			mountOptions += ",implicit-dirs,metadata-cache:stat-cache-max-size-mb:-1,metadata-cache:type-cache-max-size-mb:-1,metadata-cache:ttl-secs:-1"
+		case ManySmallFilesWithTypeCacheMaxEntriesPrefix:
+			CreateManySmallFilesInBucket(bucketName, ManySmallFilesDirCount, ManySmallFilesPerDir)
+			mountOptions += ",implicit-dirs,metadata-cache:stat-cache-max-size-mb:-1,metadata-cache:ttl-secs:-1"
+			v.typeCacheMaxEntries = strconv.Itoa(ManySmallFilesPerDir)
+		case RequesterPaysBucketPrefix, RequesterPaysBucketWithoutBillingProjectPrefix:
+			// The test file is created before requester pays is enabled, so the upload is not billed to a user project.
+			CreateTestFileInBucket(RequesterPaysTestFileName, bucketName)
+			EnableRequesterPaysOnBucket(bucketName)
+			v.requesterPays = true
+			if config.Prefix == RequesterPaysBucketPrefix {
+				v.billingProject = n.meta.GetProjectID()
+				n.grantServiceUsageConsumer(ctx, config.Framework.Namespace.Name)
+			}
 		case EnableMetadataPrefetchAndInvalidMountOptionsVolumePrefix:
 			mountOptions += ",file-system:kernel-list-cache-ttl-secs:-1,invalid-option"
 			v.metadataPrefetch = true
@@ -248,7 +337,7 @@ func (n *GCSFuseCSITestDriver) CreateVolume(ctx context.Context, config *storage
 		}
 
 		switch config.Prefix {
-		case "", EnableFileCachePrefix, EnableFileCacheWithLargeCapacityPrefix, EnableFileCacheAndMetricsPrefix:
+		case "", EnableFileCachePrefix, EnableFileCacheWithLargeCapacityPrefix, EnableFileCacheAndMetricsPrefix, EnableFileCacheWithEtagValidationPrefix, EnableFileCacheWithTTLValidationPrefix, EnableFileCacheWithNonRootPrefix, EnableFileCacheInMemoryPrefix, EnableFileCacheWithCacheCleanupPrefix, WriteDurabilitySynchronousPrefix, ManySmallFilesWithTypeCacheMaxEntriesPrefix, PinGenerationPrefix, ChunkTransferTimeoutPrefix, BucketDeletedDuringMountPrefix:
 			// Use config.Prefix to pass the bucket names back to the test suite.
 			config.Prefix = bucketName
 		}
@@ -284,6 +373,70 @@ func (n *GCSFuseCSITestDriver) GetPersistentVolumeSource(readOnly bool, _ string
 		va[driver.VolumeContextKeyFileCacheCapacity] = gv.fileCacheCapacity
 	}
 
+	if gv.cacheValidationMode != "" {
+		va[driver.VolumeContextKeyCacheValidationMode] = gv.cacheValidationMode
+	}
+
+	if gv.billingProject != "" {
+		va[driver.VolumeContextKeyBillingProject] = gv.billingProject
+	}
+
+	if gv.writeDurability != "" {
+		va[driver.VolumeContextKeyWriteDurability] = gv.writeDurability
+	}
+
+	if gv.sharedMounter {
+		va[driver.VolumeContextKeySharedMounter] = util.TrueStr
+	}
+
+	if gv.sourceReadOnly {
+		va[driver.VolumeContextKeySourceReadOnly] = util.TrueStr
+	}
+
+	if gv.typeCacheMaxEntries != "" {
+		va[driver.VolumeContextKeyTypeCacheMaxEntries] = gv.typeCacheMaxEntries
+	}
+
+	if gv.pinGeneration {
+		va[driver.VolumeContextKeyPinGeneration] = util.TrueStr
+	}
+
+	if gv.createUmask != "" {
+		va[driver.VolumeContextKeyCreateUmask] = gv.createUmask
+	}
+
+	if gv.enableEmptyManagedFolders {
+		va[driver.VolumeContextKeyEnableEmptyManagedFolders] = util.TrueStr
+	}
+
+	if gv.chunkTransferTimeout != "" {
+		va[driver.VolumeContextKeyChunkTransferTimeoutSeconds] = gv.chunkTransferTimeout
+	}
+
+	if gv.maxRetryAttempts != "" {
+		va[driver.VolumeContextKeyMaxRetryAttempts] = gv.maxRetryAttempts
+	}
+
+	if gv.cacheMedium != "" {
+		va[driver.VolumeContextKeyCacheMedium] = gv.cacheMedium
+	}
+
+	if gv.cacheCleanupOnUnmount != "" {
+		va[driver.VolumeContextKeyCacheCleanupOnUnmount] = gv.cacheCleanupOnUnmount
+	}
+
+	if gv.enableParallelDirops {
+		va[driver.VolumeContextKeyEnableParallelDirops] = util.TrueStr
+	}
+
+	if gv.mountOverNonEmpty {
+		va[driver.VolumeContextKeyMountOverNonEmpty] = util.TrueStr
+	}
+
+	if gv.fsName != "" {
+		va[driver.VolumeContextKeyFsName] = gv.fsName
+	}
+
 	if gv.skipBucketAccessCheck {
 		va[driver.VolumeContextKeySkipCSIBucketAccessCheck] = util.TrueStr
 	}
@@ -315,6 +468,70 @@ func (n *GCSFuseCSITestDriver) GetVolume(config *storageframework.PerTestConfig,
 		va[driver.VolumeContextKeyFileCacheCapacity] = gv.fileCacheCapacity
 	}
 
+	if gv.cacheValidationMode != "" {
+		va[driver.VolumeContextKeyCacheValidationMode] = gv.cacheValidationMode
+	}
+
+	if gv.billingProject != "" {
+		va[driver.VolumeContextKeyBillingProject] = gv.billingProject
+	}
+
+	if gv.writeDurability != "" {
+		va[driver.VolumeContextKeyWriteDurability] = gv.writeDurability
+	}
+
+	if gv.sharedMounter {
+		va[driver.VolumeContextKeySharedMounter] = util.TrueStr
+	}
+
+	if gv.sourceReadOnly {
+		va[driver.VolumeContextKeySourceReadOnly] = util.TrueStr
+	}
+
+	if gv.typeCacheMaxEntries != "" {
+		va[driver.VolumeContextKeyTypeCacheMaxEntries] = gv.typeCacheMaxEntries
+	}
+
+	if gv.pinGeneration {
+		va[driver.VolumeContextKeyPinGeneration] = util.TrueStr
+	}
+
+	if gv.createUmask != "" {
+		va[driver.VolumeContextKeyCreateUmask] = gv.createUmask
+	}
+
+	if gv.enableEmptyManagedFolders {
+		va[driver.VolumeContextKeyEnableEmptyManagedFolders] = util.TrueStr
+	}
+
+	if gv.chunkTransferTimeout != "" {
+		va[driver.VolumeContextKeyChunkTransferTimeoutSeconds] = gv.chunkTransferTimeout
+	}
+
+	if gv.maxRetryAttempts != "" {
+		va[driver.VolumeContextKeyMaxRetryAttempts] = gv.maxRetryAttempts
+	}
+
+	if gv.cacheMedium != "" {
+		va[driver.VolumeContextKeyCacheMedium] = gv.cacheMedium
+	}
+
+	if gv.cacheCleanupOnUnmount != "" {
+		va[driver.VolumeContextKeyCacheCleanupOnUnmount] = gv.cacheCleanupOnUnmount
+	}
+
+	if gv.enableParallelDirops {
+		va[driver.VolumeContextKeyEnableParallelDirops] = util.TrueStr
+	}
+
+	if gv.mountOverNonEmpty {
+		va[driver.VolumeContextKeyMountOverNonEmpty] = util.TrueStr
+	}
+
+	if gv.fsName != "" {
+		va[driver.VolumeContextKeyFsName] = gv.fsName
+	}
+
 	if gv.skipBucketAccessCheck {
 		va[driver.VolumeContextKeySkipCSIBucketAccessCheck] = util.TrueStr
 	}
@@ -453,8 +670,23 @@ func (n *GCSFuseCSITestDriver) createBucket(ctx context.Context, serviceAccountN
 	return bucket.Name
 }
 
-// deleteBucket deletes the GCS bucket.
-func (n *GCSFuseCSITestDriver) deleteBucket(ctx context.Context, bucketName string) error {
+// grantServiceUsageConsumer allows the workload identity to bill requests to the project,
+// which is required to access requester-pays buckets.
+func (n *GCSFuseCSITestDriver) grantServiceUsageConsumer(ctx context.Context, serviceAccountNamespace string) {
+	member := fmt.Sprintf("serviceAccount:%v.svc.id.goog[%v/%v]", n.meta.GetProjectID(), serviceAccountNamespace, K8sServiceAccountName)
+	if !n.skipGcpSaTest {
+		member = fmt.Sprintf("serviceAccount:%v@%v.iam.gserviceaccount.com", prepareGcpSAName(serviceAccountNamespace), n.meta.GetProjectID())
+	}
+	testGCPProjectIAMPolicyBinding := NewTestGCPProjectIAMPolicyBinding(n.meta.GetProjectID(), member, "roles/serviceusage.serviceUsageConsumer", "")
+	testGCPProjectIAMPolicyBinding.Create(ctx)
+
+	ginkgo.DeferCleanup(func() {
+		testGCPProjectIAMPolicyBinding.Cleanup(ctx)
+	})
+}
+
+// deleteBucket deletes the GCS bucket, billing the requests to the billingProject if it is set.
+func (n *GCSFuseCSITestDriver) deleteBucket(ctx context.Context, bucketName, billingProject string) error {
 	if bucketName == InvalidVolume {
 		return nil
 	}
@@ -465,7 +697,7 @@ func (n *GCSFuseCSITestDriver) deleteBucket(ctx context.Context, bucketName stri
 	}
 
 	ginkgo.By(fmt.Sprintf("Deleting bucket %q", bucketName))
-	err = storageService.DeleteBucket(ctx, &storage.ServiceBucket{Name: bucketName})
+	err = storageService.DeleteBucket(ctx, &storage.ServiceBucket{Name: bucketName, BillingProject: billingProject})
 	if err != nil {
 		return fmt.Errorf("failed to delete the GCS bucket: %w", err)
 	}
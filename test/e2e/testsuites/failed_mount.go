@@ -247,7 +247,7 @@ func (t *gcsFuseCSIFailedMountTestSuite) DefineTests(driver storageframework.Tes
 
 		ginkgo.By("Checking that the pod has failed mount error Unauthenticated")
 		tPod.WaitForFailedMountError(ctx, codes.Unauthenticated.String())
-		tPod.WaitForFailedMountError(ctx, "storage service manager failed to setup service: context deadline exceeded")
+		tPod.WaitForFailedMountError(ctx, "storage service manager failed to setup service")
 	}
 
 	ginkgo.It("should fail when the specified service account does not have access to the GCS bucket", func() {
@@ -314,6 +314,28 @@ func (t *gcsFuseCSIFailedMountTestSuite) DefineTests(driver storageframework.Tes
 		tPod.VerifyExecInPodFail(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data", mountPath), 1)
 	})
 
+	ginkgo.It("should fail after the max retry attempts when the GCS endpoint is unreachable", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		init(specs.MaxRetryAttemptsWithUnreachableEndpointPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		// Without the retry cap, gcsfuse keeps retrying the connection refused errors and the mount never fails.
+		ginkgo.By("Checking that gcsfuse gives up and the pod has failed mount error Unavailable")
+		tPod.WaitForFailedMountError(ctx, codes.Unavailable.String())
+		tPod.WaitForFailedMountError(ctx, "connection refused")
+	})
+
 	testCaseSidecarNotInjected := func(configPrefix string) {
 		init(configPrefix)
 		defer cleanup()
@@ -385,6 +407,66 @@ func (t *gcsFuseCSIFailedMountTestSuite) DefineTests(driver storageframework.Tes
 		testCaseGCSFuseOOM(specs.SkipCSIBucketAccessCheckPrefix)
 	})
 
+	ginkgo.It("should restart the sidecar and serve intact data from a recreated pod after the gcsfuse OOM", func() {
+		init()
+		defer cleanup()
+
+		ginkgo.By("Configuring the first pod")
+		tPod1 := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod1.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the first pod")
+		tPod1.Create(ctx)
+
+		ginkgo.By("Checking that the first pod is running")
+		tPod1.WaitForRunning(ctx)
+
+		ginkgo.By("Writing data to the volume")
+		tPod1.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data && grep 'hello world' %v/data", mountPath, mountPath))
+
+		ginkgo.By("Deleting the first pod")
+		tPod1.Cleanup(ctx)
+
+		ginkgo.By("Configuring the second pod with a tight sidecar memory limit")
+		tPod2 := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod2.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+		tPod2.SetCommand(fmt.Sprintf("while true; do cat %v/data > /dev/null; echo $(date) >> %v/test_file; done", mountPath, mountPath))
+		tPod2.SetAnnotations(map[string]string{
+			"gke-gcsfuse/memory-limit":   "15Mi",
+			"gke-gcsfuse/memory-request": "15Mi",
+		})
+
+		ginkgo.By("Deploying the second pod")
+		tPod2.Create(ctx)
+
+		ginkgo.By("Checking that the OOM kill is reported as a failed mount error")
+		tPod2.WaitForFailedMountError(ctx, codes.ResourceExhausted.String())
+
+		ginkgo.By("Checking that the sidecar container is restarted after the OOM kill")
+		tPod2.WaitForSidecarOOMKilledAndRestarted(ctx, supportsNativeSidecar)
+
+		// The fuse connection of the killed gcsfuse process cannot be served again,
+		// so the mount is recovered by recreating the pod with enough sidecar memory.
+		ginkgo.By("Deleting the second pod")
+		tPod2.Cleanup(ctx)
+
+		ginkgo.By("Configuring the third pod with the default sidecar memory")
+		tPod3 := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod3.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the third pod")
+		tPod3.Create(ctx)
+		defer tPod3.Cleanup(ctx)
+
+		ginkgo.By("Checking that the third pod is running")
+		tPod3.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the mount is usable and the data is intact")
+		tPod3.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep rw,", mountPath))
+		tPod3.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep 'hello world' %v/data", mountPath))
+		tPod3.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello again' > %v/data2 && grep 'hello again' %v/data2", mountPath, mountPath))
+	})
+
 	testcaseInvalidMountOptions := func(configPrefix string) {
 		init(configPrefix)
 		defer cleanup()
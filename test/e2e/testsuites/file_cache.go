@@ -20,10 +20,14 @@ package testsuites
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/util"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
 	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/kubernetes/test/e2e/framework"
@@ -87,6 +91,19 @@ func (t *gcsFuseCSIFileCacheTestSuite) DefineTests(driver storageframework.TestD
 		framework.ExpectNoError(err, "while cleaning up")
 	}
 
+	// cachedObjectPath returns the path of a cached object in the cache volume mounted at /cache,
+	// following the file cache layout of the sidecar gcsfuse version.
+	cachedObjectPath := func(cacheSubfolder, bucketName, fileName string) string {
+		if gcsfuseVersionStr == "" {
+			gcsfuseVersionStr = specs.GetGCSFuseVersion(ctx, f.ClientSet)
+		}
+
+		path, err := util.FileCacheObjectPath(filepath.Join("/cache/.volumes", cacheSubfolder), util.FileCacheLayoutVersion("v"+gcsfuseVersionStr), bucketName, fileName)
+		framework.ExpectNoError(err)
+
+		return path
+	}
+
 	ginkgo.It("should cache the data", func() {
 		init(specs.EnableFileCachePrefix)
 		defer cleanup()
@@ -119,7 +136,131 @@ func (t *gcsFuseCSIFileCacheTestSuite) DefineTests(driver storageframework.TestD
 		ginkgo.By("Checking that the pod command exits with no error")
 		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep rw,", mountPath))
 		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
-		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' /cache/.volumes/%v/gcsfuse-file-cache/%v/%v", fileName, cacheSubfolder, bucketName, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cachedObjectPath(cacheSubfolder, bucketName, fileName)))
+	})
+
+	ginkgo.It("should cache the objects with near-maximum-length keys", func() {
+		init(specs.EnableFileCachePrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+
+		// Object names are limited to 1024 bytes, and each path component to the 255 bytes NAME_MAX of the kernel,
+		// so the keys are built from the longest components that fit in both limits.
+		longestName := strings.Repeat("n", 255)
+		deepObjectName := strings.Repeat(strings.Repeat("d", 203)+"/", 4) + strings.Repeat("f", 203)
+		gomega.Expect(deepObjectName).To(gomega.HaveLen(1019))
+		objectNames := []string{longestName, deepObjectName}
+		for _, objectName := range objectNames {
+			specs.CreateTestObjectInBucket(objectName, bucketName, fmt.Sprintf("content-%d", len(objectName)))
+		}
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+		// Mount the gcsfuse cache volume to the test container
+		tPod.SetupCacheVolumeMount("/cache")
+
+		cacheSubfolder := volumeName
+		if l.volumeResource.Pv != nil {
+			cacheSubfolder = l.volumeResource.Pv.Name
+		}
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		for _, objectName := range objectNames {
+			content := fmt.Sprintf("content-%d", len(objectName))
+
+			ginkgo.By(fmt.Sprintf("Reading the object with a %d bytes key", len(objectName)))
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v/%v", content, mountPath, objectName))
+
+			ginkgo.By(fmt.Sprintf("Checking that the object with a %d bytes key is cached", len(objectName)))
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", content, cachedObjectPath(cacheSubfolder, bucketName, objectName)))
+		}
+
+		ginkgo.By("Checking that a file with a near-maximum-length key can be written and read back")
+		writtenObjectName := strings.Repeat(strings.Repeat("w", 203)+"/", 4) + strings.Repeat("g", 203)
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mkdir -p %v/%v && echo 'written' > %v/%v", mountPath, filepath.Dir(writtenObjectName), mountPath, writtenObjectName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep 'written' %v/%v", mountPath, writtenObjectName))
+	})
+
+	ginkgo.It("should cache the data written by a non-root user", func() {
+		init(specs.EnableFileCacheWithNonRootPrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+		// Mount the gcsfuse cache volume to the test container
+		tPod.SetupCacheVolumeMount("/cache")
+		tPod.SetNonRootSecurityContext(1001, 2002, 3003)
+
+		cacheSubfolder := volumeName
+		if l.volumeResource.Pv != nil {
+			cacheSubfolder = l.volumeResource.Pv.Name
+		}
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the cache dir is owned by the Pod user and fsGroup")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("stat -c '%%u %%g' /cache/.volumes/%v | grep '1001 3003'", cacheSubfolder))
+
+		ginkgo.By("Checking that the non-root user can write and read through the cache")
+		fileName := uuid.NewString()
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo '%v' > %v/%v", fileName, mountPath, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v/%v", fileName, mountPath, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cachedObjectPath(cacheSubfolder, bucketName, fileName)))
+	})
+
+	ginkgo.It("should cache the data in memory when the cache medium is memory", func() {
+		init(specs.EnableFileCacheInMemoryPrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+
+		// Create files using gsutil
+		fileName := uuid.NewString()
+		specs.CreateTestFileInBucket(fileName, bucketName)
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+		// Mount the gcsfuse cache volume to the test container
+		tPod.SetupCacheVolumeMount("/cache")
+
+		cacheSubfolder := volumeName
+		if l.volumeResource.Pv != nil {
+			cacheSubfolder = l.volumeResource.Pv.Name
+		}
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the cache volume is memory-backed")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, "mount | grep ' /cache ' | grep tmpfs")
+
+		ginkgo.By("Checking that the pod command exits with no error")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cachedObjectPath(cacheSubfolder, bucketName, fileName)))
 	})
 
 	ginkgo.It("should cache the data using custom cache volume", func() {
@@ -160,7 +301,144 @@ func (t *gcsFuseCSIFileCacheTestSuite) DefineTests(driver storageframework.TestD
 		ginkgo.By("Checking that the pod command exits with no error")
 		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep rw,", mountPath))
 		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
-		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' /cache/.volumes/%v/gcsfuse-file-cache/%v/%v", fileName, cacheSubfolder, bucketName, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cachedObjectPath(cacheSubfolder, bucketName, fileName)))
+	})
+
+	ginkgo.It("should serve the files with the custom cache volume of a deleted pod", func() {
+		init(specs.EnableFileCachePrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+
+		// Create files using gsutil
+		fileName := uuid.NewString()
+		specs.CreateTestFileInBucket(fileName, bucketName)
+
+		tPVC := specs.NewTestPVC(f.ClientSet, f.Namespace, "custom-cache", "standard-rwo", "5Gi", corev1.ReadWriteOnce)
+
+		cacheSubfolder := volumeName
+		if l.volumeResource.Pv != nil {
+			cacheSubfolder = l.volumeResource.Pv.Name
+		}
+		cacheFilePath := cachedObjectPath(cacheSubfolder, bucketName, fileName)
+
+		newPod := func() *specs.TestPod {
+			tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+			tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+			tPod.SetupVolume(&storageframework.VolumeResource{Pvc: tPVC.PVC}, webhook.SidecarContainerCacheVolumeName, "", false)
+			tPod.SetupCacheVolumeMount("/cache")
+			tPod.SetNonRootSecurityContext(0, 0, 1000)
+
+			return tPod
+		}
+
+		ginkgo.By("Creating the PVC")
+		tPVC.Create(ctx)
+		defer tPVC.Cleanup(ctx)
+
+		ginkgo.By("Deploying the first pod")
+		tPod1 := newPod()
+		tPod1.Create(ctx)
+
+		ginkgo.By("Checking that the first pod is running")
+		tPod1.WaitForRunning(ctx)
+
+		ginkgo.By("Reading the file to fill the cache")
+		tPod1.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
+		tPod1.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cacheFilePath))
+
+		ginkgo.By("Deleting the first pod")
+		tPod1.Cleanup(ctx)
+		tPod1.WaitForPodNotFoundInNamespace(ctx)
+
+		ginkgo.By("Deploying the second pod with the same cache PVC")
+		tPod2 := newPod()
+		tPod2.Create(ctx)
+		defer tPod2.Cleanup(ctx)
+
+		ginkgo.By("Checking that the second pod is running")
+		tPod2.WaitForRunning(ctx)
+
+		// gcsfuse does not guarantee to reuse the cache files left by the previous mount,
+		// so only check that the file is served and cached with the cache dir of the deleted pod in place.
+		ginkgo.By("Checking that the file is served and cached by the second pod")
+		tPod2.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
+		tPod2.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cacheFilePath))
+	})
+
+	testCaseCacheCleanup := func(configPrefix string, expectCacheDirRemoved bool) {
+		init(configPrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+
+		// Create files using gsutil
+		fileName := uuid.NewString()
+		specs.CreateTestFileInBucket(fileName, bucketName)
+
+		tPVC := specs.NewTestPVC(f.ClientSet, f.Namespace, "custom-cache", "standard-rwo", "5Gi", corev1.ReadWriteOnce)
+
+		cacheSubfolder := volumeName
+		if l.volumeResource.Pv != nil {
+			cacheSubfolder = l.volumeResource.Pv.Name
+		}
+		cacheFilePath := cachedObjectPath(cacheSubfolder, bucketName, fileName)
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+		tPod.SetupVolume(&storageframework.VolumeResource{Pvc: tPVC.PVC}, webhook.SidecarContainerCacheVolumeName, "", false)
+		tPod.SetupCacheVolumeMount("/cache")
+		tPod.SetNonRootSecurityContext(0, 0, 1000)
+
+		ginkgo.By("Creating the PVC")
+		tPVC.Create(ctx)
+		defer tPVC.Cleanup(ctx)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Reading the file to fill the cache")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cacheFilePath))
+
+		ginkgo.By("Deleting the pod")
+		tPod.Cleanup(ctx)
+		tPod.WaitForPodNotFoundInNamespace(ctx)
+
+		ginkgo.By("Deploying a pod inspecting the cache PVC without the gcsfuse volume")
+		tInspectorPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tInspectorPod.SetAnnotations(map[string]string{
+			"gke-gcsfuse/volumes": "false",
+		})
+		tInspectorPod.SetupVolume(&storageframework.VolumeResource{Pvc: tPVC.PVC}, "custom-cache", "/cache", false)
+		tInspectorPod.Create(ctx)
+		defer tInspectorPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the inspector pod is running")
+		tInspectorPod.WaitForRunning(ctx)
+
+		if expectCacheDirRemoved {
+			ginkgo.By("Checking that no cache dir is left in the cache PVC")
+			tInspectorPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("test ! -e /cache/.volumes/%v", cacheSubfolder))
+			tInspectorPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, `test -z "$(ls -A /cache/.volumes)"`)
+		} else {
+			ginkgo.By("Checking that the cached file is retained in the cache PVC")
+			tInspectorPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cacheFilePath))
+		}
+	}
+
+	ginkgo.It("should retain the cache dir in the custom cache volume after the pod is deleted", func() {
+		testCaseCacheCleanup(specs.EnableFileCachePrefix, false)
+	})
+
+	ginkgo.It("should remove the cache dir from the custom cache volume after the pod is deleted when the cache cleanup policy is delete", func() {
+		testCaseCacheCleanup(specs.EnableFileCacheWithCacheCleanupPrefix, true)
 	})
 
 	ginkgo.It("should cache the data using in-memory custom cache volume", func() {
@@ -202,7 +480,51 @@ func (t *gcsFuseCSIFileCacheTestSuite) DefineTests(driver storageframework.TestD
 		ginkgo.By("Checking that the pod command exits with no error")
 		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep rw,", mountPath))
 		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, fileName))
-		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' /cache/.volumes/%v/gcsfuse-file-cache/%v/%v", fileName, cacheSubfolder, bucketName, fileName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep '%v' %v", fileName, cachedObjectPath(cacheSubfolder, bucketName, fileName)))
+	})
+
+	testCaseCacheValidation := func(configPrefix string, expectRefetch bool) {
+		init(configPrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+
+		// Create files using gsutil
+		fileName := uuid.NewString()
+		specs.CreateTestFileWithContentInBucket(fileName, bucketName, "original-content")
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Reading the object to populate the file cache")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep 'original-content' %v/%v", mountPath, fileName))
+
+		ginkgo.By("Overwriting the object in the bucket")
+		specs.CreateTestFileWithContentInBucket(fileName, bucketName, "updated-content")
+
+		ginkgo.By("Checking the content served from the mount")
+		if expectRefetch {
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep 'updated-content' %v/%v", mountPath, fileName))
+		} else {
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep 'original-content' %v/%v", mountPath, fileName))
+		}
+	}
+
+	ginkgo.It("should refetch the changed object when the cache validation mode is etag", func() {
+		testCaseCacheValidation(specs.EnableFileCacheWithEtagValidationPrefix, true)
+	})
+
+	ginkgo.It("should serve the stale object before the TTL expires when the cache validation mode is ttl", func() {
+		testCaseCacheValidation(specs.EnableFileCacheWithTTLValidationPrefix, false)
 	})
 
 	ginkgo.It("should not cache the data when the file cache is disabled", func() {
@@ -0,0 +1,237 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuites
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	e2evolume "k8s.io/kubernetes/test/e2e/framework/volume"
+	storageframework "k8s.io/kubernetes/test/e2e/storage/framework"
+	admissionapi "k8s.io/pod-security-admission/api"
+	"local/test/e2e/specs"
+)
+
+// manySmallFilesListingThreshold is the maximum time a full recursive listing of the many small files bucket may take.
+const manySmallFilesListingThreshold = 10 * time.Minute
+
+// The concurrent directory operations workload runs diropsWorkers shell loops,
+// each creating and listing diropsPerWorker sub-directories in its own directory.
+const (
+	diropsWorkers   = 16
+	diropsPerWorker = 20
+)
+
+type gcsFuseCSIManySmallFilesTestSuite struct {
+	tsInfo storageframework.TestSuiteInfo
+}
+
+// InitGcsFuseCSIManySmallFilesTestSuite returns gcsFuseCSIManySmallFilesTestSuite that implements TestSuite interface.
+func InitGcsFuseCSIManySmallFilesTestSuite() storageframework.TestSuite {
+	return &gcsFuseCSIManySmallFilesTestSuite{
+		tsInfo: storageframework.TestSuiteInfo{
+			Name: "manySmallFiles",
+			TestPatterns: []storageframework.TestPattern{
+				storageframework.DefaultFsPreprovisionedPV,
+			},
+		},
+	}
+}
+
+func (t *gcsFuseCSIManySmallFilesTestSuite) GetTestSuiteInfo() storageframework.TestSuiteInfo {
+	return t.tsInfo
+}
+
+func (t *gcsFuseCSIManySmallFilesTestSuite) SkipUnsupportedTests(_ storageframework.TestDriver, _ storageframework.TestPattern) {
+}
+
+func (t *gcsFuseCSIManySmallFilesTestSuite) DefineTests(driver storageframework.TestDriver, pattern storageframework.TestPattern) {
+	type local struct {
+		config         *storageframework.PerTestConfig
+		volumeResource *storageframework.VolumeResource
+	}
+	var l local
+	ctx := context.Background()
+
+	// Beware that it also registers an AfterEach which renders f unusable. Any code using
+	// f must run inside an It or Context callback.
+	f := framework.NewFrameworkWithCustomTimeouts("many-small-files", storageframework.GetDriverTimeouts(driver))
+	f.NamespacePodSecurityEnforceLevel = admissionapi.LevelPrivileged
+
+	init := func(configPrefix ...string) {
+		l = local{}
+		l.config = driver.PrepareTest(ctx, f)
+		if len(configPrefix) > 0 {
+			l.config.Prefix = configPrefix[0]
+		}
+		l.volumeResource = storageframework.CreateVolumeResource(ctx, driver, l.config, pattern, e2evolume.SizeRange{})
+	}
+
+	cleanup := func() {
+		var cleanUpErrs []error
+		cleanUpErrs = append(cleanUpErrs, l.volumeResource.CleanupResource(ctx))
+		err := utilerrors.NewAggregate(cleanUpErrs)
+		framework.ExpectNoError(err, "while cleaning up")
+	}
+
+	testCaseListManySmallFiles := func(configPrefix string) {
+		init(configPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetResource("1", "1Gi", "5Gi")
+		// The metadata caches of the sidecar container grow with the number of objects.
+		tPod.SetAnnotations(map[string]string{
+			"gke-gcsfuse/memory-limit": "1Gi",
+		})
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		wantFileCount := specs.ManySmallFilesDirCount * specs.ManySmallFilesPerDir
+		listAndVerify := func(cmd string) time.Duration {
+			start := time.Now()
+			output := tPod.VerifyExecInPodSucceedWithOutput(f, specs.TesterContainerName, cmd)
+			elapsed := time.Since(start)
+
+			gotFileCount, err := strconv.Atoi(strings.TrimSpace(output))
+			framework.ExpectNoError(err)
+			gomega.Expect(gotFileCount).To(gomega.Equal(wantFileCount))
+			gomega.Expect(elapsed).To(gomega.BeNumerically("<", manySmallFilesListingThreshold))
+
+			return elapsed
+		}
+
+		ginkgo.By("Listing all the files with find")
+		findDuration := listAndVerify(fmt.Sprintf("find %v -type f | wc -l", mountPath))
+
+		ginkgo.By("Listing all the files with ls -R")
+		lsDuration := listAndVerify(fmt.Sprintf("ls -lR %v | grep -c '^-'", mountPath))
+
+		framework.Logf("Listing %d small files with %q took %v with find and %v with ls -lR", wantFileCount, configPrefix, findDuration, lsDuration)
+	}
+
+	testCaseConcurrentDirops := func(configPrefix string) time.Duration {
+		init(configPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Creating and listing directories concurrently")
+		cmd := fmt.Sprintf("cd %v && for i in $(seq %d); do (for j in $(seq %d); do mkdir -p dir-$i/sub-$j && ls dir-$i > /dev/null || exit 1; done) & done; wait", mountPath, diropsWorkers, diropsPerWorker)
+		start := time.Now()
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, cmd)
+		elapsed := time.Since(start)
+
+		ginkgo.By("Checking that all the directories are created")
+		output := tPod.VerifyExecInPodSucceedWithOutput(f, specs.TesterContainerName, fmt.Sprintf("ls -d %v/dir-*/sub-* | wc -l", mountPath))
+		gotDirCount, err := strconv.Atoi(strings.TrimSpace(output))
+		framework.ExpectNoError(err)
+		gomega.Expect(gotDirCount).To(gomega.Equal(diropsWorkers * diropsPerWorker))
+
+		return elapsed
+	}
+
+	ginkgo.It("should run concurrent directory operations faster with the parallel directory operations", func() {
+		if gcsfuseVersionStr == "" {
+			gcsfuseVersionStr = specs.GetGCSFuseVersion(ctx, f.ClientSet)
+		}
+		v, err := version.ParseSemantic(gcsfuseVersionStr)
+		if err != nil || !v.AtLeast(version.MustParseSemantic("v3.0.0")) {
+			e2eskipper.Skipf("skip for gcsfuse version %v, the parallel directory operations require gcsfuse v3.0.0 or later", gcsfuseVersionStr)
+		}
+
+		serialDuration := testCaseConcurrentDirops(specs.ConcurrentDiropsPrefix)
+		parallelDuration := testCaseConcurrentDirops(specs.ConcurrentDiropsWithParallelDiropsPrefix)
+
+		framework.Logf("Creating and listing %d directories with %d workers took %v without and %v with the parallel directory operations", diropsWorkers*diropsPerWorker, diropsWorkers, serialDuration, parallelDuration)
+		gomega.Expect(parallelDuration).To(gomega.BeNumerically("<=", serialDuration))
+	})
+
+	ginkgo.It("should list many small files within the threshold", func() {
+		testCaseListManySmallFiles(specs.ManySmallFilesPrefix)
+	})
+
+	ginkgo.It("should list many small files within the threshold with stat and type cache tuning", func() {
+		testCaseListManySmallFiles(specs.ManySmallFilesWithMetadataCachePrefix)
+	})
+
+	ginkgo.It("should serve the metadata of a large directory from the cache with typeCacheMaxEntries", func() {
+		init(specs.ManySmallFilesWithTypeCacheMaxEntriesPrefix)
+		defer cleanup()
+
+		// the bucket name is passed back by the test driver using l.config.Prefix
+		bucketName := l.config.Prefix
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetResource("1", "1Gi", "5Gi")
+		tPod.SetAnnotations(map[string]string{
+			"gke-gcsfuse/memory-limit": "1Gi",
+		})
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Listing a large directory to populate the metadata caches")
+		output := tPod.VerifyExecInPodSucceedWithOutput(f, specs.TesterContainerName, fmt.Sprintf("ls -l %v/dir-0 | grep -c '^-'", mountPath))
+		gotFileCount, err := strconv.Atoi(strings.TrimSpace(output))
+		framework.ExpectNoError(err)
+		gomega.Expect(gotFileCount).To(gomega.Equal(specs.ManySmallFilesPerDir))
+
+		ginkgo.By("Deleting an object from the bucket directly")
+		specs.DeleteTestFileInBucket("dir-0/file-0", bucketName)
+
+		ginkgo.By("Checking that the deleted object is still served from the metadata cache")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("test -f %v/dir-0/file-0", mountPath))
+
+		ginkgo.By("Listing the large directory again within the threshold")
+		start := time.Now()
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("ls -l %v/dir-0 > /dev/null", mountPath))
+		gomega.Expect(time.Since(start)).To(gomega.BeNumerically("<", manySmallFilesListingThreshold))
+	})
+}
@@ -245,6 +245,42 @@ func (t *gcsFuseCSIMultiVolumeTestSuite) DefineTests(driver storageframework.Tes
 		testOnePodTwoVols()
 	})
 
+	// This tests below configuration, where one gcsfuse process serves all the volumes:
+	//               [pod1]
+	//          /      |      \
+	//   [volume1] [volume2] [volume3]
+	//          \      |      /
+	//              [bucket1]
+	ginkgo.It("should serve multiple read-only volumes backed by the same bucket from one gcsfuse process when sharedMounter is enabled", func() {
+		// Different pre-provisioned PVs of the same bucket are treated as the same volume, see the test above.
+		if pattern.VolType == storageframework.PreprovisionedPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.PreprovisionedPV)
+		}
+
+		init(3, specs.SharedMounterPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		for i, vr := range l.volumeResourceList {
+			tPod.SetupVolume(vr, fmt.Sprintf("%v-%v", volumeName, i), fmt.Sprintf("%v/%v", mountPath, i), true)
+		}
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that all the volumes are served by the same fuse connection")
+		// Each gcsfuse process serves one fuse connection, which has its own device number.
+		for i := range l.volumeResourceList {
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v/%v | grep ro,", mountPath, i))
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("[ $(stat -c %%d %v/0) = $(stat -c %%d %v/%v) ] && ls %v/%v", mountPath, mountPath, i, mountPath, i))
+		}
+	})
+
 	// This tests below configuration:
 	//          [pod1]
 	//          /    \
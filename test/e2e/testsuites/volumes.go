@@ -23,8 +23,10 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/googlecloudplatform/gcs-fuse-csi-driver/pkg/webhook"
 	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
@@ -257,6 +259,28 @@ func (t *gcsFuseCSIVolumesTestSuite) DefineTests(driver storageframework.TestDri
 		testCaseStoreRetainData(specs.SkipCSIBucketAccessCheckAndNonRootVolumePrefix, 1001, 2002, 0)
 	})
 
+	ginkgo.It("[non-root] should grant the supplemental group of the pod access to the volume", func() {
+		init("")
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetNonRootSecurityContext(1001, 0, 0)
+		tPod.SetSupplementalGroups(2002)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the pod command exits with no error")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data", mountPath))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("[ $(stat -c %%g %v/data) = 2002 ] && grep 'hello world' %v/data", mountPath, mountPath))
+	})
+
 	ginkgo.It("[fsgroup delegation] should store data and retain the data", func() {
 		testCaseStoreRetainData("", 1001, 2002, 3003)
 	})
@@ -306,6 +330,285 @@ func (t *gcsFuseCSIVolumesTestSuite) DefineTests(driver storageframework.TestDri
 		testCaseImplicitDir(specs.SkipCSIBucketAccessCheckAndImplicitDirsVolumePrefix)
 	})
 
+	testCaseImplicitDirTraversal := func(configPrefix string, traversable bool) {
+		init(configPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking the implicit directory traversal")
+		cmd := fmt.Sprintf("cd %v/%v && ls", mountPath, specs.ImplicitDirsPath)
+		if traversable {
+			tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, cmd)
+		} else {
+			tPod.VerifyExecInPodFail(f, specs.TesterContainerName, cmd, 1)
+		}
+	}
+	ginkgo.It("should not traverse implicit directory when implicit dirs are disabled", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		testCaseImplicitDirTraversal(specs.ImplicitDirsDisabledVolumePrefix, false)
+	})
+	ginkgo.It("should traverse implicit directory when implicit dirs are enabled", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		testCaseImplicitDirTraversal(specs.ImplicitDirsVolumePrefix, true)
+	})
+
+	testCaseRequesterPays := func(configPrefix string, billingProjectSet bool) {
+		init(configPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		if !billingProjectSet {
+			ginkgo.By("Checking that the pod has failed mount error")
+			tPod.WaitForFailedMountError(ctx, "requester pays")
+
+			return
+		}
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the pod command exits with no error")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("cat %v/%v", mountPath, specs.RequesterPaysTestFileName))
+	}
+	ginkgo.It("should read data from a requester-pays bucket when billingProject is set", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		testCaseRequesterPays(specs.RequesterPaysBucketPrefix, true)
+	})
+	ginkgo.It("should fail to mount a requester-pays bucket when billingProject is not set", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		testCaseRequesterPays(specs.RequesterPaysBucketWithoutBillingProjectPrefix, false)
+	})
+
+	ginkgo.It("should persist the data to GCS when the file is closed in synchronous write durability mode", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		init(specs.WriteDurabilitySynchronousPrefix)
+		defer cleanup()
+
+		// The test driver uses config.Prefix to pass the bucket names back to the test suite.
+		bucketName := l.config.Prefix
+		fileName := uuid.NewString()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the volume is mounted with the sync option")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep sync", mountPath))
+
+		ginkgo.By("Writing a file and checking that the object is in the bucket right after the file is closed")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/%v", mountPath, fileName))
+		gomega.Expect(specs.ReadTestFileFromBucket(fileName, bucketName)).To(gomega.Equal("hello world\n"))
+	})
+
+	ginkgo.It("should fail when write to a writable mount in source read-only mode", func() {
+		init(specs.SourceReadOnlyPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the volume is mounted writable but gcsfuse refuses the writes")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep rw,", mountPath))
+		tPod.VerifyExecInPodFail(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data", mountPath), 1)
+		tPod.VerifyExecInPodFail(f, specs.TesterContainerName, fmt.Sprintf("mkdir %v/dir", mountPath), 1)
+	})
+
+	ginkgo.It("should keep serving the pinned generation when the object is overwritten", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		init(specs.PinGenerationPrefix)
+		defer cleanup()
+
+		// the bucket name is passed back by the test driver using l.config.Prefix
+		bucketName := l.config.Prefix
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Reading the object to pin its generation")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep -x original %v/%v", mountPath, specs.PinGenerationTestFileName))
+
+		ginkgo.By("Overwriting the live object in the bucket")
+		specs.CreateTestFileWithContentInBucket(specs.PinGenerationTestFileName, bucketName, "overwritten")
+
+		ginkgo.By("Checking that the pinned content does not change")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("grep -x original %v/%v", mountPath, specs.PinGenerationTestFileName))
+
+		ginkgo.By("Checking that the writes are refused")
+		tPod.VerifyExecInPodFail(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data", mountPath), 1)
+	})
+
+	ginkgo.It("should fail the reads when the bucket is deleted during the mount", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		init(specs.BucketDeletedDuringMountPrefix)
+		defer cleanup()
+
+		// the bucket name is passed back by the test driver using l.config.Prefix
+		bucketName := l.config.Prefix
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Writing and reading a file before the bucket is deleted")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data && grep 'hello world' %v/data", mountPath, mountPath))
+
+		ginkgo.By("Deleting the bucket out of band")
+		specs.DeleteBucketOutOfBand(bucketName)
+
+		ginkgo.By("Checking that the reads fail instead of hanging")
+		tPod.VerifyExecInPodFail(f, specs.TesterContainerName, fmt.Sprintf("timeout 60 cat %v/data", mountPath), 1)
+
+		ginkgo.By("Checking that the sidecar container reports the missing bucket")
+		tPod.WaitForLog(ctx, webhook.GcsFuseSidecarName, "The specified bucket does not exist")
+
+		ginkgo.By("Checking that the sidecar container keeps running")
+		tPod.WaitForRunning(ctx)
+	})
+
+	ginkgo.It("should report the file mode with the create umask applied", func() {
+		init(specs.CreateUmaskPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Creating a file and checking its permissions")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data && stat -c %%a %v/data | grep -x 640", mountPath, mountPath))
+	})
+
+	ginkgo.It("should upload a large file in chunks with the chunk transfer timeout", func() {
+		init(specs.ChunkTransferTimeoutPrefix)
+		defer cleanup()
+
+		// the bucket name is passed back by the test driver using l.config.Prefix
+		bucketName := l.config.Prefix
+		fileName := "chunk-transfer-timeout-test-file"
+		fileSizeMiB := 64
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		// The stalled chunks cannot be induced in the test cluster network,
+		// the test verifies that the chunked upload completes with the timeout configured.
+		ginkgo.By("Writing a large file uploaded in multiple chunks")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("dd if=/dev/zero of=%v/%v bs=1M count=%v", mountPath, fileName, fileSizeMiB))
+
+		ginkgo.By("Checking that the object is fully uploaded to the bucket")
+		gomega.Expect(specs.ReadTestFileFromBucket(fileName, bucketName)).To(gomega.HaveLen(fileSizeMiB * 1024 * 1024))
+	})
+
+	ginkgo.It("should list the empty managed folders", func() {
+		if pattern.VolType == storageframework.DynamicPV {
+			e2eskipper.Skipf("skip for volume type %v", storageframework.DynamicPV)
+		}
+
+		if !hnsEnabled(driver) {
+			e2eskipper.Skipf("skip for the buckets without hierarchical namespace")
+		}
+
+		init(specs.EnableEmptyManagedFoldersPrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the empty managed folder is listed")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("ls %v | grep -x %v", mountPath, specs.EmptyManagedFolderName))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("[ -d %v/%v ]", mountPath, specs.EmptyManagedFolderName))
+	})
+
 	testCaseStoreDataCustomContainerImage := func(configPrefix string) {
 		init(configPrefix)
 		defer cleanup()
@@ -393,6 +696,58 @@ func (t *gcsFuseCSIVolumesTestSuite) DefineTests(driver storageframework.TestDri
 		testCaseCustomBufferVol(specs.SkipCSIBucketAccessCheckPrefix)
 	})
 
+	ginkgo.It("should mount over a directory with existing files", func() {
+		init(specs.MountOverNonEmptyPrefix)
+		defer cleanup()
+
+		const dataPath = "/data"
+		bucketMountPath := dataPath + "/bucket"
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		existingData := &storageframework.VolumeResource{
+			VolSource: &corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}
+		tPod.SetupVolume(existingData, "existing-data", dataPath, false)
+		tPod.SetupVolume(l.volumeResource, volumeName, bucketMountPath, false)
+		tPod.SetInitContainerWithCommand(fmt.Sprintf("mkdir -p %v && echo 'existing data' > %v/existing-file", bucketMountPath, bucketMountPath))
+		tPod.SetupVolumeForInitContainer("existing-data", dataPath, false, "")
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the volume hides the existing files")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep %v | grep rw,", bucketMountPath))
+		tPod.VerifyExecInPodFail(f, specs.TesterContainerName, fmt.Sprintf("test -e %v/existing-file", bucketMountPath), 1)
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("echo 'hello world' > %v/data && grep 'hello world' %v/data", bucketMountPath, bucketMountPath))
+	})
+
+	ginkgo.It("should show the fsName as the mount source", func() {
+		init(specs.FsNamePrefix)
+		defer cleanup()
+
+		ginkgo.By("Configuring the pod")
+		tPod := specs.NewTestPod(f.ClientSet, f.Namespace)
+		tPod.SetupVolume(l.volumeResource, volumeName, mountPath, false)
+
+		ginkgo.By("Deploying the pod")
+		tPod.Create(ctx)
+		defer tPod.Cleanup(ctx)
+
+		ginkgo.By("Checking that the pod is running")
+		tPod.WaitForRunning(ctx)
+
+		ginkgo.By("Checking that the mount table shows the fsName")
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("mount | grep '^%v on %v type fuse'", specs.FsNamePrefix, mountPath))
+		tPod.VerifyExecInPodSucceed(f, specs.TesterContainerName, fmt.Sprintf("df %v | grep '^%v'", mountPath, specs.FsNamePrefix))
+	})
+
 	testCaseStoreDataInitContainer := func(configPrefix string) {
 		init(configPrefix)
 		defer cleanup()